@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image/color"
+	"os"
+	"strings"
+
+	"fortio.org/terminal/ansipixels"
+	"fortio.org/terminal/ansipixels/tcolor"
+	"github.com/ansipixels/trophy/math3d"
+	"github.com/ansipixels/trophy/models"
+)
+
+// checkDefects is the exit status of the check command for meshes that are
+// not printable.
+const checkDefects = 3
+
+// checkCommand implements "trophy check [flags] <model>".
+func checkCommand(args []string) int {
+	fset := flag.NewFlagSet("check", flag.ContinueOnError)
+	limit := fset.Int("limit", 100, "Maximum number of locations listed per defect, 0 for all")
+	fset.Usage = func() {
+		fmt.Fprintf(fset.Output(), "Usage: trophy check [flags] <model>\n\n"+
+			"Checks whether a model is printable and prints a JSON report of its defects: non-manifold edges,\n"+
+			"holes, inconsistently wound, degenerate and duplicate faces and self-intersections.\n"+
+			"Exits with status %d when the model has defects.\nFlags:\n", checkDefects)
+		fset.PrintDefaults()
+	}
+	positional, err := parseArgs(fset, args)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case err != nil:
+		return 2 // already reported by the flag set
+	case len(positional) != 1:
+		fmt.Fprintf(os.Stderr, "trophy check: expected a model file, got %d arguments\n", len(positional))
+		fset.Usage()
+		return 2
+	}
+	input := positional[0]
+	fsys, fsPath, err := selectFilesystem(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "trophy check: %v\n", err)
+		return 1
+	}
+	mesh, err := loadMesh(fsys, fsPath, 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "trophy check: load %s: %v\n", input, err)
+		return 1
+	}
+	report := models.Analyze(mesh)
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(newCheckResult(input, mesh, report, *limit)); err != nil {
+		fmt.Fprintf(os.Stderr, "trophy check: %v\n", err)
+		return 1
+	}
+	if !report.Printable() {
+		return checkDefects
+	}
+	return 0
+}
+
+// checkResult is the JSON output of the check command.
+type checkResult struct {
+	File              string                  `json:"file"`
+	Triangles         int                     `json:"triangles"`
+	Vertices          int                     `json:"vertices"`
+	Printable         bool                    `json:"printable"`
+	Watertight        bool                    `json:"watertight"`
+	Holes             int                     `json:"holes"`
+	NonManifoldEdges  checkList[checkEdge]    `json:"nonManifoldEdges"`
+	BoundaryEdges     checkList[checkEdge]    `json:"boundaryEdges"`
+	InconsistentFaces checkList[checkFace]    `json:"inconsistentFaces"`
+	DegenerateFaces   checkList[checkFace]    `json:"degenerateFaces"`
+	DuplicateFaces    checkList[checkFace]    `json:"duplicateFaces"`
+	SelfIntersections checkList[[2]checkFace] `json:"selfIntersections"`
+}
+
+// checkList is a defect count with the first locations.
+type checkList[T any] struct {
+	Count int `json:"count"`
+	Items []T `json:"items,omitempty"`
+}
+
+// checkEdge locates an edge by its end points and faces.
+type checkEdge struct {
+	From  [3]float64 `json:"from"`
+	To    [3]float64 `json:"to"`
+	Faces []int      `json:"faces"`
+}
+
+// checkFace locates a face by its index and center.
+type checkFace struct {
+	Face   int        `json:"face"`
+	Center [3]float64 `json:"center"`
+}
+
+// newCheckResult converts a report, listing up to limit locations per defect
+// (0 for all).
+func newCheckResult(file string, mesh *models.Mesh, r *models.MeshReport, limit int) checkResult {
+	face := func(i int) checkFace {
+		f := mesh.Faces[i]
+		center := mesh.Vertices[f.V[0]].Position.Add(mesh.Vertices[f.V[1]].Position).
+			Add(mesh.Vertices[f.V[2]].Position).Scale(1.0 / 3)
+		return checkFace{Face: i, Center: vec3Array(center)}
+	}
+	edge := func(e models.ReportEdge) checkEdge {
+		return checkEdge{From: vec3Array(e.From), To: vec3Array(e.To), Faces: e.Faces}
+	}
+	pair := func(p [2]int) [2]checkFace {
+		return [2]checkFace{face(p[0]), face(p[1])}
+	}
+	return checkResult{
+		File:              file,
+		Triangles:         r.Triangles,
+		Vertices:          r.Vertices,
+		Printable:         r.Printable(),
+		Watertight:        r.Watertight(),
+		Holes:             r.Holes,
+		NonManifoldEdges:  newCheckList(r.NonManifoldEdges, limit, edge),
+		BoundaryEdges:     newCheckList(r.BoundaryEdges, limit, edge),
+		InconsistentFaces: newCheckList(r.InconsistentFaces, limit, face),
+		DegenerateFaces:   newCheckList(r.DegenerateFaces, limit, face),
+		DuplicateFaces:    newCheckList(r.DuplicateFaces, limit, face),
+		SelfIntersections: newCheckList(r.SelfIntersections, limit, pair),
+	}
+}
+
+func newCheckList[S, T any](items []S, limit int, convert func(S) T) checkList[T] {
+	list := checkList[T]{Count: len(items)}
+	for i, item := range items {
+		if limit > 0 && i >= limit {
+			break
+		}
+		list.Items = append(list.Items, convert(item))
+	}
+	return list
+}
+
+func vec3Array(v math3d.Vec3) [3]float64 {
+	return [3]float64{v.X, v.Y, v.Z}
+}
+
+// Colors of the defects in the viewer's analysis overlay.
+var (
+	boundaryColor     = color.RGBA{255, 220, 0, 255}   // Yellow
+	nonManifoldColor  = color.RGBA{255, 0, 255, 255}   // Magenta
+	inconsistentColor = color.RGBA{255, 128, 0, 255}   // Orange
+	intersectionColor = color.RGBA{255, 0, 0, 255}     // Red
+	duplicateColor    = color.RGBA{0, 200, 255, 255}   // Cyan
+	degenerateColor   = color.RGBA{255, 255, 255, 255} // White
+)
+
+// analysisOverlay returns the defects of a report as a mesh of colored lines
+// (edges and face outlines) and points (degenerate faces), to draw over the
+// analyzed mesh.
+func analysisOverlay(mesh *models.Mesh, r *models.MeshReport) *models.Mesh {
+	overlay := models.NewMesh("analysis")
+	overlay.VertexColors = true
+	vertex := func(p math3d.Vec3, c color.RGBA) int {
+		overlay.Vertices = append(overlay.Vertices, models.MeshVertex{Position: p, Color: c})
+		return len(overlay.Vertices) - 1
+	}
+	line := func(p0, p1 math3d.Vec3, c color.RGBA) {
+		overlay.Lines = append(overlay.Lines, models.Line{V: [2]int{vertex(p0, c), vertex(p1, c)}, Material: -1})
+	}
+	outline := func(face int, c color.RGBA) {
+		f := mesh.Faces[face]
+		for k := range 3 {
+			line(mesh.Vertices[f.V[k]].Position, mesh.Vertices[f.V[(k+1)%3]].Position, c)
+		}
+	}
+	for _, f := range r.InconsistentFaces {
+		outline(f, inconsistentColor)
+	}
+	for _, f := range r.DuplicateFaces {
+		outline(f, duplicateColor)
+	}
+	for _, p := range r.SelfIntersections {
+		outline(p[0], intersectionColor)
+		outline(p[1], intersectionColor)
+	}
+	for _, e := range r.BoundaryEdges {
+		line(e.From, e.To, boundaryColor)
+	}
+	for _, e := range r.NonManifoldEdges {
+		line(e.From, e.To, nonManifoldColor)
+	}
+	for _, face := range r.DegenerateFaces {
+		p := mesh.Vertices[mesh.Faces[face].V[0]].Position
+		overlay.Points = append(overlay.Points, models.Point{V: vertex(p, degenerateColor), Material: -1})
+	}
+	return overlay
+}
+
+// animating reports whether the model is animated, which pauses the
+// analysis: it is only redone once the pose holds still.
+func (h *HUD) animating() bool {
+	return len(h.clips) > 0 && h.state.Animation.Playing
+}
+
+// drawAnalysis renders the legend of the analysis overlay.
+func (h *HUD) drawAnalysis(ap *ansipixels.AnsiPixels) {
+	r := h.analysis
+	if !h.state.Analysis {
+		return
+	}
+	if h.animating() {
+		ap.WriteRight(1, "analysis paused while the animation plays (P)")
+		return
+	}
+	if r == nil {
+		return
+	}
+	if r.Printable() {
+		ap.WriteRight(1, "%s✓ printable%s", tcolor.Green.Foreground(), tcolor.Reset)
+		return
+	}
+	var parts []string
+	add := func(c color.RGBA, count int, format string, args ...any) {
+		if count > 0 {
+			parts = append(parts, tcolor.RGBColor{R: c.R, G: c.G, B: c.B}.Foreground()+fmt.Sprintf(format, args...))
+		}
+	}
+	add(boundaryColor, len(r.BoundaryEdges), "%d boundary edges (%d holes)", len(r.BoundaryEdges), r.Holes)
+	add(nonManifoldColor, len(r.NonManifoldEdges), "%d non-manifold edges", len(r.NonManifoldEdges))
+	add(inconsistentColor, len(r.InconsistentFaces), "%d flipped faces", len(r.InconsistentFaces))
+	add(intersectionColor, len(r.SelfIntersections), "%d intersecting pairs", len(r.SelfIntersections))
+	add(duplicateColor, len(r.DuplicateFaces), "%d duplicate faces", len(r.DuplicateFaces))
+	add(degenerateColor, len(r.DegenerateFaces), "%d degenerate faces", len(r.DegenerateFaces))
+	ap.WriteRight(1, "%s%s", strings.Join(parts, "  "), tcolor.Reset)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ansipixels/trophy/math3d"
+	"github.com/ansipixels/trophy/models"
+)
+
+func TestCheckCommand(t *testing.T) {
+	closed := boxMesh(math3d.V3(10, 10, 10))
+	open := boxMesh(math3d.V3(10, 10, 10))
+	open.Faces = open.Faces[:len(open.Faces)-1]
+	for _, tt := range []struct {
+		name      string
+		mesh      *models.Mesh
+		limit     string
+		status    int
+		holes     int
+		boundary  int
+		locations int
+	}{
+		{"closed.stl", closed, "100", 0, 0, 0, 0},
+		{"open.stl", open, "100", checkDefects, 1, 3, 3},
+		{"open.stl", open, "2", checkDefects, 1, 3, 2},
+		{"open.stl", open, "0", checkDefects, 1, 3, 3},
+	} {
+		status, output := runCommand(t, checkCommand, "-limit", tt.limit, saveModel(t, tt.name, tt.mesh))
+		if status != tt.status {
+			t.Errorf("check %s: status %d, want %d", tt.name, status, tt.status)
+		}
+		var result checkResult
+		if err := json.Unmarshal(output, &result); err != nil {
+			t.Fatalf("check %s: %v: %s", tt.name, err, output)
+		}
+		if result.Printable != (tt.status == 0) || result.Watertight != (tt.holes == 0) || result.Holes != tt.holes ||
+			result.BoundaryEdges.Count != tt.boundary || len(result.BoundaryEdges.Items) != tt.locations {
+			t.Errorf("check %s -limit %s: %+v", tt.name, tt.limit, result)
+		}
+		for _, e := range result.BoundaryEdges.Items {
+			if len(e.Faces) != 1 {
+				t.Errorf("check %s: boundary edge with faces %v", tt.name, e.Faces)
+			}
+		}
+	}
+}
+
+func TestCheckCommandErrors(t *testing.T) {
+	for _, tt := range []struct {
+		args   []string
+		status int
+	}{
+		{nil, 2},
+		{[]string{"-limit", "many", "cube.stl"}, 2},
+		{[]string{"missing.stl"}, 1},
+	} {
+		if status, _ := runCommand(t, checkCommand, tt.args...); status != tt.status {
+			t.Errorf("check %q: status %d, want %d", tt.args, status, tt.status)
+		}
+	}
+}
+
+func TestNewCheckResult(t *testing.T) {
+	mesh := boxMesh(math3d.V3(3, 3, 3))
+	mesh.Faces = append(mesh.Faces, mesh.Faces[0])
+	result := newCheckResult("box", mesh, models.Analyze(mesh), 100)
+	if result.Printable || result.DuplicateFaces.Count != 1 {
+		t.Fatalf("duplicate faces %+v", result.DuplicateFaces)
+	}
+	// The copy of the bottom triangle {0, 3, 2}
+	f := result.DuplicateFaces.Items[0]
+	if f.Face != 12 || f.Center != [3]float64{1, 2, 0} {
+		t.Errorf("duplicate face %+v, want face 12 centered on 1, 2, 0", f)
+	}
+}
+
+func TestAnalysisOverlay(t *testing.T) {
+	mesh := boxMesh(math3d.V3(1, 1, 1))
+	if overlay := analysisOverlay(mesh, models.Analyze(mesh)); len(overlay.Lines)+len(overlay.Points) != 0 {
+		t.Errorf("overlay of a printable mesh: %d lines, %d points", len(overlay.Lines), len(overlay.Points))
+	}
+	mesh.Faces = mesh.Faces[1:]
+	overlay := analysisOverlay(mesh, models.Analyze(mesh))
+	if len(overlay.Lines) != 3 || len(overlay.Points) != 0 {
+		t.Errorf("overlay of a mesh with a hole: %d lines, %d points", len(overlay.Lines), len(overlay.Points))
+	}
+	for _, v := range overlay.Vertices {
+		if v.Color != boundaryColor {
+			t.Errorf("boundary overlay color %v", v.Color)
+		}
+	}
+}
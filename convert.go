@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"fortio.org/log"
+	"github.com/ansipixels/trophy/math3d"
+	"github.com/ansipixels/trophy/models"
+)
+
+// subcommands run instead of the viewer when named by the first argument.
+var subcommands = map[string]func(args []string) int{
+	"check":   checkCommand,
+	"convert": convertCommand,
+	"info":    infoCommand,
+}
+
+// lengthUnits are the length suffixes, in millimeters.
+var lengthUnits = map[string]float64{"mm": 1, "cm": 10, "m": 1000, "in": 25.4}
+
+// parseLength parses a length with an optional unit suffix ("100mm", "4in"),
+// returned in millimeters with mm set. A bare number is returned as is.
+func parseLength(s string) (length float64, mm bool, err error) {
+	s = strings.TrimSpace(s)
+	number := strings.TrimSpace(strings.TrimRightFunc(s, unicode.IsLetter))
+	scale := 1.0
+	if unit := s[len(strings.TrimRightFunc(s, unicode.IsLetter)):]; unit != "" {
+		size, ok := lengthUnits[strings.ToLower(unit)]
+		if !ok {
+			return 0, false, fmt.Errorf("unknown unit %q (use mm, cm, m or in)", unit)
+		}
+		scale, mm = size, true
+	}
+	v, err := strconv.ParseFloat(number, 64)
+	if err != nil || v <= 0 || math.IsInf(v, 0) {
+		return 0, false, fmt.Errorf("invalid length %q", s)
+	}
+	return v * scale, mm, nil
+}
+
+// parseArgs parses flags placed anywhere among the positional arguments,
+// which it returns.
+func parseArgs(fset *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fset.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fset.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// convertOptions are the processing steps of the convert command, applied in
+// this order after loading.
+type convertOptions struct {
+	mergeTolerance float64 // STL vertex merging tolerance
+	clean          bool    // Remove degenerate, internal and duplicate faces
+	repair         bool    // Weld T-junctions, unify and orient the winding, fill holes
+	simplify       int     // Target triangle count, 0 to keep them all
+	smooth         bool    // Recompute smooth vertex normals
+	center         bool    // Move the bounding box center to the origin
+	scaleTo        float64 // Largest bounding box dimension, 0 to keep the size
+	scaleToMM      bool    // scaleTo is in millimeters, else in model units
+}
+
+// convertCommand implements "trophy convert [flags] <input> <output>".
+func convertCommand(args []string) int {
+	fset := flag.NewFlagSet("convert", flag.ContinueOnError)
+	var opts convertOptions
+	fset.Float64Var(&opts.mergeTolerance, "merge-tolerance", 0, "STL input: merge vertices closer than this (0 = exact)")
+	fset.BoolVar(&opts.clean, "clean", false, "Remove degenerate, internal and duplicate faces")
+	fset.BoolVar(&opts.repair, "repair", false,
+		"Weld T-junctions, make the face winding consistent and outward, and fill holes")
+	fset.IntVar(&opts.simplify, "simplify", 0,
+		"Simplify to about this many `triangles`, preserving borders, seams and attributes (0 = keep all)")
+	fset.BoolVar(&opts.smooth, "smooth", false, "Recompute smooth vertex normals")
+	fset.BoolVar(&opts.center, "center", false, "Center the model's bounding box on the origin")
+	scaleTo := fset.String("scale-to", "",
+		"Scale uniformly so the largest dimension is this `length`: a number in model units, or with a mm, cm, m or in\n"+
+			"suffix (models are in millimeters, glTF ones in meters)")
+	fset.Usage = func() {
+		fmt.Fprintf(fset.Output(), "Usage: trophy convert [flags] <input> <output.stl|output.obj|output.ply|output.glb>\n\n"+
+			"Converts a model, which can be any format the viewer loads, optionally processing it.\nFlags:\n")
+		fset.PrintDefaults()
+	}
+	positional, err := parseArgs(fset, args)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case err != nil:
+		return 2 // already reported by the flag set
+	case len(positional) != 2:
+		fmt.Fprintf(os.Stderr, "trophy convert: expected an input and an output file, got %d arguments\n", len(positional))
+		fset.Usage()
+		return 2
+	}
+	if opts.simplify < 0 {
+		fmt.Fprintf(os.Stderr, "trophy convert: -simplify: invalid triangle count %d\n", opts.simplify)
+		return 2
+	}
+	if *scaleTo != "" {
+		if opts.scaleTo, opts.scaleToMM, err = parseLength(*scaleTo); err != nil {
+			fmt.Fprintf(os.Stderr, "trophy convert: -scale-to: %v\n", err)
+			return 2
+		}
+	}
+	if err := convert(positional[0], positional[1], opts); err != nil {
+		fmt.Fprintf(os.Stderr, "trophy convert: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// convert loads input, processes it and writes it to output.
+func convert(input, output string, opts convertOptions) error {
+	fsys, fsPath, err := selectFilesystem(input)
+	if err != nil {
+		return err
+	}
+	mesh, err := loadMesh(fsys, fsPath, opts.mergeTolerance)
+	if err != nil {
+		return fmt.Errorf("load %s: %w", input, err)
+	}
+	if len(mesh.Faces)+len(mesh.Lines)+len(mesh.Points) == 0 {
+		return fmt.Errorf("%s has no geometry", input)
+	}
+	log.Infof("Loaded %s: %d vertices, %d triangles", input, mesh.VertexCount(), mesh.TriangleCount())
+	if opts.clean {
+		log.Infof("Cleaned: removed %d faces", mesh.CleanMesh())
+	}
+	if opts.repair {
+		stats := mesh.Repair(models.RepairOptions{
+			WeldTJunctions: true, UnifyWinding: true, FillHoles: true, OrientOutward: true,
+		})
+		log.Infof("Repaired: welded %d T-junctions, flipped %d faces, filled %d holes with %d faces",
+			stats.TJunctions, stats.FlippedFaces, stats.FilledHoles, stats.AddedFaces)
+	}
+	if opts.simplify > 0 {
+		start := time.Now()
+		removed := mesh.Simplify(opts.simplify)
+		log.Infof("Simplified: removed %d faces, %d left, in %v", removed, mesh.TriangleCount(), time.Since(start))
+	}
+	if opts.smooth {
+		mesh.CalculateSmoothNormals()
+	}
+	mesh.CalculateBounds()
+	if opts.center {
+		mesh.Transform(math3d.Translate(mesh.Center().Negate()))
+	}
+	if opts.scaleTo > 0 {
+		size := mesh.Size()
+		largest := math.Max(size.X, math.Max(size.Y, size.Z))
+		if largest == 0 {
+			return errors.New("cannot scale a model without extent")
+		}
+		target := opts.scaleTo
+		if opts.scaleToMM {
+			target /= mesh.UnitSize()
+		}
+		mesh.Transform(math3d.ScaleUniform(target / largest))
+	}
+	if err := models.SaveMesh(output, mesh); err != nil {
+		return err
+	}
+	log.Infof("Wrote %s: %d vertices, %d triangles", output, mesh.VertexCount(), mesh.TriangleCount())
+	return nil
+}
+
+// loadMesh loads a model as one mesh: STL files with the merge tolerance,
+// and scenes flattened.
+func loadMesh(fsys fs.FS, fsPath string, mergeTolerance float64) (*models.Mesh, error) {
+	format, err := models.DetectFormat(fsys, fsPath)
+	if err != nil {
+		return nil, err
+	}
+	if format.Name == "STL" {
+		data, err := fs.ReadFile(fsys, fsPath)
+		if err != nil {
+			return nil, err
+		}
+		loader := models.NewSTLLoader()
+		loader.MergeTolerance = mergeTolerance
+		return loader.LoadBytes(data, fsPath)
+	}
+	mesh, scene, _, err := format.Load(fsys, fsPath)
+	if err != nil {
+		return nil, err
+	}
+	if scene != nil {
+		mesh = scene.Flatten()
+	}
+	if mesh.Unit == 0 {
+		mesh.Unit = format.Unit
+	}
+	return mesh, nil
+}
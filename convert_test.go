@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ansipixels/trophy/math3d"
+	"github.com/ansipixels/trophy/models"
+)
+
+func TestParseLength(t *testing.T) {
+	for _, tt := range []struct {
+		s      string
+		length float64
+		mm     bool
+	}{
+		{"100mm", 100, true},
+		{"2.5 cm", 25, true},
+		{" 1M ", 1000, true},
+		{"4in", 101.6, true},
+		{"2.5", 2.5, false},
+		{"1e3", 1000, false},
+	} {
+		length, mm, err := parseLength(tt.s)
+		if err != nil || !near(length, tt.length) || mm != tt.mm {
+			t.Errorf("parseLength(%q) = %g, %v, %v, want %g, %v", tt.s, length, mm, err, tt.length, tt.mm)
+		}
+	}
+	for _, s := range []string{"", "mm", "0", "-1cm", "10ft", "ten", "inf", "1e400"} {
+		if length, _, err := parseLength(s); err == nil {
+			t.Errorf("parseLength(%q) = %g, want an error", s, length)
+		}
+	}
+}
+
+// loadFile loads a local model as one mesh.
+func loadFile(t *testing.T, path string) *models.Mesh {
+	t.Helper()
+	fsys, name, err := selectFilesystem(path)
+	if err != nil {
+		t.Fatalf("select %s: %v", path, err)
+	}
+	mesh, err := loadMesh(fsys, name, 0)
+	if err != nil {
+		t.Fatalf("load %s: %v", path, err)
+	}
+	mesh.CalculateBounds()
+	return mesh
+}
+
+func TestConvertScaleTo(t *testing.T) {
+	input := saveModel(t, "box.stl", boxMesh(math3d.V3(10, 20, 5)))
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		output, scaleTo string
+		center          bool
+		size            math3d.Vec3 // In the output units
+		min             math3d.Vec3
+	}{
+		{"same.stl", "", false, math3d.V3(10, 20, 5), math3d.V3(0, 0, 0)},
+		{"mm.stl", "100mm", false, math3d.V3(50, 100, 25), math3d.V3(0, 0, 0)},
+		{"bare.stl", "40", true, math3d.V3(20, 40, 10), math3d.V3(-10, -20, -5)},
+		{"mm.glb", "100mm", false, math3d.V3(0.05, 0.1, 0.025), math3d.V3(0, 0, 0)},
+		{"inches.glb", "1in", true, math3d.V3(0.0127, 0.0254, 0.00635), math3d.V3(-0.00635, -0.0127, -0.003175)},
+		{"same.glb", "", false, math3d.V3(0.01, 0.02, 0.005), math3d.V3(0, 0, 0)},
+	} {
+		args := []string{input, filepath.Join(dir, tt.output)}
+		if tt.scaleTo != "" {
+			args = append([]string{"-scale-to", tt.scaleTo}, args...)
+		}
+		if tt.center {
+			args = append([]string{"-center"}, args...)
+		}
+		if status := convertCommand(args); status != 0 {
+			t.Fatalf("convert %q: status %d", args, status)
+		}
+		mesh := loadFile(t, filepath.Join(dir, tt.output))
+		if mesh.TriangleCount() != 12 {
+			t.Errorf("%s: %d triangles", tt.output, mesh.TriangleCount())
+		}
+		assertSize := func(what string, got, want math3d.Vec3) {
+			if !near(got.X, want.X) || !near(got.Y, want.Y) || !near(got.Z, want.Z) {
+				t.Errorf("%s: %s %v, want %v", tt.output, what, got, want)
+			}
+		}
+		assertSize("size", mesh.Size(), tt.size)
+		assertSize("min", mesh.BoundsMin, tt.min)
+	}
+}
+
+func TestConvertProcessing(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "teapot.stl")
+	if err := os.WriteFile(input, teapotSTL(t), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "teapot.ply")
+	if status := convertCommand([]string{"-clean", "-repair", "-simplify", "2000", "-smooth", input, output}); status != 0 {
+		t.Fatalf("convert: status %d", status)
+	}
+	mesh := loadFile(t, output)
+	if n := mesh.TriangleCount(); n < 1500 || n > 2500 {
+		t.Errorf("simplified to %d triangles, want about 2000", n)
+	}
+	if r := models.Analyze(mesh); r.Holes != 0 || len(r.NonManifoldEdges) != 0 {
+		t.Errorf("repaired teapot: %d holes, %d non-manifold edges", r.Holes, len(r.NonManifoldEdges))
+	}
+}
+
+func TestConvertCommandErrors(t *testing.T) {
+	input := saveModel(t, "box.stl", boxMesh(math3d.V3(1, 1, 1)))
+	empty := saveModel(t, "empty.stl", models.NewMesh("empty"))
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		args   []string
+		status int
+	}{
+		{[]string{input}, 2},
+		{[]string{"-simplify", "-1", input, filepath.Join(dir, "out.stl")}, 2},
+		{[]string{"-scale-to", "10ft", input, filepath.Join(dir, "out.stl")}, 2},
+		{[]string{input, filepath.Join(dir, "out.fbx")}, 1},
+		{[]string{filepath.Join(dir, "missing.stl"), filepath.Join(dir, "out.stl")}, 1},
+		{[]string{empty, filepath.Join(dir, "out.stl")}, 1},
+	} {
+		if status := convertCommand(tt.args); status != tt.status {
+			t.Errorf("convert %q: status %d, want %d", tt.args, status, tt.status)
+		}
+	}
+}
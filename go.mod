@@ -7,6 +7,7 @@ require (
 	fortio.org/log v1.18.3
 	fortio.org/terminal v0.64.1
 	github.com/charmbracelet/harmonica v0.2.0
+	github.com/klauspost/compress v1.18.2
 	github.com/qmuntal/gltf v0.28.0
 )
 
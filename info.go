@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/ansipixels/trophy/models"
+)
+
+// unitsUsage describes the -units flag of the viewer and the info command.
+const unitsUsage = "Unit of the reported lengths, areas and volumes: mm, cm, m or in " +
+	"(model coordinates are taken as millimeters, glTF ones as meters)"
+
+// unitSize returns the size of a reporting unit in millimeters.
+func unitSize(units string) (float64, error) {
+	mm, ok := lengthUnits[strings.ToLower(units)]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q (use mm, cm, m or in)", units)
+	}
+	return mm, nil
+}
+
+// properties are the physical properties of a mesh, for a density of 1.
+type properties struct {
+	Units    string        `json:"units"`
+	Volume   float64       `json:"volume"`      // Cubic units, negative for inside out meshes
+	Area     float64       `json:"surfaceArea"` // Square units
+	Centroid [3]float64    `json:"centroid"`
+	Inertia  [3][3]float64 `json:"inertia"` // About the centroid, units⁵ (multiply by the density)
+}
+
+// meshProperties measures a mesh, scale being the number of reporting units
+// per mesh coordinate unit (see models.Mesh.UnitSize).
+func meshProperties(mesh *models.Mesh, units string, scale float64) properties {
+	p := properties{
+		Units:    strings.ToLower(units),
+		Volume:   mesh.SignedVolume() * scale * scale * scale,
+		Area:     mesh.SurfaceArea() * scale * scale,
+		Centroid: vec3Array(mesh.Centroid().Scale(scale)),
+		Inertia:  mesh.InertiaTensor(),
+	}
+	scale5 := math.Pow(scale, 5)
+	for i := range 3 {
+		for j := range 3 {
+			p.Inertia[i][j] *= scale5
+		}
+	}
+	return p
+}
+
+// summary formats the volume and area for the HUD.
+func (p properties) summary() string {
+	return fmt.Sprintf("%s %s³  %s %s²", formatMeasure(p.Volume), p.Units, formatMeasure(p.Area), p.Units)
+}
+
+// formatMeasure formats a measure with 3 significant digits, or none after
+// the decimal point from 100 on.
+func formatMeasure(v float64) string {
+	if math.Abs(v) >= 100 {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.3g", v)
+}
+
+// infoCommand implements "trophy info [flags] <model>".
+func infoCommand(args []string) int {
+	fset := flag.NewFlagSet("info", flag.ContinueOnError)
+	units := fset.String("units", "mm", unitsUsage)
+	fset.Usage = func() {
+		fmt.Fprintf(fset.Output(), "Usage: trophy info [flags] <model>\n\n"+
+			"Prints the size and physical properties of a model as JSON: bounds, signed volume, surface area,\n"+
+			"centroid and inertia tensor (about the centroid, for a density of 1). The volume is only\n"+
+			"meaningful for closed meshes (see trophy check).\nFlags:\n")
+		fset.PrintDefaults()
+	}
+	positional, err := parseArgs(fset, args)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case err != nil:
+		return 2 // already reported by the flag set
+	case len(positional) != 1:
+		fmt.Fprintf(os.Stderr, "trophy info: expected a model file, got %d arguments\n", len(positional))
+		fset.Usage()
+		return 2
+	}
+	unitMM, err := unitSize(*units)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "trophy info: -units: %v\n", err)
+		return 2
+	}
+	input := positional[0]
+	fsys, fsPath, err := selectFilesystem(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "trophy info: %v\n", err)
+		return 1
+	}
+	mesh, err := loadMesh(fsys, fsPath, 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "trophy info: load %s: %v\n", input, err)
+		return 1
+	}
+	mesh.CalculateBounds()
+	scale := mesh.UnitSize() / unitMM
+	result := infoResult{
+		File:      input,
+		Triangles: mesh.TriangleCount(),
+		Vertices:  mesh.VertexCount(),
+		Bounds: infoBounds{
+			Min:  vec3Array(mesh.BoundsMin.Scale(scale)),
+			Max:  vec3Array(mesh.BoundsMax.Scale(scale)),
+			Size: vec3Array(mesh.Size().Scale(scale)),
+		},
+		properties: meshProperties(mesh, *units, scale),
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		fmt.Fprintf(os.Stderr, "trophy info: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// infoResult is the JSON output of the info command, lengths in its units.
+type infoResult struct {
+	File      string     `json:"file"`
+	Triangles int        `json:"triangles"`
+	Vertices  int        `json:"vertices"`
+	Bounds    infoBounds `json:"bounds"`
+	properties
+}
+
+// infoBounds is the bounding box computed by CalculateBounds.
+type infoBounds struct {
+	Min  [3]float64 `json:"min"`
+	Max  [3]float64 `json:"max"`
+	Size [3]float64 `json:"size"`
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ansipixels/trophy/math3d"
+	"github.com/ansipixels/trophy/models"
+)
+
+// boxMesh returns a closed box with a corner at the origin.
+func boxMesh(size math3d.Vec3) *models.Mesh {
+	mesh := models.NewMesh("box")
+	for i := range 8 {
+		p := math3d.V3(float64(i&1)*size.X, float64(i>>1&1)*size.Y, float64(i>>2&1)*size.Z)
+		mesh.Vertices = append(mesh.Vertices, models.MeshVertex{Position: p})
+	}
+	// Quads counter clockwise seen from outside, reversed into engine winding
+	for _, q := range [][4]int{{0, 2, 3, 1}, {4, 5, 7, 6}, {0, 1, 5, 4}, {2, 6, 7, 3}, {0, 4, 6, 2}, {1, 3, 7, 5}} {
+		mesh.Faces = append(mesh.Faces, models.Face{V: [3]int{q[0], q[2], q[1]}}, models.Face{V: [3]int{q[0], q[3], q[2]}})
+	}
+	mesh.CalculateBounds()
+	return mesh
+}
+
+// saveModel saves a mesh in a temporary directory and returns its path.
+func saveModel(t *testing.T, name string, mesh *models.Mesh) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := models.SaveMesh(path, mesh); err != nil {
+		t.Fatalf("save %s: %v", name, err)
+	}
+	return path
+}
+
+// runCommand runs a subcommand and returns its exit status and standard
+// output.
+func runCommand(t *testing.T, command func([]string) int, args ...string) (int, []byte) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := os.Stdout
+	os.Stdout = w
+	output := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- data
+	}()
+	status := command(args)
+	os.Stdout = saved
+	w.Close()
+	return status, <-output
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-6*math.Max(1, math.Abs(b))
+}
+
+func TestMeshProperties(t *testing.T) {
+	// A 20 mm cube reported in cm: a 2 cm cube
+	p := meshProperties(boxMesh(math3d.V3(20, 20, 20)), "CM", 0.1)
+	if p.Units != "cm" || !near(p.Volume, 8) || !near(p.Area, 24) {
+		t.Errorf("units %q, volume %g, area %g, want cm, 8, 24", p.Units, p.Volume, p.Area)
+	}
+	if !near(p.Centroid[0], 1) || !near(p.Centroid[1], 1) || !near(p.Centroid[2], 1) {
+		t.Errorf("centroid %v, want 1, 1, 1", p.Centroid)
+	}
+	// Cube of side a and density 1: a⁵/6 on the diagonal
+	for i := range 3 {
+		for j := range 3 {
+			want := 0.0
+			if i == j {
+				want = 32.0 / 6
+			}
+			if !near(p.Inertia[i][j], want) {
+				t.Errorf("inertia[%d][%d] = %g, want %g", i, j, p.Inertia[i][j], want)
+			}
+		}
+	}
+	if s := p.summary(); s != "8 cm³  24 cm²" {
+		t.Errorf("summary %q", s)
+	}
+}
+
+func TestInfoCommand(t *testing.T) {
+	// The same 1 m cube, in millimeters in STL and in meters in glTF
+	cube := boxMesh(math3d.V3(1000, 1000, 1000))
+	for _, model := range []string{saveModel(t, "cube.stl", cube), saveModel(t, "cube.glb", cube)} {
+		for _, tt := range []struct {
+			units          string
+			size, volume   float64
+			area, centroid float64
+		}{
+			{"mm", 1000, 1e9, 6e6, 500},
+			{"m", 1, 1, 6, 0.5},
+			{"in", 1000 / 25.4, 1e9 / (25.4 * 25.4 * 25.4), 6e6 / (25.4 * 25.4), 500 / 25.4},
+		} {
+			status, output := runCommand(t, infoCommand, "-units", tt.units, model)
+			if status != 0 {
+				t.Fatalf("info %s in %s: status %d", filepath.Base(model), tt.units, status)
+			}
+			var result infoResult
+			if err := json.Unmarshal(output, &result); err != nil {
+				t.Fatalf("info %s: %v: %s", filepath.Base(model), err, output)
+			}
+			if result.Triangles != 12 || result.Units != tt.units || !near(result.Bounds.Size[0], tt.size) ||
+				!near(result.Volume, tt.volume) || !near(result.Area, tt.area) || !near(result.Centroid[2], tt.centroid) {
+				t.Errorf("info %s in %s: %+v", filepath.Base(model), tt.units, result)
+			}
+		}
+	}
+}
+
+func TestInfoCommandErrors(t *testing.T) {
+	model := saveModel(t, "cube.stl", boxMesh(math3d.V3(1, 1, 1)))
+	for _, tt := range []struct {
+		args   []string
+		status int
+	}{
+		{nil, 2},
+		{[]string{model, model}, 2},
+		{[]string{"-units", "ft", model}, 2},
+		{[]string{"-bogus", model}, 2},
+		{[]string{filepath.Join(filepath.Dir(model), "missing.stl")}, 1},
+	} {
+		if status, _ := runCommand(t, infoCommand, tt.args...); status != tt.status {
+			t.Errorf("info %q: status %d, want %d", tt.args, status, tt.status)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"fortio.org/log"
+	"github.com/ansipixels/trophy/math3d"
+	"github.com/ansipixels/trophy/models"
+	"github.com/ansipixels/trophy/render"
+)
+
+const (
+	lodMinTriangles      = 2000 // Meshes below are drawn as is
+	lodTrianglesPerPixel = 2.0  // Detail worth drawing: about a front and a back face per pixel
+	lodRenderShare       = 0.5  // Share of the frame period for rasterization, the rest for the terminal
+	lodMinDetail         = 0.001
+	lodFinerMargin       = 0.75 // A finer level must fit in this share of the budget, against flickering
+)
+
+// lodChain is a mesh and its simplified levels of detail, each with about
+// half the triangles of the previous one. The levels are built in the
+// background and become available as they are done.
+type lodChain struct {
+	mu      sync.Mutex
+	levels  []*models.Mesh
+	current int
+	center  math3d.Vec3
+	radius  float64 // Bounding sphere
+}
+
+// newLODChain starts simplifying a mesh, which must not change afterwards.
+func newLODChain(mesh *models.Mesh) *lodChain {
+	mesh.CalculateBounds()
+	c := &lodChain{
+		levels: []*models.Mesh{mesh},
+		center: mesh.Center(),
+		radius: mesh.Size().Len() / 2,
+	}
+	go c.build()
+	return c
+}
+
+func (c *lodChain) build() {
+	level := c.levels[0]
+	for level.TriangleCount() > lodMinTriangles {
+		start := time.Now()
+		next := level.Clone()
+		next.Simplify(level.TriangleCount() / 2)
+		if next.TriangleCount() > level.TriangleCount()*3/4 {
+			break // Held by seams and corners
+		}
+		next.CalculateBounds()
+		next.BuildBVH()
+		log.LogVf("LOD %s: %d triangles in %v", next.Name, next.TriangleCount(), time.Since(start))
+		c.mu.Lock()
+		c.levels = append(c.levels, next)
+		c.mu.Unlock()
+		level = next
+	}
+}
+
+// level returns the finest level within budget triangles, or the coarsest
+// one. Going back to a finer level takes some margin.
+func (c *lodChain) level(budget float64) *models.Mesh {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := c.current
+	for i < len(c.levels)-1 && float64(c.levels[i].TriangleCount()) > budget {
+		i++
+	}
+	for i > 0 && float64(c.levels[i-1].TriangleCount()) <= budget*lodFinerMargin {
+		i--
+	}
+	c.current = i
+	return c.levels[i]
+}
+
+// lodSelector picks the levels of detail drawn each frame (-lod): no more
+// triangles than the projected size of a mesh can show, and fewer when
+// rasterizing them does not fit the -fps frame period.
+type lodSelector struct {
+	chains map[*models.Mesh]*lodChain
+	detail float64 // Share of the triangles drawn to hold the frame rate
+}
+
+// newLODSelector starts building the levels of detail of the large meshes.
+func newLODSelector(meshes []*models.Mesh) *lodSelector {
+	s := &lodSelector{chains: make(map[*models.Mesh]*lodChain), detail: 1}
+	for _, m := range meshes {
+		if s.chains[m] == nil && m.Rig == nil && m.TriangleCount() > lodMinTriangles {
+			s.chains[m] = newLODChain(m)
+		}
+	}
+	return s
+}
+
+// pick returns the level of detail of a mesh drawn with transform, the mesh
+// itself without levels of detail.
+func (s *lodSelector) pick(m *models.Mesh, transform math3d.Mat4, camera *render.Camera, width, height int) *models.Mesh {
+	if s == nil || s.chains[m] == nil {
+		return m
+	}
+	c := s.chains[m]
+	pixels := float64(width * height)
+	scale := math.Max(transform.MulVec3Dir(math3d.V3(1, 0, 0)).Len(),
+		math.Max(transform.MulVec3Dir(math3d.V3(0, 1, 0)).Len(), transform.MulVec3Dir(math3d.V3(0, 0, 1)).Len()))
+	center := transform.MulVec3(c.center)
+	x0, y0, _, ok0 := camera.WorldToScreen(center, width, height)
+	x1, y1, _, ok1 := camera.WorldToScreen(center.Add(camera.Up().Scale(c.radius*scale)), width, height)
+	if ok0 && ok1 {
+		// Partly off screen meshes keep the detail of their whole projection
+		r := math.Hypot(x1-x0, y1-y0)
+		pixels = math.Min(pixels, math.Pi*r*r)
+	}
+	budget := math.Min(lodTrianglesPerPixel*pixels, s.detail*float64(m.TriangleCount()))
+	return c.level(budget)
+}
+
+// update adapts the detail to the time the last frame took to rasterize.
+func (s *lodSelector) update(elapsed time.Duration, fps float64) {
+	if s == nil {
+		return
+	}
+	load := elapsed.Seconds() * fps / lodRenderShare
+	switch {
+	case load > 1:
+		s.detail = math.Max(lodMinDetail, s.detail*0.9/load)
+	case load < 0.5:
+		s.detail = math.Min(1, s.detail*1.1)
+	}
+}
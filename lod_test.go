@@ -0,0 +1,102 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ansipixels/trophy/math3d"
+	"github.com/ansipixels/trophy/models"
+	"github.com/ansipixels/trophy/render"
+)
+
+// teapotChain returns the levels of detail of the teapot, built in the
+// foreground, and the transform fitting it in the viewer's 2 unit cube.
+func teapotChain(t *testing.T) (*lodChain, math3d.Mat4) {
+	t.Helper()
+	mesh, err := models.NewSTLLoader().LoadBytes(teapotSTL(t), "teapot.stl")
+	if err != nil {
+		t.Fatalf("load teapot: %v", err)
+	}
+	mesh.CalculateBounds()
+	c := &lodChain{levels: []*models.Mesh{mesh}, center: mesh.Center(), radius: mesh.Size().Len() / 2}
+	c.build()
+	size := mesh.Size()
+	scale := 2 / math.Max(size.X, math.Max(size.Y, size.Z))
+	return c, math3d.ScaleUniform(scale).Mul(math3d.Translate(c.center.Negate()))
+}
+
+func TestLODChain(t *testing.T) {
+	c, _ := teapotChain(t)
+	if len(c.levels) < 3 {
+		t.Fatalf("%d levels of detail", len(c.levels))
+	}
+	for i := 1; i < len(c.levels); i++ {
+		prev, n := c.levels[i-1].TriangleCount(), c.levels[i].TriangleCount()
+		if n > prev*3/4 {
+			t.Errorf("level %d: %d triangles after %d", i, n, prev)
+		}
+	}
+	last := len(c.levels) - 1
+	if n := c.levels[last].TriangleCount(); n > 2*lodMinTriangles {
+		t.Errorf("coarsest level: %d triangles", n)
+	}
+	full := float64(c.levels[0].TriangleCount())
+	if got := c.level(full); got != c.levels[0] {
+		t.Errorf("level(%g) = %d triangles, want the mesh", full, got.TriangleCount())
+	}
+	if got := c.level(1); got != c.levels[last] {
+		t.Errorf("level(1) = %d triangles, want the coarsest", got.TriangleCount())
+	}
+	// Going back to the full mesh takes a margin over its triangle count
+	if got := c.level(full); got != c.levels[1] {
+		t.Errorf("level(%g) from the coarsest = %d triangles, want level 1", full, got.TriangleCount())
+	}
+	if got := c.level(full / lodFinerMargin); got != c.levels[0] {
+		t.Errorf("level(%g) = %d triangles, want the mesh", full/lodFinerMargin, got.TriangleCount())
+	}
+}
+
+func TestLODSelector(t *testing.T) {
+	c, transform := teapotChain(t)
+	mesh := c.levels[0]
+	small := boxMesh(math3d.V3(1, 1, 1))
+	if s := newLODSelector([]*models.Mesh{small}); len(s.chains) != 0 {
+		t.Errorf("levels of detail for a %d triangle mesh", small.TriangleCount())
+	}
+	s := &lodSelector{chains: map[*models.Mesh]*lodChain{mesh: c}, detail: 1}
+	camera := render.NewCamera()
+	camera.SetAspectRatio(1)
+	camera.SetPosition(math3d.V3(0, 0, initialCameraZ))
+	camera.LookAt(math3d.V3(0, 0, 0))
+	if got := s.pick(small, transform, camera, 1000, 1000); got != small {
+		t.Error("pick of a mesh without levels of detail")
+	}
+	var none *lodSelector
+	if got := none.pick(mesh, transform, camera, 1000, 1000); got != mesh {
+		t.Error("pick without -lod")
+	}
+	none.update(time.Second, 30)
+	// Filling a large screen shows every triangle, a few pixels only the
+	// coarsest level
+	if got := s.pick(mesh, transform, camera, 1000, 1000); got != mesh {
+		t.Errorf("pick on a large screen = %d triangles", got.TriangleCount())
+	}
+	if got := s.pick(mesh, transform, camera, 8, 8); got != c.levels[len(c.levels)-1] {
+		t.Errorf("pick on a small screen = %d triangles", got.TriangleCount())
+	}
+	// Frames slower than the frame period lower the detail
+	s.update(time.Second, 30)
+	if s.detail >= 0.1 || s.detail < lodMinDetail {
+		t.Errorf("detail %g after a slow frame", s.detail)
+	}
+	if got := s.pick(mesh, transform, camera, 1000, 1000); got == mesh {
+		t.Error("pick after a slow frame drew every triangle")
+	}
+	for range 100 {
+		s.update(0, 30)
+	}
+	if s.detail != 1 {
+		t.Errorf("detail %g after fast frames", s.detail)
+	}
+}
@@ -1,5 +1,5 @@
 // trophy - Terminal 3D Model Viewer - ansipixels port + improvements,
-// view OBJ and GLB files in your terminal with full 3D rendering.
+// view OBJ, GLB, STL, PLY, 3MF, OFF and Collada files in your terminal with full 3D rendering.
 //
 // Controls:
 //
@@ -13,7 +13,20 @@
 //	T           - Toggle texture on/off
 //	X           - Toggle wireframe mode (x-ray)
 //	L           - Light positioning mode (move mouse, click to set, Esc to cancel)
-//	?           - Toggle HUD overlay (FPS, filename, poly count, mode status)
+//	K           - Toggle section (clip plane) view
+//	[ / ]       - Move section plane back/forward
+//	, / .       - Rotate section plane around the vertical axis
+//	< / >       - Tilt section plane
+//	V           - Toggle solid section cap
+//	P           - Play/pause animation (animated GLB files)
+//	N           - Next animation clip
+//	( / )       - Scrub animation back/forward
+//	M           - Morph target mode ([ / ] select target, +/- adjust weight)
+//	O           - Scene tree panel ([ / ] select node, H hide, I isolate; static GLTF and grouped OBJ)
+//	C           - Cycle the authored GLTF cameras (static GLTF only)
+//	G           - Toggle the authored GLTF lights (KHR_lights_punctual)
+//	F           - Toggle the printability analysis overlay (see trophy check), paused while animating
+//	?           - Toggle HUD overlay (FPS, filename, poly count, volume, area, mode status)
 //	+/-         - Adjust zoom
 //	Esc         - Quit (or cancel light mode)
 package main
@@ -28,6 +41,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -44,6 +58,8 @@ import (
 var (
 	texturePath string
 	targetFPS   float64
+	reportUnits string
+	autoLOD     bool
 	// Embed default model files (GLB and STL only from docs/)
 	//go:embed docs/*.glb docs/*.stl
 	docsEmbedFS embed.FS
@@ -65,10 +81,22 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
 	flag.StringVar(&texturePath, "texture", "", "Path to texture image (PNG/JPG)")
 	flag.Float64Var(&targetFPS, "fps", 60, "Target FPS")
+	flag.StringVar(&reportUnits, "units", "mm", unitsUsage)
+	flag.BoolVar(&autoLOD, "lod", false,
+		"Draw simplified levels of detail of large models, picked from their on-screen size and to hold the -fps rate")
 	listEmbedded := flag.Bool("ls", false, "List embedded model options (res: files) and exit")
-	cli.ArgsHelp = "<model.obj|model.glb|model.stl> (default: " + embeddedPrefix + "trophy.glb)"
+	cli.ArgsHelp = "<model.obj|model.glb|model.stl|model.stl.gz|archive.zip:model.gltf|- for stdin> (default: " +
+		embeddedPrefix + "trophy.glb)\n" +
+		"or: trophy convert [flags] <input> <output> (see trophy convert -h)\n" +
+		"or: trophy check [flags] <model> (see trophy check -h)\n" +
+		"or: trophy info [flags] <model> (see trophy info -h)"
 	cli.MinArgs = 0
 	cli.MaxArgs = 1
 	cli.Main()
@@ -175,6 +203,118 @@ type ViewState struct {
 	ShowHUD        bool        // Whether to show the HUD overlay
 	SpinMode       bool        // Whether auto-spin is enabled
 	BackfaceCull   bool        // Whether to cull backfaces (true = cull, false = show both sides)
+	Section        SectionState
+	Animation      AnimationState
+	Morph          MorphState
+	Tree           TreeState
+	Camera         int  // Authored camera looked through, -1 for the default orbit camera
+	AuthoredLights bool // Whether the file's lights replace LightDir
+	Analysis       bool // Whether the mesh analysis overlay is shown
+}
+
+// AnimationState controls playback of the model's animation clips.
+type AnimationState struct {
+	Playing bool    // Whether time advances each frame
+	Clip    int     // Index of the current clip
+	Time    float64 // Seconds into the current clip
+}
+
+// SectionState describes the user clip plane in model space.
+type SectionState struct {
+	Enabled bool    // Whether the model is cut by the plane
+	Cap     bool    // Whether to draw the cut as solid material
+	Offset  float64 // Distance of the plane from the model center along its normal
+	Yaw     float64 // Rotation of the plane normal around Y (radians)
+	Pitch   float64 // Rotation of the plane normal around X (radians)
+}
+
+// Plane returns the model-space clip plane. The half-space the normal points
+// to is kept; by default that is the back half of the model (-Z), so the cut
+// faces the camera.
+func (s SectionState) Plane() render.Plane {
+	normal := math3d.RotateY(s.Yaw).Mul(math3d.RotateX(s.Pitch)).MulVec3Dir(math3d.V3(0, 0, -1))
+	return render.NewPlaneFromPointNormal(normal.Scale(s.Offset), normal)
+}
+
+// MorphState selects the morph target weight adjusted with the keyboard.
+type MorphState struct {
+	Enabled  bool // Whether [ ] and +/- edit morph weights instead of section and zoom
+	Selected int  // Index into the rig's MorphWeights
+}
+
+// morphStep is the weight change per key press.
+const morphStep = 0.1
+
+// MorphKey handles a key press in morph mode. Returns whether the key was
+// consumed and whether a weight changed.
+func (v *ViewState) MorphKey(b byte, rig *models.Rig, morphs []models.MorphWeight) (handled, changed bool) {
+	if !v.Morph.Enabled || len(morphs) == 0 {
+		return false, false
+	}
+	m := morphs[v.Morph.Selected]
+	switch b {
+	case '[':
+		v.Morph.Selected = (v.Morph.Selected + len(morphs) - 1) % len(morphs)
+	case ']':
+		v.Morph.Selected = (v.Morph.Selected + 1) % len(morphs)
+	case '+', '=':
+		rig.SetMorphWeight(m.Node, m.Index, min(1, rig.MorphWeight(m.Node, m.Index)+morphStep))
+		return true, true
+	case '-', '_':
+		rig.SetMorphWeight(m.Node, m.Index, max(0, rig.MorphWeight(m.Node, m.Index)-morphStep))
+		return true, true
+	default:
+		return false, false
+	}
+	return true, false
+}
+
+// TreeState selects the scene node edited in the tree panel.
+type TreeState struct {
+	Enabled  bool // Whether the panel is shown and [ ] H I edit the scene
+	Selected int  // Row in the panel (depth first node order)
+}
+
+// treeRow is one line of the scene tree panel.
+type treeRow struct {
+	node, depth int
+}
+
+// sceneTreeRows lists the scene nodes depth first for the tree panel.
+func sceneTreeRows(scene *models.Scene) []treeRow {
+	var rows []treeRow
+	scene.Walk(func(node, depth int) {
+		rows = append(rows, treeRow{node, depth})
+	})
+	return rows
+}
+
+// TreeKey handles a key press in the scene tree panel. Returns whether the
+// key was consumed and whether the visible instances changed.
+func (v *ViewState) TreeKey(b byte, scene *models.Scene, rows []treeRow) (handled, changed bool) {
+	if !v.Tree.Enabled || len(rows) == 0 {
+		return false, false
+	}
+	node := rows[v.Tree.Selected].node
+	switch b {
+	case '[':
+		v.Tree.Selected = (v.Tree.Selected + len(rows) - 1) % len(rows)
+	case ']':
+		v.Tree.Selected = (v.Tree.Selected + 1) % len(rows)
+	case 'h', 'H':
+		scene.SetHidden(node, !scene.Nodes[node].Hidden)
+		return true, true
+	case 'i', 'I':
+		if scene.Isolated == node {
+			scene.Isolate(-1)
+		} else {
+			scene.Isolate(node)
+		}
+		return true, true
+	default:
+		return false, false
+	}
+	return true, false
 }
 
 // NewViewState creates default view state.
@@ -185,6 +325,9 @@ func NewViewState() *ViewState {
 		LightMode:      false,
 		LightDir:       math3d.V3(0.5, 1, 0.3).Normalize(),
 		BackfaceCull:   false, // Default OFF - most STL files are single-sided shells
+		Section:        SectionState{Cap: true},
+		Animation:      AnimationState{Playing: true},
+		Camera:         -1,
 	}
 }
 
@@ -196,6 +339,16 @@ type HUD struct {
 	fpsFrames int
 	fpsTime   time.Time
 	state     *ViewState
+	clips     []models.AnimationClip
+	rig       *models.Rig
+	morphs    []models.MorphWeight
+	scene     *models.Scene
+	treeRows  []treeRow
+	cameras   []models.CameraView
+	lights    int
+	analysis  *models.MeshReport
+	physical  properties
+	drawn     int // Triangles drawn with levels of detail (-lod)
 }
 
 // NewHUD creates a new HUD.
@@ -227,6 +380,15 @@ func (h *HUD) Draw(ap *ansipixels.AnsiPixels) {
 			tcolor.BrightYellow.Foreground(), tcolor.Reset)
 		return
 	}
+	// Editing panels and the analysis legend are shown even without the HUD
+	h.drawTree(ap)
+	h.drawAnalysis(ap)
+	// Morph weight being edited
+	if h.state.Morph.Enabled && h.state.Morph.Selected < len(h.morphs) {
+		m := h.morphs[h.state.Morph.Selected]
+		ap.WriteRight(ap.H-2, "%s◆ %s (%d/%d) %.2f%s", tcolor.Purple.Foreground(), m.Name,
+			h.state.Morph.Selected+1, len(h.morphs), h.rig.MorphWeight(m.Node, m.Index), tcolor.Reset)
+	}
 	if !h.state.ShowHUD {
 		return
 	}
@@ -234,8 +396,12 @@ func (h *HUD) Draw(ap *ansipixels.AnsiPixels) {
 	ap.WriteAt(0, 0, tcolor.Green.Foreground()+"%.0f FPS "+tcolor.Reset, h.fps)
 	// Top middle: filename
 	ap.WriteCentered(0, "%s", h.filename)
-	// Top right: polygon count
-	ap.WriteRight(0, tcolor.Cyan.Foreground()+"%d polys"+tcolor.Reset, h.polyCount)
+	// Top right: polygon count (drawn with levels of detail), volume and surface area
+	polys := strconv.Itoa(h.polyCount)
+	if h.drawn > 0 && h.drawn < h.polyCount {
+		polys = fmt.Sprintf("%d/%d", h.drawn, h.polyCount)
+	}
+	ap.WriteRight(0, tcolor.Cyan.Foreground()+"%s polys  %s"+tcolor.Reset, polys, h.physical.summary())
 	// Bottom: mode indicators
 	checkTex := "[ ]"
 	if h.state.TextureEnabled && h.state.RenderMode != RenderModeWireframe {
@@ -245,11 +411,71 @@ func (h *HUD) Draw(ap *ansipixels.AnsiPixels) {
 	if h.state.RenderMode == RenderModeWireframe {
 		checkWire = "[✓]"
 	}
-	ap.WriteAt(0, ap.H-1, "%s Texture  %s X-Ray (wireframe)", checkTex, checkWire)
+	checkSection := "[ ]"
+	if h.state.Section.Enabled {
+		checkSection = "[✓]"
+	}
+	ap.WriteAt(0, ap.H-1, "%s Texture  %s X-Ray (wireframe)  %s Section", checkTex, checkWire, checkSection)
+	if h.lights > 0 {
+		checkLights := "[ ]"
+		if h.state.AuthoredLights {
+			checkLights = "[✓]"
+		}
+		ap.WriteAt(0, ap.H-3, "%s %d file lights (G)", checkLights, h.lights)
+	}
+	// Authored camera below the FPS
+	if c := h.state.Camera; c >= 0 && c < len(h.cameras) {
+		name := h.cameras[c].Name
+		if name == "" {
+			name = fmt.Sprintf("camera %d", c)
+		}
+		ap.WriteAt(0, 1, "%s◎ %s (%d/%d)%s", tcolor.Cyan.Foreground(), name, c+1, len(h.cameras), tcolor.Reset)
+	}
+	// Animation status above the mode line
+	if anim := h.state.Animation; anim.Clip < len(h.clips) {
+		status := "⏸"
+		if anim.Playing {
+			status = "▶"
+		}
+		clip := h.clips[anim.Clip]
+		ap.WriteAt(0, ap.H-2, "%s %s (%d/%d) %.2f/%.2fs", status, clip.Name, anim.Clip+1, len(h.clips),
+			anim.Time, clip.Duration)
+	}
 	// Bottom right: light hint
 	ap.WriteRight(ap.H-1, "%sL: position light%s", tcolor.Yellow.Foreground(), tcolor.Reset)
 }
 
+// drawTree renders the scene tree panel, scrolled to keep the selection visible.
+func (h *HUD) drawTree(ap *ansipixels.AnsiPixels) {
+	if !h.state.Tree.Enabled || h.scene == nil {
+		return
+	}
+	const top = 1
+	lines := max(1, ap.H-3)
+	first := max(0, min(h.state.Tree.Selected-lines/2, len(h.treeRows)-lines))
+	for i := first; i < len(h.treeRows) && i < first+lines; i++ {
+		row := h.treeRows[i]
+		n := &h.scene.Nodes[row.node]
+		check := "[✓]"
+		if !h.scene.Visible(row.node) {
+			check = "[ ]"
+		}
+		cursor, color := " ", tcolor.Reset
+		if i == h.state.Tree.Selected {
+			cursor, color = "›", tcolor.BrightYellow.Foreground()
+		}
+		mark := ""
+		if n.Mesh >= 0 {
+			mark = " ▲"
+		}
+		if h.scene.Isolated == row.node {
+			mark += " (isolated)"
+		}
+		ap.WriteAt(0, top+i-first, "%s%s%s %s%s%s%s", color, cursor, strings.Repeat("  ", row.depth), check, n.Name,
+			mark, tcolor.Reset)
+	}
+}
+
 // ScreenToLightDir converts a screen position to a light direction.
 // Maps screen coords to a hemisphere above the object.
 func (v *ViewState) ScreenToLightDir(screenX, screenY, width, height int) math3d.Vec3 {
@@ -270,10 +496,23 @@ func (v *ViewState) ScreenToLightDir(screenX, screenY, width, height int) math3d
 	return math3d.V3(nx, -ny, nz).Normalize()
 }
 
-// selectFilesystem resolves a file path to the appropriate filesystem.
-// Supports "res:" URI prefix for explicit embedded files,
-// or searches embedded FS first, then falls back to local FS.
+// selectFilesystem resolves a model argument to a filesystem and a path in it:
+// "-" reads stdin, "res:" names embedded files, "archive.zip:path" a model in
+// a zip archive, and other paths are searched in the embedded then the local
+// filesystem. Gzip and zstd compressed models are decompressed.
 func selectFilesystem(modelPath string) (fs.FS, string, error) {
+	fsys, name, err := resolveFilesystem(modelPath)
+	if err != nil {
+		return nil, "", err
+	}
+	return decompress(fsys, name)
+}
+
+// resolveFilesystem is selectFilesystem without decompression.
+func resolveFilesystem(modelPath string) (fs.FS, string, error) {
+	if modelPath == stdinPath {
+		return readStdin()
+	}
 	// Check for explicit res: prefix
 	if strings.HasPrefix(modelPath, embeddedPrefix) {
 		cleanPath := modelPath[len(embeddedPrefix):]
@@ -283,36 +522,159 @@ func selectFilesystem(modelPath string) (fs.FS, string, error) {
 		}
 		return docsFS, cleanPath, nil
 	}
+	if archive, member, ok := splitArchivePath(modelPath); ok {
+		return openArchive(archive, member)
+	}
 	// Try embedded FS first
 	if _, err := fs.Stat(docsFS, modelPath); err == nil {
 		return docsFS, modelPath, nil
 	}
 	// Fall back to local FS
 	if _, err := os.Stat(modelPath); err == nil {
-		// Clean the path for os.DirFS compatibility (remove ./ prefix, etc.)
-		cleanPath := filepath.Clean(modelPath)
-		return os.DirFS("."), cleanPath, nil
+		fsys, cleanPath := localFilesystem(modelPath)
+		return fsys, cleanPath, nil
 	}
 	return nil, "", fmt.Errorf("file not found in embedded or local filesystem: %s", modelPath)
 }
 
 // LoadModelFromFS loads a model from a filesystem interface (embed.FS or os.DirFS).
-// GLB/GLTF files are decoded using the provided filesystem, avoiding temp files.
-func LoadModelFromFS(fsys fs.FS, modelPath string) (*models.Mesh, image.Image, error) {
-	ext := strings.ToLower(filepath.Ext(modelPath))
-	switch ext {
-	case ".glb", ".gltf":
-		mesh, img, err := models.LoadGLBWithTextureFromFS(fsys, modelPath)
-		return mesh, img, err
-	case ".obj":
-		mesh, err := models.LoadOBJFromFS(fsys, modelPath)
-		return mesh, nil, err
-	case ".stl":
-		mesh, err := models.LoadSTLFromFS(fsys, modelPath)
-		return mesh, nil, err
-	default:
-		return nil, nil, fmt.Errorf("unsupported format: %s (use .obj, .glb, or .stl)", ext)
+// The format is detected from the content, else the extension, by the
+// models registry. GLB/GLTF files are decoded using the provided filesystem,
+// avoiding temp files.
+// Static GLTF files return their scene graph and a nil mesh, as do meshes
+// with several objects or groups (OBJ, 3MF, Collada) whose groups become
+// scene nodes for the tree panel; every other model returns a mesh and a nil
+// scene.
+func LoadModelFromFS(fsys fs.FS, modelPath string) (*models.Mesh, *models.Scene, image.Image, error) {
+	mesh, scene, img, err := models.LoadModel(fsys, modelPath)
+	if err != nil {
+		return nil, nil, nil, err
 	}
+	if mesh != nil {
+		if groups := mesh.GroupScene(); groups != nil && len(groups.Meshes) > 1 {
+			return nil, groups, nil, nil
+		}
+	}
+	return mesh, scene, img, nil
+}
+
+// plainGray is the color of untextured models.
+var plainGray = render.RGB(200, 200, 200)
+
+// materialSurfaces converts model materials to render surfaces, textured and
+// plain (untextured, tinting plainGray). A non nil override replaces every
+// base color texture.
+func materialSurfaces(materials []models.Material, override *render.Texture) (textured, plain []render.Surface) {
+	textures := make(map[image.Image]*render.Texture)
+	toTexture := func(img image.Image) *render.Texture {
+		if img == nil {
+			return nil
+		}
+		if tex, ok := textures[img]; ok {
+			return tex
+		}
+		tex := render.TextureFromImage(img)
+		textures[img] = tex
+		return tex
+	}
+	textured = make([]render.Surface, len(materials))
+	plain = make([]render.Surface, len(materials))
+	for i, m := range materials {
+		s := render.Surface{
+			Unlit:    m.Unlit,
+			Emissive: unitColor(m.Emissive[0], m.Emissive[1], m.Emissive[2]),
+		}
+		s.Color = render.ModulateColor(plainGray, unitColor(m.BaseColor[0], m.BaseColor[1], m.BaseColor[2]))
+		plain[i] = s
+		s.Color = unitColor(m.BaseColor[0], m.BaseColor[1], m.BaseColor[2])
+		s.Texture = override
+		if s.Texture == nil {
+			s.Texture = toTexture(m.BaseMap)
+		}
+		s.EmissiveMap = toTexture(m.EmissiveMap)
+		textured[i] = s
+	}
+	return textured, plain
+}
+
+// unitColor converts 0-1 color components, clamping them, to a color.
+func unitColor(r, g, b float64) render.Color {
+	c := func(v float64) uint8 {
+		return uint8(math.Round(255 * math.Max(0, math.Min(1, v))))
+	}
+	return render.RGB(c(r), c(g), c(b))
+}
+
+// useCamera looks through the authored camera idx of views, or through the
+// default orbit camera at distance cameraZ when idx is out of range. The
+// viewport keeps its own aspect ratio.
+func useCamera(camera *render.Camera, views []models.CameraView, idx int, cameraZ float64) {
+	if idx < 0 || idx >= len(views) {
+		camera.SetOrthographic(false, 0)
+		camera.SetFOV(math.Pi / 3)
+		camera.SetClipPlanes(0.1, 100)
+		camera.SetPosition(math3d.V3(0, 0, cameraZ))
+		camera.LookAt(math3d.V3(0, 0, 0))
+		return
+	}
+	v := views[idx]
+	near, far := v.ZNear, v.ZFar
+	if !v.Orthographic && near <= 0 {
+		near = 0.01
+	}
+	if far <= near {
+		// Infinite far plane
+		far = v.Position.Len() + 100
+	}
+	camera.SetClipPlanes(near, far)
+	camera.SetOrthographic(v.Orthographic, v.YMag)
+	if !v.Orthographic {
+		camera.SetFOV(v.YFov)
+	}
+	camera.SetPosition(v.Position)
+	camera.SetOrientation(v.Forward, v.Up)
+}
+
+// renderLights converts the scene lights to rasterizer lights, exposed so
+// that the brightest one fully lights a surface at the model center facing it.
+func renderLights(views []models.LightView) []render.Light {
+	lights := make([]render.Light, len(views))
+	brightest := 0.0
+	for i, v := range views {
+		l := render.Light{
+			Kind:      render.DirectionalLight,
+			Position:  v.Position,
+			Direction: v.Direction,
+			Range:     v.Range,
+			InnerCone: v.InnerConeAngle,
+			OuterCone: v.OuterConeAngle,
+		}
+		switch v.Type {
+		case models.LightPoint:
+			l.Kind = render.PointLight
+		case models.LightSpot:
+			l.Kind = render.SpotLight
+		case models.LightDirectional:
+		}
+		for c := range 3 {
+			l.Color[c] = v.Color[c] * v.Intensity
+		}
+		facing := v.Direction.Negate()
+		if l.Kind != render.DirectionalLight {
+			facing = v.Position.Normalize()
+		}
+		e := l.Irradiance(math3d.Zero3(), facing)
+		brightest = math.Max(brightest, math.Max(e[0], math.Max(e[1], e[2])))
+		lights[i] = l
+	}
+	if brightest > 0 {
+		for i := range lights {
+			for c := range 3 {
+				lights[i].Color[c] /= brightest
+			}
+		}
+	}
+	return lights
 }
 
 //nolint:gocognit,gocyclo,funlen,maintidx // yeah it's kinda long.
@@ -323,6 +685,10 @@ func run(modelPath string) int {
 	if err != nil {
 		return log.FErrf("resolve model path: %v", err)
 	}
+	unitMM, err := unitSize(reportUnits)
+	if err != nil {
+		return log.FErrf("-units: %v", err)
+	}
 	// Initialize ansipixels for terminal rendering
 	ap := ansipixels.NewAnsiPixels(float64(targetFPS))
 	if err = ap.Open(); err != nil {
@@ -350,20 +716,28 @@ func run(modelPath string) int {
 	camera.LookAt(math3d.V3(0, 0, 0))
 	rasterizer := render.NewRasterizer(camera, fb)
 	// Load texture if specified
-	var texture *render.Texture
+	var texture, override *render.Texture
 	if texturePath != "" {
 		texture, err = render.LoadTexture(texturePath)
 		if err != nil {
 			return log.FErrf("Could not load texture: %v", err)
 		}
+		override = texture
 	}
 	// Load model
-	var mesh *models.Mesh
-	var embeddedImg image.Image
-	mesh, embeddedImg, err = LoadModelFromFS(modelFS, resolvedPath)
+	mesh, scene, embeddedImg, err := LoadModelFromFS(modelFS, resolvedPath)
 	if err != nil {
 		return log.FErrf("load model: %v", err)
 	}
+	// Static GLTF files keep their scene graph, posable and other models a single mesh
+	var rig *models.Rig
+	vertexCount, triangleCount := 0, 0
+	if scene != nil {
+		vertexCount, triangleCount = scene.VertexCount(), scene.TriangleCount()
+	} else {
+		rig = mesh.Rig
+		vertexCount, triangleCount = mesh.VertexCount(), mesh.TriangleCount()
+	}
 	// Use embedded texture if no explicit texture and one exists
 	if texture == nil && embeddedImg != nil {
 		texture = render.TextureFromImage(embeddedImg)
@@ -373,27 +747,124 @@ func run(modelPath string) int {
 	if texture == nil {
 		texture = render.NewCheckerTexture(64, 64, 8, render.RGB(200, 200, 200), render.RGB(100, 100, 100))
 	}
-	fmt.Printf("Loaded: %s (%d vertices, %d triangles)\n", filepath.Base(modelPath), mesh.VertexCount(), mesh.TriangleCount())
+	// Per material shading, with and without textures
+	var materials []models.Material
+	if scene != nil {
+		materials = scene.Materials
+	} else {
+		materials = mesh.Materials
+	}
+	surfaces, plainSurfaces := materialSurfaces(materials, override)
+	displayName := filepath.Base(modelPath)
+	if modelPath == stdinPath {
+		displayName = stdinName
+	}
+	fmt.Printf("Loaded: %s (%d vertices, %d triangles)\n", displayName, vertexCount, triangleCount)
 	// Initialize rotation and view state
 	rotation := NewRotationState(int(math.Round(targetFPS)))
 	viewState := NewViewState()
 	// Create HUD
-	hud := NewHUD(filepath.Base(modelPath), mesh.TriangleCount(), viewState)
+	hud := NewHUD(displayName, triangleCount, viewState)
 	// Center and scale model
-	mesh.CalculateBounds()
-	center := mesh.Center()
-	size := mesh.Size()
+	var center, size math3d.Vec3
+	if scene != nil {
+		center = scene.Center()
+		size = scene.Size()
+	} else {
+		mesh.CalculateBounds()
+		center = mesh.Center()
+		size = mesh.Size()
+	}
 	maxDim := math.Max(size.X, math.Max(size.Y, size.Z))
+	var modelUnit float64
+	if scene != nil {
+		modelUnit = scene.UnitSize()
+	} else {
+		modelUnit = mesh.UnitSize()
+	}
+	unitScale := modelUnit / unitMM // Reporting units per viewer unit
 	if maxDim > 0 {
 		scale := 2.0 / maxDim
+		unitScale /= scale
 		transform := math3d.Scale(math3d.V3(scale, scale, scale)).Mul(math3d.Translate(center.Scale(-1)))
-		mesh.Transform(transform)
+		if scene != nil {
+			scene.Transform(transform)
+		} else {
+			mesh.Transform(transform)
+		}
+	}
+	// Spatial index so the rasterizer can cull per node when zoomed in
+	var instances []models.Instance
+	var treeRows []treeRow
+	if scene != nil {
+		scene.BuildBVH()
+		instances = scene.Instances()
+		treeRows = sceneTreeRows(scene)
+		hud.scene = scene
+		hud.treeRows = treeRows
+	}
+	// Authored cameras and lights, in the normalized scene space
+	var cameras []models.CameraView
+	var lights, frameLights []render.Light
+	if scene != nil {
+		cameras = scene.CameraViews()
+		lights = renderLights(scene.LightViews())
+		viewState.AuthoredLights = len(lights) > 0
+		hud.cameras = cameras
+		hud.lights = len(lights)
+	} else {
+		mesh.BuildBVH()
+	}
+	// Levels of detail, simplified in the background
+	var lod *lodSelector
+	if autoLOD {
+		if scene != nil {
+			lod = newLODSelector(scene.Meshes)
+		} else {
+			lod = newLODSelector([]*models.Mesh{mesh})
+		}
+	}
+	hud.rig = rig
+	if rig.ClipCount() > 0 {
+		hud.clips = rig.Clips
+	}
+	morphs := rig.MorphWeights()
+	hud.morphs = morphs
+	// Physical properties of the visible nodes, in the original model size
+	measure := func() {
+		measured := mesh
+		if scene != nil {
+			measured = scene.Flatten()
+		}
+		hud.physical = meshProperties(measured, reportUnits, unitScale)
+	}
+	measure()
+	// Printability analysis of the current pose and visible nodes, on demand
+	var overlay *models.Mesh
+	analyze := func() {
+		analyzed := mesh
+		if scene != nil {
+			analyzed = scene.Flatten()
+		}
+		start := time.Now()
+		hud.analysis = models.Analyze(analyzed)
+		overlay = analysisOverlay(analyzed, hud.analysis)
+		overlay.CalculateBounds()
+		log.LogVf("Analyzed %d triangles in %v", hud.analysis.Triangles, time.Since(start))
 	}
 	// Input state
 	inputTorque := struct{ pitch, yaw, roll float64 }{}
 	const torqueStrength = 3.0
+	const (
+		sectionStep = 0.05         // Plane movement per key press (model is normalized to size 2)
+		sectionTurn = math.Pi / 36 // Plane rotation per key press (5 degrees)
+		animScrub   = 0.1          // Animation scrub step in seconds
+	)
 	// Main loop
 	lastFrame := time.Now()
+	lastAnimFrame := time.Now()
+	posed := AnimationState{Clip: -1}
+	morphChanged := false
 	cameraZ := initialCameraZ
 	lastMouseX, lastMouseY := 0, 0
 	zoomChange := 0.0
@@ -409,7 +880,9 @@ func run(modelPath string) int {
 			dy := ap.My - lastMouseY
 			rotation.ApplyImpulse(float64(dy)*0.03, float64(dx)*0.03, 0)
 		}
-		camera.SetPosition(math3d.V3(0, 0, cameraZ))
+		if viewState.Camera < 0 {
+			camera.SetPosition(math3d.V3(0, 0, cameraZ))
+		}
 		if viewState.LightMode {
 			// Convert screen coordinates to light direction
 			viewState.PendingLight = viewState.ScreenToLightDir(ap.Mx, ap.My, ap.W, ap.H)
@@ -428,6 +901,27 @@ func run(modelPath string) int {
 		camera.SetAspectRatio(float64(fb.Width) / float64(fb.Height))
 		return nil
 	}
+	// drawMesh draws a mesh based on render mode
+	drawMesh := func(m *models.Mesh, transform math3d.Mat4, lightDir math3d.Vec3) {
+		switch viewState.RenderMode {
+		case RenderModeWireframe:
+			// X-ray wireframe mode
+			rasterizer.DrawMeshWireframe(m, transform, render.RGB(0, 255, 128))
+		case RenderModeFlat:
+			// Flat shading (no texture)
+			rasterizer.DrawMeshSurfacesOpt(m, transform, plainSurfaces, render.Surface{Color: plainGray}, lightDir)
+		default:
+			// Textured mode
+			if viewState.TextureEnabled {
+				fallback := render.Surface{Color: render.RGB(255, 255, 255), Texture: texture}
+				rasterizer.DrawMeshSurfacesOpt(m, transform, surfaces, fallback, lightDir)
+			} else {
+				rasterizer.DrawMeshSurfacesOpt(m, transform, plainSurfaces, render.Surface{Color: plainGray}, lightDir)
+			}
+		}
+		// Line and point primitives, if any
+		rasterizer.DrawMeshPrimitives(m, transform, render.RGB(255, 200, 80))
+	}
 	now := time.Now()
 	err = ap.FPSTicks(func() bool {
 		dt := now.Sub(lastFrame).Seconds()
@@ -438,6 +932,23 @@ func run(modelPath string) int {
 		// Process keyboard input from ap.Data
 		if len(ap.Data) > 0 { //nolint:nestif // it's just a big switch
 			for _, b := range ap.Data {
+				if handled, changed := viewState.MorphKey(b, rig, morphs); handled {
+					morphChanged = morphChanged || changed
+					continue
+				}
+				if handled, changed := viewState.TreeKey(b, scene, treeRows); handled {
+					if changed {
+						instances = scene.Instances()
+						hud.polyCount = scene.TriangleCount()
+						measure()
+						lights = renderLights(scene.LightViews())
+						hud.lights = len(lights)
+						if viewState.Analysis && !hud.animating() {
+							analyze()
+						}
+					}
+					continue
+				}
 				switch b {
 				case 'q', 'Q':
 					inputTorque.roll = -torqueStrength
@@ -454,7 +965,8 @@ func run(modelPath string) int {
 				case 'r', 'R':
 					rotation.Reset()
 					cameraZ = initialCameraZ
-					camera.SetPosition(math3d.V3(0, 0, cameraZ))
+					viewState.Camera = -1
+					useCamera(camera, cameras, viewState.Camera, cameraZ)
 					zoomChange = 0
 				case 't', 'T':
 					// Toggle texture
@@ -467,12 +979,71 @@ func run(modelPath string) int {
 						viewState.RenderMode = RenderModeWireframe
 					}
 				case 'l', 'L':
-					// Enter light positioning mode
+					// Enter light positioning mode, with the single positioned light
 					viewState.LightMode = true
+					viewState.AuthoredLights = false
 					viewState.PendingLight = viewState.LightDir
 				case 'b', 'B':
 					// Toggle backface culling
 					viewState.BackfaceCull = !viewState.BackfaceCull
+				case 'k', 'K':
+					// Toggle section view
+					viewState.Section.Enabled = !viewState.Section.Enabled
+				case 'v', 'V':
+					// Toggle solid section cap
+					viewState.Section.Cap = !viewState.Section.Cap
+				case '[':
+					viewState.Section.Offset -= sectionStep
+				case ']':
+					viewState.Section.Offset += sectionStep
+				case ',':
+					viewState.Section.Yaw -= sectionTurn
+				case '.':
+					viewState.Section.Yaw += sectionTurn
+				case '<':
+					viewState.Section.Pitch -= sectionTurn
+				case '>':
+					viewState.Section.Pitch += sectionTurn
+				case 'p', 'P':
+					// Play/pause animation
+					viewState.Animation.Playing = !viewState.Animation.Playing
+				case 'n', 'N':
+					// Next animation clip
+					if n := rig.ClipCount(); n > 0 {
+						viewState.Animation.Clip = (viewState.Animation.Clip + 1) % n
+						viewState.Animation.Time = 0
+					}
+				case 'm', 'M':
+					// Toggle morph target mode
+					viewState.Morph.Enabled = !viewState.Morph.Enabled && len(morphs) > 0
+					viewState.Tree.Enabled = viewState.Tree.Enabled && !viewState.Morph.Enabled
+				case 'o', 'O':
+					// Toggle scene tree panel
+					viewState.Tree.Enabled = !viewState.Tree.Enabled && len(treeRows) > 0
+					viewState.Morph.Enabled = viewState.Morph.Enabled && !viewState.Tree.Enabled
+				case 'c', 'C':
+					// Next authored camera, then back to the default one
+					if len(cameras) > 0 {
+						viewState.Camera++
+						if viewState.Camera >= len(cameras) {
+							viewState.Camera = -1
+						}
+						rotation.Reset()
+						useCamera(camera, cameras, viewState.Camera, cameraZ)
+					}
+				case 'g', 'G':
+					// Toggle the authored lights
+					viewState.AuthoredLights = !viewState.AuthoredLights && len(lights) > 0
+				case 'f', 'F':
+					// Toggle the analysis overlay, analyzing the model as shown
+					viewState.Analysis = !viewState.Analysis
+					if viewState.Analysis && !hud.animating() {
+						analyze()
+					}
+				case '(':
+					viewState.Animation.Time -= animScrub
+				case ')':
+					viewState.Animation.Time += animScrub
 				case '?':
 					// Toggle HUD
 					viewState.ShowHUD = !viewState.ShowHUD
@@ -499,7 +1070,7 @@ func run(modelPath string) int {
 				}
 			}
 		}
-		if zoomChange != 0 {
+		if zoomChange != 0 && viewState.Camera < 0 {
 			cameraZ += zoomChange
 			if cameraZ < 1 {
 				cameraZ = 1
@@ -508,8 +1079,8 @@ func run(modelPath string) int {
 				cameraZ = 20
 			}
 			camera.SetPosition(math3d.V3(0, 0, cameraZ))
-			zoomChange = 0
 		}
+		zoomChange = 0
 		// Apply input torque and decay it
 		rotation.ApplyImpulse(
 			inputTorque.pitch*dt,
@@ -521,6 +1092,24 @@ func run(modelPath string) int {
 		inputTorque.roll *= 0.9
 		// Update springs (harmonica handles timing internally)
 		rotation.Update(!viewState.SpinMode)
+		// Advance and apply the animation (CPU skinning)
+		animNow := time.Now()
+		if anim := &viewState.Animation; rig.ClipCount() > 0 {
+			if anim.Playing {
+				anim.Time += animNow.Sub(lastAnimFrame).Seconds()
+			}
+			anim.Time = models.WrapClipTime(anim.Time, rig.Clips[anim.Clip].Duration)
+		}
+		if anim := viewState.Animation; rig != nil && (morphChanged || anim != posed) {
+			mesh.Pose(anim.Clip, anim.Time)
+			posed = anim
+			morphChanged = false
+			// The analysis follows the pose whenever the animation holds still
+			if viewState.Analysis && !hud.animating() {
+				analyze()
+			}
+		}
+		lastAnimFrame = animNow
 		// Build transform
 		transform := math3d.RotateX(rotation.Pitch.Position).
 			Mul(math3d.RotateY(rotation.Yaw.Position)).
@@ -533,23 +1122,46 @@ func run(modelPath string) int {
 		if viewState.LightMode {
 			lightDir = viewState.PendingLight
 		}
+		// Authored lights follow the model's rotation
+		rasterizer.Lights = nil
+		if viewState.AuthoredLights {
+			frameLights = frameLights[:0]
+			for _, l := range lights {
+				l.Position = transform.MulVec3(l.Position)
+				l.Direction = transform.MulVec3Dir(l.Direction)
+				frameLights = append(frameLights, l)
+			}
+			rasterizer.Lights = frameLights
+		}
 		// Set backface culling mode
 		rasterizer.DisableBackfaceCulling = !viewState.BackfaceCull
-		// Draw mesh based on render mode
-		switch viewState.RenderMode {
-		case RenderModeWireframe:
-			// X-ray wireframe mode
-			rasterizer.DrawMeshWireframe(mesh, transform, render.RGB(0, 255, 128))
-		case RenderModeFlat:
-			// Flat shading (no texture)
-			rasterizer.DrawMeshGouraudOpt(mesh, transform, render.RGB(200, 200, 200), lightDir)
-		default:
-			// Textured mode
-			if viewState.TextureEnabled {
-				rasterizer.DrawMeshTexturedOpt(mesh, transform, texture, lightDir)
-			} else {
-				rasterizer.DrawMeshGouraudOpt(mesh, transform, render.RGB(200, 200, 200), lightDir)
+		// Section plane follows the model's rotation
+		if viewState.Section.Enabled {
+			rasterizer.SetClipPlanes(viewState.Section.Plane().Transform(transform))
+		} else {
+			rasterizer.SetClipPlanes()
+		}
+		rasterizer.ClipCap = viewState.Section.Cap
+		// Draw the mesh, or each scene instance with its own transform, at
+		// their level of detail
+		renderStart := time.Now()
+		hud.drawn = 0
+		if scene != nil {
+			for _, inst := range instances {
+				instTransform := transform.Mul(inst.Transform)
+				m := lod.pick(inst.Mesh, instTransform, camera, fb.Width, fb.Height)
+				hud.drawn += m.TriangleCount()
+				drawMesh(m, instTransform, lightDir)
 			}
+		} else {
+			m := lod.pick(mesh, transform, camera, fb.Width, fb.Height)
+			hud.drawn = m.TriangleCount()
+			drawMesh(m, transform, lightDir)
+		}
+		lod.update(time.Since(renderStart), targetFPS)
+		// Defects over the model, not depth tested so hidden ones show
+		if viewState.Analysis && !hud.animating() {
+			rasterizer.DrawMeshPrimitives(overlay, transform, render.RGB(255, 0, 0))
 		}
 		// Convert framebuffer to image for ansipixels
 		img := fb.ToImage()
@@ -0,0 +1,105 @@
+package math3d
+
+import "math"
+
+// Quat is a rotation quaternion (X, Y, Z vector part, W scalar),
+// in the same component order as GLTF.
+type Quat struct {
+	X, Y, Z, W float64
+}
+
+// Q creates a new Quat.
+func Q(x, y, z, w float64) Quat {
+	return Quat{x, y, z, w}
+}
+
+// QuatIdentity returns the identity rotation.
+func QuatIdentity() Quat {
+	return Quat{0, 0, 0, 1}
+}
+
+// QuatFromAxisAngle creates a rotation of angle radians around axis.
+func QuatFromAxisAngle(axis Vec3, angle float64) Quat {
+	axis = axis.Normalize()
+	s, c := math.Sincos(angle / 2)
+	return Quat{axis.X * s, axis.Y * s, axis.Z * s, c}
+}
+
+// Add returns the component-wise sum.
+func (q Quat) Add(b Quat) Quat {
+	return Quat{q.X + b.X, q.Y + b.Y, q.Z + b.Z, q.W + b.W}
+}
+
+// Scale returns the quaternion scaled by s.
+func (q Quat) Scale(s float64) Quat {
+	return Quat{q.X * s, q.Y * s, q.Z * s, q.W * s}
+}
+
+// Dot returns the 4D dot product.
+func (q Quat) Dot(b Quat) float64 {
+	return q.X*b.X + q.Y*b.Y + q.Z*b.Z + q.W*b.W
+}
+
+// Mul returns the Hamilton product q*b (apply b, then q).
+func (q Quat) Mul(b Quat) Quat {
+	return Quat{
+		q.W*b.X + q.X*b.W + q.Y*b.Z - q.Z*b.Y,
+		q.W*b.Y - q.X*b.Z + q.Y*b.W + q.Z*b.X,
+		q.W*b.Z + q.X*b.Y - q.Y*b.X + q.Z*b.W,
+		q.W*b.W - q.X*b.X - q.Y*b.Y - q.Z*b.Z,
+	}
+}
+
+// Normalize returns the unit quaternion (identity if q is zero).
+func (q Quat) Normalize() Quat {
+	l := math.Sqrt(q.Dot(q))
+	if l == 0 {
+		return QuatIdentity()
+	}
+	return q.Scale(1 / l)
+}
+
+// Rotate rotates the vector v by q.
+func (q Quat) Rotate(v Vec3) Vec3 {
+	u := Vec3{q.X, q.Y, q.Z}
+	t := u.Cross(v).Scale(2)
+	return v.Add(t.Scale(q.W)).Add(u.Cross(t))
+}
+
+// Slerp returns the spherical linear interpolation between q and b by t,
+// taking the shortest path.
+func (q Quat) Slerp(b Quat, t float64) Quat {
+	d := q.Dot(b)
+	if d < 0 {
+		b = b.Scale(-1)
+		d = -d
+	}
+	if d > 0.9995 {
+		// Nearly parallel: fall back to normalized lerp
+		return q.Scale(1 - t).Add(b.Scale(t)).Normalize()
+	}
+	theta := math.Acos(d)
+	sinTheta := math.Sin(theta)
+	wa := math.Sin((1-t)*theta) / sinTheta
+	wb := math.Sin(t*theta) / sinTheta
+	return q.Scale(wa).Add(b.Scale(wb))
+}
+
+// Mat4 returns the rotation matrix for q.
+func (q Quat) Mat4() Mat4 {
+	return QuatToMat4(q.X, q.Y, q.Z, q.W)
+}
+
+// TRS composes a translation, rotation and scale into a matrix (T * R * S).
+func TRS(t Vec3, r Quat, s Vec3) Mat4 {
+	m := r.Mat4()
+	for i := range 3 {
+		m[i] *= s.X
+		m[4+i] *= s.Y
+		m[8+i] *= s.Z
+	}
+	m[12] = t.X
+	m[13] = t.Y
+	m[14] = t.Z
+	return m
+}
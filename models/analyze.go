@@ -0,0 +1,405 @@
+package models
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// MeshReport lists the defects keeping a mesh from being a closed,
+// consistently wound solid, as found by Analyze. Faces are indices into
+// Mesh.Faces.
+type MeshReport struct {
+	Triangles         int          // Number of faces
+	Vertices          int          // Number of distinct vertex positions
+	NonManifoldEdges  []ReportEdge // Edges shared by more than two faces
+	BoundaryEdges     []ReportEdge // Edges of a single face
+	Holes             int          // Number of boundary loops
+	InconsistentFaces []int        // Faces wound against the rest of their surface
+	DegenerateFaces   []int        // Faces with zero or near-zero area
+	DuplicateFaces    []int        // Faces with the same vertices as an earlier face
+	SelfIntersections [][2]int     // Pairs of faces crossing each other
+}
+
+// ReportEdge is an edge listed by a MeshReport.
+type ReportEdge struct {
+	V        [2]int      // Vertex indices
+	From, To math3d.Vec3 // Vertex positions
+	Faces    []int       // Faces using the edge
+}
+
+// Watertight reports whether every edge is shared by exactly two faces.
+func (r *MeshReport) Watertight() bool {
+	return len(r.BoundaryEdges) == 0 && len(r.NonManifoldEdges) == 0
+}
+
+// Printable reports whether the mesh has no defect at all.
+func (r *MeshReport) Printable() bool {
+	return r.Watertight() && len(r.InconsistentFaces) == 0 && len(r.DegenerateFaces) == 0 &&
+		len(r.DuplicateFaces) == 0 && len(r.SelfIntersections) == 0
+}
+
+// Analyze checks the mesh for 3D printing. Vertices at the same position are
+// considered the same vertex, so that UV and normal seams do not open the
+// surface. Degenerate faces (see RemoveDegenerateFaces) and duplicate faces
+// are reported and left out of the other checks. Faces sharing a vertex are
+// not tested for intersection.
+func Analyze(m *Mesh) *MeshReport {
+	r := &MeshReport{Triangles: len(m.Faces)}
+	ids, count := m.weldedIDs()
+	r.Vertices = count
+	valid, degenerate, duplicate := m.topologyFaces(ids)
+	r.DegenerateFaces, r.DuplicateFaces = degenerate, duplicate
+	edges := m.meshEdges(ids, valid)
+	holes := newUnionFind(count)
+	for _, e := range edges {
+		if len(e.uses) == 2 {
+			continue
+		}
+		re := ReportEdge{V: e.v, From: m.Vertices[e.v[0]].Position, To: m.Vertices[e.v[1]].Position}
+		for _, u := range e.uses {
+			re.Faces = append(re.Faces, u.face)
+		}
+		if len(e.uses) == 1 {
+			r.BoundaryEdges = append(r.BoundaryEdges, re)
+			holes.union(ids[e.v[0]], ids[e.v[1]])
+		} else {
+			r.NonManifoldEdges = append(r.NonManifoldEdges, re)
+		}
+	}
+	loops := make(map[int]bool)
+	for _, e := range r.BoundaryEdges {
+		loops[holes.find(ids[e.V[0]])] = true
+	}
+	r.Holes = len(loops)
+	r.InconsistentFaces = inconsistentFaces(len(m.Faces), edges)
+	r.SelfIntersections = m.selfIntersections(ids, valid)
+	return r
+}
+
+// weldedIDs numbers the distinct vertex positions, returning the number of
+// each vertex and the number of positions.
+func (m *Mesh) weldedIDs() ([]int, int) {
+	ids := make([]int, len(m.Vertices))
+	index := make(map[math3d.Vec3]int, len(m.Vertices))
+	for i, v := range m.Vertices {
+		id, ok := index[v.Position]
+		if !ok {
+			id = len(index)
+			index[v.Position] = id
+		}
+		ids[i] = id
+	}
+	return ids, len(index)
+}
+
+// topologyFaces returns which faces take part in the topology checks, and
+// the degenerate and duplicate faces left out.
+func (m *Mesh) topologyFaces(ids []int) (valid []bool, degenerate, duplicate []int) {
+	valid = make([]bool, len(m.Faces))
+	seen := make(map[[3]int]bool, len(m.Faces))
+	for i, f := range m.Faces {
+		if m.isDegenerate(f) {
+			degenerate = append(degenerate, i)
+			continue
+		}
+		key := faceKey(ids[f.V[0]], ids[f.V[1]], ids[f.V[2]])
+		if seen[key] {
+			duplicate = append(duplicate, i)
+			continue
+		}
+		seen[key] = true
+		valid[i] = true
+	}
+	return valid, degenerate, duplicate
+}
+
+// edgeUse is a face using an edge, forward when its winding goes from the
+// lower to the higher welded vertex.
+type edgeUse struct {
+	face    int
+	forward bool
+}
+
+// meshEdge is an edge between two welded vertices, v being the vertex
+// indices of its first use.
+type meshEdge struct {
+	v    [2]int
+	uses []edgeUse
+}
+
+// meshEdges returns the edges of the valid faces, in order of first use.
+func (m *Mesh) meshEdges(ids []int, valid []bool) []meshEdge {
+	var edges []meshEdge
+	index := make(map[[2]int]int, len(m.Faces)*3/2)
+	for i, f := range m.Faces {
+		if !valid[i] {
+			continue
+		}
+		for k := range 3 {
+			v0, v1 := f.V[k], f.V[(k+1)%3]
+			a, b := ids[v0], ids[v1]
+			key := [2]int{min(a, b), max(a, b)}
+			e, ok := index[key]
+			if !ok {
+				e = len(edges)
+				index[key] = e
+				edges = append(edges, meshEdge{v: [2]int{v0, v1}})
+			}
+			edges[e].uses = append(edges[e].uses, edgeUse{face: i, forward: a < b})
+		}
+	}
+	return edges
+}
+
+// faceNeighbor is a face sharing a manifold edge, traversed in the same
+// direction (inconsistently) when same is true.
+type faceNeighbor struct {
+	face int
+	same bool
+}
+
+// manifoldNeighbors returns the neighbors of each face across the edges
+// shared by exactly two faces.
+func manifoldNeighbors(faces int, edges []meshEdge) [][]faceNeighbor {
+	neighbors := make([][]faceNeighbor, faces)
+	for _, e := range edges {
+		if len(e.uses) != 2 {
+			continue
+		}
+		a, b := e.uses[0], e.uses[1]
+		same := a.forward == b.forward
+		neighbors[a.face] = append(neighbors[a.face], faceNeighbor{b.face, same})
+		neighbors[b.face] = append(neighbors[b.face], faceNeighbor{a.face, same})
+	}
+	return neighbors
+}
+
+// orientationComponents walks the surfaces connected by manifold edges,
+// calling visit with the faces of each one and whether each face must be
+// flipped to agree with the first face of its surface. Non-orientable
+// surfaces keep the first flip found for each face.
+func orientationComponents(faces int, edges []meshEdge, visit func(component []int, flipped []bool)) {
+	neighbors := manifoldNeighbors(faces, edges)
+	visited := make([]bool, faces)
+	flipped := make([]bool, faces)
+	for seed := range faces {
+		if visited[seed] || len(neighbors[seed]) == 0 {
+			continue
+		}
+		visited[seed] = true
+		component := []int{seed}
+		for i := 0; i < len(component); i++ {
+			f := component[i]
+			for _, n := range neighbors[f] {
+				if !visited[n.face] {
+					visited[n.face] = true
+					flipped[n.face] = flipped[f] != n.same
+					component = append(component, n.face)
+				}
+			}
+		}
+		visit(component, flipped)
+	}
+}
+
+// inconsistentFaces returns the faces wound against the majority of their
+// surface, sorted.
+func inconsistentFaces(faces int, edges []meshEdge) []int {
+	var result []int
+	orientationComponents(faces, edges, func(component []int, flipped []bool) {
+		count := 0
+		for _, f := range component {
+			if flipped[f] {
+				count++
+			}
+		}
+		minority := count <= len(component)-count
+		for _, f := range component {
+			if flipped[f] == minority {
+				result = append(result, f)
+			}
+		}
+	})
+	slices.Sort(result)
+	return result
+}
+
+// selfIntersections returns the pairs of valid faces without common vertex
+// that cross each other, sorted.
+func (m *Mesh) selfIntersections(ids []int, valid []bool) [][2]int {
+	if len(m.Faces) < 2 {
+		return nil
+	}
+	bvh := NewBVH(m)
+	lo, hi := bvh.Bounds()
+	eps := 1e-9 * hi.Sub(lo).Len()
+	triangle := func(f Face) [3]math3d.Vec3 {
+		return [3]math3d.Vec3{m.Vertices[f.V[0]].Position, m.Vertices[f.V[1]].Position, m.Vertices[f.V[2]].Position}
+	}
+	var pairs [][2]int
+	for i, f := range m.Faces {
+		if !valid[i] {
+			continue
+		}
+		a := triangle(f)
+		fMin, fMax := m.faceBounds(f)
+		bvh.QueryAABB(fMin, fMax, func(j int) bool {
+			g := m.Faces[j]
+			if j <= i || !valid[j] {
+				return true
+			}
+			for _, v := range f.V {
+				if ids[v] == ids[g.V[0]] || ids[v] == ids[g.V[1]] || ids[v] == ids[g.V[2]] {
+					return true
+				}
+			}
+			if trianglesIntersect(a, triangle(g), eps) {
+				pairs = append(pairs, [2]int{i, j})
+			}
+			return true
+		})
+	}
+	slices.SortFunc(pairs, func(a, b [2]int) int {
+		return cmp.Or(cmp.Compare(a[0], b[0]), cmp.Compare(a[1], b[1]))
+	})
+	return pairs
+}
+
+// trianglesIntersect reports whether two triangles cross (Möller's interval
+// test). Triangles only touching within eps do not intersect.
+func trianglesIntersect(a, b [3]math3d.Vec3, eps float64) bool {
+	na := a[1].Sub(a[0]).Cross(a[2].Sub(a[0])).Normalize()
+	nb := b[1].Sub(b[0]).Cross(b[2].Sub(b[0])).Normalize()
+	da, aSide := planeDistances(a, b[0], nb, eps)
+	if aSide != 0 {
+		return false
+	}
+	db, bSide := planeDistances(b, a[0], na, eps)
+	if bSide != 0 {
+		return false
+	}
+	if da == [3]float64{} {
+		return coplanarTrianglesIntersect(a, b, na, eps)
+	}
+	dir := na.Cross(nb).Normalize()
+	aLo, aHi := planeCrossing(a, da, dir)
+	bLo, bHi := planeCrossing(b, db, dir)
+	return min(aHi, bHi)-max(aLo, bLo) > eps
+}
+
+// planeDistances returns the signed distances of a triangle's vertices to a
+// plane, snapped to 0 within eps, and the side of the plane the triangle is
+// strictly on (1 or -1), or 0 if it touches or crosses it.
+func planeDistances(t [3]math3d.Vec3, origin, normal math3d.Vec3, eps float64) ([3]float64, int) {
+	var d [3]float64
+	pos, neg := 0, 0
+	for i, p := range t {
+		d[i] = p.Sub(origin).Dot(normal)
+		switch {
+		case d[i] > eps:
+			pos++
+		case d[i] < -eps:
+			neg++
+		default:
+			d[i] = 0
+		}
+	}
+	switch {
+	case pos == 3:
+		return d, 1
+	case neg == 3:
+		return d, -1
+	}
+	return d, 0
+}
+
+// planeCrossing returns the interval, along dir, where a triangle crosses the
+// plane it has the distances d to.
+func planeCrossing(t [3]math3d.Vec3, d [3]float64, dir math3d.Vec3) (lo, hi float64) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	add := func(p math3d.Vec3) {
+		s := p.Dot(dir)
+		lo, hi = min(lo, s), max(hi, s)
+	}
+	for i := range 3 {
+		j := (i + 1) % 3
+		if d[i] == 0 {
+			add(t[i])
+		}
+		if d[i]*d[j] < 0 {
+			add(t[i].Add(t[j].Sub(t[i]).Scale(d[i] / (d[i] - d[j]))))
+		}
+	}
+	return lo, hi
+}
+
+// coplanarTrianglesIntersect reports whether two triangles of the same plane
+// overlap: an edge properly crossing another or a vertex strictly inside the
+// other triangle.
+func coplanarTrianglesIntersect(a, b [3]math3d.Vec3, normal math3d.Vec3, eps float64) bool {
+	// Project on the plane of the two largest normal axes
+	project := func(p math3d.Vec3) [2]float64 {
+		x, y, z := math.Abs(normal.X), math.Abs(normal.Y), math.Abs(normal.Z)
+		switch {
+		case x >= y && x >= z:
+			return [2]float64{p.Y, p.Z}
+		case y >= z:
+			return [2]float64{p.X, p.Z}
+		}
+		return [2]float64{p.X, p.Y}
+	}
+	var pa, pb [3][2]float64
+	for i := range 3 {
+		pa[i], pb[i] = project(a[i]), project(b[i])
+	}
+	tol := eps * eps
+	orient := func(p, q, r [2]float64) float64 {
+		return (q[0]-p[0])*(r[1]-p[1]) - (q[1]-p[1])*(r[0]-p[0])
+	}
+	opposite := func(s, t float64) bool {
+		return (s > tol && t < -tol) || (s < -tol && t > tol)
+	}
+	inside := func(p [2]float64, t [3][2]float64) bool {
+		o0, o1, o2 := orient(t[0], t[1], p), orient(t[1], t[2], p), orient(t[2], t[0], p)
+		return (o0 > tol && o1 > tol && o2 > tol) || (o0 < -tol && o1 < -tol && o2 < -tol)
+	}
+	for i := range 3 {
+		p, q := pa[i], pa[(i+1)%3]
+		for j := range 3 {
+			r, s := pb[j], pb[(j+1)%3]
+			if opposite(orient(p, q, r), orient(p, q, s)) && opposite(orient(r, s, p), orient(r, s, q)) {
+				return true
+			}
+		}
+		if inside(pa[i], pb) || inside(pb[i], pa) {
+			return true
+		}
+	}
+	return false
+}
+
+// unionFind is a disjoint set forest over integers.
+type unionFind []int
+
+func newUnionFind(n int) unionFind {
+	u := make(unionFind, n)
+	for i := range u {
+		u[i] = i
+	}
+	return u
+}
+
+func (u unionFind) find(i int) int {
+	for u[i] != i {
+		u[i] = u[u[i]]
+		i = u[i]
+	}
+	return i
+}
+
+func (u unionFind) union(a, b int) {
+	u[u.find(a)] = u.find(b)
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// tetrahedronMesh returns a closed, consistently wound tetrahedron whose
+// apex is split along a UV seam (two vertices at the same position).
+func tetrahedronMesh() *Mesh {
+	mesh := NewMesh("tetrahedron")
+	for _, p := range []math3d.Vec3{
+		math3d.V3(0, 0, 0), math3d.V3(1, 0, 0), math3d.V3(0, 1, 0), math3d.V3(0, 0, 1), math3d.V3(0, 0, 1),
+	} {
+		mesh.Vertices = append(mesh.Vertices, MeshVertex{Position: p})
+	}
+	mesh.Faces = []Face{
+		{V: [3]int{0, 1, 2}},
+		{V: [3]int{0, 3, 1}},
+		{V: [3]int{0, 2, 4}},
+		{V: [3]int{1, 3, 2}},
+	}
+	return mesh
+}
+
+func TestAnalyzeClosedMesh(t *testing.T) {
+	r := Analyze(tetrahedronMesh())
+	if !r.Printable() || r.Holes != 0 || r.Vertices != 4 || r.Triangles != 4 {
+		t.Errorf("Analyze(tetrahedron) = %+v, want a printable mesh with 4 vertices", r)
+	}
+}
+
+func TestAnalyzeDefects(t *testing.T) {
+	t.Run("hole", func(t *testing.T) {
+		mesh := tetrahedronMesh()
+		mesh.Faces = mesh.Faces[:3]
+		r := Analyze(mesh)
+		if len(r.BoundaryEdges) != 3 || r.Holes != 1 || r.Watertight() {
+			t.Errorf("got %d boundary edges, %d holes, want 3 and 1", len(r.BoundaryEdges), r.Holes)
+		}
+		if e := r.BoundaryEdges[0]; len(e.Faces) != 1 || e.From == e.To {
+			t.Errorf("boundary edge %+v, want one face and two positions", e)
+		}
+	})
+	t.Run("flipped face", func(t *testing.T) {
+		mesh := tetrahedronMesh()
+		mesh.Faces[2].V = [3]int{0, 4, 2}
+		r := Analyze(mesh)
+		if !slices.Equal(r.InconsistentFaces, []int{2}) || !r.Watertight() {
+			t.Errorf("InconsistentFaces = %v, want [2]", r.InconsistentFaces)
+		}
+	})
+	t.Run("degenerate and duplicate", func(t *testing.T) {
+		mesh := tetrahedronMesh()
+		mesh.Faces = append(mesh.Faces, Face{V: [3]int{0, 0, 1}}, Face{V: [3]int{1, 2, 0}}, Face{V: [3]int{2, 1, 0}})
+		r := Analyze(mesh)
+		if !slices.Equal(r.DegenerateFaces, []int{4}) || !slices.Equal(r.DuplicateFaces, []int{5, 6}) {
+			t.Errorf("degenerate %v, duplicate %v, want [4] and [5 6]", r.DegenerateFaces, r.DuplicateFaces)
+		}
+		if !r.Watertight() || len(r.InconsistentFaces) != 0 || r.Printable() {
+			t.Errorf("duplicates should not affect the topology: %+v", r)
+		}
+	})
+	t.Run("non-manifold edge", func(t *testing.T) {
+		mesh := tetrahedronMesh()
+		mesh.Vertices = append(mesh.Vertices, MeshVertex{Position: math3d.V3(1, 1, 1)})
+		mesh.Faces = append(mesh.Faces, Face{V: [3]int{1, 5, 2}})
+		r := Analyze(mesh)
+		if len(r.NonManifoldEdges) != 1 || len(r.NonManifoldEdges[0].Faces) != 3 {
+			t.Errorf("NonManifoldEdges = %+v, want one edge of 3 faces", r.NonManifoldEdges)
+		}
+	})
+}
+
+func TestAnalyzeSelfIntersections(t *testing.T) {
+	mesh := NewMesh("crossing")
+	for _, p := range []math3d.Vec3{
+		// Horizontal triangle
+		math3d.V3(-1, 0, -1), math3d.V3(1, 0, -1), math3d.V3(0, 0, 1),
+		// Vertical triangle through it
+		math3d.V3(0, -1, 0), math3d.V3(0, 1, 0), math3d.V3(0, 1, 0.5),
+		// Vertical triangle sharing a corner with the first one
+		math3d.V3(-1, 0, -1), math3d.V3(5, 0, -1), math3d.V3(5, 1, -1),
+		// Coplanar triangle overlapping the first one
+		math3d.V3(0, 0, 0), math3d.V3(2, 0, 0), math3d.V3(2, 0, 2),
+	} {
+		mesh.Vertices = append(mesh.Vertices, MeshVertex{Position: p})
+	}
+	mesh.Faces = []Face{{V: [3]int{0, 1, 2}}, {V: [3]int{3, 4, 5}}, {V: [3]int{6, 7, 8}}, {V: [3]int{9, 10, 11}}}
+	got := Analyze(mesh).SelfIntersections
+	want := [][2]int{{0, 1}, {0, 3}}
+	if !slices.Equal(got, want) {
+		t.Errorf("SelfIntersections = %v, want %v", got, want)
+	}
+}
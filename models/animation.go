@@ -0,0 +1,491 @@
+package models
+
+import (
+	"math"
+	"slices"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// Node is a transform node of a GLTF scene hierarchy.
+type Node struct {
+	Name        string
+	Parent      int // -1 for root nodes
+	Children    []int
+	Translation math3d.Vec3
+	Rotation    math3d.Quat
+	Scale       math3d.Vec3
+	Matrix      *math3d.Mat4 // Fixed local matrix, used instead of TRS when set
+	Weights     []float64    // Morph target weights of the node's mesh
+}
+
+// Local returns the node's transform relative to its parent.
+func (n *Node) Local() math3d.Mat4 {
+	if n.Matrix != nil {
+		return *n.Matrix
+	}
+	return math3d.TRS(n.Translation, n.Rotation, n.Scale)
+}
+
+// Skin binds vertices to a set of joint nodes.
+type Skin struct {
+	Name        string
+	Joints      []int         // Node indices
+	InverseBind []math3d.Mat4 // One per joint (identity when absent)
+}
+
+// AnimationPath is the node property an animation channel drives.
+type AnimationPath int
+
+const (
+	PathTranslation AnimationPath = iota
+	PathRotation
+	PathScale
+	PathWeights // Morph target weights
+)
+
+// Interpolation is the keyframe interpolation of an animation channel.
+type Interpolation int
+
+const (
+	InterpolationLinear Interpolation = iota
+	InterpolationStep
+	InterpolationCubicSpline // Values hold (in-tangent, value, out-tangent) per key
+)
+
+// AnimationChannel animates one property of one node.
+type AnimationChannel struct {
+	Node          int
+	Path          AnimationPath
+	Interpolation Interpolation
+	Times         []float64 // Keyframe times in seconds, ascending
+	Values        []float64 // Flattened keyframe values (3, 4 or one per morph target per element)
+}
+
+// AnimationClip is a named set of channels played together.
+type AnimationClip struct {
+	Name     string
+	Channels []AnimationChannel
+	Duration float64 // Seconds (last keyframe time)
+}
+
+// RigPart is a contiguous range of Mesh.Vertices attached to a node or skin.
+type RigPart struct {
+	Node    int // Node the geometry is instanced by
+	Skin    int // Index into Rig.Skins, -1 for rigid parts
+	First   int // First vertex
+	Count   int // Number of vertices
+	Joints  [][4]int
+	Weights [][4]float64
+	Targets []MorphTarget // Blend shapes, weighted by the node's Weights
+}
+
+// MorphTarget holds the per-vertex deltas of one blend shape of a RigPart.
+type MorphTarget struct {
+	Name      string
+	Positions []math3d.Vec3
+	Normals   []math3d.Vec3 // Optional
+}
+
+// MorphWeight identifies one morph target weight of a node.
+type MorphWeight struct {
+	Node  int
+	Index int
+	Name  string
+}
+
+// Rig holds the node hierarchy, skins and animation clips of a GLTF model
+// so that the flattened mesh can be re-posed at any time of a clip.
+type Rig struct {
+	Nodes []Node
+	Skins []Skin
+	Clips []AnimationClip
+	Parts []RigPart
+	// Bind holds the source vertices in node space, indexed like Mesh.Vertices.
+	Bind []MeshVertex
+	// Root is applied after the scene transforms (accumulates Mesh.Transform).
+	Root math3d.Mat4
+	// Recompute normals after posing (the source had none).
+	CalculateNormals bool
+	SmoothNormals    bool
+	// Interactive morph weight adjustments per node, added to the rest or animated weights
+	offsets [][]float64
+	// Scratch space reused between poses
+	buf   []float64
+	pose  []Node
+	world []math3d.Mat4
+	done  []bool
+	joint []math3d.Mat4
+}
+
+// NewRig creates an empty rig.
+func NewRig() *Rig {
+	return &Rig{Root: math3d.Identity()}
+}
+
+// ClipCount returns the number of animation clips.
+func (r *Rig) ClipCount() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.Clips)
+}
+
+// clone returns a copy sharing the immutable rig data.
+func (r *Rig) clone() *Rig {
+	if r == nil {
+		return nil
+	}
+	c := *r
+	c.buf, c.pose, c.world, c.done, c.joint = nil, nil, nil, nil, nil
+	c.Nodes = slices.Clone(r.Nodes)
+	for i := range c.Nodes {
+		c.Nodes[i].Weights = slices.Clone(c.Nodes[i].Weights)
+	}
+	c.offsets = slices.Clone(r.offsets)
+	for i := range c.offsets {
+		c.offsets[i] = slices.Clone(c.offsets[i])
+	}
+	return &c
+}
+
+// MorphWeights lists the adjustable morph target weights, per node.
+func (r *Rig) MorphWeights() []MorphWeight {
+	if r == nil {
+		return nil
+	}
+	var result []MorphWeight
+	seen := make(map[int]bool)
+	for _, p := range r.Parts {
+		if len(p.Targets) == 0 || seen[p.Node] {
+			continue
+		}
+		seen[p.Node] = true
+		for i, t := range p.Targets {
+			result = append(result, MorphWeight{Node: p.Node, Index: i, Name: t.Name})
+		}
+	}
+	return result
+}
+
+// MorphWeight returns the weight of morph target index of node used by the
+// last Pose, or the rest weight plus any adjustment before the first pose.
+func (r *Rig) MorphWeight(node, index int) float64 {
+	if node < 0 || node >= len(r.Nodes) || index < 0 {
+		return 0
+	}
+	if node < len(r.pose) {
+		return weightAt(r.pose[node].Weights, index)
+	}
+	w := weightAt(r.Nodes[node].Weights, index)
+	if node < len(r.offsets) {
+		w += weightAt(r.offsets[node], index)
+	}
+	return w
+}
+
+// SetMorphWeight makes the current pose use weight w for morph target index
+// of node. The change is kept as an offset that is added to the rest or
+// animated weight of later poses, so it combines with clips animating the
+// node's weights.
+func (r *Rig) SetMorphWeight(node, index int, w float64) {
+	if node < 0 || node >= len(r.Nodes) || index < 0 {
+		return
+	}
+	delta := w - r.MorphWeight(node, index)
+	if node >= len(r.offsets) {
+		r.offsets = append(r.offsets, make([][]float64, node+1-len(r.offsets))...)
+	}
+	r.offsets[node] = addWeight(r.offsets[node], index, delta)
+	if node < len(r.pose) {
+		// Copy since posed weights may share the rest weights
+		r.pose[node].Weights = addWeight(slices.Clone(r.pose[node].Weights), index, delta)
+	}
+}
+
+// weightAt returns weights[index], or 0 past the end.
+func weightAt(weights []float64, index int) float64 {
+	if index >= len(weights) {
+		return 0
+	}
+	return weights[index]
+}
+
+// addWeight adds delta to weights[index], growing weights as needed.
+func addWeight(weights []float64, index int, delta float64) []float64 {
+	if index >= len(weights) {
+		weights = append(weights, make([]float64, index+1-len(weights))...)
+	}
+	weights[index] += delta
+	return weights
+}
+
+// Pose evaluates clip at time t (seconds) and writes the posed vertices.
+// A clip index out of range poses the rest (bind) transforms of the nodes.
+func (r *Rig) Pose(clip int, t float64, vertices []MeshVertex) {
+	r.pose = append(r.pose[:0], r.Nodes...)
+	if clip >= 0 && clip < len(r.Clips) {
+		for i := range r.Clips[clip].Channels {
+			ch := &r.Clips[clip].Channels[i]
+			if ch.Node < 0 || ch.Node >= len(r.pose) {
+				continue
+			}
+			v := ch.Sample(t, r.buf)
+			r.buf = v
+			n := &r.pose[ch.Node]
+			switch ch.Path {
+			case PathTranslation:
+				n.Translation = math3d.V3(v[0], v[1], v[2])
+			case PathRotation:
+				n.Rotation = math3d.Q(v[0], v[1], v[2], v[3]).Normalize()
+			case PathScale:
+				n.Scale = math3d.V3(v[0], v[1], v[2])
+			case PathWeights:
+				n.Weights = append([]float64(nil), v...)
+				continue // weights do not affect the transform
+			}
+			n.Matrix = nil // animated nodes always use TRS
+		}
+	}
+	for node, offs := range r.offsets {
+		if len(offs) == 0 || node >= len(r.pose) {
+			continue
+		}
+		n := &r.pose[node]
+		n.Weights = slices.Clone(n.Weights)
+		for i, o := range offs {
+			n.Weights = addWeight(n.Weights, i, o)
+		}
+	}
+	r.computeWorld()
+	for pi := range r.Parts {
+		p := &r.Parts[pi]
+		if p.Skin < 0 || p.Skin >= len(r.Skins) {
+			r.poseRigid(p, vertices)
+		} else {
+			r.poseSkinned(p, vertices)
+		}
+	}
+}
+
+// computeWorld fills r.world with the Root-relative transform of every node.
+func (r *Rig) computeWorld() {
+	n := len(r.pose)
+	r.world = slices.Grow(r.world[:0], n)[:n]
+	r.done = slices.Grow(r.done[:0], n)[:n]
+	clear(r.done)
+	for i := range r.pose {
+		r.worldOf(i)
+	}
+}
+
+func (r *Rig) worldOf(i int) math3d.Mat4 {
+	if r.done[i] {
+		return r.world[i]
+	}
+	r.done[i] = true // also guards against cycles in malformed files
+	parent := r.Root
+	if p := r.pose[i].Parent; p >= 0 && p < len(r.pose) {
+		parent = r.worldOf(p)
+	}
+	r.world[i] = parent.Mul(r.pose[i].Local())
+	return r.world[i]
+}
+
+func (r *Rig) poseRigid(p *RigPart, vertices []MeshVertex) {
+	m := r.Root
+	if p.Node >= 0 && p.Node < len(r.world) {
+		m = r.world[p.Node]
+	}
+	for k := range p.Count {
+		pos, normal := r.morph(p, k)
+		vertices[p.First+k].Position = m.MulVec3(pos)
+		vertices[p.First+k].Normal = m.MulVec3Dir(normal).Normalize()
+	}
+}
+
+// morph returns the bind position and normal of vertex k of p with the
+// posed morph target weights applied.
+func (r *Rig) morph(p *RigPart, k int) (math3d.Vec3, math3d.Vec3) {
+	b := r.Bind[p.First+k]
+	if len(p.Targets) == 0 || p.Node < 0 || p.Node >= len(r.pose) {
+		return b.Position, b.Normal
+	}
+	pos, normal := b.Position, b.Normal
+	weights := r.pose[p.Node].Weights
+	for i := range min(len(weights), len(p.Targets)) {
+		w := weights[i]
+		if w == 0 {
+			continue
+		}
+		t := &p.Targets[i]
+		if k < len(t.Positions) {
+			pos = pos.Add(t.Positions[k].Scale(w))
+		}
+		if k < len(t.Normals) {
+			normal = normal.Add(t.Normals[k].Scale(w))
+		}
+	}
+	return pos, normal
+}
+
+func (r *Rig) poseSkinned(p *RigPart, vertices []MeshVertex) {
+	skin := &r.Skins[p.Skin]
+	// The skinned mesh's own node transform is ignored (GLTF spec)
+	r.joint = r.joint[:0]
+	for j, node := range skin.Joints {
+		m := r.Root
+		if node >= 0 && node < len(r.world) {
+			m = r.world[node]
+		}
+		if j < len(skin.InverseBind) {
+			m = m.Mul(skin.InverseBind[j])
+		}
+		r.joint = append(r.joint, m)
+	}
+	for k := range p.Count {
+		i := p.First + k
+		bindPos, bindNormal := r.morph(p, k)
+		var pos, normal math3d.Vec3
+		total := 0.0
+		for w := range 4 {
+			weight := p.Weights[k][w]
+			j := p.Joints[k][w]
+			if weight == 0 || j < 0 || j >= len(r.joint) {
+				continue
+			}
+			m := &r.joint[j]
+			pos = pos.Add(m.MulVec3(bindPos).Scale(weight))
+			normal = normal.Add(m.MulVec3Dir(bindNormal).Scale(weight))
+			total += weight
+		}
+		if total == 0 {
+			pos = r.Root.MulVec3(bindPos)
+			normal = r.Root.MulVec3Dir(bindNormal)
+		} else if total != 1 {
+			pos = pos.Scale(1 / total)
+		}
+		vertices[i].Position = pos
+		vertices[i].Normal = normal.Normalize()
+	}
+}
+
+// components returns the number of values per keyframe element.
+func (c *AnimationChannel) components() int {
+	switch c.Path {
+	case PathRotation:
+		return 4
+	case PathWeights:
+		perKey := len(c.Times)
+		if c.Interpolation == InterpolationCubicSpline {
+			perKey *= 3
+		}
+		if perKey == 0 {
+			return 0
+		}
+		return len(c.Values) / perKey
+	default:
+		return 3
+	}
+}
+
+// Sample evaluates the channel at time t into out (grown as needed) and
+// returns the written values. Times before the first or after the last
+// keyframe clamp to the end values.
+func (c *AnimationChannel) Sample(t float64, out []float64) []float64 {
+	comps := c.components()
+	out = slices.Grow(out[:0], comps)[:comps]
+	keys := len(c.Times)
+	stride, offset := comps, 0
+	if c.Interpolation == InterpolationCubicSpline {
+		stride, offset = 3*comps, comps
+	}
+	if keys == 0 || len(c.Values) < keys*stride {
+		return out
+	}
+	value := func(k int) []float64 {
+		return c.Values[k*stride+offset : k*stride+offset+comps]
+	}
+	if t <= c.Times[0] {
+		copy(out, value(0))
+		return out
+	}
+	if t >= c.Times[keys-1] {
+		copy(out, value(keys-1))
+		return out
+	}
+	k1, _ := slices.BinarySearch(c.Times, t)
+	k0 := k1 - 1
+	t0, t1 := c.Times[k0], c.Times[k1]
+	h := t1 - t0
+	if h <= 0 {
+		copy(out, value(k1))
+		return out
+	}
+	u := (t - t0) / h
+	switch c.Interpolation {
+	case InterpolationStep:
+		copy(out, value(k0))
+	case InterpolationCubicSpline:
+		// Hermite spline with tangents scaled by the keyframe interval
+		u2 := u * u
+		u3 := u2 * u
+		h00 := 2*u3 - 3*u2 + 1
+		h10 := u3 - 2*u2 + u
+		h01 := -2*u3 + 3*u2
+		h11 := u3 - u2
+		p0, p1 := value(k0), value(k1)
+		out0 := c.Values[k0*stride+2*comps:]
+		in1 := c.Values[k1*stride:]
+		for i := range comps {
+			out[i] = h00*p0[i] + h10*h*out0[i] + h01*p1[i] + h11*h*in1[i]
+		}
+		if c.Path == PathRotation {
+			q := math3d.Q(out[0], out[1], out[2], out[3]).Normalize()
+			out[0], out[1], out[2], out[3] = q.X, q.Y, q.Z, q.W
+		}
+	default:
+		a, b := value(k0), value(k1)
+		if c.Path == PathRotation {
+			q := math3d.Q(a[0], a[1], a[2], a[3]).Slerp(math3d.Q(b[0], b[1], b[2], b[3]), u)
+			out[0], out[1], out[2], out[3] = q.X, q.Y, q.Z, q.W
+			return out
+		}
+		for i := range comps {
+			out[i] = a[i] + (b[i]-a[i])*u
+		}
+	}
+	return out
+}
+
+// Pose re-poses an animated mesh at time t (seconds) of the given clip and
+// updates normals, bounds and the BVH. Meshes without a Rig are unchanged.
+func (m *Mesh) Pose(clip int, t float64) {
+	if m.Rig == nil || len(m.Rig.Bind) != len(m.Vertices) {
+		return
+	}
+	m.Rig.Pose(clip, t, m.Vertices)
+	if m.Rig.CalculateNormals {
+		if m.Rig.SmoothNormals {
+			m.CalculateSmoothNormals()
+		} else {
+			m.CalculateNormals()
+		}
+	}
+	m.CalculateBounds()
+	if m.BVH.Valid() {
+		m.BVH.Refit()
+	}
+}
+
+// WrapClipTime maps t into [0, duration) for looping playback.
+func WrapClipTime(t, duration float64) float64 {
+	if duration <= 0 {
+		return 0
+	}
+	t = math.Mod(t, duration)
+	if t < 0 {
+		t += duration
+	}
+	return t
+}
@@ -0,0 +1,257 @@
+package models
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ansipixels/trophy/math3d"
+	"github.com/qmuntal/gltf"
+	"github.com/qmuntal/gltf/modeler"
+)
+
+// encodeGLB encodes doc as a binary GLTF file named model.glb.
+func encodeGLB(t *testing.T, doc *gltf.Document) fstest.MapFS {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gltf.NewEncoder(&buf).Encode(doc); err != nil {
+		t.Fatalf("encode glb: %v", err)
+	}
+	return fstest.MapFS{"model.glb": {Data: buf.Bytes()}}
+}
+
+// skinnedArmDoc builds a triangle skinned to a two-joint arm. The tip vertex
+// follows the second joint, which the first clip rotates 90° around Z and the
+// second clip (STEP) moves the whole arm by +2 on X.
+func skinnedArmDoc() *gltf.Document {
+	doc := gltf.NewDocument()
+	pos := modeler.WritePosition(doc, [][3]float32{{0, 0, 0}, {1, 0, 0}, {0, 2, 0}})
+	joints := modeler.WriteJoints(doc, [][4]uint8{{0, 0, 0, 0}, {0, 0, 0, 0}, {1, 0, 0, 0}})
+	weights := modeler.WriteWeights(doc, [][4]float32{{1, 0, 0, 0}, {1, 0, 0, 0}, {1, 0, 0, 0}})
+	// Indexed [row][column]
+	ibm := modeler.WriteInverseBindMatrices(doc, [][4][4]float32{
+		{{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}, {0, 0, 0, 1}},
+		{{1, 0, 0, 0}, {0, 1, 0, -1}, {0, 0, 1, 0}, {0, 0, 0, 1}},
+	})
+	times := modeler.WriteAccessor(doc, gltf.TargetNone, []float32{0, 1})
+	s := float32(math.Sqrt2 / 2)
+	rotations := modeler.WriteAccessor(doc, gltf.TargetNone, [][4]float32{{0, 0, 0, 1}, {0, 0, s, s}})
+	moves := modeler.WriteAccessor(doc, gltf.TargetNone, [][3]float32{{0, 0, 0}, {2, 0, 0}})
+	doc.Meshes = []*gltf.Mesh{{
+		Primitives: []*gltf.Primitive{{
+			Attributes: gltf.PrimitiveAttributes{
+				gltf.POSITION:  pos,
+				gltf.JOINTS_0:  joints,
+				gltf.WEIGHTS_0: weights,
+			},
+		}},
+	}}
+	doc.Nodes = []*gltf.Node{
+		{Name: "body", Mesh: gltf.Index(0), Skin: gltf.Index(0)},
+		{Name: "shoulder", Children: []int{2}},
+		{Name: "elbow", Translation: [3]float64{0, 1, 0}},
+	}
+	doc.Skins = []*gltf.Skin{{Joints: []int{1, 2}, InverseBindMatrices: gltf.Index(ibm)}}
+	doc.Scenes = []*gltf.Scene{{Nodes: []int{0, 1}}}
+	doc.Scene = gltf.Index(0)
+	doc.Animations = []*gltf.Animation{
+		{
+			Name:     "bend",
+			Samplers: []*gltf.AnimationSampler{{Input: times, Output: rotations}},
+			Channels: []*gltf.AnimationChannel{{
+				Sampler: 0,
+				Target:  gltf.AnimationChannelTarget{Node: gltf.Index(2), Path: gltf.TRSRotation},
+			}},
+		},
+		{
+			Samplers: []*gltf.AnimationSampler{{Input: times, Output: moves, Interpolation: gltf.InterpolationStep}},
+			Channels: []*gltf.AnimationChannel{{
+				Sampler: 0,
+				Target:  gltf.AnimationChannelTarget{Node: gltf.Index(1), Path: gltf.TRSTranslation},
+			}},
+		},
+	}
+	return doc
+}
+
+func assertVec3(t *testing.T, what string, got, want math3d.Vec3) {
+	t.Helper()
+	if got.Distance(want) > 1e-5 {
+		t.Errorf("%s = %v, want %v", what, got, want)
+	}
+}
+
+func TestLoadSkinnedGLB(t *testing.T) {
+	mesh, err := LoadGLBFromFS(encodeGLB(t, skinnedArmDoc()), "model.glb")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	rig := mesh.Rig
+	if rig == nil {
+		t.Fatal("expected a rig for a skinned model")
+	}
+	if rig.ClipCount() != 2 || rig.Clips[0].Name != "bend" || rig.Clips[1].Name != "clip 2" {
+		t.Fatalf("unexpected clips: %+v", rig.Clips)
+	}
+	if rig.Clips[0].Duration != 1 {
+		t.Errorf("duration = %v, want 1", rig.Clips[0].Duration)
+	}
+	if len(rig.Parts) != 1 || rig.Parts[0].Skin != 0 || rig.Parts[0].Count != 3 {
+		t.Fatalf("unexpected parts: %+v", rig.Parts)
+	}
+	// Rest pose matches the bind pose
+	assertVec3(t, "rest tip", mesh.Vertices[2].Position, math3d.V3(0, 2, 0))
+	mesh.Pose(0, 1)
+	assertVec3(t, "bent tip", mesh.Vertices[2].Position, math3d.V3(-1, 1, 0))
+	assertVec3(t, "bent base", mesh.Vertices[1].Position, math3d.V3(1, 0, 0))
+	mesh.Pose(0, 0.5)
+	assertVec3(t, "half bent tip", mesh.Vertices[2].Position, math3d.V3(-math.Sqrt2/2, 1+math.Sqrt2/2, 0))
+	// STEP holds the first key until the next one
+	mesh.Pose(1, 0.99)
+	assertVec3(t, "step tip", mesh.Vertices[2].Position, math3d.V3(0, 2, 0))
+	mesh.Pose(1, 1)
+	assertVec3(t, "moved tip", mesh.Vertices[2].Position, math3d.V3(2, 2, 0))
+	if mesh.BoundsMin.X != 2 {
+		t.Errorf("bounds not updated after pose: %v", mesh.BoundsMin)
+	}
+}
+
+func TestPoseKeepsMeshTransform(t *testing.T) {
+	mesh, err := LoadGLBFromFS(encodeGLB(t, skinnedArmDoc()), "model.glb")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	mesh.Transform(math3d.ScaleUniform(2))
+	mesh.BuildBVH()
+	mesh.Pose(0, 1)
+	assertVec3(t, "scaled bent tip", mesh.Vertices[2].Position, math3d.V3(-2, 2, 0))
+	if minV, _ := mesh.BVH.Bounds(); math.Abs(minV.X+2) > 1e-9 {
+		t.Errorf("BVH not refit after pose: min %v", minV)
+	}
+	clone := mesh.Clone()
+	clone.Pose(-1, 0)
+	assertVec3(t, "clone rest tip", clone.Vertices[2].Position, math3d.V3(0, 4, 0))
+	assertVec3(t, "original still bent", mesh.Vertices[2].Position, math3d.V3(-2, 2, 0))
+}
+
+func TestStaticGLBHasNoRig(t *testing.T) {
+	doc := gltf.NewDocument()
+	pos := modeler.WritePosition(doc, [][3]float32{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}})
+	doc.Meshes = []*gltf.Mesh{{Primitives: []*gltf.Primitive{{Attributes: gltf.PrimitiveAttributes{gltf.POSITION: pos}}}}}
+	doc.Nodes = []*gltf.Node{{Mesh: gltf.Index(0)}}
+	doc.Scenes = []*gltf.Scene{{Nodes: []int{0}}}
+	mesh, err := LoadGLBFromFS(encodeGLB(t, doc), "model.glb")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if mesh.Rig != nil {
+		t.Error("static model should not have a rig")
+	}
+	mesh.Pose(0, 1) // no-op
+	if mesh.TriangleCount() != 1 {
+		t.Errorf("triangles = %d, want 1", mesh.TriangleCount())
+	}
+}
+
+func TestCubicSplineSample(t *testing.T) {
+	// Tangents equal to the slope make the Hermite curve a straight line
+	ch := AnimationChannel{
+		Path:          PathTranslation,
+		Interpolation: InterpolationCubicSpline,
+		Times:         []float64{0, 2},
+		Values: []float64{
+			0.5, 0.5, 0.5, 0, 0, 0, 0.5, 0.5, 0.5,
+			0.5, 0.5, 0.5, 1, 1, 1, 0.5, 0.5, 0.5,
+		},
+	}
+	var buf [4]float64
+	for _, tc := range []struct{ t, want float64 }{{-1, 0}, {0.5, 0.25}, {1, 0.5}, {3, 1}} {
+		if got := ch.Sample(tc.t, buf[:]); math.Abs(got[0]-tc.want) > 1e-12 {
+			t.Errorf("Sample(%v) = %v, want %v", tc.t, got[0], tc.want)
+		}
+	}
+}
+
+func TestWrapClipTime(t *testing.T) {
+	for _, tc := range []struct{ t, d, want float64 }{{2.5, 2, 0.5}, {-0.5, 2, 1.5}, {1, 0, 0}} {
+		if got := WrapClipTime(tc.t, tc.d); math.Abs(got-tc.want) > 1e-12 {
+			t.Errorf("WrapClipTime(%v, %v) = %v, want %v", tc.t, tc.d, got, tc.want)
+		}
+	}
+}
+
+// morphQuadDoc builds a triangle with two named morph targets ("smile" moves
+// the tip up, "wide" moves the base right) and a clip animating the weights.
+func morphQuadDoc() *gltf.Document {
+	doc := gltf.NewDocument()
+	pos := modeler.WritePosition(doc, [][3]float32{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}})
+	smile := modeler.WriteAccessor(doc, gltf.TargetArrayBuffer, [][3]float32{{0, 0, 0}, {0, 0, 0}, {0, 1, 0}})
+	wide := modeler.WriteAccessor(doc, gltf.TargetArrayBuffer, [][3]float32{{0, 0, 0}, {1, 0, 0}, {0, 0, 0}})
+	times := modeler.WriteAccessor(doc, gltf.TargetNone, []float32{0, 1})
+	weights := modeler.WriteAccessor(doc, gltf.TargetNone, []float32{0, 0, 1, 0.5})
+	doc.Meshes = []*gltf.Mesh{{
+		Extras: map[string]any{"targetNames": []any{"smile", "wide"}},
+		Primitives: []*gltf.Primitive{{
+			Attributes: gltf.PrimitiveAttributes{gltf.POSITION: pos},
+			Targets: []gltf.PrimitiveAttributes{
+				{gltf.POSITION: smile},
+				{gltf.POSITION: wide},
+			},
+		}},
+		Weights: []float64{0.5, 0},
+	}}
+	doc.Nodes = []*gltf.Node{{Mesh: gltf.Index(0)}}
+	doc.Scenes = []*gltf.Scene{{Nodes: []int{0}}}
+	doc.Animations = []*gltf.Animation{{
+		Samplers: []*gltf.AnimationSampler{{Input: times, Output: weights}},
+		Channels: []*gltf.AnimationChannel{{
+			Sampler: 0,
+			Target:  gltf.AnimationChannelTarget{Node: gltf.Index(0), Path: gltf.TRSWeights},
+		}},
+	}}
+	return doc
+}
+
+func TestLoadMorphTargets(t *testing.T) {
+	mesh, err := LoadGLBFromFS(encodeGLB(t, morphQuadDoc()), "model.glb")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	rig := mesh.Rig
+	if rig == nil {
+		t.Fatal("expected a rig for a morphed model")
+	}
+	morphs := rig.MorphWeights()
+	if len(morphs) != 2 || morphs[0].Name != "smile" || morphs[1].Name != "wide" {
+		t.Fatalf("unexpected morph weights: %+v", morphs)
+	}
+	// Default mesh weights apply at load
+	assertVec3(t, "rest tip", mesh.Vertices[2].Position, math3d.V3(0, 1.5, 0))
+	// Interactive weights
+	rig.SetMorphWeight(0, 1, 1)
+	if w := rig.MorphWeight(0, 1); w != 1 {
+		t.Errorf("MorphWeight = %v, want 1", w)
+	}
+	mesh.Pose(-1, 0)
+	assertVec3(t, "wide base", mesh.Vertices[1].Position, math3d.V3(2, 0, 0))
+	// Interactive weights are added to the animated ones
+	mesh.Pose(0, 0.5)
+	assertVec3(t, "animated tip", mesh.Vertices[2].Position, math3d.V3(0, 1.5, 0))
+	assertVec3(t, "animated base", mesh.Vertices[1].Position, math3d.V3(2.25, 0, 0))
+	if w := rig.MorphWeight(0, 1); w != 1.25 {
+		t.Errorf("posed MorphWeight = %v, want 1.25", w)
+	}
+	rig.SetMorphWeight(0, 1, 0.25)
+	if w := rig.MorphWeight(0, 1); w != 0.25 {
+		t.Errorf("MorphWeight after edit = %v, want 0.25", w)
+	}
+	mesh.Pose(0, 0.5)
+	assertVec3(t, "animated base", mesh.Vertices[1].Position, math3d.V3(1.25, 0, 0))
+	// Clones keep their own weights
+	clone := mesh.Clone()
+	clone.Rig.SetMorphWeight(0, 0, 0)
+	if rig.MorphWeight(0, 0) != 0.5 {
+		t.Error("clone weights should not affect the original")
+	}
+}
@@ -0,0 +1,513 @@
+package models
+
+import (
+	"math"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// BVH is a bounding volume hierarchy over the faces of a Mesh.
+// It is built top-down with a binned surface area heuristic (SAH) and
+// answers ray, box and frustum-style queries without touching every face.
+// Node bounds are in the mesh's local space.
+type BVH struct {
+	Nodes       []BVHNode // Nodes[0] is the root
+	FaceIndices []int     // Face indices, each leaf references a contiguous range
+	mesh        *Mesh
+}
+
+// BVHNode is a single node of a BVH.
+// Leaves have Count > 0 and reference FaceIndices[First:First+Count].
+// Interior nodes have Count == 0; their left child immediately follows
+// them in BVH.Nodes and their right child is at index Right.
+type BVHNode struct {
+	Min, Max math3d.Vec3
+	Right    int
+	First    int
+	Count    int
+}
+
+// IsLeaf returns true if the node references faces directly.
+func (n *BVHNode) IsLeaf() bool {
+	return n.Count > 0
+}
+
+// RayHit describes the closest intersection found by BVH.IntersectRay.
+type RayHit struct {
+	Face  int         // Index into Mesh.Faces
+	T     float64     // Distance along the ray (in units of the direction length)
+	U, V  float64     // Barycentric coordinates of the hit (weights of V[1] and V[2])
+	Point math3d.Vec3 // Hit position
+}
+
+const (
+	bvhMaxLeafFaces  = 4  // Never split below this many faces
+	bvhBins          = 12 // SAH bins per axis
+	bvhTraversalCost = 1.0
+	bvhIntersectCost = 1.0
+)
+
+// bvhBuilder holds per-face data only needed while building.
+type bvhBuilder struct {
+	bvh       *BVH
+	faceMin   []math3d.Vec3
+	faceMax   []math3d.Vec3
+	centroids []math3d.Vec3
+}
+
+// NewBVH builds a bounding volume hierarchy over all faces of the mesh.
+// The BVH keeps a reference to the mesh; call Refit after moving vertices
+// and rebuild it after adding or removing faces.
+func NewBVH(m *Mesh) *BVH {
+	n := len(m.Faces)
+	b := &bvhBuilder{
+		bvh: &BVH{
+			Nodes:       make([]BVHNode, 0, 2*n/bvhMaxLeafFaces+1),
+			FaceIndices: make([]int, n),
+			mesh:        m,
+		},
+		faceMin:   make([]math3d.Vec3, n),
+		faceMax:   make([]math3d.Vec3, n),
+		centroids: make([]math3d.Vec3, n),
+	}
+	for i, f := range m.Faces {
+		b.bvh.FaceIndices[i] = i
+		fMin, fMax := m.faceBounds(f)
+		b.faceMin[i] = fMin
+		b.faceMax[i] = fMax
+		b.centroids[i] = fMin.Add(fMax).Scale(0.5)
+	}
+	if n == 0 {
+		return b.bvh
+	}
+	b.build(0, n)
+	return b.bvh
+}
+
+// BuildBVH builds a BVH for the mesh and stores it in m.BVH.
+func (m *Mesh) BuildBVH() *BVH {
+	m.BVH = NewBVH(m)
+	return m.BVH
+}
+
+// faceBounds returns the bounding box of a single face.
+func (m *Mesh) faceBounds(f Face) (minV, maxV math3d.Vec3) {
+	p0 := m.Vertices[f.V[0]].Position
+	p1 := m.Vertices[f.V[1]].Position
+	p2 := m.Vertices[f.V[2]].Position
+	return p0.Min(p1).Min(p2), p0.Max(p1).Max(p2)
+}
+
+// build recursively creates the node for FaceIndices[first:first+count]
+// and returns its index.
+func (b *bvhBuilder) build(first, count int) int {
+	idx := len(b.bvh.Nodes)
+	b.bvh.Nodes = append(b.bvh.Nodes, BVHNode{})
+	faces := b.bvh.FaceIndices[first : first+count]
+	nodeMin, nodeMax := b.faceMin[faces[0]], b.faceMax[faces[0]]
+	cMin, cMax := b.centroids[faces[0]], b.centroids[faces[0]]
+	for _, fi := range faces[1:] {
+		nodeMin = nodeMin.Min(b.faceMin[fi])
+		nodeMax = nodeMax.Max(b.faceMax[fi])
+		cMin = cMin.Min(b.centroids[fi])
+		cMax = cMax.Max(b.centroids[fi])
+	}
+	b.bvh.Nodes[idx].Min = nodeMin
+	b.bvh.Nodes[idx].Max = nodeMax
+	if count <= bvhMaxLeafFaces {
+		b.makeLeaf(idx, first, count)
+		return idx
+	}
+	axis, split, cost := b.findSplit(faces, cMin, cMax, surfaceArea(nodeMin, nodeMax, count))
+	leafCost := bvhIntersectCost * float64(count)
+	if axis < 0 || cost >= leafCost {
+		b.makeLeaf(idx, first, count)
+		return idx
+	}
+	// Partition faces so that those in bins below split come first
+	lo := axisComponent(cMin, axis)
+	extent := axisComponent(cMax, axis) - lo
+	i, j := 0, len(faces)-1
+	for i <= j {
+		if binIndex(axisComponent(b.centroids[faces[i]], axis), lo, extent) < split {
+			i++
+		} else {
+			faces[i], faces[j] = faces[j], faces[i]
+			j--
+		}
+	}
+	if i == 0 || i == len(faces) {
+		b.makeLeaf(idx, first, count)
+		return idx
+	}
+	b.build(first, i)
+	right := b.build(first+i, count-i)
+	b.bvh.Nodes[idx].Right = right
+	return idx
+}
+
+func (b *bvhBuilder) makeLeaf(idx, first, count int) {
+	b.bvh.Nodes[idx].First = first
+	b.bvh.Nodes[idx].Count = count
+}
+
+// findSplit evaluates the SAH cost of every bin boundary on every axis.
+// Returns the best axis (-1 if none), the first bin of the right side and the cost.
+func (b *bvhBuilder) findSplit(
+	faces []int,
+	cMin, cMax math3d.Vec3,
+	parentArea float64,
+) (bestAxis, bestSplit int, bestCost float64) {
+	bestAxis = -1
+	bestCost = math.Inf(1)
+	type bin struct {
+		min, max math3d.Vec3
+		count    int
+	}
+	for axis := range 3 {
+		lo := axisComponent(cMin, axis)
+		extent := axisComponent(cMax, axis) - lo
+		if extent <= 0 {
+			continue
+		}
+		var bins [bvhBins]bin
+		for _, fi := range faces {
+			bi := binIndex(axisComponent(b.centroids[fi], axis), lo, extent)
+			if bins[bi].count == 0 {
+				bins[bi].min, bins[bi].max = b.faceMin[fi], b.faceMax[fi]
+			} else {
+				bins[bi].min = bins[bi].min.Min(b.faceMin[fi])
+				bins[bi].max = bins[bi].max.Max(b.faceMax[fi])
+			}
+			bins[bi].count++
+		}
+		// Sweep from the right to collect suffix areas and counts
+		var rightArea [bvhBins]float64
+		var rightCount [bvhBins]int
+		var accMin, accMax math3d.Vec3
+		acc := 0
+		for i := bvhBins - 1; i > 0; i-- {
+			if bins[i].count > 0 {
+				if acc == 0 {
+					accMin, accMax = bins[i].min, bins[i].max
+				} else {
+					accMin = accMin.Min(bins[i].min)
+					accMax = accMax.Max(bins[i].max)
+				}
+				acc += bins[i].count
+			}
+			rightCount[i] = acc
+			rightArea[i] = surfaceArea(accMin, accMax, acc)
+		}
+		// Sweep from the left, evaluating each split
+		acc = 0
+		for i := range bvhBins - 1 {
+			if bins[i].count > 0 {
+				if acc == 0 {
+					accMin, accMax = bins[i].min, bins[i].max
+				} else {
+					accMin = accMin.Min(bins[i].min)
+					accMax = accMax.Max(bins[i].max)
+				}
+				acc += bins[i].count
+			}
+			if acc == 0 || rightCount[i+1] == 0 {
+				continue
+			}
+			cost := surfaceArea(accMin, accMax, acc)*float64(acc) + rightArea[i+1]*float64(rightCount[i+1])
+			if cost < bestCost {
+				bestCost, bestAxis, bestSplit = cost, axis, i+1
+			}
+		}
+	}
+	if bestAxis < 0 {
+		return -1, 0, bestCost
+	}
+	// Normalize by the parent area so the cost is comparable to the leaf cost
+	if parentArea <= 0 {
+		return bestAxis, bestSplit, 0
+	}
+	return bestAxis, bestSplit, bvhTraversalCost + bvhIntersectCost*bestCost/parentArea
+}
+
+// surfaceArea returns the surface area of a box (0 for an empty set).
+func surfaceArea(minV, maxV math3d.Vec3, count int) float64 {
+	if count == 0 {
+		return 0
+	}
+	d := maxV.Sub(minV)
+	return 2 * (d.X*d.Y + d.Y*d.Z + d.Z*d.X)
+}
+
+func binIndex(c, lo, extent float64) int {
+	bi := int(float64(bvhBins) * (c - lo) / extent)
+	return max(0, min(bvhBins-1, bi))
+}
+
+func axisComponent(v math3d.Vec3, axis int) float64 {
+	switch axis {
+	case 0:
+		return v.X
+	case 1:
+		return v.Y
+	default:
+		return v.Z
+	}
+}
+
+// Refit recomputes all node bounds from the current vertex positions
+// without changing the tree topology. Use it after transforming or
+// deforming the mesh; rebuild instead if the faces changed.
+func (b *BVH) Refit() {
+	m := b.mesh
+	// Children always have a larger index than their parent, so a reverse
+	// sweep visits children before parents.
+	for i := len(b.Nodes) - 1; i >= 0; i-- {
+		n := &b.Nodes[i]
+		if n.IsLeaf() {
+			n.Min, n.Max = m.faceBounds(m.Faces[b.FaceIndices[n.First]])
+			for _, fi := range b.FaceIndices[n.First+1 : n.First+n.Count] {
+				fMin, fMax := m.faceBounds(m.Faces[fi])
+				n.Min = n.Min.Min(fMin)
+				n.Max = n.Max.Max(fMax)
+			}
+			continue
+		}
+		left, right := &b.Nodes[i+1], &b.Nodes[n.Right]
+		n.Min = left.Min.Min(right.Min)
+		n.Max = left.Max.Max(right.Max)
+	}
+}
+
+// Valid returns true if the BVH still covers exactly the faces of its mesh.
+func (b *BVH) Valid() bool {
+	return b != nil && b.mesh != nil && len(b.FaceIndices) == len(b.mesh.Faces)
+}
+
+// Bounds returns the bounds of the root node.
+func (b *BVH) Bounds() (minV, maxV math3d.Vec3) {
+	if len(b.Nodes) == 0 {
+		return math3d.Zero3(), math3d.Zero3()
+	}
+	return b.Nodes[0].Min, b.Nodes[0].Max
+}
+
+// Query walks the hierarchy, calling test for each reached node's bounds.
+// If test reports the node as not visible, its subtree is skipped. If it
+// reports the node as fully contained, every face below it is visited
+// without testing further nodes. Otherwise the children are tested.
+// This is the building block for frustum culling.
+func (b *BVH) Query(test func(minV, maxV math3d.Vec3) (visible, contained bool), visit func(face int)) {
+	if len(b.Nodes) == 0 {
+		return
+	}
+	stack := make([]int, 1, 64)
+	for len(stack) > 0 {
+		idx := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		n := &b.Nodes[idx]
+		visible, contained := test(n.Min, n.Max)
+		if !visible {
+			continue
+		}
+		if contained {
+			b.visitSubtree(idx, visit)
+			continue
+		}
+		if n.IsLeaf() {
+			for _, fi := range b.FaceIndices[n.First : n.First+n.Count] {
+				visit(fi)
+			}
+			continue
+		}
+		stack = append(stack, n.Right, idx+1)
+	}
+}
+
+// visitSubtree visits every face below node idx.
+// Subtrees are contiguous in FaceIndices, so this is a slice walk.
+func (b *BVH) visitSubtree(idx int, visit func(face int)) {
+	first, last := b.subtreeRange(idx)
+	for _, fi := range b.FaceIndices[first:last] {
+		visit(fi)
+	}
+}
+
+// subtreeRange returns the FaceIndices range covered by node idx.
+func (b *BVH) subtreeRange(idx int) (first, last int) {
+	lo := idx
+	for !b.Nodes[lo].IsLeaf() {
+		lo++ // leftmost path
+	}
+	hi := idx
+	for !b.Nodes[hi].IsLeaf() {
+		hi = b.Nodes[hi].Right // rightmost path
+	}
+	return b.Nodes[lo].First, b.Nodes[hi].First + b.Nodes[hi].Count
+}
+
+// QueryAABB calls visit for every face whose bounding box overlaps the
+// given box. Returning false from visit stops the query early.
+func (b *BVH) QueryAABB(minV, maxV math3d.Vec3, visit func(face int) bool) {
+	if len(b.Nodes) == 0 {
+		return
+	}
+	m := b.mesh
+	stack := make([]int, 1, 64)
+	for len(stack) > 0 {
+		idx := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		n := &b.Nodes[idx]
+		if !boxesOverlap(n.Min, n.Max, minV, maxV) {
+			continue
+		}
+		if n.IsLeaf() {
+			for _, fi := range b.FaceIndices[n.First : n.First+n.Count] {
+				fMin, fMax := m.faceBounds(m.Faces[fi])
+				if boxesOverlap(fMin, fMax, minV, maxV) && !visit(fi) {
+					return
+				}
+			}
+			continue
+		}
+		stack = append(stack, n.Right, idx+1)
+	}
+}
+
+func boxesOverlap(aMin, aMax, bMin, bMax math3d.Vec3) bool {
+	return aMin.X <= bMax.X && aMax.X >= bMin.X &&
+		aMin.Y <= bMax.Y && aMax.Y >= bMin.Y &&
+		aMin.Z <= bMax.Z && aMax.Z >= bMin.Z
+}
+
+// IntersectRay returns the closest face hit by the ray origin + t*dir
+// with 0 <= t <= maxT. Faces are treated as double sided.
+func (b *BVH) IntersectRay(origin, dir math3d.Vec3, maxT float64) (RayHit, bool) {
+	hit := RayHit{Face: -1, T: maxT}
+	b.traceRay(origin, dir, func(face int, t, u, v float64) bool {
+		if t < hit.T {
+			hit = RayHit{Face: face, T: t, U: u, V: v}
+		}
+		return true
+	}, &hit.T)
+	if hit.Face < 0 {
+		return RayHit{Face: -1}, false
+	}
+	hit.Point = origin.Add(dir.Scale(hit.T))
+	return hit, true
+}
+
+// Occluded returns true if any face intersects the ray within (0, maxT].
+// It stops at the first hit, which makes it cheaper than IntersectRay for
+// shadow and visibility rays.
+func (b *BVH) Occluded(origin, dir math3d.Vec3, maxT float64) bool {
+	found := false
+	limit := maxT
+	b.traceRay(origin, dir, func(int, float64, float64, float64) bool {
+		found = true
+		return false
+	}, &limit)
+	return found
+}
+
+// traceRay walks nodes hit by the ray, nearest child first, and reports
+// every face intersection closer than *maxT. onHit returns false to stop.
+func (b *BVH) traceRay(origin, dir math3d.Vec3, onHit func(face int, t, u, v float64) bool, maxT *float64) {
+	if len(b.Nodes) == 0 {
+		return
+	}
+	m := b.mesh
+	invDir := math3d.V3(1/dir.X, 1/dir.Y, 1/dir.Z)
+	stack := make([]int, 1, 64)
+	for len(stack) > 0 {
+		idx := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		n := &b.Nodes[idx]
+		if _, ok := rayBoxEntry(origin, invDir, n.Min, n.Max, *maxT); !ok {
+			continue
+		}
+		if n.IsLeaf() {
+			for _, fi := range b.FaceIndices[n.First : n.First+n.Count] {
+				f := m.Faces[fi]
+				t, u, v, ok := rayTriangle(origin, dir,
+					m.Vertices[f.V[0]].Position,
+					m.Vertices[f.V[1]].Position,
+					m.Vertices[f.V[2]].Position)
+				if ok && t <= *maxT && !onHit(fi, t, u, v) {
+					return
+				}
+			}
+			continue
+		}
+		// Push the farther child first so the nearer one is popped next
+		left, right := idx+1, n.Right
+		tl, okL := rayBoxEntry(origin, invDir, b.Nodes[left].Min, b.Nodes[left].Max, *maxT)
+		tr, okR := rayBoxEntry(origin, invDir, b.Nodes[right].Min, b.Nodes[right].Max, *maxT)
+		switch {
+		case okL && okR:
+			if tl > tr {
+				left, right = right, left
+			}
+			stack = append(stack, right, left)
+		case okL:
+			stack = append(stack, left)
+		case okR:
+			stack = append(stack, right)
+		}
+	}
+}
+
+// rayBoxEntry returns the entry distance of a ray into a box (slab test).
+func rayBoxEntry(origin, invDir, minV, maxV math3d.Vec3, maxT float64) (float64, bool) {
+	t1 := (minV.X - origin.X) * invDir.X
+	t2 := (maxV.X - origin.X) * invDir.X
+	tMin, tMax := math.Min(t1, t2), math.Max(t1, t2)
+	t1 = (minV.Y - origin.Y) * invDir.Y
+	t2 = (maxV.Y - origin.Y) * invDir.Y
+	tMin, tMax = math.Max(tMin, math.Min(t1, t2)), math.Min(tMax, math.Max(t1, t2))
+	t1 = (minV.Z - origin.Z) * invDir.Z
+	t2 = (maxV.Z - origin.Z) * invDir.Z
+	tMin, tMax = math.Max(tMin, math.Min(t1, t2)), math.Min(tMax, math.Max(t1, t2))
+	if math.IsNaN(tMin) || math.IsNaN(tMax) {
+		// Ray lies in a slab plane; treat as a hit and let the triangle test decide
+		return 0, true
+	}
+	return tMin, tMax >= math.Max(tMin, 0) && tMin <= maxT
+}
+
+// rayTriangle is the Möller–Trumbore ray/triangle intersection test.
+func rayTriangle(origin, dir, p0, p1, p2 math3d.Vec3) (t, u, v float64, ok bool) {
+	const epsilon = 1e-12
+	e1 := p1.Sub(p0)
+	e2 := p2.Sub(p0)
+	pvec := dir.Cross(e2)
+	det := e1.Dot(pvec)
+	if math.Abs(det) < epsilon {
+		return 0, 0, 0, false
+	}
+	invDet := 1 / det
+	tvec := origin.Sub(p0)
+	u = tvec.Dot(pvec) * invDet
+	if u < 0 || u > 1 {
+		return 0, 0, 0, false
+	}
+	qvec := tvec.Cross(e1)
+	v = dir.Dot(qvec) * invDet
+	if v < 0 || u+v > 1 {
+		return 0, 0, 0, false
+	}
+	t = e2.Dot(qvec) * invDet
+	if t < 0 {
+		return 0, 0, 0, false
+	}
+	return t, u, v, true
+}
+
+// clone returns a copy of the BVH referencing another mesh with the same faces.
+func (b *BVH) clone(m *Mesh) *BVH {
+	return &BVH{
+		Nodes:       append([]BVHNode(nil), b.Nodes...),
+		FaceIndices: append([]int(nil), b.FaceIndices...),
+		mesh:        m,
+	}
+}
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// gridMesh creates an n x n grid of quads (2 triangles each) on the XY plane.
+func gridMesh(n int) *Mesh {
+	mesh := NewMesh("grid")
+	for y := 0; y <= n; y++ {
+		for x := 0; x <= n; x++ {
+			mesh.Vertices = append(mesh.Vertices, MeshVertex{Position: math3d.V3(float64(x), float64(y), 0)})
+		}
+	}
+	idx := func(x, y int) int { return y*(n+1) + x }
+	for y := range n {
+		for x := range n {
+			mesh.Faces = append(mesh.Faces,
+				Face{V: [3]int{idx(x, y), idx(x+1, y+1), idx(x+1, y)}},
+				Face{V: [3]int{idx(x, y), idx(x, y+1), idx(x+1, y+1)}},
+			)
+		}
+	}
+	mesh.CalculateBounds()
+	return mesh
+}
+
+func TestBVHCoversAllFaces(t *testing.T) {
+	mesh := gridMesh(16)
+	bvh := mesh.BuildBVH()
+	if !bvh.Valid() {
+		t.Fatal("BVH should be valid right after building")
+	}
+	seen := make(map[int]bool)
+	for _, n := range bvh.Nodes {
+		if !n.IsLeaf() {
+			continue
+		}
+		if n.Count > bvhMaxLeafFaces*4 {
+			t.Errorf("leaf with %d faces, SAH should split it", n.Count)
+		}
+		for _, fi := range bvh.FaceIndices[n.First : n.First+n.Count] {
+			if seen[fi] {
+				t.Errorf("face %d referenced twice", fi)
+			}
+			seen[fi] = true
+		}
+	}
+	if len(seen) != len(mesh.Faces) {
+		t.Errorf("leaves reference %d faces, want %d", len(seen), len(mesh.Faces))
+	}
+	minV, maxV := bvh.Bounds()
+	if minV != mesh.BoundsMin || maxV != mesh.BoundsMax {
+		t.Errorf("root bounds = %v..%v, want %v..%v", minV, maxV, mesh.BoundsMin, mesh.BoundsMax)
+	}
+}
+
+func TestBVHIntersectRayMatchesBruteForce(t *testing.T) {
+	mesh := gridMesh(8)
+	// Bend the grid so faces are at different depths
+	for i := range mesh.Vertices {
+		p := &mesh.Vertices[i].Position
+		p.Z = math.Sin(p.X) + math.Cos(p.Y)
+	}
+	bvh := mesh.BuildBVH()
+	rng := rand.New(rand.NewSource(1))
+	for range 200 {
+		origin := math3d.V3(rng.Float64()*8, rng.Float64()*8, 5)
+		dir := math3d.V3(rng.Float64()-0.5, rng.Float64()-0.5, -1).Normalize()
+		hit, ok := bvh.IntersectRay(origin, dir, math.Inf(1))
+		// Brute force
+		bestT := math.Inf(1)
+		bestFace := -1
+		for fi, f := range mesh.Faces {
+			tHit, _, _, hitOK := rayTriangle(origin, dir,
+				mesh.Vertices[f.V[0]].Position,
+				mesh.Vertices[f.V[1]].Position,
+				mesh.Vertices[f.V[2]].Position)
+			if hitOK && tHit < bestT {
+				bestT, bestFace = tHit, fi
+			}
+		}
+		if ok != (bestFace >= 0) {
+			t.Fatalf("IntersectRay ok = %v, brute force found face %d", ok, bestFace)
+		}
+		if ok && math.Abs(hit.T-bestT) > 1e-9 {
+			t.Errorf("IntersectRay T = %v (face %d), want %v (face %d)", hit.T, hit.Face, bestT, bestFace)
+		}
+		if ok != bvh.Occluded(origin, dir, math.Inf(1)) {
+			t.Errorf("Occluded disagrees with IntersectRay (ok=%v)", ok)
+		}
+	}
+}
+
+func TestBVHRayMiss(t *testing.T) {
+	mesh := gridMesh(4)
+	bvh := mesh.BuildBVH()
+	if _, ok := bvh.IntersectRay(math3d.V3(-1, -1, 1), math3d.V3(0, 0, -1), 10); ok {
+		t.Error("ray outside the grid should miss")
+	}
+	if bvh.Occluded(math3d.V3(2, 2, 1), math3d.V3(0, 0, -1), 0.5) {
+		t.Error("grid is beyond maxT, ray should not be occluded")
+	}
+	hit, ok := bvh.IntersectRay(math3d.V3(2.25, 2.75, 1), math3d.V3(0, 0, -1), 10)
+	if !ok {
+		t.Fatal("ray straight down onto the grid should hit")
+	}
+	if math.Abs(hit.T-1) > 1e-12 || hit.Point.Distance(math3d.V3(2.25, 2.75, 0)) > 1e-12 {
+		t.Errorf("hit = %+v, want T=1 at (2.25, 2.75, 0)", hit)
+	}
+}
+
+func TestBVHQueryAABB(t *testing.T) {
+	mesh := gridMesh(10)
+	bvh := mesh.BuildBVH()
+	boxMin, boxMax := math3d.V3(2.5, 2.5, -1), math3d.V3(4.5, 3.5, 1)
+	got := make(map[int]bool)
+	bvh.QueryAABB(boxMin, boxMax, func(face int) bool {
+		got[face] = true
+		return true
+	})
+	for fi, f := range mesh.Faces {
+		fMin, fMax := mesh.faceBounds(f)
+		want := boxesOverlap(fMin, fMax, boxMin, boxMax)
+		if got[fi] != want {
+			t.Errorf("face %d: in query = %v, want %v", fi, got[fi], want)
+		}
+	}
+	// Early stop
+	count := 0
+	bvh.QueryAABB(boxMin, boxMax, func(int) bool {
+		count++
+		return false
+	})
+	if count != 1 {
+		t.Errorf("query should stop after first face, visited %d", count)
+	}
+}
+
+func TestBVHRefitAfterTransform(t *testing.T) {
+	mesh := gridMesh(6)
+	mesh.BuildBVH()
+	mesh.Transform(math3d.Translate(math3d.V3(10, 0, 0)))
+	minV, maxV := mesh.BVH.Bounds()
+	if minV != mesh.BoundsMin || maxV != mesh.BoundsMax {
+		t.Errorf("refit root bounds = %v..%v, want %v..%v", minV, maxV, mesh.BoundsMin, mesh.BoundsMax)
+	}
+	hit, ok := mesh.BVH.IntersectRay(math3d.V3(13.1, 3.1, 1), math3d.V3(0, 0, -1), 10)
+	if !ok || hit.Point.Distance(math3d.V3(13.1, 3.1, 0)) > 1e-9 {
+		t.Errorf("ray after refit: ok=%v hit=%+v", ok, hit)
+	}
+	// Clone keeps an independent, valid BVH
+	clone := mesh.Clone()
+	if !clone.BVH.Valid() || clone.BVH == mesh.BVH {
+		t.Error("Clone should copy the BVH")
+	}
+	// Face edits make the BVH stale
+	mesh.Faces = mesh.Faces[:len(mesh.Faces)-1]
+	if mesh.BVH.Valid() {
+		t.Error("BVH should be stale after removing faces")
+	}
+}
+
+func TestMeshQueryFaces(t *testing.T) {
+	mesh := gridMesh(8)
+	// Only the half with x < 4 is "visible"
+	test := func(minV, maxV math3d.Vec3) (bool, bool) {
+		return minV.X < 4, maxV.X <= 4
+	}
+	count := func() int {
+		n := 0
+		mesh.QueryFaces(test, func(int) { n++ })
+		return n
+	}
+	// Without a BVH the mesh is tested as a whole
+	if got := count(); got != len(mesh.Faces) {
+		t.Errorf("without BVH visited %d faces, want all %d", got, len(mesh.Faces))
+	}
+	mesh.BuildBVH()
+	got := count()
+	if got < len(mesh.Faces)/2 || got >= len(mesh.Faces) {
+		t.Errorf("with BVH visited %d faces, want between %d and %d", got, len(mesh.Faces)/2, len(mesh.Faces)-1)
+	}
+}
@@ -0,0 +1,731 @@
+package models
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"math"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+const colladaMaxDepth = 64 // Node nesting limit (guards against instance_node cycles)
+
+// colladaDocument is the subset of a COLLADA (1.4 and 1.5) document that is
+// loaded: static geometry, node transforms and common profile effects.
+type colladaDocument struct {
+	UpAxis       string               `xml:"asset>up_axis"`
+	Images       []colladaImage       `xml:"library_images>image"`
+	Effects      []colladaEffect      `xml:"library_effects>effect"`
+	Materials    []colladaMaterial    `xml:"library_materials>material"`
+	Geometries   []colladaGeometry    `xml:"library_geometries>geometry"`
+	Controllers  []colladaController  `xml:"library_controllers>controller"`
+	Nodes        []colladaNode        `xml:"library_nodes>node"`
+	VisualScenes []colladaVisualScene `xml:"library_visual_scenes>visual_scene"`
+	Scene        colladaURL           `xml:"scene>instance_visual_scene"`
+}
+
+type colladaURL struct {
+	URL string `xml:"url,attr"`
+}
+
+type colladaImage struct {
+	ID       string `xml:"id,attr"`
+	InitFrom struct {
+		Path string `xml:",chardata"` // 1.4
+		Ref  string `xml:"ref"`       // 1.5
+	} `xml:"init_from"`
+}
+
+type colladaEffect struct {
+	ID      string `xml:"id,attr"`
+	Profile struct {
+		Params    []colladaParam `xml:"newparam"`
+		Technique struct {
+			Shaders []colladaShader `xml:",any"`
+		} `xml:"technique"`
+	} `xml:"profile_COMMON"`
+}
+
+// colladaParam is a surface or sampler2D effect parameter.
+type colladaParam struct {
+	SID           string     `xml:"sid,attr"`
+	SurfaceInit   string     `xml:"surface>init_from"`
+	SamplerSource string     `xml:"sampler2D>source"`
+	SamplerImage  colladaURL `xml:"sampler2D>instance_image"`
+}
+
+// colladaShader is a lambert, phong, blinn or constant technique.
+type colladaShader struct {
+	XMLName  xml.Name
+	Diffuse  *colladaColorOrTexture `xml:"diffuse"`
+	Emission *colladaColorOrTexture `xml:"emission"`
+}
+
+type colladaColorOrTexture struct {
+	Color   string `xml:"color"`
+	Texture *struct {
+		Texture string `xml:"texture,attr"`
+	} `xml:"texture"`
+}
+
+type colladaMaterial struct {
+	ID     string     `xml:"id,attr"`
+	Name   string     `xml:"name,attr"`
+	Effect colladaURL `xml:"instance_effect"`
+}
+
+type colladaGeometry struct {
+	ID   string `xml:"id,attr"`
+	Name string `xml:"name,attr"`
+	Mesh *struct {
+		Sources  []colladaSource `xml:"source"`
+		Vertices struct {
+			ID     string         `xml:"id,attr"`
+			Inputs []colladaInput `xml:"input"`
+		} `xml:"vertices"`
+		Triangles []colladaPrimitive `xml:"triangles"`
+		Polylists []colladaPrimitive `xml:"polylist"`
+		Polygons  []colladaPrimitive `xml:"polygons"`
+	} `xml:"mesh"`
+}
+
+type colladaSource struct {
+	ID       string `xml:"id,attr"`
+	Floats   string `xml:"float_array"`
+	Accessor struct {
+		Count  int `xml:"count,attr"`
+		Stride int `xml:"stride,attr"`
+	} `xml:"technique_common>accessor"`
+}
+
+type colladaInput struct {
+	Semantic string `xml:"semantic,attr"`
+	Source   string `xml:"source,attr"`
+	Offset   int    `xml:"offset,attr"`
+	Set      string `xml:"set,attr"`
+}
+
+// colladaPrimitive is a triangles, polylist or polygons element: the
+// polygons have vcount vertices (3 for triangles), or one <p> each.
+type colladaPrimitive struct {
+	Material string         `xml:"material,attr"`
+	Inputs   []colladaInput `xml:"input"`
+	VCount   string         `xml:"vcount"`
+	P        []string       `xml:"p"`
+}
+
+type colladaController struct {
+	ID   string `xml:"id,attr"`
+	Skin struct {
+		Source string `xml:"source,attr"`
+	} `xml:"skin"`
+}
+
+type colladaNode struct {
+	ID         string `xml:"id,attr"`
+	Name       string `xml:"name,attr"`
+	Geometries []struct {
+		URL       string            `xml:"url,attr"`
+		Materials []colladaBindings `xml:"bind_material>technique_common>instance_material"`
+	} `xml:"instance_geometry"`
+	Controllers []struct {
+		URL       string            `xml:"url,attr"`
+		Materials []colladaBindings `xml:"bind_material>technique_common>instance_material"`
+	} `xml:"instance_controller"`
+	Instances []colladaURL  `xml:"instance_node"`
+	Children  []colladaNode `xml:"node"`
+	// Transform elements, in document order (and other unknown elements)
+	Transforms []struct {
+		XMLName xml.Name
+		Values  string `xml:",chardata"`
+	} `xml:",any"`
+}
+
+type colladaBindings struct {
+	Symbol string `xml:"symbol,attr"`
+	Target string `xml:"target,attr"`
+}
+
+type colladaVisualScene struct {
+	ID    string        `xml:"id,attr"`
+	Nodes []colladaNode `xml:"node"`
+}
+
+// colladaLoader flattens a COLLADA visual scene into a mesh.
+type colladaLoader struct {
+	doc       *colladaDocument
+	fsys      fs.FS
+	dir       string
+	mesh      *Mesh
+	materials map[string]int // Material id to mesh material
+	textures  map[string]image.Image
+	hasNormal []bool // Whether each mesh vertex has a normal from the file
+}
+
+// LoadCollada loads a COLLADA (.dae) file from disk, with its textures.
+func LoadCollada(path string) (*Mesh, error) {
+	fsys, name := fsForPath(path)
+	return LoadColladaFromFS(fsys, name)
+}
+
+// LoadColladaFromFS loads a COLLADA (.dae) file from an fs.FS, with its
+// textures resolved relative to the file.
+func LoadColladaFromFS(fsys fs.FS, daePath string) (*Mesh, error) {
+	data, err := fs.ReadFile(fsys, daePath)
+	if err != nil {
+		return nil, fmt.Errorf("read COLLADA file: %w", err)
+	}
+	return loadCollada(data, daePath, fsys, path.Dir(daePath))
+}
+
+// LoadColladaBytes parses a COLLADA document; textures are not loaded.
+func LoadColladaBytes(data []byte, name string) (*Mesh, error) {
+	return loadCollada(data, name, nil, "")
+}
+
+// loadCollada parses a COLLADA document: the triangles, polylist and
+// polygons geometry instanced by the nodes of its visual scene (skin
+// controllers in their bind pose), with the node transforms, flattened into
+// one mesh with a FaceGroup per node. Bound materials become Materials with
+// their diffuse color or texture. Z or X up documents are rotated to Y up.
+func loadCollada(data []byte, name string, fsys fs.FS, dir string) (*Mesh, error) {
+	doc := &colladaDocument{}
+	if err := xml.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("parse COLLADA: %w", err)
+	}
+	l := &colladaLoader{
+		doc:       doc,
+		fsys:      fsys,
+		dir:       dir,
+		mesh:      NewMesh(name),
+		materials: make(map[string]int),
+		textures:  make(map[string]image.Image),
+	}
+	if len(doc.VisualScenes) == 0 {
+		return nil, errors.New("no visual scene")
+	}
+	scene := &doc.VisualScenes[0]
+	for i := range doc.VisualScenes {
+		if "#"+doc.VisualScenes[i].ID == doc.Scene.URL {
+			scene = &doc.VisualScenes[i]
+		}
+	}
+	root := math3d.Identity()
+	switch strings.TrimSpace(doc.UpAxis) {
+	case "Z_UP":
+		root = math3d.RotateX(-math.Pi / 2)
+	case "X_UP":
+		root = math3d.RotateZ(math.Pi / 2)
+	}
+	for i := range scene.Nodes {
+		if err := l.addNode(&scene.Nodes[i], root, 0); err != nil {
+			return nil, err
+		}
+	}
+	if len(l.mesh.Groups) == 1 {
+		l.mesh.Groups = nil
+		for i := range l.mesh.Faces {
+			l.mesh.Faces[i].Group = 0
+		}
+	}
+	l.mesh.CalculateBounds()
+	l.fillNormals()
+	return l.mesh, nil
+}
+
+// addNode appends the geometry of a node and its descendants.
+func (l *colladaLoader) addNode(node *colladaNode, parent math3d.Mat4, depth int) error {
+	if depth > colladaMaxDepth {
+		return errors.New("nodes nested too deeply")
+	}
+	transform := parent
+	for _, t := range node.Transforms {
+		m, err := colladaTransform(t.XMLName.Local, t.Values)
+		if err != nil {
+			return fmt.Errorf("node %s: %w", node.ID, err)
+		}
+		transform = transform.Mul(m)
+	}
+	group := -1
+	addGeometry := func(url string, bindings []colladaBindings) error {
+		geom := l.geometry(url)
+		if geom == nil {
+			return nil
+		}
+		if group < 0 {
+			name := node.Name
+			if name == "" {
+				name = node.ID
+			}
+			l.mesh.Groups = append(l.mesh.Groups, FaceGroup{Name: name})
+			group = len(l.mesh.Groups) - 1
+		}
+		if err := l.addGeometry(geom, bindings, transform, group); err != nil {
+			return fmt.Errorf("geometry %s: %w", geom.ID, err)
+		}
+		return nil
+	}
+	for _, g := range node.Geometries {
+		if err := addGeometry(g.URL, g.Materials); err != nil {
+			return err
+		}
+	}
+	for _, c := range node.Controllers {
+		for _, ctrl := range l.doc.Controllers {
+			if "#"+ctrl.ID == c.URL {
+				if err := addGeometry(ctrl.Skin.Source, c.Materials); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for i := range node.Children {
+		if err := l.addNode(&node.Children[i], transform, depth+1); err != nil {
+			return err
+		}
+	}
+	for _, inst := range node.Instances {
+		for i := range l.doc.Nodes {
+			if "#"+l.doc.Nodes[i].ID == inst.URL {
+				if err := l.addNode(&l.doc.Nodes[i], transform, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// geometry returns the mesh geometry referenced by url, nil if there is none.
+func (l *colladaLoader) geometry(url string) *colladaGeometry {
+	for i := range l.doc.Geometries {
+		if g := &l.doc.Geometries[i]; "#"+g.ID == url && g.Mesh != nil {
+			return g
+		}
+	}
+	return nil
+}
+
+// colladaTransform parses a matrix, translate, rotate or scale element;
+// other elements are the identity.
+func colladaTransform(kind, text string) (math3d.Mat4, error) {
+	want := map[string]int{"matrix": 16, "translate": 3, "rotate": 4, "scale": 3}[kind]
+	if want == 0 {
+		return math3d.Identity(), nil
+	}
+	v, err := parseColladaFloats(text)
+	if err != nil || len(v) != want {
+		return math3d.Mat4{}, fmt.Errorf("invalid %s %q", kind, strings.TrimSpace(text))
+	}
+	switch kind {
+	case "matrix":
+		// Row major
+		var m math3d.Mat4
+		for r := range 4 {
+			for c := range 4 {
+				m[r+c*4] = v[r*4+c]
+			}
+		}
+		return m, nil
+	case "translate":
+		return math3d.Translate(math3d.V3(v[0], v[1], v[2])), nil
+	case "rotate":
+		if v[0] == 0 && v[1] == 0 && v[2] == 0 {
+			return math3d.Identity(), nil
+		}
+		return math3d.Rotate(math3d.V3(v[0], v[1], v[2]), v[3]*math.Pi/180), nil
+	default:
+		return math3d.Scale(math3d.V3(v[0], v[1], v[2])), nil
+	}
+}
+
+// colladaStream is a resolved primitive input: its source values.
+type colladaStream struct {
+	offset int
+	values []float64
+	stride int
+}
+
+// at returns component k of element i, 0 when out of range.
+func (s *colladaStream) at(i, k int) float64 {
+	if i < 0 || k >= s.stride || i*s.stride+k >= len(s.values) {
+		return 0
+	}
+	return s.values[i*s.stride+k]
+}
+
+// count returns the number of elements of the stream.
+func (s *colladaStream) count() int {
+	return len(s.values) / s.stride
+}
+
+// addGeometry appends the primitives of a geometry instance.
+func (l *colladaLoader) addGeometry(geom *colladaGeometry, bindings []colladaBindings, transform math3d.Mat4, group int) error {
+	for kind, list := range [][]colladaPrimitive{geom.Mesh.Triangles, geom.Mesh.Polylists, geom.Mesh.Polygons} {
+		for i := range list {
+			prim := &list[i]
+			streams, stride, err := l.streams(geom, prim)
+			if err != nil {
+				return err
+			}
+			indices, counts, err := colladaIndices(prim, kind, stride)
+			if err != nil {
+				return err
+			}
+			material := l.boundMaterial(prim.Material, bindings)
+			if err := l.addPolygons(streams, stride, indices, counts, transform, material, group); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// colladaIndices returns the index tuples of a triangles (kind 0), polylist
+// (1) or polygons (2) element, and the vertex count of each polygon.
+func colladaIndices(prim *colladaPrimitive, kind, stride int) ([]int, []int, error) {
+	var indices, counts []int
+	for _, p := range prim.P {
+		values, err := parseColladaInts(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		indices = append(indices, values...)
+		if kind == 2 {
+			counts = append(counts, len(values)/stride)
+		}
+	}
+	switch kind {
+	case 0:
+		counts = make([]int, len(indices)/(3*stride))
+		for i := range counts {
+			counts[i] = 3
+		}
+	case 1:
+		var err error
+		if counts, err = parseColladaInts(prim.VCount); err != nil {
+			return nil, nil, err
+		}
+	}
+	total := 0
+	for _, c := range counts {
+		if c < 0 {
+			return nil, nil, fmt.Errorf("invalid vertex count %d", c)
+		}
+		total += c
+	}
+	if total*stride != len(indices) {
+		return nil, nil, fmt.Errorf("%d indices for %d vertices of %d inputs", len(indices), total, stride)
+	}
+	return indices, counts, nil
+}
+
+// addPolygons appends polygons of index tuples, deduplicating vertices.
+func (l *colladaLoader) addPolygons(
+	streams map[string]*colladaStream, stride int, indices, counts []int,
+	transform math3d.Mat4, material, group int,
+) error {
+	position, normal, texcoord := streams["POSITION"], streams["NORMAL"], streams["TEXCOORD"]
+	if position == nil {
+		return errors.New("no POSITION input")
+	}
+	normalMatrix := transform.Inverse().Transpose()
+	// Mirroring transforms flip the winding
+	flip := transform.Determinant() < 0
+	vertices := make(map[string]int)
+	var faceVerts []int
+	start := 0
+	for _, n := range counts {
+		faceVerts = faceVerts[:0]
+		for k := range n {
+			tuple := indices[start+k*stride : start+(k+1)*stride]
+			key := fmt.Sprint(tuple)
+			v, ok := vertices[key]
+			if !ok {
+				p := tuple[position.offset]
+				if p < 0 || p >= position.count() {
+					return fmt.Errorf("position index %d out of range", p)
+				}
+				v = len(l.mesh.Vertices)
+				vertices[key] = v
+				vert := MeshVertex{Position: transform.MulVec3(math3d.V3(position.at(p, 0), position.at(p, 1), position.at(p, 2)))}
+				if normal != nil {
+					i := tuple[normal.offset]
+					vert.Normal = normalMatrix.MulVec3Dir(math3d.V3(normal.at(i, 0), normal.at(i, 1), normal.at(i, 2))).Normalize()
+				}
+				if texcoord != nil {
+					i := tuple[texcoord.offset]
+					vert.UV = math3d.V2(texcoord.at(i, 0), texcoord.at(i, 1))
+				}
+				l.mesh.Vertices = append(l.mesh.Vertices, vert)
+				l.hasNormal = append(l.hasNormal, normal != nil)
+			}
+			faceVerts = append(faceVerts, v)
+		}
+		start += n * stride
+		if n < 3 {
+			continue
+		}
+		for _, tri := range triangulateFace(l.mesh, faceVerts) {
+			if flip {
+				tri[1], tri[2] = tri[2], tri[1]
+			}
+			// COLLADA polygons are counter clockwise: reverse the winding
+			l.mesh.Faces = append(l.mesh.Faces, Face{V: [3]int{tri[0], tri[2], tri[1]}, Material: material, Group: group})
+		}
+	}
+	return nil
+}
+
+// streams resolves the inputs of a primitive, expanding its VERTEX input,
+// and returns them by semantic with the index tuple size. Only the first
+// TEXCOORD set is used.
+func (l *colladaLoader) streams(geom *colladaGeometry, prim *colladaPrimitive) (map[string]*colladaStream, int, error) {
+	streams := make(map[string]*colladaStream)
+	stride := 0
+	add := func(semantic, source string, offset int) error {
+		if _, ok := streams[semantic]; ok {
+			return nil
+		}
+		for i := range geom.Mesh.Sources {
+			src := &geom.Mesh.Sources[i]
+			if "#"+src.ID != source {
+				continue
+			}
+			values, err := parseColladaFloats(src.Floats)
+			if err != nil {
+				return fmt.Errorf("source %s: %w", src.ID, err)
+			}
+			streams[semantic] = &colladaStream{offset: offset, values: values, stride: max(1, src.Accessor.Stride)}
+			return nil
+		}
+		return fmt.Errorf("source %s not found", source)
+	}
+	for _, in := range prim.Inputs {
+		if in.Offset < 0 {
+			return nil, 0, fmt.Errorf("invalid input offset %d", in.Offset)
+		}
+		stride = max(stride, in.Offset+1)
+		if in.Semantic != "VERTEX" {
+			if in.Semantic == "NORMAL" || in.Semantic == "TEXCOORD" {
+				if err := add(in.Semantic, in.Source, in.Offset); err != nil {
+					return nil, 0, err
+				}
+			}
+			continue
+		}
+		for _, v := range geom.Mesh.Vertices.Inputs {
+			if err := add(v.Semantic, v.Source, in.Offset); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+	if stride == 0 {
+		return nil, 0, errors.New("primitive without inputs")
+	}
+	return streams, stride, nil
+}
+
+// boundMaterial returns the mesh material bound to a primitive's material
+// symbol, -1 if there is none.
+func (l *colladaLoader) boundMaterial(symbol string, bindings []colladaBindings) int {
+	if symbol == "" {
+		return -1
+	}
+	id := symbol
+	for _, b := range bindings {
+		if b.Symbol == symbol {
+			id = strings.TrimPrefix(b.Target, "#")
+		}
+	}
+	if m, ok := l.materials[id]; ok {
+		return m
+	}
+	m := -1
+	for _, mat := range l.doc.Materials {
+		if mat.ID == id {
+			m = len(l.mesh.Materials)
+			l.mesh.Materials = append(l.mesh.Materials, l.convertMaterial(&mat))
+			break
+		}
+	}
+	l.materials[id] = m
+	return m
+}
+
+// convertMaterial converts a material's common profile effect: its diffuse
+// color or texture, and emission. Constant (unlit) effects use their
+// emission as the base color.
+func (l *colladaLoader) convertMaterial(mat *colladaMaterial) Material {
+	name := mat.Name
+	if name == "" {
+		name = mat.ID
+	}
+	out := Material{Name: name, BaseColor: [4]float64{1, 1, 1, 1}, Roughness: 1}
+	for i := range l.doc.Effects {
+		effect := &l.doc.Effects[i]
+		if "#"+effect.ID != mat.Effect.URL {
+			continue
+		}
+		for _, shader := range effect.Profile.Technique.Shaders {
+			diffuse := shader.Diffuse
+			switch shader.XMLName.Local {
+			case "constant":
+				diffuse = shader.Emission
+				out.Unlit = true
+			case "lambert", "phong", "blinn":
+				if c, ok := parseColladaColor(shader.Emission); ok {
+					out.Emissive = [3]float64{c[0], c[1], c[2]}
+				}
+			default:
+				continue
+			}
+			if c, ok := parseColladaColor(diffuse); ok {
+				out.BaseColor = c
+			}
+			if diffuse != nil && diffuse.Texture != nil {
+				if img := l.texture(effect, diffuse.Texture.Texture); img != nil {
+					out.BaseMap = img
+					out.HasTexture = true
+				}
+			}
+			return out
+		}
+	}
+	return out
+}
+
+// parseColladaColor parses a <color> "r g b [a]", if any.
+func parseColladaColor(c *colladaColorOrTexture) ([4]float64, bool) {
+	if c == nil {
+		return [4]float64{}, false
+	}
+	v, err := parseColladaFloats(c.Color)
+	if err != nil || (len(v) != 3 && len(v) != 4) {
+		return [4]float64{}, false
+	}
+	out := [4]float64{v[0], v[1], v[2], 1}
+	if len(v) == 4 {
+		out[3] = v[3]
+	}
+	return out, true
+}
+
+// texture returns the image of an effect's texture sampler, nil if it
+// cannot be resolved or read. Samplers refer to a surface parameter (1.4)
+// or directly to an image (1.5); some exporters name the image itself.
+func (l *colladaLoader) texture(effect *colladaEffect, sampler string) image.Image {
+	imageID := sampler
+	params := effect.Profile.Params
+	for _, p := range params {
+		if p.SID != sampler {
+			continue
+		}
+		if p.SamplerImage.URL != "" {
+			imageID = strings.TrimPrefix(p.SamplerImage.URL, "#")
+			break
+		}
+		for _, s := range params {
+			if s.SID == strings.TrimSpace(p.SamplerSource) {
+				imageID = strings.TrimSpace(s.SurfaceInit)
+			}
+		}
+	}
+	if img, ok := l.textures[imageID]; ok {
+		return img
+	}
+	var img image.Image
+	for _, im := range l.doc.Images {
+		if im.ID == imageID {
+			ref := im.InitFrom.Ref
+			if ref == "" {
+				ref = im.InitFrom.Path
+			}
+			img = l.loadImage(ref)
+			break
+		}
+	}
+	l.textures[imageID] = img
+	return img
+}
+
+// loadImage reads an image file referenced by URI, relative to the document.
+// Absolute paths from the authoring machine are retried as file names next
+// to the document.
+func (l *colladaLoader) loadImage(ref string) image.Image {
+	ref = strings.TrimPrefix(strings.TrimSpace(ref), "file://")
+	if unescaped, err := url.PathUnescape(ref); err == nil {
+		ref = unescaped
+	}
+	ref = strings.ReplaceAll(ref, `\`, "/")
+	if l.fsys == nil || ref == "" {
+		return nil
+	}
+	data, err := fs.ReadFile(l.fsys, path.Join(l.dir, ref))
+	if err != nil {
+		if data, err = fs.ReadFile(l.fsys, path.Join(l.dir, path.Base(ref))); err != nil {
+			return nil
+		}
+	}
+	img, err := decodeImage(data)
+	if err != nil {
+		return nil
+	}
+	return img
+}
+
+// fillNormals computes smooth normals for the vertices the file gave none.
+func (l *colladaLoader) fillNormals() {
+	missing := false
+	for _, has := range l.hasNormal {
+		missing = missing || !has
+	}
+	if !missing || len(l.mesh.Faces) == 0 {
+		return
+	}
+	given := make([]math3d.Vec3, len(l.mesh.Vertices))
+	for i := range l.mesh.Vertices {
+		given[i] = l.mesh.Vertices[i].Normal
+	}
+	l.mesh.CalculateSmoothNormals()
+	for i, has := range l.hasNormal {
+		if has {
+			l.mesh.Vertices[i].Normal = given[i]
+		}
+	}
+}
+
+// parseColladaFloats parses a whitespace separated list of numbers.
+func parseColladaFloats(s string) ([]float64, error) {
+	fields := strings.Fields(s)
+	values := make([]float64, len(fields))
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", f)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// parseColladaInts parses a whitespace separated list of integers.
+func parseColladaInts(s string) ([]int, error) {
+	fields := strings.Fields(s)
+	values := make([]int, len(fields))
+	for i, f := range fields {
+		v, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index %q", f)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"image/color"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+const colladaDoc = `<?xml version="1.0" encoding="utf-8"?>
+<COLLADA xmlns="http://www.collada.org/2005/11/COLLADASchema" version="1.4.1">
+ <asset><up_axis>Z_UP</up_axis></asset>
+ <library_images>
+  <image id="wood-img"><init_from>C:\Users\artist\My%20Textures\wood.png</init_from></image>
+ </library_images>
+ <library_effects>
+  <effect id="wood-fx"><profile_COMMON>
+   <newparam sid="wood-surface"><surface type="2D"><init_from>wood-img</init_from></surface></newparam>
+   <newparam sid="wood-sampler"><sampler2D><source>wood-surface</source></sampler2D></newparam>
+   <technique sid="common"><phong>
+    <emission><color>0.1 0.2 0.3 1</color></emission>
+    <diffuse><texture texture="wood-sampler" texcoord="UVMap"/></diffuse>
+   </phong></technique>
+  </profile_COMMON></effect>
+  <effect id="red-fx"><profile_COMMON><technique sid="common"><lambert>
+   <diffuse><color>1 0 0 1</color></diffuse>
+  </lambert></technique></profile_COMMON></effect>
+ </library_effects>
+ <library_materials>
+  <material id="wood" name="Wood"><instance_effect url="#wood-fx"/></material>
+  <material id="red" name="Red"><instance_effect url="#red-fx"/></material>
+ </library_materials>
+ <library_geometries>
+  <geometry id="quad" name="Quad"><mesh>
+   <source id="quad-pos"><float_array count="12">0 0 0 1 0 0 1 1 0 0 1 0</float_array>
+    <technique_common><accessor source="#quad-pos-array" count="4" stride="3"/></technique_common></source>
+   <source id="quad-nrm"><float_array count="3">0 0 1</float_array>
+    <technique_common><accessor count="1" stride="3"/></technique_common></source>
+   <source id="quad-uv"><float_array count="8">0 0 1 0 1 1 0 1</float_array>
+    <technique_common><accessor count="4" stride="2"/></technique_common></source>
+   <vertices id="quad-verts"><input semantic="POSITION" source="#quad-pos"/></vertices>
+   <polylist material="mat0" count="1">
+    <input semantic="VERTEX" source="#quad-verts" offset="0"/>
+    <input semantic="NORMAL" source="#quad-nrm" offset="1"/>
+    <input semantic="TEXCOORD" source="#quad-uv" offset="2" set="0"/>
+    <vcount>4</vcount>
+    <p>0 0 0 1 0 1 2 0 2 3 0 3</p>
+   </polylist>
+  </mesh></geometry>
+  <geometry id="tri"><mesh>
+   <source id="tri-pos"><float_array count="9">0 0 0 1 0 0 0 1 0</float_array>
+    <technique_common><accessor count="3" stride="3"/></technique_common></source>
+   <vertices id="tri-verts"><input semantic="POSITION" source="#tri-pos"/></vertices>
+   <triangles material="red-symbol" count="1">
+    <input semantic="VERTEX" source="#tri-verts" offset="0"/>
+    <p>0 1 2</p>
+   </triangles>
+  </mesh></geometry>
+ </library_geometries>
+ <library_nodes>
+  <node id="lib-tri" name="Triangle">
+   <rotate>0 0 1 90</rotate>
+   <instance_geometry url="#tri">
+    <bind_material><technique_common>
+     <instance_material symbol="red-symbol" target="#red"/>
+    </technique_common></bind_material>
+   </instance_geometry>
+  </node>
+ </library_nodes>
+ <library_visual_scenes>
+  <visual_scene id="scene">
+   <node id="floor" name="Floor">
+    <matrix>1 0 0 5  0 1 0 0  0 0 1 0  0 0 0 1</matrix>
+    <instance_geometry url="#quad">
+     <bind_material><technique_common>
+      <instance_material symbol="mat0" target="#wood"/>
+     </technique_common></bind_material>
+    </instance_geometry>
+   </node>
+   <node id="holder">
+    <translate>0 0 2</translate>
+    <instance_node url="#lib-tri"/>
+   </node>
+  </visual_scene>
+ </library_visual_scenes>
+ <scene><instance_visual_scene url="#scene"/></scene>
+</COLLADA>`
+
+func TestLoadCollada(t *testing.T) {
+	fsys := fstest.MapFS{
+		"models/room.dae": {Data: []byte(colladaDoc)},
+		"models/wood.png": {Data: pngBytes(t, color.RGBA{120, 80, 40, 255})},
+	}
+	mesh, err := LoadColladaFromFS(fsys, "models/room.dae")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if mesh.VertexCount() != 7 || mesh.TriangleCount() != 3 {
+		t.Fatalf("vertices %d, triangles %d", mesh.VertexCount(), mesh.TriangleCount())
+	}
+	if len(mesh.Groups) != 2 || mesh.Groups[0].Name != "Floor" || mesh.Groups[1].Name != "Triangle" {
+		t.Fatalf("groups %+v", mesh.Groups)
+	}
+	// Z up to Y up: (x, y, z) becomes (x, z, -y)
+	assertVec3(t, "quad corner", mesh.Vertices[2].Position, math3d.V3(6, 0, -1))
+	assertVec3(t, "quad normal", mesh.Vertices[0].Normal, math3d.V3(0, 1, 0))
+	if mesh.Vertices[2].UV != math3d.V2(1, 1) {
+		t.Errorf("uv %v", mesh.Vertices[2].UV)
+	}
+	// Rotated about Z by the library node, lifted by its instancing node
+	assertVec3(t, "triangle vertex", mesh.Vertices[5].Position, math3d.V3(0, 2, -1))
+	// Generated normal of the triangle, from the engine winding like OBJ ones
+	assertVec3(t, "triangle normal", mesh.Vertices[5].Normal, math3d.V3(0, -1, 0))
+	wood := mesh.Materials[mesh.Faces[0].Material]
+	if wood.Name != "Wood" || !wood.HasTexture || wood.BaseMap == nil || wood.Emissive != [3]float64{0.1, 0.2, 0.3} {
+		t.Errorf("wood material %+v", wood)
+	}
+	red := mesh.Materials[mesh.Faces[2].Material]
+	if red.Name != "Red" || red.BaseColor != [4]float64{1, 0, 0, 1} || red.HasTexture {
+		t.Errorf("red material %+v", red)
+	}
+	if scene := mesh.GroupScene(); scene == nil || len(scene.Meshes) != 2 {
+		t.Errorf("group scene %+v", scene)
+	}
+}
+
+func TestLoadColladaErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"not xml":  "solid cube",
+		"no scene": `<COLLADA></COLLADA>`,
+		"bad index": `<COLLADA><library_geometries><geometry id="g"><mesh>
+			<source id="p"><float_array>0 0 0</float_array><technique_common><accessor stride="3"/></technique_common></source>
+			<vertices id="v"><input semantic="POSITION" source="#p"/></vertices>
+			<triangles><input semantic="VERTEX" source="#v" offset="0"/><p>0 1 2</p></triangles>
+			</mesh></geometry></library_geometries>
+			<library_visual_scenes><visual_scene id="s"><node><instance_geometry url="#g"/></node></visual_scene></library_visual_scenes>
+			</COLLADA>`,
+		"cycle": `<COLLADA><library_nodes><node id="n"><instance_node url="#n"/></node></library_nodes>
+			<library_visual_scenes><visual_scene id="s"><node><instance_node url="#n"/></node></visual_scene></library_visual_scenes>
+			</COLLADA>`,
+	} {
+		if _, err := LoadColladaBytes([]byte(data), name); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package models
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"fortio.org/log"
+	"github.com/qmuntal/gltf"
+)
+
+// extDraco is the name of the Draco primitive compression extension.
+const extDraco = "KHR_draco_mesh_compression"
+
+// errDracoEdgebreaker is returned for Draco meshes with edgebreaker
+// connectivity, which is not decoded.
+var errDracoEdgebreaker = errors.New("draco edgebreaker connectivity is not supported")
+
+// dracoCompression is the KHR_draco_mesh_compression primitive extension:
+// the compressed buffer view and the Draco attribute id of each attribute.
+type dracoCompression struct {
+	BufferView int            `json:"bufferView"`
+	Attributes map[string]int `json:"attributes"`
+}
+
+// Draco header values.
+const (
+	dracoEncoderPointCloud = 0
+	dracoEncoderMesh       = 1
+	dracoMethodSequential  = 0
+	dracoMethodEdgebreaker = 1
+	dracoMetadataFlag      = 0x8000
+)
+
+// Sequential connectivity index coding.
+const (
+	dracoIndicesCompressed   = 0
+	dracoIndicesUncompressed = 1
+)
+
+// Sequential attribute decoder types.
+const (
+	dracoAttributeGeneric      = 0
+	dracoAttributeInteger      = 1
+	dracoAttributeQuantization = 2
+	dracoAttributeNormals      = 3
+)
+
+// Draco attribute data types.
+const (
+	dracoInt8 = iota + 1
+	dracoUint8
+	dracoInt16
+	dracoUint16
+	dracoInt32
+	dracoUint32
+	dracoInt64
+	dracoUint64
+	dracoFloat32
+	dracoFloat64
+	dracoBool
+)
+
+// Prediction schemes and transforms. Sequential streams have no connectivity
+// to predict from, so every scheme decodes as a difference from the previous value.
+const (
+	dracoPredictionNone          = -2
+	dracoNumPredictionSchemes    = 7
+	dracoTransformWrap           = 1
+	dracoTransformOctahedron     = 2
+	dracoTransformOctahedronCano = 3
+)
+
+// dracoMesh is a decoded Draco mesh: triangles over points, and per point
+// attribute values keyed by their unique id.
+type dracoMesh struct {
+	Faces      [][3]uint32
+	NumPoints  int
+	Attributes map[int]*dracoAttribute
+}
+
+// dracoAttribute holds the decoded values of one attribute, Components per point.
+type dracoAttribute struct {
+	Type       uint8
+	DataType   uint8
+	Components int
+	Values     []float64
+}
+
+// decompressDraco decodes KHR_draco_mesh_compression primitives and points
+// their accessors at new uncompressed float (and uint32 index) buffers, so the
+// rest of the loader reads them like any other accessor. Edgebreaker coded
+// primitives are dropped with a warning, the rest of the model still loads.
+func decompressDraco(doc *gltf.Document) error {
+	decoded := make(map[int]*dracoMesh)
+	for mi, m := range doc.Meshes {
+		kept := m.Primitives[:0]
+		for pi, prim := range m.Primitives {
+			var ext dracoCompression
+			found, err := decodeExtension(prim.Extensions, extDraco, &ext)
+			if found && err == nil {
+				err = decompressDracoPrimitive(doc, prim, ext, decoded)
+			}
+			if errors.Is(err, errDracoEdgebreaker) {
+				log.Warnf("Skipping mesh %d primitive %d: %v", mi, pi, err)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("mesh %d primitive %d: %w", mi, pi, err)
+			}
+			delete(prim.Extensions, extDraco)
+			kept = append(kept, prim)
+		}
+		m.Primitives = kept
+	}
+	return nil
+}
+
+func decompressDracoPrimitive(doc *gltf.Document, prim *gltf.Primitive, ext dracoCompression, decoded map[int]*dracoMesh) error {
+	mesh, ok := decoded[ext.BufferView]
+	if !ok {
+		src, _, err := bufferViewData(doc, ext.BufferView)
+		if err != nil {
+			return err
+		}
+		if mesh, err = decodeDraco(src); err != nil {
+			return err
+		}
+		decoded[ext.BufferView] = mesh
+	}
+	for name, id := range ext.Attributes {
+		accessorIdx, ok := prim.Attributes[name]
+		if !ok {
+			continue
+		}
+		attr, ok := mesh.Attributes[id]
+		if !ok {
+			return fmt.Errorf("%s: draco attribute %d not found", name, id)
+		}
+		if accessorIdx < 0 || accessorIdx >= len(doc.Accessors) {
+			return fmt.Errorf("%s: accessor %d out of range", name, accessorIdx)
+		}
+		accessor := doc.Accessors[accessorIdx]
+		if accessor.Type.Components() != attr.Components {
+			return fmt.Errorf("%s: %d components, accessor expects %d", name, attr.Components, accessor.Type.Components())
+		}
+		scale := 1.0
+		if accessor.Normalized {
+			scale = dracoNormalizedScale(accessor.ComponentType)
+		}
+		out := make([]byte, 4*len(attr.Values))
+		for i, v := range attr.Values {
+			f := float32(v / scale)
+			if scale != 1 {
+				f = max(f, -1)
+			}
+			binary.LittleEndian.PutUint32(out[4*i:], math.Float32bits(f))
+		}
+		setDecodedAccessor(doc, accessor, out, gltf.ComponentFloat, mesh.NumPoints)
+	}
+	if prim.Indices != nil {
+		if *prim.Indices < 0 || *prim.Indices >= len(doc.Accessors) {
+			return fmt.Errorf("indices accessor %d out of range", *prim.Indices)
+		}
+		out := make([]byte, 12*len(mesh.Faces))
+		for i, f := range mesh.Faces {
+			for j, v := range f {
+				binary.LittleEndian.PutUint32(out[12*i+4*j:], v)
+			}
+		}
+		setDecodedAccessor(doc, doc.Accessors[*prim.Indices], out, gltf.ComponentUint, 3*len(mesh.Faces))
+	}
+	return nil
+}
+
+// setDecodedAccessor stores data in a new buffer and view read by accessor.
+func setDecodedAccessor(doc *gltf.Document, accessor *gltf.Accessor, data []byte, ct gltf.ComponentType, count int) {
+	doc.Buffers = append(doc.Buffers, &gltf.Buffer{ByteLength: len(data), Data: data})
+	doc.BufferViews = append(doc.BufferViews, &gltf.BufferView{
+		Buffer:     len(doc.Buffers) - 1,
+		ByteLength: len(data),
+	})
+	accessor.BufferView = gltf.Index(len(doc.BufferViews) - 1)
+	accessor.ByteOffset = 0
+	accessor.ComponentType = ct
+	accessor.Normalized = false
+	accessor.Count = count
+	accessor.Sparse = nil
+}
+
+// dracoNormalizedScale returns the divisor of a normalized integer component type.
+func dracoNormalizedScale(ct gltf.ComponentType) float64 {
+	switch ct {
+	case gltf.ComponentByte:
+		return math.MaxInt8
+	case gltf.ComponentUbyte:
+		return math.MaxUint8
+	case gltf.ComponentShort:
+		return math.MaxInt16
+	case gltf.ComponentUshort:
+		return math.MaxUint16
+	default:
+		return 1
+	}
+}
+
+// decodeDraco decodes a Draco mesh. Only the sequential encoding is
+// supported; edgebreaker streams return errDracoEdgebreaker.
+func decodeDraco(data []byte) (*dracoMesh, error) {
+	r := &dracoReader{data: data}
+	magic, err := r.bytes(5)
+	if err != nil || string(magic) != "DRACO" {
+		return nil, errors.New("not a draco stream")
+	}
+	header, err := r.bytes(4)
+	if err != nil {
+		return nil, err
+	}
+	major, minor, encoder, method := header[0], header[1], header[2], header[3]
+	if major != 2 || minor != 2 {
+		return nil, fmt.Errorf("unsupported draco version %d.%d", major, minor)
+	}
+	if encoder != dracoEncoderMesh {
+		return nil, errors.New("draco stream is not a triangle mesh")
+	}
+	flags, err := r.u16()
+	if err != nil {
+		return nil, err
+	}
+	if flags&dracoMetadataFlag != 0 {
+		if err := skipDracoMetadata(r); err != nil {
+			return nil, fmt.Errorf("metadata: %w", err)
+		}
+	}
+	if method != dracoMethodSequential {
+		if method == dracoMethodEdgebreaker {
+			return nil, errDracoEdgebreaker
+		}
+		return nil, fmt.Errorf("unsupported draco encoding method %d", method)
+	}
+	mesh := &dracoMesh{Attributes: make(map[int]*dracoAttribute)}
+	if err := decodeDracoSequentialConnectivity(r, mesh); err != nil {
+		return nil, fmt.Errorf("connectivity: %w", err)
+	}
+	if err := decodeDracoAttributes(r, mesh); err != nil {
+		return nil, fmt.Errorf("attributes: %w", err)
+	}
+	return mesh, nil
+}
+
+// skipDracoMetadata skips the attribute and file metadata.
+func skipDracoMetadata(r *dracoReader) error {
+	numAttributes, err := r.count(r.remaining())
+	if err != nil {
+		return err
+	}
+	for range numAttributes {
+		if _, err := r.varint(); err != nil {
+			return err
+		}
+		if err := skipDracoMetadataElement(r, 0); err != nil {
+			return err
+		}
+	}
+	return skipDracoMetadataElement(r, 0)
+}
+
+// skipDracoMetadataElement skips key/value entries and nested metadata.
+func skipDracoMetadataElement(r *dracoReader, depth int) error {
+	if depth > 32 {
+		return errors.New("metadata nested too deep")
+	}
+	entries, err := r.count(r.remaining())
+	if err != nil {
+		return err
+	}
+	for range 2 * entries {
+		// Key then value, each with a byte length prefix
+		n, err := r.u8()
+		if err != nil {
+			return err
+		}
+		if _, err := r.bytes(int(n)); err != nil {
+			return err
+		}
+	}
+	children, err := r.count(r.remaining())
+	if err != nil {
+		return err
+	}
+	for range children {
+		n, err := r.u8()
+		if err != nil {
+			return err
+		}
+		if _, err := r.bytes(int(n)); err != nil {
+			return err
+		}
+		if err := skipDracoMetadataElement(r, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeDracoSequentialConnectivity reads the face list, either as delta
+// coded symbols or as plain indices sized by the number of points.
+func decodeDracoSequentialConnectivity(r *dracoReader, mesh *dracoMesh) error {
+	// Like the reference decoder, every index must fit in the remaining data
+	numFaces, err := r.count(min(r.remaining()/3, math.MaxUint32/3))
+	if err != nil {
+		return err
+	}
+	numPoints, err := r.count(3*numFaces + r.remaining())
+	if err != nil {
+		return err
+	}
+	mesh.NumPoints = numPoints
+	method, err := r.u8()
+	if err != nil {
+		return err
+	}
+	mesh.Faces = make([][3]uint32, numFaces)
+	switch method {
+	case dracoIndicesCompressed:
+		symbols := make([]uint32, numFaces*3)
+		if err := decodeDracoSymbols(r, len(symbols), 1, symbols); err != nil {
+			return err
+		}
+		var last int64
+		for i, v := range symbols {
+			diff := int64(v >> 1)
+			if v&1 != 0 {
+				diff = -diff
+			}
+			last += diff
+			if last < 0 {
+				return errors.New("negative point index")
+			}
+			mesh.Faces[i/3][i%3] = uint32(last) //nolint:gosec // checked against the point count below
+		}
+	case dracoIndicesUncompressed:
+		for i := range numFaces * 3 {
+			var v uint32
+			switch {
+			case numPoints < 1<<8:
+				var b uint8
+				b, err = r.u8()
+				v = uint32(b)
+			case numPoints < 1<<16:
+				var s uint16
+				s, err = r.u16()
+				v = uint32(s)
+			case numPoints < 1<<21:
+				var l uint64
+				l, err = r.varint()
+				v = uint32(min(l, math.MaxUint32)) //nolint:gosec // clamped
+			default:
+				v, err = r.u32()
+			}
+			if err != nil {
+				return err
+			}
+			mesh.Faces[i/3][i%3] = v
+		}
+	default:
+		return fmt.Errorf("unsupported index coding %d", method)
+	}
+	for _, f := range mesh.Faces {
+		for _, v := range f {
+			if int(v) >= numPoints {
+				return fmt.Errorf("point index %d out of range (%d points)", v, numPoints)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,566 @@
+package models
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// dracoSequentialAttribute is an attribute being decoded by a sequential
+// attribute decoder, with its integer (portable) values and parameters.
+type dracoSequentialAttribute struct {
+	attr        *dracoAttribute
+	decoderType uint8
+	portable    []int32
+	// Quantization
+	minValues []float32
+	rangeSize float32
+	bits      int
+}
+
+// decodeDracoAttributes reads the attribute decoders and their values. Each
+// decoder lists its attributes, then decodes their values, the data needed
+// to dequantize them and finally converts them to floats.
+func decodeDracoAttributes(r *dracoReader, mesh *dracoMesh) error {
+	numDecoders, err := r.u8()
+	if err != nil {
+		return err
+	}
+	decoders := make([][]*dracoSequentialAttribute, numDecoders)
+	for i := range decoders {
+		numAttributes, err := r.count(r.remaining() / 5)
+		if err != nil {
+			return err
+		}
+		if numAttributes == 0 {
+			return errors.New("attribute decoder without attributes")
+		}
+		atts := make([]*dracoSequentialAttribute, numAttributes)
+		for j := range atts {
+			header, err := r.bytes(4)
+			if err != nil {
+				return err
+			}
+			id, err := r.count(math.MaxInt32)
+			if err != nil {
+				return err
+			}
+			attr := &dracoAttribute{
+				Type:       header[0],
+				DataType:   header[1],
+				Components: int(header[2]),
+			}
+			if attr.Components == 0 || dracoDataTypeSize(attr.DataType) == 0 {
+				return fmt.Errorf("attribute %d: invalid type %d x %d", id, attr.DataType, attr.Components)
+			}
+			mesh.Attributes[id] = attr
+			atts[j] = &dracoSequentialAttribute{attr: attr}
+		}
+		for _, a := range atts {
+			if a.decoderType, err = r.u8(); err != nil {
+				return err
+			}
+		}
+		decoders[i] = atts
+	}
+	for _, atts := range decoders {
+		for _, a := range atts {
+			if err := a.decodeValues(r, mesh.NumPoints); err != nil {
+				return err
+			}
+		}
+		for _, a := range atts {
+			if err := a.decodeParameters(r); err != nil {
+				return err
+			}
+		}
+		for _, a := range atts {
+			a.finish(mesh.NumPoints)
+		}
+	}
+	return nil
+}
+
+// dracoDataTypeSize returns the size in bytes of a Draco data type, 0 if unknown.
+func dracoDataTypeSize(t uint8) int {
+	switch t {
+	case dracoInt8, dracoUint8, dracoBool:
+		return 1
+	case dracoInt16, dracoUint16:
+		return 2
+	case dracoInt32, dracoUint32, dracoFloat32:
+		return 4
+	case dracoInt64, dracoUint64, dracoFloat64:
+		return 8
+	default:
+		return 0
+	}
+}
+
+// valueComponents returns the number of integer components per point:
+// normals are stored as two octahedral coordinates.
+func (a *dracoSequentialAttribute) valueComponents() int {
+	if a.decoderType == dracoAttributeNormals {
+		return 2
+	}
+	return a.attr.Components
+}
+
+// decodeValues reads the raw or integer coded values of every point.
+func (a *dracoSequentialAttribute) decodeValues(r *dracoReader, numPoints int) error {
+	switch a.decoderType {
+	case dracoAttributeGeneric:
+		size := dracoDataTypeSize(a.attr.DataType)
+		n := numPoints * a.attr.Components
+		if n > r.remaining()/size {
+			return errDracoTruncated
+		}
+		a.attr.Values = make([]float64, n)
+		for i := range a.attr.Values {
+			b, _ := r.bytes(size)
+			a.attr.Values[i] = dracoValue(b, a.attr.DataType)
+		}
+		return nil
+	case dracoAttributeInteger, dracoAttributeQuantization, dracoAttributeNormals:
+		if a.decoderType == dracoAttributeInteger && (a.attr.DataType < dracoInt8 || a.attr.DataType > dracoUint32) {
+			return errors.New("integer attributes must be at most 32 bits")
+		}
+		if a.decoderType != dracoAttributeInteger && a.attr.DataType != dracoFloat32 {
+			return errors.New("quantized attributes must be floats")
+		}
+		if a.decoderType == dracoAttributeNormals && a.attr.Components != 3 {
+			return errors.New("normals must have 3 components")
+		}
+		return a.decodeIntegers(r, numPoints)
+	default:
+		return fmt.Errorf("unsupported attribute decoder %d", a.decoderType)
+	}
+}
+
+// decodeIntegers reads the prediction scheme, the (possibly entropy coded)
+// corrections and reverts the prediction.
+//
+//nolint:gocognit // mirrors the reference decoder
+func (a *dracoSequentialAttribute) decodeIntegers(r *dracoReader, numPoints int) error {
+	method, err := r.u8()
+	if err != nil {
+		return err
+	}
+	transform := int8(-1)
+	if m := int8(method); m != dracoPredictionNone {
+		if m < dracoPredictionNone || m >= dracoNumPredictionSchemes {
+			return fmt.Errorf("invalid prediction scheme %d", m)
+		}
+		t, err := r.u8()
+		if err != nil {
+			return err
+		}
+		transform = int8(t)
+	}
+	// Decoders only accept the transform matching their data, others mean no prediction
+	predicted := transform == dracoTransformWrap && a.decoderType != dracoAttributeNormals ||
+		(transform == dracoTransformOctahedron || transform == dracoTransformOctahedronCano) &&
+			a.decoderType == dracoAttributeNormals
+	comps := a.valueComponents()
+	n := numPoints * comps
+	compressed, err := r.u8()
+	if err != nil {
+		return err
+	}
+	// Point counts are bounded by the stream size, which bounds n too
+	symbols := make([]uint32, n)
+	if compressed > 0 {
+		if err := decodeDracoSymbols(r, n, comps, symbols); err != nil {
+			return err
+		}
+	} else {
+		size, err := r.u8()
+		if err != nil {
+			return err
+		}
+		if size == 0 || size > 4 {
+			return fmt.Errorf("invalid value size %d", size)
+		}
+		if n > r.remaining()/int(size) {
+			return errDracoTruncated
+		}
+		for i := range symbols {
+			var buf [4]byte
+			b, _ := r.bytes(int(size))
+			copy(buf[:], b)
+			symbols[i] = binary.LittleEndian.Uint32(buf[:])
+		}
+	}
+	a.portable = make([]int32, n)
+	octahedral := predicted && a.decoderType == dracoAttributeNormals
+	for i, v := range symbols {
+		if octahedral {
+			// Octahedral corrections are coded as positive values
+			a.portable[i] = int32(v) //nolint:gosec // same bits as the reference
+		} else {
+			a.portable[i] = dracoSigned(v)
+		}
+	}
+	if !predicted || n == 0 {
+		return nil
+	}
+	// The transform data follows the values
+	if octahedral {
+		return a.revertOctahedralDifference(r, comps, transform == dracoTransformOctahedronCano)
+	}
+	return a.revertWrappedDifference(r, comps)
+}
+
+// revertWrappedDifference undoes difference prediction with values wrapped
+// into the [min, max] range stored after the corrections.
+func (a *dracoSequentialAttribute) revertWrappedDifference(r *dracoReader, comps int) error {
+	lo, err := r.u32()
+	if err != nil {
+		return err
+	}
+	hi, err := r.u32()
+	if err != nil {
+		return err
+	}
+	minValue, maxValue := int32(lo), int32(hi) //nolint:gosec // stored as int32
+	dif := int64(maxValue) - int64(minValue)
+	if dif < 0 || dif >= math.MaxInt32 {
+		return errors.New("invalid wrap range")
+	}
+	maxDif := int32(dif + 1)
+	values := a.portable
+	pred := make([]int32, comps)
+	for i := 0; i < len(values); i += comps {
+		for c := range comps {
+			p := min(max(pred[c], minValue), maxValue)
+			v := int32(uint32(p) + uint32(values[i+c])) //nolint:gosec // wraps like the reference
+			if v > maxValue {
+				v -= maxDif
+			} else if v < minValue {
+				v += maxDif
+			}
+			values[i+c] = v
+		}
+		copy(pred, values[i:i+comps])
+	}
+	return nil
+}
+
+// revertOctahedralDifference undoes difference prediction of octahedral
+// normal coordinates, where corrections wrap around the octahedron. The
+// canonicalized variant also rotates predictions into the bottom left quadrant.
+func (a *dracoSequentialAttribute) revertOctahedralDifference(r *dracoReader, comps int, canonicalized bool) error {
+	maxQuantized, err := r.u32()
+	if err != nil {
+		return err
+	}
+	bits := bitLength(maxQuantized)
+	if comps != 2 || maxQuantized%2 == 0 || bits < 2 || bits > 30 {
+		return errors.New("invalid octahedral transform")
+	}
+	oct := newDracoOctahedron(bits)
+	var pred [2]int32
+	for i := 0; i < len(a.portable); i += 2 {
+		corr := [2]int32{a.portable[i], a.portable[i+1]}
+		var orig [2]int32
+		if canonicalized {
+			orig = oct.canonicalizedOriginal(pred, corr)
+		} else {
+			orig = oct.original(pred, corr)
+		}
+		a.portable[i], a.portable[i+1] = orig[0], orig[1]
+		pred = orig
+	}
+	return nil
+}
+
+// bitLength returns the number of bits needed to represent v.
+func bitLength(v uint32) int {
+	n := 0
+	for ; v != 0; v >>= 1 {
+		n++
+	}
+	return n
+}
+
+// decodeParameters reads the data needed to convert integer values back,
+// which follows the values of every attribute of the decoder.
+func (a *dracoSequentialAttribute) decodeParameters(r *dracoReader) error {
+	switch a.decoderType {
+	case dracoAttributeQuantization:
+		a.minValues = make([]float32, a.attr.Components)
+		for i := range a.minValues {
+			v, err := r.f32()
+			if err != nil {
+				return err
+			}
+			a.minValues[i] = v
+		}
+		var err error
+		if a.rangeSize, err = r.f32(); err != nil {
+			return err
+		}
+		bits, err := r.u8()
+		if err != nil {
+			return err
+		}
+		if bits < 1 || bits > 30 {
+			return fmt.Errorf("invalid quantization bits %d", bits)
+		}
+		a.bits = int(bits)
+	case dracoAttributeNormals:
+		bits, err := r.u8()
+		if err != nil {
+			return err
+		}
+		if bits < 2 || bits > 30 {
+			return fmt.Errorf("invalid normal quantization bits %d", bits)
+		}
+		a.bits = int(bits)
+	}
+	return nil
+}
+
+// finish converts the integer values to the attribute's float values;
+// generic values were read directly.
+func (a *dracoSequentialAttribute) finish(numPoints int) {
+	attr := a.attr
+	switch a.decoderType {
+	case dracoAttributeInteger:
+		attr.Values = make([]float64, len(a.portable))
+		for i, v := range a.portable {
+			attr.Values[i] = dracoCastInteger(int64(v), attr.DataType)
+		}
+	case dracoAttributeQuantization:
+		delta := a.rangeSize / float32(uint32(1)<<a.bits-1)
+		attr.Values = make([]float64, len(a.portable))
+		for i, v := range a.portable {
+			attr.Values[i] = float64(float32(v)*delta + a.minValues[i%attr.Components])
+		}
+	case dracoAttributeNormals:
+		oct := newDracoOctahedron(a.bits)
+		attr.Values = make([]float64, 0, numPoints*3)
+		for i := 0; i+1 < len(a.portable); i += 2 {
+			n := oct.unitVector(a.portable[i], a.portable[i+1])
+			attr.Values = append(attr.Values, float64(n[0]), float64(n[1]), float64(n[2]))
+		}
+	}
+}
+
+// dracoValue decodes a little-endian value of the given data type.
+func dracoValue(b []byte, t uint8) float64 {
+	switch t {
+	case dracoFloat32:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case dracoFloat64:
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	case dracoInt8:
+		return float64(int8(b[0]))
+	case dracoUint8, dracoBool:
+		return float64(b[0])
+	case dracoInt16:
+		return float64(int16(binary.LittleEndian.Uint16(b))) //nolint:gosec // reinterpreted bits
+	case dracoUint16:
+		return float64(binary.LittleEndian.Uint16(b))
+	case dracoInt32:
+		return float64(int32(binary.LittleEndian.Uint32(b))) //nolint:gosec // reinterpreted bits
+	case dracoUint32:
+		return float64(binary.LittleEndian.Uint32(b))
+	case dracoInt64:
+		return float64(int64(binary.LittleEndian.Uint64(b))) //nolint:gosec // reinterpreted bits
+	default:
+		return float64(binary.LittleEndian.Uint64(b))
+	}
+}
+
+// dracoCastInteger converts a decoded integer to the attribute's data type,
+// truncating like a C cast.
+func dracoCastInteger(v int64, t uint8) float64 {
+	switch t {
+	case dracoInt8:
+		return float64(int8(v)) //nolint:gosec // truncating cast
+	case dracoUint8:
+		return float64(uint8(v)) //nolint:gosec // truncating cast
+	case dracoInt16:
+		return float64(int16(v)) //nolint:gosec // truncating cast
+	case dracoUint16:
+		return float64(uint16(v)) //nolint:gosec // truncating cast
+	case dracoUint32:
+		return float64(uint32(v)) //nolint:gosec // truncating cast
+	default:
+		return float64(v)
+	}
+}
+
+// dracoOctahedron converts quantized octahedral coordinates, mirroring
+// Draco's octahedron tool box.
+type dracoOctahedron struct {
+	maxQuantized int32 // 2^bits - 1
+	maxValue     int32 // 2^bits - 2
+	center       int32
+}
+
+func newDracoOctahedron(bits int) dracoOctahedron {
+	maxQuantized := int32(1)<<bits - 1
+	return dracoOctahedron{
+		maxQuantized: maxQuantized,
+		maxValue:     maxQuantized - 1,
+		center:       (maxQuantized - 1) / 2,
+	}
+}
+
+// unitVector decodes quantized coordinates (s, t) into a unit vector.
+func (o dracoOctahedron) unitVector(s, t int32) [3]float32 {
+	scale := 2 / float32(o.maxValue)
+	y := float32(s)*scale - 1
+	z := float32(t)*scale - 1
+	x := 1 - abs32(y) - abs32(z)
+	offset := max(-x, 0)
+	if y < 0 {
+		y += offset
+	} else {
+		y -= offset
+	}
+	if z < 0 {
+		z += offset
+	} else {
+		z -= offset
+	}
+	norm := x*x + y*y + z*z
+	if norm < 1e-6 {
+		return [3]float32{}
+	}
+	d := 1 / float32(math.Sqrt(float64(norm)))
+	return [3]float32{x * d, y * d, z * d}
+}
+
+func abs32(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// inDiamond returns true if the centered point lies inside the inner diamond.
+func (o dracoOctahedron) inDiamond(s, t int32) bool {
+	return absInt32(s)+absInt32(t) <= o.center
+}
+
+func absInt32(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// invertDiamond maps a centered point between the inner diamond and the
+// outer triangles of the octahedron.
+func (o dracoOctahedron) invertDiamond(s, t int32) (int32, int32) {
+	var signS, signT int32
+	switch {
+	case s >= 0 && t >= 0:
+		signS, signT = 1, 1
+	case s <= 0 && t <= 0:
+		signS, signT = -1, -1
+	default:
+		signS, signT = 1, 1
+		if s <= 0 {
+			signS = -1
+		}
+		if t <= 0 {
+			signT = -1
+		}
+	}
+	cornerS, cornerT := signS*o.center, signT*o.center
+	s, t = 2*s-cornerS, 2*t-cornerT
+	if signS*signT >= 0 {
+		s, t = -t, -s
+	} else {
+		s, t = t, s
+	}
+	return (s + cornerS) / 2, (t + cornerT) / 2
+}
+
+// modMax wraps a centered coordinate back into range.
+func (o dracoOctahedron) modMax(x int32) int32 {
+	if x > o.center {
+		return x - o.maxQuantized
+	}
+	if x < -o.center {
+		return x + o.maxQuantized
+	}
+	return x
+}
+
+// original reverts an octahedral correction relative to a prediction.
+func (o dracoOctahedron) original(pred, corr [2]int32) [2]int32 {
+	s, t := pred[0]-o.center, pred[1]-o.center
+	inDiamond := o.inDiamond(s, t)
+	if !inDiamond {
+		s, t = o.invertDiamond(s, t)
+	}
+	s, t = o.modMax(s+corr[0]), o.modMax(t+corr[1])
+	if !inDiamond {
+		s, t = o.invertDiamond(s, t)
+	}
+	return [2]int32{s + o.center, t + o.center}
+}
+
+// canonicalizedOriginal is original with the prediction rotated into the
+// bottom left quadrant first.
+func (o dracoOctahedron) canonicalizedOriginal(pred, corr [2]int32) [2]int32 {
+	s, t := pred[0]-o.center, pred[1]-o.center
+	inDiamond := o.inDiamond(s, t)
+	if !inDiamond {
+		s, t = o.invertDiamond(s, t)
+	}
+	bottomLeft := s == 0 && t == 0 || s < 0 && t <= 0
+	rotation := octahedronRotation(s, t)
+	if !bottomLeft {
+		s, t = rotateOctahedral(s, t, rotation)
+	}
+	s, t = o.modMax(s+corr[0]), o.modMax(t+corr[1])
+	if !bottomLeft {
+		s, t = rotateOctahedral(s, t, (4-rotation)%4)
+	}
+	if !inDiamond {
+		s, t = o.invertDiamond(s, t)
+	}
+	return [2]int32{s + o.center, t + o.center}
+}
+
+// octahedronRotation returns the number of quarter turns bringing a point
+// into the bottom left quadrant.
+func octahedronRotation(s, t int32) int {
+	switch {
+	case s == 0 && t == 0:
+		return 0
+	case s == 0 && t > 0:
+		return 3
+	case s == 0:
+		return 1
+	case s > 0 && t >= 0:
+		return 2
+	case s > 0:
+		return 1
+	case t <= 0:
+		return 0
+	default:
+		return 3
+	}
+}
+
+// rotateOctahedral rotates a centered point by count quarter turns.
+func rotateOctahedral(s, t int32, count int) (int32, int32) {
+	switch count {
+	case 1:
+		return t, -s
+	case 2:
+		return -s, -t
+	case 3:
+		return -t, s
+	default:
+		return s, t
+	}
+}
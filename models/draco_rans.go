@@ -0,0 +1,320 @@
+package models
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var errDracoTruncated = errors.New("truncated draco data")
+
+// dracoReader reads the little-endian Draco bitstream, with an optional bit
+// mode for the LSB-first packed sections.
+type dracoReader struct {
+	data []byte
+	pos  int
+	// Bit mode
+	bits   []byte
+	bitPos int
+}
+
+func (r *dracoReader) remaining() int {
+	return len(r.data) - r.pos
+}
+
+func (r *dracoReader) bytes(n int) ([]byte, error) {
+	if n < 0 || n > r.remaining() {
+		return nil, errDracoTruncated
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *dracoReader) u8() (uint8, error) {
+	b, err := r.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *dracoReader) u16() (uint16, error) {
+	b, err := r.bytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+func (r *dracoReader) u32() (uint32, error) {
+	b, err := r.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (r *dracoReader) f32() (float32, error) {
+	v, err := r.u32()
+	return math.Float32frombits(v), err
+}
+
+// varint reads an unsigned LEB128 value of at most 64 bits.
+func (r *dracoReader) varint() (uint64, error) {
+	var v uint64
+	for shift := 0; shift < 64; shift += 7 {
+		b, err := r.u8()
+		if err != nil {
+			return 0, err
+		}
+		v |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+	return 0, errors.New("draco varint overflow")
+}
+
+// count reads a varint element count, rejecting values above limit.
+func (r *dracoReader) count(limit int) (int, error) {
+	v, err := r.varint()
+	if err != nil {
+		return 0, err
+	}
+	if v > uint64(limit) {
+		return 0, errors.New("draco count exceeds the available data")
+	}
+	return int(v), nil
+}
+
+// startBits switches to bit mode at the current position.
+func (r *dracoReader) startBits() {
+	r.bits = r.data[r.pos:]
+	r.bitPos = 0
+}
+
+// readBits reads n bits, least significant first. Reads past the end yield zeros.
+func (r *dracoReader) readBits(n int) uint32 {
+	var v uint32
+	for i := range n {
+		byteOffset := (r.bitPos >> 3)
+		if byteOffset < len(r.bits) {
+			v |= uint32(r.bits[byteOffset]>>(r.bitPos&7)&1) << i
+		}
+		r.bitPos++
+	}
+	return v
+}
+
+// endBits leaves bit mode, skipping the bytes consumed by the bit reads.
+func (r *dracoReader) endBits() {
+	r.pos += (r.bitPos + 7) >> 3
+	r.pos = min(r.pos, len(r.data))
+	r.bits = nil
+}
+
+// dracoANSIOBase is the renormalization base of Draco's rANS coder.
+const dracoANSIOBase = 256
+
+// ansInit reads the final rANS state stored in the last one to four bytes of buf.
+func ansInit(buf []byte, lBase uint32) (state uint32, offset int, err error) {
+	n := len(buf)
+	if n < 1 {
+		return 0, 0, errDracoTruncated
+	}
+	switch x := buf[n-1] >> 6; {
+	case x == 0:
+		offset, state = n-1, uint32(buf[n-1]&0x3f)
+	case x == 1 && n >= 2:
+		offset, state = n-2, uint32(binary.LittleEndian.Uint16(buf[n-2:]))&0x3fff
+	case x == 2 && n >= 3:
+		offset = n - 3
+		state = (uint32(buf[n-3]) | uint32(buf[n-2])<<8 | uint32(buf[n-1])<<16) & 0x3fffff
+	case x == 3 && n >= 4:
+		offset, state = n-4, binary.LittleEndian.Uint32(buf[n-4:])&0x3fffffff
+	default:
+		return 0, 0, errors.New("invalid ans state")
+	}
+	state += lBase
+	if uint64(state) >= uint64(lBase)*dracoANSIOBase {
+		return 0, 0, errors.New("invalid ans state")
+	}
+	return state, offset, nil
+}
+
+// dracoSymbolDecoder decodes symbols with a coded probability table (rANS).
+type dracoSymbolDecoder struct {
+	precision uint32
+	probs     []uint32
+	cumProbs  []uint32
+	lookup    []uint32
+	buf       []byte
+	offset    int
+	state     uint32
+}
+
+// dracoRANSPrecision returns the precision in bits used for symbols of the
+// given maximum bit length.
+func dracoRANSPrecision(bitLength int) int {
+	return min(20, max(12, 3*bitLength/2))
+}
+
+// create reads the probability table.
+func (d *dracoSymbolDecoder) create(r *dracoReader, precisionBits int) error {
+	d.precision = 1 << precisionBits
+	numSymbols, err := r.count(r.remaining() * 64)
+	if err != nil {
+		return err
+	}
+	d.probs = make([]uint32, numSymbols)
+	for i := 0; i < numSymbols; i++ {
+		b, err := r.u8()
+		if err != nil {
+			return err
+		}
+		token := b & 3
+		if token == 3 {
+			// Run of zero probabilities
+			run := int(b >> 2)
+			if i+run >= numSymbols {
+				return errors.New("invalid draco probability table")
+			}
+			i += run
+			continue
+		}
+		prob := uint32(b >> 2)
+		for k := range int(token) {
+			eb, err := r.u8()
+			if err != nil {
+				return err
+			}
+			prob |= uint32(eb) << (8*(k+1) - 2)
+		}
+		d.probs[i] = prob
+	}
+	// Cumulative probabilities and the reverse lookup table
+	d.cumProbs = make([]uint32, numSymbols)
+	d.lookup = make([]uint32, d.precision)
+	var cum uint32
+	for i, p := range d.probs {
+		d.cumProbs[i] = cum
+		if p > d.precision-cum {
+			return errors.New("invalid draco probability table")
+		}
+		for j := cum; j < cum+p; j++ {
+			d.lookup[j] = uint32(i) //nolint:gosec // bounded by the table size
+		}
+		cum += p
+	}
+	if numSymbols > 0 && cum != d.precision {
+		return errors.New("invalid draco probability table")
+	}
+	return nil
+}
+
+// start reads the coded symbols from r.
+func (d *dracoSymbolDecoder) start(r *dracoReader) error {
+	size, err := r.count(r.remaining())
+	if err != nil {
+		return err
+	}
+	if d.buf, err = r.bytes(size); err != nil {
+		return err
+	}
+	d.state, d.offset, err = ansInit(d.buf, 4*d.precision)
+	return err
+}
+
+// symbol decodes the next symbol.
+func (d *dracoSymbolDecoder) symbol() uint32 {
+	lBase := 4 * d.precision
+	for d.state < lBase && d.offset > 0 {
+		d.offset--
+		d.state = d.state*dracoANSIOBase + uint32(d.buf[d.offset])
+	}
+	quot, rem := d.state/d.precision, d.state%d.precision
+	s := d.lookup[rem]
+	d.state = quot*d.probs[s] + rem - d.cumProbs[s]
+	return s
+}
+
+// Symbol coding schemes.
+const (
+	dracoSymbolsTagged = 0
+	dracoSymbolsRaw    = 1
+)
+
+// decodeDracoSymbols decodes count unsigned symbols into out.
+func decodeDracoSymbols(r *dracoReader, count, components int, out []uint32) error {
+	if count == 0 {
+		return nil
+	}
+	scheme, err := r.u8()
+	if err != nil {
+		return err
+	}
+	switch scheme {
+	case dracoSymbolsTagged:
+		return decodeDracoTaggedSymbols(r, count, components, out)
+	case dracoSymbolsRaw:
+		maxBitLength, err := r.u8()
+		if err != nil {
+			return err
+		}
+		if maxBitLength < 1 || maxBitLength > 18 {
+			return errors.New("invalid draco symbol bit length")
+		}
+		var d dracoSymbolDecoder
+		if err := d.create(r, dracoRANSPrecision(int(maxBitLength))); err != nil {
+			return err
+		}
+		if len(d.probs) == 0 {
+			return errors.New("empty draco symbol table")
+		}
+		if err := d.start(r); err != nil {
+			return err
+		}
+		for i := range count {
+			out[i] = d.symbol()
+		}
+		return nil
+	default:
+		return errors.New("unsupported draco symbol coding")
+	}
+}
+
+// decodeDracoTaggedSymbols decodes groups of components values sharing a
+// bit length, the tags being rANS coded and the values raw bits.
+func decodeDracoTaggedSymbols(r *dracoReader, count, components int, out []uint32) error {
+	var tags dracoSymbolDecoder
+	if err := tags.create(r, dracoRANSPrecision(5)); err != nil {
+		return err
+	}
+	if err := tags.start(r); err != nil {
+		return err
+	}
+	if len(tags.probs) == 0 {
+		return errors.New("empty draco tag table")
+	}
+	r.startBits()
+	for i := 0; i < count; i += components {
+		bitLength := int(tags.symbol())
+		if bitLength > 32 {
+			return errors.New("invalid draco tag")
+		}
+		for j := i; j < min(i+components, count); j++ {
+			out[j] = r.readBits(bitLength)
+		}
+	}
+	r.endBits()
+	return nil
+}
+
+// dracoSigned converts a zigzag coded symbol back to a signed value.
+func dracoSigned(v uint32) int32 {
+	if v&1 == 0 {
+		return int32(v >> 1) //nolint:gosec // at most 31 bits
+	}
+	return -int32(v>>1) - 1 //nolint:gosec // at most 31 bits
+}
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ansipixels/trophy/math3d"
+	"github.com/qmuntal/gltf"
+)
+
+// dracoHeader starts a Draco 2.2 sequential mesh with faces and points.
+func dracoHeader(faces, points byte) []byte {
+	return append([]byte("DRACO"), 2, 2, dracoEncoderMesh, dracoMethodSequential, 0, 0, faces, points)
+}
+
+// dracoTaggedBits codes values as tagged symbols that all share bitLength:
+// a tag table with a single symbol of full probability, whose rANS state
+// never changes, followed by the LSB-first packed values.
+func dracoTaggedBits(bitLength int, values ...uint32) []byte {
+	out := []byte{
+		dracoSymbolsTagged,
+		byte(bitLength + 1),        // number of symbols
+		byte((bitLength-1)<<2 | 3), // zero probability run before the tag
+		1, 64,                      // probability 4096 (token 1, extra byte)
+		1, 0, // one byte of rANS state
+	}
+	var bits []byte
+	pos := 0
+	for _, v := range values {
+		for i := range bitLength {
+			if pos/8 == len(bits) {
+				bits = append(bits, 0)
+			}
+			bits[pos/8] |= byte(v>>i&1) << (pos % 8)
+			pos++
+		}
+	}
+	return append(out, bits...)
+}
+
+func appendFloats(b []byte, v ...float32) []byte {
+	for _, f := range v {
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(f))
+	}
+	return b
+}
+
+// dracoSquare is two triangles over four points with generic float positions.
+func dracoSquare() []byte {
+	data := dracoHeader(2, 4)
+	data = append(data, dracoIndicesUncompressed, 0, 1, 2, 2, 1, 3)
+	// One decoder with a generic float32 vec3 attribute of id 0
+	data = append(data, 1, 1, 0, dracoFloat32, 3, 0, 0, dracoAttributeGeneric)
+	return appendFloats(data, 0, 0, 0, 1, 0, 0, 0, 1, 0, 1, 1, 0)
+}
+
+func TestDecodeDracoGeneric(t *testing.T) {
+	mesh, err := decodeDraco(dracoSquare())
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(mesh.Faces) != 2 || mesh.Faces[1] != [3]uint32{2, 1, 3} || mesh.NumPoints != 4 {
+		t.Fatalf("faces = %v points = %d", mesh.Faces, mesh.NumPoints)
+	}
+	attr := mesh.Attributes[0]
+	if attr == nil || attr.Components != 3 || len(attr.Values) != 12 || attr.Values[9] != 1 || attr.Values[10] != 1 {
+		t.Fatalf("attribute = %+v", attr)
+	}
+}
+
+func TestDecodeDracoCompressedQuantized(t *testing.T) {
+	data := dracoHeader(2, 4)
+	// Indices delta coded with the sign in the low bit: 0 1 2 2 1 3
+	data = append(data, dracoIndicesCompressed)
+	data = append(data, dracoTaggedBits(3, 0, 2, 2, 0, 3, 4)...)
+	// Positions quantized to 2 bits, difference predicted and wrapped into [0, 3]
+	data = append(data, 1, 1, 0, dracoFloat32, 3, 0, 5, dracoAttributeQuantization)
+	data = append(data, 0, dracoTransformWrap, 0, 1) // difference, wrap, raw bytes
+	// Points (0,0,0) (3,0,0) (0,3,0) (3,3,0): deltas zigzag coded
+	data = append(data, 0, 0, 0, 6, 0, 0, 5, 6, 0, 6, 0, 0)
+	data = binary.LittleEndian.AppendUint32(data, 0)
+	data = binary.LittleEndian.AppendUint32(data, 3)
+	data = appendFloats(data, 1, 2, 3, 3) // minimum and range
+	data = append(data, 2)
+	mesh, err := decodeDraco(data)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if mesh.Faces[0] != [3]uint32{0, 1, 2} || mesh.Faces[1] != [3]uint32{2, 1, 3} {
+		t.Errorf("faces = %v", mesh.Faces)
+	}
+	want := []float64{1, 2, 3, 4, 2, 3, 1, 5, 3, 4, 5, 3}
+	attr := mesh.Attributes[5]
+	if attr == nil || len(attr.Values) != len(want) {
+		t.Fatalf("attribute = %+v", attr)
+	}
+	for i, v := range attr.Values {
+		if math.Abs(v-want[i]) > 1e-6 {
+			t.Fatalf("values = %v, want %v", attr.Values, want)
+		}
+	}
+}
+
+func TestDecodeDracoNormals(t *testing.T) {
+	data := dracoHeader(0, 4)
+	data = append(data, dracoIndicesUncompressed)
+	data = append(data, 1, 1, 1, dracoFloat32, 3, 0, 0, dracoAttributeNormals)
+	// No prediction, 3 bit octahedral coordinates (center 3), zigzag coded
+	data = append(data, 0xfe, 0, 1, 6, 6, 12, 6, 6, 0, 0, 0, 3)
+	mesh, err := decodeDraco(data)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := []math3d.Vec3{math3d.V3(1, 0, 0), math3d.V3(0, 1, 0), math3d.V3(0, 0, -1), math3d.V3(-1, 0, 0)}
+	v := mesh.Attributes[0].Values
+	for i, w := range want {
+		assertVec3(t, "normal", math3d.V3(v[3*i], v[3*i+1], v[3*i+2]), w)
+	}
+}
+
+func TestDracoOctahedronTransforms(t *testing.T) {
+	oct := newDracoOctahedron(4)
+	c := oct.center
+	for s := -c; s <= c; s++ {
+		for tt := -c; tt <= c; tt++ {
+			// A zero correction reproduces the predicted direction (the
+			// corners of the octahedral square are the same point)
+			pred := [2]int32{s + c, tt + c}
+			w := oct.unitVector(pred[0], pred[1])
+			for name, got := range map[string][2]int32{
+				"original":              oct.original(pred, [2]int32{}),
+				"canonicalizedOriginal": oct.canonicalizedOriginal(pred, [2]int32{}),
+			} {
+				n := oct.unitVector(got[0], got[1])
+				assertVec3(t, name, math3d.V3(float64(n[0]), float64(n[1]), float64(n[2])),
+					math3d.V3(float64(w[0]), float64(w[1]), float64(w[2])))
+			}
+		}
+	}
+}
+
+func TestDecodeDracoErrors(t *testing.T) {
+	edgebreaker := dracoSquare()
+	edgebreaker[8] = dracoMethodEdgebreaker
+	outOfRange := dracoSquare()
+	outOfRange[14] = 4
+	tests := map[string][]byte{
+		"magic":       []byte("DRACU\x02\x02\x01\x00\x00\x00"),
+		"edgebreaker": edgebreaker,
+		"index":       outOfRange,
+		"truncated":   dracoSquare()[:40],
+		"faces":       append(dracoHeader(0x7f, 0), 0xff, 0xff, 0xff),
+	}
+	for name, data := range tests {
+		if _, err := decodeDraco(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		} else if errors.Is(err, errDracoEdgebreaker) != (name == "edgebreaker") {
+			t.Errorf("%s: error %v", name, err)
+		}
+	}
+}
+
+// dracoPrimitive returns a primitive compressed in a Draco buffer view,
+// with its position and index accessors.
+func dracoPrimitive(doc *gltf.Document, view int) *gltf.Primitive {
+	doc.Accessors = append(doc.Accessors,
+		&gltf.Accessor{ComponentType: gltf.ComponentFloat, Type: gltf.AccessorVec3, Count: 4},
+		&gltf.Accessor{ComponentType: gltf.ComponentUshort, Type: gltf.AccessorScalar, Count: 6})
+	return &gltf.Primitive{
+		Indices:    gltf.Index(len(doc.Accessors) - 1),
+		Attributes: gltf.PrimitiveAttributes{gltf.POSITION: len(doc.Accessors) - 2},
+		Extensions: gltf.Extensions{extDraco: map[string]any{
+			"bufferView": view,
+			"attributes": map[string]int{gltf.POSITION: 0},
+		}},
+	}
+}
+
+func TestLoadDracoGLB(t *testing.T) {
+	b := newGLBBuilder()
+	view := b.view(dracoSquare(), 0)
+	edgebreaker := dracoSquare()
+	edgebreaker[8] = dracoMethodEdgebreaker
+	skipped := b.view(edgebreaker, 0)
+	doc := b.finish()
+	doc.ExtensionsUsed = []string{extDraco}
+	doc.ExtensionsRequired = []string{extDraco}
+	// The edgebreaker primitive is skipped, the sequential one still loads
+	doc.Meshes = []*gltf.Mesh{{Primitives: []*gltf.Primitive{dracoPrimitive(doc, skipped), dracoPrimitive(doc, view)}}}
+	doc.Nodes = []*gltf.Node{{Mesh: gltf.Index(0)}}
+	doc.Scenes = []*gltf.Scene{{Nodes: []int{0}}}
+	mesh, err := LoadGLBFromFS(encodeGLB(t, doc), "model.glb")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if mesh.VertexCount() != 4 || mesh.TriangleCount() != 2 {
+		t.Fatalf("vertices=%d triangles=%d, want 4 and 2", mesh.VertexCount(), mesh.TriangleCount())
+	}
+	assertVec3(t, "vertex 3", mesh.Vertices[3].Position, math3d.V3(1, 1, 0))
+	// (2, 1, 3) with the engine's winding swap
+	if f := mesh.Faces[1].V; f != [3]int{2, 3, 1} {
+		t.Errorf("face 1 = %v, want [2 3 1]", f)
+	}
+}
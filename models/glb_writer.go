@@ -0,0 +1,206 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"slices"
+
+	"github.com/qmuntal/gltf"
+	"github.com/qmuntal/gltf/modeler"
+)
+
+// WriteGLB writes a mesh as a binary glTF with one node: its vertex
+// attributes (normals, UVs and COLOR_0 when the mesh has them) shared by one
+// primitive per material and mode (triangles, lines, points), and its
+// materials with their textures embedded as loaded, or as PNG.
+func WriteGLB(w io.Writer, mesh *Mesh) error {
+	doc, err := gltfDocument(mesh)
+	if err != nil {
+		return err
+	}
+	if err := gltf.NewEncoder(w).Encode(doc); err != nil {
+		return fmt.Errorf("encode GLB: %w", err)
+	}
+	return nil
+}
+
+// gltfDocument converts a mesh to a glTF document.
+func gltfDocument(mesh *Mesh) (*gltf.Document, error) {
+	doc := gltf.NewDocument()
+	materials, err := gltfMaterials(doc, mesh.Materials)
+	if err != nil {
+		return nil, err
+	}
+	doc.Materials = materials
+	if len(mesh.Vertices) == 0 {
+		return doc, nil
+	}
+	positions := make([][3]float32, len(mesh.Vertices))
+	for i := range mesh.Vertices {
+		p := mesh.Vertices[i].Position
+		positions[i] = [3]float32{float32(p.X), float32(p.Y), float32(p.Z)}
+	}
+	attributes := gltf.PrimitiveAttributes{gltf.POSITION: modeler.WritePosition(doc, positions)}
+	if mesh.hasVertexNormals() {
+		normals := make([][3]float32, len(mesh.Vertices))
+		for i := range mesh.Vertices {
+			n := mesh.Vertices[i].Normal
+			normals[i] = [3]float32{float32(n.X), float32(n.Y), float32(n.Z)}
+		}
+		attributes[gltf.NORMAL] = modeler.WriteNormal(doc, normals)
+	}
+	if mesh.hasVertexUVs() {
+		uvs := make([][2]float32, len(mesh.Vertices))
+		for i := range mesh.Vertices {
+			// glTF V runs downwards, the loader flips it
+			uv := mesh.Vertices[i].UV
+			uvs[i] = [2]float32{float32(uv.X), float32(1 - uv.Y)}
+		}
+		attributes[gltf.TEXCOORD_0] = modeler.WriteTextureCoord(doc, uvs)
+	}
+	if mesh.VertexColors {
+		colors := make([][4]uint8, len(mesh.Vertices))
+		for i := range mesh.Vertices {
+			c := mesh.Vertices[i].Color
+			colors[i] = [4]uint8{c.R, c.G, c.B, c.A}
+		}
+		attributes[gltf.COLOR_0] = modeler.WriteColor(doc, colors)
+	}
+	// One primitive per mode and material, in order of first use
+	type primitiveKey struct {
+		mode     gltf.PrimitiveMode
+		material int
+	}
+	var keys []primitiveKey
+	indices := make(map[primitiveKey][]uint32)
+	add := func(mode gltf.PrimitiveMode, material int, vs ...int) {
+		k := primitiveKey{mode, material}
+		if _, ok := indices[k]; !ok {
+			keys = append(keys, k)
+		}
+		for _, v := range vs {
+			indices[k] = append(indices[k], uint32(v)) //nolint:gosec // vertex indices fit glTF's uint32
+		}
+	}
+	for _, f := range mesh.Faces {
+		t := fileTriangle(f)
+		add(gltf.PrimitiveTriangles, f.Material, t[0], t[1], t[2])
+	}
+	for _, l := range mesh.Lines {
+		add(gltf.PrimitiveLines, l.Material, l.V[0], l.V[1])
+	}
+	for _, p := range mesh.Points {
+		add(gltf.PrimitivePoints, p.Material, p.V)
+	}
+	gm := &gltf.Mesh{Name: mesh.Name}
+	for _, k := range keys {
+		prim := &gltf.Primitive{
+			Attributes: attributes,
+			Indices:    gltf.Index(modeler.WriteIndices(doc, indices[k])),
+			Mode:       k.mode,
+		}
+		if k.material >= 0 && k.material < len(doc.Materials) {
+			prim.Material = gltf.Index(k.material)
+		}
+		gm.Primitives = append(gm.Primitives, prim)
+	}
+	doc.Meshes = []*gltf.Mesh{gm}
+	doc.Nodes = []*gltf.Node{{Name: mesh.Name, Mesh: gltf.Index(0)}}
+	doc.Scenes[0].Nodes = []int{0}
+	return doc, nil
+}
+
+// gltfMaterials converts materials, embedding their base color and emissive
+// textures.
+func gltfMaterials(doc *gltf.Document, materials []Material) ([]*gltf.Material, error) {
+	textures := make(map[image.Image]int)
+	texture := func(img image.Image) (*gltf.TextureInfo, error) {
+		if img == nil {
+			return nil, nil //nolint:nilnil // no texture
+		}
+		idx, ok := textures[img]
+		if !ok {
+			data, mimeType, err := gltfImageData(img)
+			if err != nil {
+				return nil, err
+			}
+			source, err := modeler.WriteImage(doc, fmt.Sprintf("texture%d", len(textures)), mimeType, bytes.NewReader(data))
+			if err != nil {
+				return nil, fmt.Errorf("write texture: %w", err)
+			}
+			doc.Textures = append(doc.Textures, &gltf.Texture{Source: gltf.Index(source)})
+			idx = len(doc.Textures) - 1
+			textures[img] = idx
+		}
+		return &gltf.TextureInfo{Index: idx}, nil
+	}
+	out := make([]*gltf.Material, len(materials))
+	for i := range materials {
+		m := &materials[i]
+		metallic, roughness := m.Metallic, m.Roughness
+		baseColor := m.BaseColor
+		gm := &gltf.Material{
+			Name: m.Name,
+			PBRMetallicRoughness: &gltf.PBRMetallicRoughness{
+				BaseColorFactor: &baseColor,
+				MetallicFactor:  &metallic,
+				RoughnessFactor: &roughness,
+			},
+		}
+		var err error
+		if m.HasTexture {
+			if gm.PBRMetallicRoughness.BaseColorTexture, err = texture(m.BaseMap); err != nil {
+				return nil, err
+			}
+		}
+		if gm.EmissiveTexture, err = texture(m.EmissiveMap); err != nil {
+			return nil, err
+		}
+		if baseColor[3] < 1 {
+			gm.AlphaMode = gltf.AlphaBlend
+		}
+		// Emissive colors above 1 need KHR_materials_emissive_strength
+		strength := math.Max(m.Emissive[0], math.Max(m.Emissive[1], m.Emissive[2]))
+		if strength > 1 {
+			gm.EmissiveFactor = [3]float64{m.Emissive[0] / strength, m.Emissive[1] / strength, m.Emissive[2] / strength}
+			gm.Extensions = gltf.Extensions{extEmissiveStrength: emissiveStrengthExt{EmissiveStrength: &strength}}
+			addExtensionUsed(doc, extEmissiveStrength)
+		} else {
+			gm.EmissiveFactor = m.Emissive
+		}
+		if m.Unlit {
+			if gm.Extensions == nil {
+				gm.Extensions = gltf.Extensions{}
+			}
+			gm.Extensions[extUnlit] = struct{}{}
+			addExtensionUsed(doc, extUnlit)
+		}
+		out[i] = gm
+	}
+	return out, nil
+}
+
+// gltfImageData returns the file data of a texture: the loaded file for
+// the PNG and JPEG images glTF supports, else the image encoded as PNG.
+func gltfImageData(img image.Image) ([]byte, string, error) {
+	if e, ok := img.(*EncodedImage); ok && (e.MIMEType == "image/png" || e.MIMEType == "image/jpeg") {
+		return e.Data, e.MIMEType, nil
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", fmt.Errorf("encode texture: %w", err)
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+// addExtensionUsed lists an extension in the document's extensionsUsed.
+func addExtensionUsed(doc *gltf.Document, name string) {
+	if slices.Contains(doc.ExtensionsUsed, name) {
+		return
+	}
+	doc.ExtensionsUsed = append(doc.ExtensionsUsed, name)
+}
@@ -3,16 +3,17 @@ package models
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"image"
+	"image/color"
 	_ "image/jpeg" // for decoding JPEG images in GLTF files
 	_ "image/png"  // for decoding PNG images in GLTF files
 	"io/fs"
+	"maps"
+	"math"
 	"os"
 	"path/filepath"
 	"slices"
-	"unsafe"
 
 	"github.com/ansipixels/trophy/math3d"
 	"github.com/qmuntal/gltf"
@@ -66,57 +67,81 @@ func (l *GLTFLoader) LoadFromFS(fsys fs.FS, path string) (*Mesh, error) {
 
 func (l *GLTFLoader) loadFromDocument(doc *gltf.Document, path string, resourceFS fs.FS) (*Mesh, error) {
 	mesh := NewMesh(filepath.Base(path))
+	mesh.Unit = Meters
 	// Extract materials first
 	mesh.Materials = extractMaterialsFromFS(doc, resourceFS)
+	// Skinned, morphed or animated files keep their hierarchy so they can be re-posed
+	var rig *Rig
+	if needsRig(doc) {
+		var err error
+		if rig, err = buildRig(doc); err != nil {
+			return nil, err
+		}
+		mesh.Rig = rig
+	}
 	// Process scene nodes with transforms (handles node hierarchy)
 	processedMeshes := make(map[int]bool)
-	if len(doc.Scenes) > 0 {
-		sceneIdx := 0
-		if doc.Scene != nil {
-			sceneIdx = *doc.Scene
-		}
-		scene := doc.Scenes[sceneIdx]
-		for _, nodeIdx := range scene.Nodes {
-			if err := l.processNode(doc, nodeIdx, math3d.Identity(), mesh, processedMeshes); err != nil {
-				return nil, err
-			}
-		}
-	} else {
-		// No scenes defined, process all root nodes
-		for i := range doc.Nodes {
-			isRoot := true
-			for _, n := range doc.Nodes {
-				if slices.Contains(n.Children, i) {
-					isRoot = false
-				}
-				if !isRoot {
-					break
-				}
-			}
-			if isRoot {
-				if err := l.processNode(doc, i, math3d.Identity(), mesh, processedMeshes); err != nil {
-					return nil, err
-				}
-			}
+	for _, nodeIdx := range sceneRoots(doc) {
+		if err := l.processNode(doc, nodeIdx, math3d.Identity(), mesh, rig, processedMeshes); err != nil {
+			return nil, err
 		}
 	}
+	if rig != nil {
+		// Start from the rest pose so skinned parts match their joints
+		rig.Pose(-1, 0, mesh.Vertices)
+	}
 	// Calculate normals if needed
-	hasNormals := false
+	if l.ensureNormals(mesh) && rig != nil {
+		rig.CalculateNormals = true
+		rig.SmoothNormals = l.SmoothNormals
+	}
+	mesh.CalculateBounds()
+	return mesh, nil
+}
+
+// ensureNormals calculates normals (if enabled) when the mesh has none.
+// Returns true if normals were calculated.
+func (l *GLTFLoader) ensureNormals(mesh *Mesh) bool {
 	for _, v := range mesh.Vertices {
 		if v.Normal.Len() > 0.001 {
-			hasNormals = true
-			break
+			return false
 		}
 	}
-	if l.CalculateNormals && !hasNormals {
-		if l.SmoothNormals {
-			mesh.CalculateSmoothNormals()
-		} else {
-			mesh.CalculateNormals()
+	if !l.CalculateNormals {
+		return false
+	}
+	if l.SmoothNormals {
+		mesh.CalculateSmoothNormals()
+	} else {
+		mesh.CalculateNormals()
+	}
+	return true
+}
+
+// sceneRoots returns the root nodes of the default scene, or all parentless
+// nodes when no scenes are defined.
+func sceneRoots(doc *gltf.Document) []int {
+	if len(doc.Scenes) > 0 {
+		sceneIdx := 0
+		if doc.Scene != nil && *doc.Scene < len(doc.Scenes) {
+			sceneIdx = *doc.Scene
+		}
+		return doc.Scenes[sceneIdx].Nodes
+	}
+	var roots []int
+	for i := range doc.Nodes {
+		isRoot := true
+		for _, n := range doc.Nodes {
+			if slices.Contains(n.Children, i) {
+				isRoot = false
+				break
+			}
+		}
+		if isRoot {
+			roots = append(roots, i)
 		}
 	}
-	mesh.CalculateBounds()
-	return mesh, nil
+	return roots
 }
 
 // processNode recursively processes a node and its children, accumulating transforms.
@@ -125,6 +150,7 @@ func (l *GLTFLoader) processNode(
 	nodeIdx int,
 	parentTransform math3d.Mat4,
 	mesh *Mesh,
+	rig *Rig,
 	processedMeshes map[int]bool,
 ) error {
 	node := doc.Nodes[nodeIdx]
@@ -159,13 +185,17 @@ func (l *GLTFLoader) processNode(
 	if node.Mesh != nil {
 		meshIdx := *node.Mesh
 		gltfMesh := doc.Meshes[meshIdx]
-		if err := l.processMeshWithTransform(doc, gltfMesh, mesh, worldTransform); err != nil {
+		part := RigPart{Node: nodeIdx, Skin: -1}
+		if node.Skin != nil {
+			part.Skin = *node.Skin
+		}
+		if err := l.processMeshWithTransform(doc, gltfMesh, mesh, worldTransform, rig, part); err != nil {
 			return err
 		}
 		processedMeshes[meshIdx] = true
 	}
 	for _, childIdx := range node.Children {
-		if err := l.processNode(doc, childIdx, worldTransform, mesh, processedMeshes); err != nil {
+		if err := l.processNode(doc, childIdx, worldTransform, mesh, rig, processedMeshes); err != nil {
 			return err
 		}
 	}
@@ -173,11 +203,16 @@ func (l *GLTFLoader) processNode(
 }
 
 // processMeshWithTransform extracts geometry from a GLTF mesh, applying the given transform.
-func (l *GLTFLoader) processMeshWithTransform(doc *gltf.Document, m *gltf.Mesh, mesh *Mesh, transform math3d.Mat4) error {
+// When rig is not nil the untransformed vertices are recorded as a copy of part per primitive.
+func (l *GLTFLoader) processMeshWithTransform(
+	doc *gltf.Document,
+	m *gltf.Mesh,
+	mesh *Mesh,
+	transform math3d.Mat4,
+	rig *Rig,
+	part RigPart,
+) error {
 	for _, prim := range m.Primitives {
-		if prim.Mode != gltf.PrimitiveTriangles && prim.Mode != 0 {
-			continue
-		}
 		posIdx, ok := prim.Attributes[gltf.POSITION]
 		if !ok {
 			continue
@@ -200,55 +235,135 @@ func (l *GLTFLoader) processMeshWithTransform(doc *gltf.Document, m *gltf.Mesh,
 				return fmt.Errorf("read uvs: %w", err)
 			}
 		}
+		var colors []float64
+		colorComps := 0
+		if colorIdx, ok := prim.Attributes[gltf.COLOR_0]; ok {
+			colors, colorComps, err = readAccessorFloats(doc, colorIdx)
+			if err != nil {
+				return fmt.Errorf("read colors: %w", err)
+			}
+			mesh.VertexColors = colorComps >= 3
+		}
 		materialIdx := -1
 		if prim.Material != nil {
 			materialIdx = *prim.Material
 		}
+		uvTransform := materialUVTransform(doc, materialIdx)
 		baseVertex := len(mesh.Vertices)
 		for i := range positions {
 			worldPos := transform.MulVec3(positions[i])
 			v := MeshVertex{
 				Position: worldPos,
+				Color:    color.RGBA{255, 255, 255, 255}, // for primitives without COLOR_0
+			}
+			if colorComps >= 3 && (i+1)*colorComps <= len(colors) {
+				v.Color = gltfVertexColor(colors[i*colorComps : (i+1)*colorComps])
 			}
 			if i < len(normals) {
 				v.Normal = transform.MulVec3Dir(normals[i]).Normalize()
 			}
 			if i < len(uvs) {
-				v.UV = math3d.V2(uvs[i].X, 1.0-uvs[i].Y)
+				uv := uvs[i]
+				if uvTransform != nil {
+					uv = uvTransform.Apply(uv)
+				}
+				v.UV = math3d.V2(uv.X, 1.0-uv.Y)
 			}
 			mesh.Vertices = append(mesh.Vertices, v)
+			if rig != nil {
+				v.Position = positions[i]
+				if i < len(normals) {
+					v.Normal = normals[i]
+				}
+				rig.Bind = append(rig.Bind, v)
+			}
+		}
+		if rig != nil {
+			if err := addRigPart(doc, prim, rig, part, morphTargetNames(m), baseVertex, len(positions)); err != nil {
+				return err
+			}
+		}
+		indices := make([]int, len(positions))
+		for i := range indices {
+			indices[i] = i
 		}
 		if prim.Indices != nil {
-			indices, err := readIndices(doc, *prim.Indices)
+			indices, err = readIndices(doc, *prim.Indices)
 			if err != nil {
 				return fmt.Errorf("read indices: %w", err)
 			}
-			for i := 0; i+2 < len(indices); i += 3 {
-				mesh.Faces = append(mesh.Faces, Face{
-					V: [3]int{
-						baseVertex + indices[i],
-						baseVertex + indices[i+2],
-						baseVertex + indices[i+1],
-					},
-					Material: materialIdx,
-				})
-			}
-		} else {
-			for i := 0; i+2 < len(positions); i += 3 {
-				mesh.Faces = append(mesh.Faces, Face{
-					V: [3]int{
-						baseVertex + i,
-						baseVertex + i + 2,
-						baseVertex + i + 1,
-					},
-					Material: materialIdx,
-				})
+		}
+		for _, idx := range indices {
+			if idx < 0 || idx >= len(positions) {
+				return fmt.Errorf("index %d out of range (%d vertices)", idx, len(positions))
 			}
 		}
+		appendPrimitives(mesh, prim.Mode, indices, baseVertex, materialIdx)
 	}
 	return nil
 }
 
+// gltfVertexColor converts a COLOR_0 RGB or RGBA element.
+func gltfVertexColor(c []float64) color.RGBA {
+	ch := func(v float64) uint8 {
+		return uint8(math.Round(255 * math.Max(0, math.Min(1, v))))
+	}
+	out := color.RGBA{ch(c[0]), ch(c[1]), ch(c[2]), 255}
+	if len(c) > 3 {
+		out.A = ch(c[3])
+	}
+	return out
+}
+
+// appendPrimitives adds the faces, lines or points described by indices in
+// the given GLTF primitive mode. Strips and fans are converted to triangles
+// following the GLTF (counter-clockwise) ordering, then swapped to the
+// engine's clockwise winding like plain triangles.
+func appendPrimitives(mesh *Mesh, mode gltf.PrimitiveMode, indices []int, base, material int) {
+	addFace := func(a, b, c int) {
+		if a == b || b == c || a == c {
+			return // degenerate, used to stitch strips
+		}
+		mesh.Faces = append(mesh.Faces, Face{V: [3]int{base + a, base + c, base + b}, Material: material})
+	}
+	addLine := func(a, b int) {
+		mesh.Lines = append(mesh.Lines, Line{V: [2]int{base + a, base + b}, Material: material})
+	}
+	switch mode {
+	case gltf.PrimitiveTriangles:
+		for i := 0; i+2 < len(indices); i += 3 {
+			addFace(indices[i], indices[i+1], indices[i+2])
+		}
+	case gltf.PrimitiveTriangleStrip:
+		for i := 0; i+2 < len(indices); i++ {
+			if i%2 == 0 {
+				addFace(indices[i], indices[i+1], indices[i+2])
+			} else {
+				addFace(indices[i], indices[i+2], indices[i+1])
+			}
+		}
+	case gltf.PrimitiveTriangleFan:
+		for i := 1; i+1 < len(indices); i++ {
+			addFace(indices[0], indices[i], indices[i+1])
+		}
+	case gltf.PrimitiveLines:
+		for i := 0; i+1 < len(indices); i += 2 {
+			addLine(indices[i], indices[i+1])
+		}
+	case gltf.PrimitiveLineStrip, gltf.PrimitiveLineLoop:
+		for i := 0; i+1 < len(indices); i++ {
+			addLine(indices[i], indices[i+1])
+		}
+		if mode == gltf.PrimitiveLineLoop && len(indices) > 2 {
+			addLine(indices[len(indices)-1], indices[0])
+		}
+	case gltf.PrimitivePoints:
+		for _, i := range indices {
+			mesh.Points = append(mesh.Points, Point{V: base + i, Material: material})
+		}
+	}
+}
+
 // extractMaterials extracts all materials from a GLTF document.
 func extractMaterialsFromFS(doc *gltf.Document, resourceFS fs.FS) []Material {
 	materials := make([]Material, len(doc.Materials))
@@ -279,27 +394,25 @@ func extractMaterialsFromFS(doc *gltf.Document, resourceFS fs.FS) []Material {
 			}
 			// Extract base color texture if present
 			if pbr.BaseColorTexture != nil {
-				texIdx := pbr.BaseColorTexture.Index
-				if texIdx < len(doc.Textures) {
-					tex := doc.Textures[texIdx]
-					if tex.Source != nil && *tex.Source < len(doc.Images) {
-						img := doc.Images[*tex.Source]
-						texImg := loadGLTFImageFromFS(doc, img, resourceFS)
-						if texImg != nil {
-							m.BaseMap = texImg
-							m.HasTexture = true
-						}
-					}
+				if texImg := textureImage(doc, pbr.BaseColorTexture.Index, resourceFS); texImg != nil {
+					m.BaseMap = texImg
+					m.HasTexture = true
 				}
 			}
 		}
+		_, m.Unlit = mat.Extensions[extUnlit]
+		m.Emissive = materialEmissive(mat)
+		if mat.EmissiveTexture != nil {
+			m.EmissiveMap = textureImage(doc, mat.EmissiveTexture.Index, resourceFS)
+		}
+		m.UVTransform = materialUVTransform(doc, i)
 		materials[i] = m
 	}
 	return materials
 }
 
-// loadGLTFImage loads an image from GLTF (embedded or external).
-// loadGLTFImageFromFS loads an image from GLTF using the provided filesystem.
+// loadGLTFImageFromFS loads an image from GLTF (embedded or external) using
+// the provided filesystem, as an EncodedImage.
 func loadGLTFImageFromFS(doc *gltf.Document, img *gltf.Image, resourceFS fs.FS) image.Image {
 	if img.BufferView != nil {
 		// Embedded image
@@ -308,7 +421,7 @@ func loadGLTFImageFromFS(doc *gltf.Document, img *gltf.Image, resourceFS fs.FS)
 		if buf.Data != nil {
 			start := bv.ByteOffset
 			end := start + bv.ByteLength
-			decoded, _, err := image.Decode(bytes.NewReader(buf.Data[start:end]))
+			decoded, err := decodeImage(buf.Data[start:end])
 			if err == nil {
 				return decoded
 			}
@@ -316,7 +429,7 @@ func loadGLTFImageFromFS(doc *gltf.Document, img *gltf.Image, resourceFS fs.FS)
 	} else if img.URI != "" && resourceFS != nil {
 		data, err := fs.ReadFile(resourceFS, img.URI)
 		if err == nil {
-			decoded, _, err := image.Decode(bytes.NewReader(data))
+			decoded, err := decodeImage(data)
 			if err == nil {
 				return decoded
 			}
@@ -326,11 +439,10 @@ func loadGLTFImageFromFS(doc *gltf.Document, img *gltf.Image, resourceFS fs.FS)
 }
 
 func openGLTFDocumentFromFS(fsys fs.FS, path string) (*gltf.Document, fs.FS, error) {
-	file, err := fsys.Open(path)
+	data, err := fs.ReadFile(fsys, path)
 	if err != nil {
 		return nil, nil, err
 	}
-	defer file.Close()
 	resourceFS := fsys
 	subDir := filepath.Dir(path)
 	if subDir != "." && subDir != "/" {
@@ -341,11 +453,16 @@ func openGLTFDocumentFromFS(fsys fs.FS, path string) (*gltf.Document, fs.FS, err
 			return nil, nil, err
 		}
 	}
-	dec := gltf.NewDecoderFS(file, resourceFS)
-	doc := new(gltf.Document)
-	if err := dec.Decode(doc); err != nil {
+	doc, err := decodeGLTF(data, resourceFS)
+	if err != nil {
 		return nil, nil, err
 	}
+	if err := decompressMeshopt(doc); err != nil {
+		return nil, nil, fmt.Errorf("meshopt: %w", err)
+	}
+	if err := decompressDraco(doc); err != nil {
+		return nil, nil, fmt.Errorf("draco: %w", err)
+	}
 	return doc, resourceFS, nil
 }
 
@@ -355,182 +472,6 @@ func fsForPath(path string) (fs.FS, string) {
 	return os.DirFS(dir), base
 }
 
-// readVec3Accessor reads Vec3 data from a GLTF accessor.
-func readVec3Accessor(doc *gltf.Document, accessorIdx int) ([]math3d.Vec3, error) {
-	accessor := doc.Accessors[accessorIdx]
-	if accessor.Type != gltf.AccessorVec3 {
-		return nil, fmt.Errorf("expected VEC3, got %v", accessor.Type)
-	}
-	data, err := readAccessorData(doc, accessor)
-	if err != nil {
-		return nil, err
-	}
-	floats, ok := data.([][3]float32)
-	if !ok {
-		return nil, errors.New("unexpected data type for VEC3")
-	}
-	result := make([]math3d.Vec3, len(floats))
-	for i, f := range floats {
-		result[i] = math3d.V3(float64(f[0]), float64(f[1]), float64(f[2]))
-	}
-	return result, nil
-}
-
-// readVec2Accessor reads Vec2 data from a GLTF accessor.
-func readVec2Accessor(doc *gltf.Document, accessorIdx int) ([]math3d.Vec2, error) {
-	accessor := doc.Accessors[accessorIdx]
-	if accessor.Type != gltf.AccessorVec2 {
-		return nil, fmt.Errorf("expected VEC2, got %v", accessor.Type)
-	}
-	data, err := readAccessorData(doc, accessor)
-	if err != nil {
-		return nil, err
-	}
-	floats, ok := data.([][2]float32)
-	if !ok {
-		return nil, errors.New("unexpected data type for VEC2")
-	}
-	result := make([]math3d.Vec2, len(floats))
-	for i, f := range floats {
-		result[i] = math3d.V2(float64(f[0]), float64(f[1]))
-	}
-	return result, nil
-}
-
-// readIndices reads index data from a GLTF accessor.
-func readIndices(doc *gltf.Document, accessorIdx int) ([]int, error) {
-	accessor := doc.Accessors[accessorIdx]
-	data, err := readAccessorData(doc, accessor)
-	if err != nil {
-		return nil, err
-	}
-	switch v := data.(type) {
-	case []uint8:
-		result := make([]int, len(v))
-		for i, x := range v {
-			result[i] = int(x)
-		}
-		return result, nil
-	case []uint16:
-		result := make([]int, len(v))
-		for i, x := range v {
-			result[i] = int(x)
-		}
-		return result, nil
-	case []uint32:
-		result := make([]int, len(v))
-		for i, x := range v {
-			result[i] = int(x)
-		}
-		return result, nil
-	default:
-		return nil, fmt.Errorf("unexpected index type: %T", data)
-	}
-}
-
-// readAccessorData reads raw data from a GLTF accessor.
-func readAccessorData(doc *gltf.Document, accessor *gltf.Accessor) (any, error) {
-	if accessor.BufferView == nil {
-		return nil, errors.New("accessor has no buffer view")
-	}
-	bufferView := doc.BufferViews[*accessor.BufferView]
-	buffer := doc.Buffers[bufferView.Buffer]
-	// Get buffer data
-	var bufData []byte
-	if buffer.URI == "" {
-		// Embedded data (GLB)
-		bufData = buffer.Data
-	} else {
-		// External file - need to load relative to document
-		return nil, errors.New("external buffers not supported yet")
-	}
-	if bufData == nil {
-		return nil, errors.New("buffer has no data")
-	}
-	// Calculate data bounds
-	start := bufferView.ByteOffset + accessor.ByteOffset
-	stride := bufferView.ByteStride
-	count := accessor.Count
-	// Read based on component type and accessor type
-	//nolint:exhaustive // handles common types, error returned for unsupported ones
-	switch accessor.Type {
-	case gltf.AccessorVec3:
-		if stride == 0 {
-			stride = 12 // 3 floats * 4 bytes
-		}
-		result := make([][3]float32, count)
-		for i := range count {
-			offset := start + i*stride
-			for j := range 3 {
-				result[i][j] = readFloat32(bufData[offset+j*4:])
-			}
-		}
-		return result, nil
-	case gltf.AccessorVec2:
-		if stride == 0 {
-			stride = 8 // 2 floats * 4 bytes
-		}
-		result := make([][2]float32, count)
-		for i := range count {
-			offset := start + i*stride
-			for j := range 2 {
-				result[i][j] = readFloat32(bufData[offset+j*4:])
-			}
-		}
-		return result, nil
-	case gltf.AccessorScalar:
-		if stride == 0 {
-			//nolint:exhaustive // handles common types
-			switch accessor.ComponentType {
-			case gltf.ComponentUbyte:
-				stride = 1
-			case gltf.ComponentUshort:
-				stride = 2
-			case gltf.ComponentUint:
-				stride = 4
-			}
-		}
-		//nolint:exhaustive // handles common types, error returned for unsupported ones
-		switch accessor.ComponentType {
-		case gltf.ComponentUbyte:
-			result := make([]uint8, count)
-			for i := range count {
-				result[i] = bufData[start+i*stride]
-			}
-			return result, nil
-		case gltf.ComponentUshort:
-			result := make([]uint16, count)
-			for i := range count {
-				offset := start + i*stride
-				result[i] = uint16(bufData[offset]) | uint16(bufData[offset+1])<<8
-			}
-			return result, nil
-		case gltf.ComponentUint:
-			result := make([]uint32, count)
-			for i := range count {
-				offset := start + i*stride
-				result[i] = uint32(bufData[offset]) |
-					uint32(bufData[offset+1])<<8 |
-					uint32(bufData[offset+2])<<16 |
-					uint32(bufData[offset+3])<<24
-			}
-			return result, nil
-		}
-	}
-	return nil, fmt.Errorf("unsupported accessor type: %v / %v", accessor.Type, accessor.ComponentType)
-}
-
-// readFloat32 reads a little-endian float32.
-func readFloat32(b []byte) float32 {
-	bits := uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
-	return float32frombits(bits)
-}
-
-// float32frombits converts bits to float32.
-func float32frombits(b uint32) float32 {
-	return *(*float32)(unsafe.Pointer(&b))
-}
-
 // LoadGLTFWithTextures loads a GLTF file and extracts embedded textures.
 // Returns the mesh and a map of image index to texture data.
 func LoadGLTFWithTextures(path string) (*Mesh, map[int][]byte, error) {
@@ -550,7 +491,11 @@ func LoadGLTFWithTexturesFromFS(fsys fs.FS, path string) (*Mesh, map[int][]byte,
 	if err != nil {
 		return nil, nil, err
 	}
-	// Extract textures
+	return mesh, documentTextures(doc, resourceFS), nil
+}
+
+// documentTextures returns the encoded data of the document images, by image index.
+func documentTextures(doc *gltf.Document, resourceFS fs.FS) map[int][]byte {
 	textures := make(map[int][]byte)
 	for i, img := range doc.Images {
 		if img.BufferView != nil {
@@ -568,7 +513,7 @@ func LoadGLTFWithTexturesFromFS(fsys fs.FS, path string) (*Mesh, map[int][]byte,
 			}
 		}
 	}
-	return mesh, textures, nil
+	return textures
 }
 
 // LoadGLBWithTexture loads a GLB file and returns the mesh plus the first embedded texture.
@@ -585,16 +530,19 @@ func LoadGLBWithTextureFromFS(fsys fs.FS, path string) (*Mesh, image.Image, erro
 	if err != nil {
 		return nil, nil, err
 	}
-	// Find the first texture
-	var textureImg image.Image
-	for _, data := range textures {
-		if len(data) > 0 {
-			img, _, err := image.Decode(bytes.NewReader(data))
-			if err == nil {
-				textureImg = img
-				break
-			}
+	return mesh, firstTextureImage(textures), nil
+}
+
+// firstTextureImage decodes the first texture that is a valid image, or returns nil.
+func firstTextureImage(textures map[int][]byte) image.Image {
+	for _, i := range slices.Sorted(maps.Keys(textures)) {
+		data := textures[i]
+		if len(data) == 0 {
+			continue
+		}
+		if img, _, err := image.Decode(bytes.NewReader(data)); err == nil {
+			return img
 		}
 	}
-	return mesh, textureImg, nil
+	return nil
 }
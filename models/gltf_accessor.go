@@ -0,0 +1,270 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ansipixels/trophy/math3d"
+	"github.com/qmuntal/gltf"
+)
+
+// Accessors are decoded generically: any component type (normalized or
+// not), interleaved buffer views and sparse substitution. This also covers
+// KHR_mesh_quantization, which only widens the component types allowed for
+// vertex attributes; the dequantization transform lives in the node hierarchy
+// and, for texture coordinates, in the KHR_texture_transform of the
+// materials (applied to UVs in processMeshWithTransform).
+
+// readVec3Accessor reads Vec3 data from a GLTF accessor.
+func readVec3Accessor(doc *gltf.Document, accessorIdx int) ([]math3d.Vec3, error) {
+	values, comps, err := readAccessorFloats(doc, accessorIdx)
+	if err != nil {
+		return nil, err
+	}
+	if comps != 3 {
+		return nil, fmt.Errorf("expected VEC3, got %v", doc.Accessors[accessorIdx].Type)
+	}
+	result := make([]math3d.Vec3, len(values)/3)
+	for i := range result {
+		result[i] = math3d.V3(values[i*3], values[i*3+1], values[i*3+2])
+	}
+	return result, nil
+}
+
+// readVec2Accessor reads Vec2 data from a GLTF accessor.
+func readVec2Accessor(doc *gltf.Document, accessorIdx int) ([]math3d.Vec2, error) {
+	values, comps, err := readAccessorFloats(doc, accessorIdx)
+	if err != nil {
+		return nil, err
+	}
+	if comps != 2 {
+		return nil, fmt.Errorf("expected VEC2, got %v", doc.Accessors[accessorIdx].Type)
+	}
+	result := make([]math3d.Vec2, len(values)/2)
+	for i := range result {
+		result[i] = math3d.V2(values[i*2], values[i*2+1])
+	}
+	return result, nil
+}
+
+// readIndices reads index data from a GLTF accessor.
+func readIndices(doc *gltf.Document, accessorIdx int) ([]int, error) {
+	if accessorIdx < 0 || accessorIdx >= len(doc.Accessors) {
+		return nil, fmt.Errorf("accessor %d out of range", accessorIdx)
+	}
+	accessor := doc.Accessors[accessorIdx]
+	//nolint:exhaustive // only unsigned integers are valid indices
+	switch accessor.ComponentType {
+	case gltf.ComponentUbyte, gltf.ComponentUshort, gltf.ComponentUint:
+	default:
+		return nil, fmt.Errorf("unexpected index type: %v", accessor.ComponentType)
+	}
+	if accessor.Type != gltf.AccessorScalar || accessor.Normalized {
+		return nil, fmt.Errorf("unexpected index accessor: %v", accessor.Type)
+	}
+	values, _, err := readAccessorFloats(doc, accessorIdx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]int, len(values))
+	for i, v := range values {
+		result[i] = int(v)
+	}
+	return result, nil
+}
+
+// readAccessorFloats reads an accessor of any type as a flat slice of values,
+// returning the number of components per element. Normalized integer
+// components are mapped to [0, 1] or [-1, 1].
+func readAccessorFloats(doc *gltf.Document, accessorIdx int) ([]float64, int, error) {
+	if accessorIdx < 0 || accessorIdx >= len(doc.Accessors) {
+		return nil, 0, fmt.Errorf("accessor %d out of range", accessorIdx)
+	}
+	accessor := doc.Accessors[accessorIdx]
+	comps := accessor.Type.Components()
+	if comps == 0 || accessor.ComponentType.ByteSize() == 0 {
+		return nil, 0, fmt.Errorf("unsupported accessor type: %v / %v", accessor.Type, accessor.ComponentType)
+	}
+	if err := checkAccessorCount(doc, accessor); err != nil {
+		return nil, 0, err
+	}
+	// No buffer view means all zeros (used by morph targets and sparse accessors)
+	result := make([]float64, accessor.Count*comps)
+	if accessor.BufferView != nil {
+		err := readElements(doc, *accessor.BufferView, accessor.ByteOffset,
+			accessor.Type, accessor.ComponentType, accessor.Normalized, result)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	if accessor.Sparse != nil {
+		if err := applySparse(doc, accessor, result); err != nil {
+			return nil, 0, fmt.Errorf("sparse: %w", err)
+		}
+	}
+	return result, comps, nil
+}
+
+// checkAccessorCount rejects element counts the file data cannot back, before
+// they are allocated. Accessors without a buffer view are bounded by the size
+// of all buffers, since they mirror other accessors.
+func checkAccessorCount(doc *gltf.Document, accessor *gltf.Accessor) error {
+	elemSize := accessor.Type.Components() * accessor.ComponentType.ByteSize()
+	available := 0
+	if accessor.BufferView != nil {
+		data, _, err := bufferViewData(doc, *accessor.BufferView)
+		if err != nil {
+			return err
+		}
+		available = len(data) / elemSize
+	} else {
+		for _, b := range doc.Buffers {
+			available += len(b.Data)
+		}
+	}
+	if accessor.Count < 0 || accessor.Count > available {
+		return fmt.Errorf("accessor count %d exceeds the available data", accessor.Count)
+	}
+	return nil
+}
+
+// applySparse substitutes the sparse values of an accessor into result.
+func applySparse(doc *gltf.Document, accessor *gltf.Accessor, result []float64) error {
+	sparse := accessor.Sparse
+	comps := accessor.Type.Components()
+	//nolint:exhaustive // only unsigned integers are valid indices
+	switch sparse.Indices.ComponentType {
+	case gltf.ComponentUbyte, gltf.ComponentUshort, gltf.ComponentUint:
+	default:
+		return fmt.Errorf("unexpected index type: %v", sparse.Indices.ComponentType)
+	}
+	if sparse.Count < 0 || sparse.Count > accessor.Count {
+		return fmt.Errorf("sparse count %d exceeds accessor count %d", sparse.Count, accessor.Count)
+	}
+	indices := make([]float64, sparse.Count)
+	err := readElements(doc, sparse.Indices.BufferView, sparse.Indices.ByteOffset,
+		gltf.AccessorScalar, sparse.Indices.ComponentType, false, indices)
+	if err != nil {
+		return fmt.Errorf("indices: %w", err)
+	}
+	values := make([]float64, sparse.Count*comps)
+	err = readElements(doc, sparse.Values.BufferView, sparse.Values.ByteOffset,
+		accessor.Type, accessor.ComponentType, accessor.Normalized, values)
+	if err != nil {
+		return fmt.Errorf("values: %w", err)
+	}
+	for i, idx := range indices {
+		e := int(idx)
+		if e >= accessor.Count {
+			return fmt.Errorf("index %d out of range (%d elements)", e, accessor.Count)
+		}
+		copy(result[e*comps:(e+1)*comps], values[i*comps:(i+1)*comps])
+	}
+	return nil
+}
+
+// readElements decodes len(out)/components elements from a buffer view,
+// starting at offset and honoring the view's byte stride.
+func readElements(
+	doc *gltf.Document,
+	viewIdx, offset int,
+	typ gltf.AccessorType,
+	ct gltf.ComponentType,
+	normalized bool,
+	out []float64,
+) error {
+	data, stride, err := bufferViewData(doc, viewIdx)
+	if err != nil {
+		return err
+	}
+	comps := typ.Components()
+	size := ct.ByteSize()
+	rows, cols := comps, 1
+	colSize := comps * size
+	if typ == gltf.AccessorMat2 || typ == gltf.AccessorMat3 || typ == gltf.AccessorMat4 {
+		// Matrix columns start on 4-byte boundaries
+		cols = map[gltf.AccessorType]int{gltf.AccessorMat2: 2, gltf.AccessorMat3: 3, gltf.AccessorMat4: 4}[typ]
+		rows = cols
+		colSize = (rows*size + 3) &^ 3
+	}
+	elemSize := cols * colSize
+	if stride == 0 {
+		stride = elemSize
+	}
+	count := len(out) / comps
+	if offset < 0 || count > 0 && offset+(count-1)*stride+elemSize > len(data) {
+		return errors.New("accessor exceeds buffer view")
+	}
+	for i := range count {
+		base := offset + i*stride
+		for c := range cols {
+			for r := range rows {
+				out[i*comps+c*rows+r] = readComponent(data[base+c*colSize+r*size:], ct, normalized)
+			}
+		}
+	}
+	return nil
+}
+
+// bufferViewData returns the bytes of a buffer view and its byte stride (0
+// when tightly packed).
+func bufferViewData(doc *gltf.Document, viewIdx int) ([]byte, int, error) {
+	if viewIdx < 0 || viewIdx >= len(doc.BufferViews) {
+		return nil, 0, fmt.Errorf("buffer view %d out of range", viewIdx)
+	}
+	view := doc.BufferViews[viewIdx]
+	if view.Buffer < 0 || view.Buffer >= len(doc.Buffers) {
+		return nil, 0, fmt.Errorf("buffer %d out of range", view.Buffer)
+	}
+	// Embedded (GLB) and external buffers are loaded by the decoder
+	data := doc.Buffers[view.Buffer].Data
+	if data == nil {
+		return nil, 0, errors.New("buffer has no data")
+	}
+	end := view.ByteOffset + view.ByteLength
+	if view.ByteOffset < 0 || end > len(data) || end < view.ByteOffset {
+		return nil, 0, errors.New("buffer view exceeds buffer")
+	}
+	return data[view.ByteOffset:end], view.ByteStride, nil
+}
+
+// readComponent decodes a single little-endian accessor component.
+func readComponent(b []byte, ct gltf.ComponentType, normalized bool) float64 {
+	switch ct {
+	case gltf.ComponentFloat:
+		return float64(readFloat32(b))
+	case gltf.ComponentByte:
+		v := float64(int8(b[0]))
+		if normalized {
+			v = max(v/127, -1)
+		}
+		return v
+	case gltf.ComponentUbyte:
+		v := float64(b[0])
+		if normalized {
+			v /= 255
+		}
+		return v
+	case gltf.ComponentShort:
+		v := float64(int16(uint16(b[0]) | uint16(b[1])<<8))
+		if normalized {
+			v = max(v/32767, -1)
+		}
+		return v
+	case gltf.ComponentUshort:
+		v := float64(uint16(b[0]) | uint16(b[1])<<8)
+		if normalized {
+			v /= 65535
+		}
+		return v
+	case gltf.ComponentUint:
+		return float64(uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24)
+	}
+	return 0
+}
+
+// readFloat32 reads a little-endian float32.
+func readFloat32(b []byte) float32 {
+	return math.Float32frombits(uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24)
+}
@@ -0,0 +1,229 @@
+package models
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/ansipixels/trophy/math3d"
+	"github.com/qmuntal/gltf"
+)
+
+// glbBuilder assembles a single binary buffer with 4-byte aligned views.
+type glbBuilder struct {
+	doc *gltf.Document
+	buf []byte
+}
+
+func newGLBBuilder() *glbBuilder {
+	return &glbBuilder{doc: gltf.NewDocument()}
+}
+
+// view appends data as a buffer view and returns its index.
+func (b *glbBuilder) view(data []byte, stride int) int {
+	for len(b.buf)%4 != 0 {
+		b.buf = append(b.buf, 0)
+	}
+	b.doc.BufferViews = append(b.doc.BufferViews, &gltf.BufferView{
+		ByteOffset: len(b.buf),
+		ByteLength: len(data),
+		ByteStride: stride,
+	})
+	b.buf = append(b.buf, data...)
+	return len(b.doc.BufferViews) - 1
+}
+
+// accessor appends an accessor and returns its index.
+func (b *glbBuilder) accessor(a *gltf.Accessor) int {
+	b.doc.Accessors = append(b.doc.Accessors, a)
+	return len(b.doc.Accessors) - 1
+}
+
+// finish stores the buffer in the document.
+func (b *glbBuilder) finish() *gltf.Document {
+	b.doc.Buffers = []*gltf.Buffer{{ByteLength: len(b.buf), Data: b.buf}}
+	return b.doc
+}
+
+func le16(v ...int) []byte {
+	out := make([]byte, 0, 2*len(v))
+	for _, x := range v {
+		out = binary.LittleEndian.AppendUint16(out, uint16(x)) //nolint:gosec // test data
+	}
+	return out
+}
+
+func TestReadAccessorComponentTypes(t *testing.T) {
+	tests := []struct {
+		name       string
+		ct         gltf.ComponentType
+		typ        gltf.AccessorType
+		normalized bool
+		data       []byte
+		want       []float64
+	}{
+		{"byte", gltf.ComponentByte, gltf.AccessorScalar, false, []byte{0xfe, 5}, []float64{-2, 5}},
+		{"byte normalized", gltf.ComponentByte, gltf.AccessorScalar, true, []byte{0x81, 0x80, 127}, []float64{-1, -1, 1}},
+		{"ubyte normalized", gltf.ComponentUbyte, gltf.AccessorScalar, true, []byte{0, 255}, []float64{0, 1}},
+		{"short", gltf.ComponentShort, gltf.AccessorScalar, false, le16(-300, 300), []float64{-300, 300}},
+		{"short normalized", gltf.ComponentShort, gltf.AccessorScalar, true, le16(-32767, 32767), []float64{-1, 1}},
+		{"ushort normalized", gltf.ComponentUshort, gltf.AccessorScalar, true, le16(0, 65535), []float64{0, 1}},
+		{"uint", gltf.ComponentUint, gltf.AccessorScalar, false, binary.LittleEndian.AppendUint32(nil, 70000), []float64{70000}},
+		{
+			"float", gltf.ComponentFloat, gltf.AccessorScalar, false,
+			binary.LittleEndian.AppendUint32(nil, math.Float32bits(1.5)), []float64{1.5},
+		},
+		// Matrix columns are padded to 4 bytes
+		{"mat2 ubyte", gltf.ComponentUbyte, gltf.AccessorMat2, false, []byte{1, 2, 0, 0, 3, 4, 0, 0}, []float64{1, 2, 3, 4}},
+		{
+			"mat3 short", gltf.ComponentShort, gltf.AccessorMat3, false,
+			le16(1, 2, 3, 0, 4, 5, 6, 0, 7, 8, 9, 0), []float64{1, 2, 3, 4, 5, 6, 7, 8, 9},
+		},
+	}
+	for _, tt := range tests {
+		b := newGLBBuilder()
+		count := len(tt.want) / tt.typ.Components()
+		idx := b.accessor(&gltf.Accessor{
+			BufferView:    gltf.Index(b.view(tt.data, 0)),
+			ComponentType: tt.ct,
+			Type:          tt.typ,
+			Normalized:    tt.normalized,
+			Count:         count,
+		})
+		got, comps, err := readAccessorFloats(b.finish(), idx)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if comps != tt.typ.Components() || len(got) != len(tt.want) {
+			t.Errorf("%s: got %v (%d components), want %v", tt.name, got, comps, tt.want)
+			continue
+		}
+		for i := range got {
+			if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestReadAccessorBounds(t *testing.T) {
+	b := newGLBBuilder()
+	idx := b.accessor(&gltf.Accessor{
+		BufferView:    gltf.Index(b.view(le16(1, 2, 3), 0)),
+		ComponentType: gltf.ComponentUshort,
+		Type:          gltf.AccessorVec2,
+		Count:         2,
+	})
+	if _, _, err := readAccessorFloats(b.finish(), idx); err == nil {
+		t.Error("expected an error for an accessor past the end of its view")
+	}
+	// Counts are checked against the data before allocating
+	huge := b.accessor(&gltf.Accessor{ComponentType: gltf.ComponentFloat, Type: gltf.AccessorVec3, Count: 1 << 40})
+	if _, _, err := readAccessorFloats(b.finish(), huge); err == nil {
+		t.Error("expected an error for a count larger than the file data")
+	}
+}
+
+// quantizedDoc builds a triangle as gltfpack would: interleaved SHORT
+// positions dequantized by the node scale, normalized BYTE normals and
+// normalized UNSIGNED_SHORT UVs, with a sparse override of the last position.
+func quantizedDoc() *gltf.Document {
+	b := newGLBBuilder()
+	var vertices []byte
+	for _, v := range [][5]int{{0, 0, 0, 0, 0}, {100, 0, 0, 65535, 0}, {0, 100, 0, 0, 65535}} {
+		vertices = append(vertices, le16(v[0], v[1], v[2], 0)...) // position + padding
+		vertices = append(vertices, 0, 0, 127, 0)                 // normal + padding
+		vertices = append(vertices, le16(v[3], v[4])...)          // uv
+	}
+	interleaved := b.view(vertices, 16)
+	pos := b.accessor(&gltf.Accessor{
+		BufferView:    gltf.Index(interleaved),
+		ComponentType: gltf.ComponentShort,
+		Type:          gltf.AccessorVec3,
+		Count:         3,
+		Sparse: &gltf.Sparse{
+			Count:   1,
+			Indices: gltf.SparseIndices{BufferView: b.view([]byte{2}, 0), ComponentType: gltf.ComponentUbyte},
+			Values:  gltf.SparseValues{BufferView: b.view(le16(0, 200, 0), 0)},
+		},
+	})
+	normal := b.accessor(&gltf.Accessor{
+		BufferView:    gltf.Index(interleaved),
+		ByteOffset:    8,
+		ComponentType: gltf.ComponentByte,
+		Normalized:    true,
+		Type:          gltf.AccessorVec3,
+		Count:         3,
+	})
+	uv := b.accessor(&gltf.Accessor{
+		BufferView:    gltf.Index(interleaved),
+		ByteOffset:    12,
+		ComponentType: gltf.ComponentUshort,
+		Normalized:    true,
+		Type:          gltf.AccessorVec2,
+		Count:         3,
+	})
+	indices := b.accessor(&gltf.Accessor{
+		BufferView:    gltf.Index(b.view([]byte{0, 1, 2}, 0)),
+		ComponentType: gltf.ComponentUbyte,
+		Type:          gltf.AccessorScalar,
+		Count:         3,
+	})
+	doc := b.finish()
+	doc.ExtensionsUsed = []string{"KHR_mesh_quantization"}
+	doc.ExtensionsRequired = []string{"KHR_mesh_quantization"}
+	doc.Meshes = []*gltf.Mesh{{Primitives: []*gltf.Primitive{{
+		Indices:    gltf.Index(indices),
+		Attributes: gltf.PrimitiveAttributes{gltf.POSITION: pos, gltf.NORMAL: normal, gltf.TEXCOORD_0: uv},
+	}}}}
+	doc.Nodes = []*gltf.Node{{Mesh: gltf.Index(0), Scale: [3]float64{0.01, 0.01, 0.01}}}
+	doc.Scenes = []*gltf.Scene{{Nodes: []int{0}}}
+	return doc
+}
+
+func TestLoadQuantizedGLB(t *testing.T) {
+	mesh, err := LoadGLBFromFS(encodeGLB(t, quantizedDoc()), "model.glb")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if mesh.VertexCount() != 3 || mesh.TriangleCount() != 1 {
+		t.Fatalf("vertices=%d triangles=%d, want 3 and 1", mesh.VertexCount(), mesh.TriangleCount())
+	}
+	assertVec3(t, "position 1", mesh.Vertices[1].Position, math3d.V3(1, 0, 0))
+	assertVec3(t, "sparse position 2", mesh.Vertices[2].Position, math3d.V3(0, 2, 0))
+	assertVec3(t, "normal", mesh.Vertices[0].Normal, math3d.V3(0, 0, 1))
+	// V is flipped on load
+	if uv := mesh.Vertices[1].UV; uv.X != 1 || uv.Y != 1 {
+		t.Errorf("uv 1 = %v, want (1, 1)", uv)
+	}
+	if uv := mesh.Vertices[2].UV; uv.X != 0 || uv.Y != 0 {
+		t.Errorf("uv 2 = %v, want (0, 0)", uv)
+	}
+}
+
+// TestLoadQuantizedUVTransform mirrors gltfpack's UV quantization: normalized
+// unsigned short UVs spanning the UV bounds, dequantized by a material level
+// KHR_texture_transform.
+func TestLoadQuantizedUVTransform(t *testing.T) {
+	doc := quantizedDoc()
+	doc.ExtensionsUsed = append(doc.ExtensionsUsed, extTextureTransform)
+	doc.Materials = []*gltf.Material{{PBRMetallicRoughness: &gltf.PBRMetallicRoughness{
+		BaseColorTexture: &gltf.TextureInfo{Index: 0, Extensions: gltf.Extensions{
+			extTextureTransform: map[string]any{"offset": []float64{0.25, 0.5}, "scale": []float64{2, 4}},
+		}},
+	}}}
+	doc.Meshes[0].Primitives[0].Material = gltf.Index(0)
+	mesh, err := LoadGLBFromFS(encodeGLB(t, doc), "model.glb")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	// Quantized (1, 0) and (0, 1) map to (2.25, 0.5) and (0.25, 4.5), then V is flipped
+	want := []math3d.Vec2{math3d.V2(0.25, 0.5), math3d.V2(2.25, 0.5), math3d.V2(0.25, -3.5)}
+	for i, w := range want {
+		if uv := mesh.Vertices[i].UV; uv.Sub(w).Len() > 1e-9 {
+			t.Errorf("uv %d = %v, want %v", i, uv, w)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/ansipixels/trophy/math3d"
+	"github.com/qmuntal/gltf"
+)
+
+// buildRig extracts the node hierarchy, morph weights, skins and animations of a GLTF document.
+func buildRig(doc *gltf.Document) (*Rig, error) {
+	rig := NewRig()
+	rig.Nodes = convertNodes(doc)
+	for _, s := range doc.Skins {
+		skin := Skin{Name: s.Name, Joints: s.Joints}
+		if s.InverseBindMatrices != nil {
+			values, comps, err := readAccessorFloats(doc, *s.InverseBindMatrices)
+			if err != nil {
+				return nil, fmt.Errorf("read inverse bind matrices: %w", err)
+			}
+			if comps != 16 {
+				return nil, fmt.Errorf("inverse bind matrices: expected MAT4, got %d components", comps)
+			}
+			for j := 0; j+16 <= len(values); j += 16 {
+				skin.InverseBind = append(skin.InverseBind, math3d.Mat4FromSlice(values[j:j+16]))
+			}
+		}
+		rig.Skins = append(rig.Skins, skin)
+	}
+	for i, a := range doc.Animations {
+		clip, err := readAnimation(doc, a)
+		if err != nil {
+			return nil, fmt.Errorf("animation %d: %w", i, err)
+		}
+		if clip.Name == "" {
+			clip.Name = fmt.Sprintf("clip %d", i+1)
+		}
+		rig.Clips = append(rig.Clips, clip)
+	}
+	return rig, nil
+}
+
+// convertNodes converts the GLTF nodes with their rest transforms and parents.
+func convertNodes(doc *gltf.Document) []Node {
+	nodes := make([]Node, len(doc.Nodes))
+	for i, n := range doc.Nodes {
+		node := Node{
+			Name:        n.Name,
+			Parent:      -1,
+			Children:    n.Children,
+			Translation: math3d.V3(n.Translation[0], n.Translation[1], n.Translation[2]),
+			Rotation:    math3d.Q(n.Rotation[0], n.Rotation[1], n.Rotation[2], n.Rotation[3]),
+			Scale:       math3d.V3(n.Scale[0], n.Scale[1], n.Scale[2]),
+		}
+		if n.Rotation == [4]float64{} {
+			node.Rotation = math3d.QuatIdentity()
+		}
+		if n.Scale == [3]float64{} {
+			node.Scale = math3d.V3(1, 1, 1)
+		}
+		if n.Matrix != gltf.DefaultMatrix && n.Matrix != [16]float64{} {
+			m := math3d.Mat4FromSlice(n.Matrix[:])
+			node.Matrix = &m
+		}
+		// Node weights override the default weights of the mesh
+		switch {
+		case len(n.Weights) > 0:
+			node.Weights = slices.Clone(n.Weights)
+		case n.Mesh != nil && *n.Mesh < len(doc.Meshes):
+			node.Weights = slices.Clone(doc.Meshes[*n.Mesh].Weights)
+		}
+		nodes[i] = node
+	}
+	for i, n := range doc.Nodes {
+		for _, c := range n.Children {
+			if c >= 0 && c < len(nodes) {
+				nodes[c].Parent = i
+			}
+		}
+	}
+	return nodes
+}
+
+// readAnimation converts a GLTF animation into a clip. Channels without a
+// target node or keyframes are skipped.
+func readAnimation(doc *gltf.Document, a *gltf.Animation) (AnimationClip, error) {
+	clip := AnimationClip{Name: a.Name}
+	for _, ch := range a.Channels {
+		if ch.Target.Node == nil || ch.Sampler < 0 || ch.Sampler >= len(a.Samplers) {
+			continue
+		}
+		var path AnimationPath
+		switch ch.Target.Path {
+		case gltf.TRSTranslation:
+			path = PathTranslation
+		case gltf.TRSRotation:
+			path = PathRotation
+		case gltf.TRSScale:
+			path = PathScale
+		case gltf.TRSWeights:
+			path = PathWeights
+		default:
+			continue
+		}
+		sampler := a.Samplers[ch.Sampler]
+		times, _, err := readAccessorFloats(doc, sampler.Input)
+		if err != nil {
+			return clip, fmt.Errorf("read keyframe times: %w", err)
+		}
+		values, _, err := readAccessorFloats(doc, sampler.Output)
+		if err != nil {
+			return clip, fmt.Errorf("read keyframe values: %w", err)
+		}
+		if len(times) == 0 {
+			continue
+		}
+		channel := AnimationChannel{
+			Node:   *ch.Target.Node,
+			Path:   path,
+			Times:  times,
+			Values: values,
+		}
+		switch sampler.Interpolation {
+		case gltf.InterpolationStep:
+			channel.Interpolation = InterpolationStep
+		case gltf.InterpolationCubicSpline:
+			channel.Interpolation = InterpolationCubicSpline
+		default:
+			channel.Interpolation = InterpolationLinear
+		}
+		clip.Duration = max(clip.Duration, times[len(times)-1])
+		clip.Channels = append(clip.Channels, channel)
+	}
+	return clip, nil
+}
+
+// needsRig returns true if the document has skins, animations or morph
+// targets, which need a posable Rig.
+func needsRig(doc *gltf.Document) bool {
+	return len(doc.Skins) > 0 || len(doc.Animations) > 0 || hasMorphTargets(doc)
+}
+
+// hasMorphTargets returns true if any primitive of the document has morph targets.
+func hasMorphTargets(doc *gltf.Document) bool {
+	for _, m := range doc.Meshes {
+		for _, prim := range m.Primitives {
+			if len(prim.Targets) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// morphTargetNames returns the target names of a mesh from the common
+// "targetNames" extras convention, or nil.
+func morphTargetNames(m *gltf.Mesh) []string {
+	extras, ok := m.Extras.(map[string]any)
+	if !ok {
+		return nil
+	}
+	list, ok := extras["targetNames"].([]any)
+	if !ok {
+		return nil
+	}
+	names := make([]string, len(list))
+	for i, v := range list {
+		names[i], _ = v.(string)
+	}
+	return names
+}
+
+// readMorphTargets reads the POSITION and NORMAL deltas of a primitive's targets.
+func readMorphTargets(doc *gltf.Document, prim *gltf.Primitive, names []string, count int) ([]MorphTarget, error) {
+	targets := make([]MorphTarget, len(prim.Targets))
+	for i, attrs := range prim.Targets {
+		t := &targets[i]
+		if i < len(names) {
+			t.Name = names[i]
+		}
+		if t.Name == "" {
+			t.Name = fmt.Sprintf("target %d", i+1)
+		}
+		if idx, ok := attrs[gltf.POSITION]; ok {
+			v, err := readVec3Floats(doc, idx, count)
+			if err != nil {
+				return nil, fmt.Errorf("morph target %d positions: %w", i, err)
+			}
+			t.Positions = v
+		}
+		if idx, ok := attrs[gltf.NORMAL]; ok {
+			v, err := readVec3Floats(doc, idx, count)
+			if err != nil {
+				return nil, fmt.Errorf("morph target %d normals: %w", i, err)
+			}
+			t.Normals = v
+		}
+	}
+	return targets, nil
+}
+
+// readVec3Floats reads count VEC3 elements of any component type.
+func readVec3Floats(doc *gltf.Document, accessorIdx, count int) ([]math3d.Vec3, error) {
+	values, comps, err := readAccessorFloats(doc, accessorIdx)
+	if err != nil {
+		return nil, err
+	}
+	if comps != 3 || len(values) < count*3 {
+		return nil, fmt.Errorf("expected %d VEC3 elements", count)
+	}
+	result := make([]math3d.Vec3, count)
+	for i := range result {
+		result[i] = math3d.V3(values[i*3], values[i*3+1], values[i*3+2])
+	}
+	return result, nil
+}
+
+// addRigPart records the vertex range of a primitive with its joints, weights
+// and morph targets.
+func addRigPart(doc *gltf.Document, prim *gltf.Primitive, rig *Rig, part RigPart, names []string, first, count int) error {
+	part.First = first
+	part.Count = count
+	if len(prim.Targets) > 0 {
+		targets, err := readMorphTargets(doc, prim, names, count)
+		if err != nil {
+			return err
+		}
+		part.Targets = targets
+	}
+	jointsIdx, hasJoints := prim.Attributes[gltf.JOINTS_0]
+	weightsIdx, hasWeights := prim.Attributes[gltf.WEIGHTS_0]
+	if part.Skin < 0 || part.Skin >= len(rig.Skins) || !hasJoints || !hasWeights {
+		part.Skin = -1
+		rig.Parts = append(rig.Parts, part)
+		return nil
+	}
+	joints, jc, err := readAccessorFloats(doc, jointsIdx)
+	if err != nil {
+		return fmt.Errorf("read joints: %w", err)
+	}
+	weights, wc, err := readAccessorFloats(doc, weightsIdx)
+	if err != nil {
+		return fmt.Errorf("read weights: %w", err)
+	}
+	if jc != 4 || wc != 4 || len(joints) < count*4 || len(weights) < count*4 {
+		return fmt.Errorf("joints/weights: expected %d VEC4 elements", count)
+	}
+	part.Joints = make([][4]int, count)
+	part.Weights = make([][4]float64, count)
+	for i := range count {
+		for j := range 4 {
+			part.Joints[i][j] = int(joints[i*4+j])
+			part.Weights[i][j] = weights[i*4+j]
+		}
+	}
+	rig.Parts = append(rig.Parts, part)
+	return nil
+}
@@ -0,0 +1,215 @@
+package models
+
+import (
+	"math"
+
+	"github.com/ansipixels/trophy/math3d"
+	"github.com/qmuntal/gltf"
+)
+
+// extLightsPunctual is the KHR_lights_punctual extension name.
+const extLightsPunctual = "KHR_lights_punctual"
+
+// Camera is an authored GLTF camera. It looks down its node's -Z axis with +Y up.
+type Camera struct {
+	Name         string
+	Orthographic bool
+	YFov         float64 // Vertical field of view in radians (perspective)
+	AspectRatio  float64 // Width / height, 0 to use the viewport's
+	XMag, YMag   float64 // Half width and height of the view (orthographic)
+	ZNear, ZFar  float64 // Clip planes, ZFar 0 meaning infinite
+}
+
+// LightType is the kind of a punctual light.
+type LightType int
+
+// Punctual light types.
+const (
+	LightDirectional LightType = iota
+	LightPoint
+	LightSpot
+)
+
+// Light is a KHR_lights_punctual light. Directional and spot lights shine
+// down their node's -Z axis.
+type Light struct {
+	Name      string
+	Type      LightType
+	Color     [3]float64 // Linear RGB
+	Intensity float64    // Lux for directional lights, candela otherwise
+	Range     float64    // Distance where the light fades out, 0 for infinite
+	// Spot cone angles in radians
+	InnerConeAngle float64
+	OuterConeAngle float64
+}
+
+// SceneCamera places a camera at a node.
+type SceneCamera struct {
+	Camera
+	Node int
+}
+
+// SceneLight places a light at a node.
+type SceneLight struct {
+	Light
+	Node int
+}
+
+// CameraView is a camera in scene space.
+type CameraView struct {
+	Camera
+	Position, Forward, Up math3d.Vec3
+}
+
+// LightView is a light in scene space.
+type LightView struct {
+	Light
+	Position, Direction math3d.Vec3
+}
+
+// lightsPunctualExt is the document level KHR_lights_punctual extension.
+type lightsPunctualExt struct {
+	Lights []struct {
+		Name      string      `json:"name"`
+		Type      string      `json:"type"`
+		Color     *[3]float64 `json:"color"`
+		Intensity *float64    `json:"intensity"`
+		Range     float64     `json:"range"`
+		Spot      *struct {
+			InnerConeAngle float64  `json:"innerConeAngle"`
+			OuterConeAngle *float64 `json:"outerConeAngle"`
+		} `json:"spot"`
+	} `json:"lights"`
+}
+
+// nodeLightExt is the node level KHR_lights_punctual extension.
+type nodeLightExt struct {
+	Light *int `json:"light"`
+}
+
+// convertCamera converts a GLTF camera.
+func convertCamera(c *gltf.Camera) Camera {
+	cam := Camera{Name: c.Name}
+	switch {
+	case c.Orthographic != nil:
+		o := c.Orthographic
+		cam.Orthographic = true
+		cam.XMag, cam.YMag = o.Xmag, o.Ymag
+		cam.ZNear, cam.ZFar = o.Znear, o.Zfar
+	case c.Perspective != nil:
+		p := c.Perspective
+		cam.YFov, cam.ZNear = p.Yfov, p.Znear
+		if p.AspectRatio != nil {
+			cam.AspectRatio = *p.AspectRatio
+		}
+		if p.Zfar != nil {
+			cam.ZFar = *p.Zfar
+		}
+	}
+	return cam
+}
+
+// documentLights returns the KHR_lights_punctual lights of a document.
+func documentLights(doc *gltf.Document) []Light {
+	var ext lightsPunctualExt
+	if found, err := decodeExtension(doc.Extensions, extLightsPunctual, &ext); !found || err != nil {
+		return nil
+	}
+	lights := make([]Light, len(ext.Lights))
+	for i, l := range ext.Lights {
+		light := Light{
+			Name:      l.Name,
+			Color:     [3]float64{1, 1, 1},
+			Intensity: 1,
+			Range:     l.Range,
+		}
+		if l.Color != nil {
+			light.Color = *l.Color
+		}
+		if l.Intensity != nil {
+			light.Intensity = *l.Intensity
+		}
+		switch l.Type {
+		case "point":
+			light.Type = LightPoint
+		case "spot":
+			light.Type = LightSpot
+			light.OuterConeAngle = math.Pi / 4
+			if l.Spot != nil {
+				light.InnerConeAngle = l.Spot.InnerConeAngle
+				if l.Spot.OuterConeAngle != nil {
+					light.OuterConeAngle = *l.Spot.OuterConeAngle
+				}
+			}
+		default:
+			light.Type = LightDirectional
+		}
+		lights[i] = light
+	}
+	return lights
+}
+
+// sceneCamerasAndLights places the document's cameras and lights at the
+// nodes referencing them.
+func sceneCamerasAndLights(doc *gltf.Document, scene *Scene) {
+	lights := documentLights(doc)
+	for i, n := range doc.Nodes {
+		if n.Camera != nil && *n.Camera >= 0 && *n.Camera < len(doc.Cameras) {
+			scene.Cameras = append(scene.Cameras, SceneCamera{Camera: convertCamera(doc.Cameras[*n.Camera]), Node: i})
+		}
+		var ext nodeLightExt
+		if found, err := decodeExtension(n.Extensions, extLightsPunctual, &ext); found && err == nil &&
+			ext.Light != nil && *ext.Light >= 0 && *ext.Light < len(lights) {
+			scene.Lights = append(scene.Lights, SceneLight{Light: lights[*ext.Light], Node: i})
+		}
+	}
+}
+
+// CameraViews returns the cameras in scene space, including Scene.Root. Clip
+// planes and orthographic magnifications are scaled with their node.
+func (s *Scene) CameraViews() []CameraView {
+	world := s.WorldTransforms()
+	views := make([]CameraView, 0, len(s.Cameras))
+	for _, c := range s.Cameras {
+		if c.Node < 0 || c.Node >= len(world) {
+			continue
+		}
+		m := world[c.Node]
+		up := m.MulVec3Dir(math3d.V3(0, 1, 0))
+		scale := up.Len()
+		view := CameraView{
+			Camera:   c.Camera,
+			Position: m.Translation(),
+			Forward:  m.MulVec3Dir(math3d.V3(0, 0, -1)).Normalize(),
+			Up:       up.Normalize(),
+		}
+		view.ZNear *= scale
+		view.ZFar *= scale
+		view.XMag *= scale
+		view.YMag *= scale
+		views = append(views, view)
+	}
+	return views
+}
+
+// LightViews returns the lights of the visible nodes in scene space,
+// including Scene.Root. Ranges are scaled with their node.
+func (s *Scene) LightViews() []LightView {
+	world := s.WorldTransforms()
+	var views []LightView
+	for _, l := range s.Lights {
+		if l.Node < 0 || l.Node >= len(world) || !s.Visible(l.Node) {
+			continue
+		}
+		m := world[l.Node]
+		dir := m.MulVec3Dir(math3d.V3(0, 0, -1))
+		view := LightView{
+			Light:     l.Light,
+			Position:  m.Translation(),
+			Direction: dir.Normalize(),
+		}
+		view.Range *= dir.Len()
+		views = append(views, view)
+	}
+	return views
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/qmuntal/gltf"
+)
+
+// GLB container constants.
+const (
+	glbMagic     = 0x46546C67 // "glTF"
+	glbChunkJSON = 0x4E4F534A // "JSON"
+	glbChunkBIN  = 0x004E4942 // "BIN\0"
+)
+
+// decodeGLTF decodes a GLTF or GLB document and loads its buffers from the
+// BIN chunk, data URIs or resourceFS. Unlike gltf.Decoder it accepts buffers
+// without a URI outside of the BIN chunk, such as the fallback buffers of
+// EXT_meshopt_compression; their Data is left nil.
+func decodeGLTF(data []byte, resourceFS fs.FS) (*gltf.Document, error) {
+	jsonData, bin, err := splitGLB(data)
+	if err != nil {
+		return nil, err
+	}
+	doc := new(gltf.Document)
+	if err := json.Unmarshal(jsonData, doc); err != nil {
+		return nil, fmt.Errorf("decode json: %w", err)
+	}
+	for i, b := range doc.Buffers {
+		switch {
+		case b.URI == "" && i == 0 && bin != nil:
+			if len(bin) < b.ByteLength {
+				return nil, errors.New("BIN chunk shorter than buffer 0")
+			}
+			b.Data = bin[:b.ByteLength]
+		case b.URI == "":
+			// No data (EXT_meshopt_compression fallback)
+		case strings.HasPrefix(b.URI, "data:"):
+			comma := strings.IndexByte(b.URI, ',')
+			if comma < 0 || !strings.HasSuffix(b.URI[:comma], ";base64") {
+				return nil, fmt.Errorf("buffer %d: unsupported data URI", i)
+			}
+			if b.Data, err = base64.StdEncoding.DecodeString(b.URI[comma+1:]); err != nil {
+				return nil, fmt.Errorf("buffer %d: %w", i, err)
+			}
+		default:
+			name, err := url.PathUnescape(strings.ReplaceAll(b.URI, "\\", "/"))
+			if err != nil || !fs.ValidPath(path.Clean(name)) {
+				return nil, fmt.Errorf("buffer %d: invalid uri %q", i, b.URI)
+			}
+			if b.Data, err = fs.ReadFile(resourceFS, path.Clean(name)); err != nil {
+				return nil, fmt.Errorf("buffer %d: %w", i, err)
+			}
+		}
+		if b.Data != nil && len(b.Data) < b.ByteLength {
+			return nil, fmt.Errorf("buffer %d: %d bytes, expected %d", i, len(b.Data), b.ByteLength)
+		}
+	}
+	return doc, nil
+}
+
+// splitGLB returns the JSON and BIN chunks of a GLB file. Other data is
+// returned as is, assumed to be GLTF JSON, with a nil BIN chunk.
+func splitGLB(data []byte) ([]byte, []byte, error) {
+	if len(data) < 12 || binary.LittleEndian.Uint32(data) != glbMagic {
+		return data, nil, nil
+	}
+	length := int(binary.LittleEndian.Uint32(data[8:]))
+	if length > len(data) {
+		return nil, nil, errors.New("truncated GLB")
+	}
+	var jsonData, bin []byte
+	for offset := 12; offset+8 <= length; {
+		size := int(binary.LittleEndian.Uint32(data[offset:]))
+		kind := binary.LittleEndian.Uint32(data[offset+4:])
+		start := offset + 8
+		if size < 0 || start+size > length {
+			return nil, nil, errors.New("truncated GLB chunk")
+		}
+		switch {
+		case kind == glbChunkJSON && jsonData == nil:
+			jsonData = data[start : start+size]
+		case kind == glbChunkBIN && bin == nil:
+			bin = data[start : start+size]
+		}
+		offset = start + (size+3)&^3
+	}
+	if jsonData == nil {
+		return nil, nil, errors.New("GLB has no JSON chunk")
+	}
+	return jsonData, bin, nil
+}
+
+// decodeExtension decodes the named extension object, which the GLTF decoder
+// leaves as raw JSON, into v. It returns false if the extension is absent.
+func decodeExtension(exts gltf.Extensions, name string, v any) (bool, error) {
+	raw, ok := exts[name]
+	if !ok {
+		return false, nil
+	}
+	data, ok := raw.(json.RawMessage)
+	if !ok {
+		var err error
+		if data, err = json.Marshal(raw); err != nil {
+			return true, err
+		}
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return true, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"image"
+	"io/fs"
+
+	"github.com/ansipixels/trophy/math3d"
+	"github.com/qmuntal/gltf"
+)
+
+// Material extensions.
+const (
+	extTextureTransform = "KHR_texture_transform"
+	extUnlit            = "KHR_materials_unlit"
+	extEmissiveStrength = "KHR_materials_emissive_strength"
+)
+
+// textureTransformExt is the KHR_texture_transform texture info extension.
+type textureTransformExt struct {
+	Offset   [2]float64  `json:"offset"`
+	Rotation float64     `json:"rotation"`
+	Scale    *[2]float64 `json:"scale"`
+}
+
+// emissiveStrengthExt is the KHR_materials_emissive_strength extension.
+type emissiveStrengthExt struct {
+	EmissiveStrength *float64 `json:"emissiveStrength"`
+}
+
+// textureInfoTransform returns the KHR_texture_transform of a texture
+// reference, nil if it has none or it is invalid.
+func textureInfoTransform(info *gltf.TextureInfo) *TextureTransform {
+	if info == nil {
+		return nil
+	}
+	var ext textureTransformExt
+	if found, err := decodeExtension(info.Extensions, extTextureTransform, &ext); !found || err != nil {
+		return nil
+	}
+	t := &TextureTransform{
+		Offset:   math3d.V2(ext.Offset[0], ext.Offset[1]),
+		Rotation: ext.Rotation,
+		Scale:    math3d.V2(1, 1),
+	}
+	if ext.Scale != nil {
+		t.Scale = math3d.V2(ext.Scale[0], ext.Scale[1])
+	}
+	return t
+}
+
+// materialUVTransform returns the UV transform of a material: the one of its
+// base color texture, or of its emissive texture when it has no base color
+// texture. The mesh has a single UV set, so other textures' transforms are ignored.
+func materialUVTransform(doc *gltf.Document, materialIdx int) *TextureTransform {
+	if materialIdx < 0 || materialIdx >= len(doc.Materials) {
+		return nil
+	}
+	mat := doc.Materials[materialIdx]
+	if pbr := mat.PBRMetallicRoughness; pbr != nil && pbr.BaseColorTexture != nil {
+		return textureInfoTransform(pbr.BaseColorTexture)
+	}
+	return textureInfoTransform(mat.EmissiveTexture)
+}
+
+// materialEmissive returns the emissive color of a material, scaled by its
+// KHR_materials_emissive_strength.
+func materialEmissive(mat *gltf.Material) [3]float64 {
+	strength := 1.0
+	var ext emissiveStrengthExt
+	if found, err := decodeExtension(mat.Extensions, extEmissiveStrength, &ext); found && err == nil &&
+		ext.EmissiveStrength != nil {
+		strength = *ext.EmissiveStrength
+	}
+	f := mat.EmissiveFactor
+	return [3]float64{f[0] * strength, f[1] * strength, f[2] * strength}
+}
+
+// textureImage loads the image of the texture at index, nil if it is missing.
+func textureImage(doc *gltf.Document, index int, resourceFS fs.FS) image.Image {
+	if index < 0 || index >= len(doc.Textures) {
+		return nil
+	}
+	tex := doc.Textures[index]
+	if tex.Source == nil || *tex.Source < 0 || *tex.Source >= len(doc.Images) {
+		return nil
+	}
+	return loadGLTFImageFromFS(doc, doc.Images[*tex.Source], resourceFS)
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"fmt"
+	"image"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/ansipixels/trophy/math3d"
+	"github.com/qmuntal/gltf"
+)
+
+// LoadGLTFScene loads a GLTF or GLB file keeping its node hierarchy.
+func LoadGLTFScene(path string) (*Scene, error) {
+	fsys, fsPath := fsForPath(path)
+	return LoadGLTFSceneFromFS(fsys, fsPath)
+}
+
+// LoadGLTFSceneFromFS loads a GLTF or GLB file from an fs.FS keeping its node hierarchy.
+func LoadGLTFSceneFromFS(fsys fs.FS, path string) (*Scene, error) {
+	loader := NewGLTFLoader()
+	return loader.LoadSceneFromFS(fsys, path)
+}
+
+// LoadSceneFromFS loads a GLTF or GLB file from an fs.FS and returns its scene graph.
+// Skins, morph targets and animations are not evaluated; use LoadFromFS and
+// Mesh.Pose for those.
+func (l *GLTFLoader) LoadSceneFromFS(fsys fs.FS, path string) (*Scene, error) {
+	doc, resourceFS, err := openGLTFDocumentFromFS(fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("open gltf: %w", err)
+	}
+	return l.sceneFromDocument(doc, path, resourceFS)
+}
+
+// LoadGLTFModelFromFS decodes a GLTF or GLB file from an fs.FS once and returns
+// either its scene graph (static files) or a posable flattened mesh (skinned,
+// morphed or animated files), plus the first embedded texture.
+// Exactly one of the returned mesh and scene is set.
+func LoadGLTFModelFromFS(fsys fs.FS, path string) (*Mesh, *Scene, image.Image, error) {
+	doc, resourceFS, err := openGLTFDocumentFromFS(fsys, path)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("open gltf: %w", err)
+	}
+	img := firstTextureImage(documentTextures(doc, resourceFS))
+	loader := NewGLTFLoader()
+	if needsRig(doc) {
+		mesh, err := loader.loadFromDocument(doc, path, resourceFS)
+		return mesh, nil, img, err
+	}
+	scene, err := loader.sceneFromDocument(doc, path, resourceFS)
+	return nil, scene, img, err
+}
+
+func (l *GLTFLoader) sceneFromDocument(doc *gltf.Document, path string, resourceFS fs.FS) (*Scene, error) {
+	scene := NewScene(filepath.Base(path))
+	scene.Unit = Meters
+	scene.Materials = extractMaterialsFromFS(doc, resourceFS)
+	// Each GLTF mesh is loaded once, in its own space
+	for i, m := range doc.Meshes {
+		name := m.Name
+		if name == "" {
+			name = fmt.Sprintf("mesh %d", i)
+		}
+		mesh := NewMesh(name)
+		mesh.Unit = Meters
+		mesh.Materials = scene.Materials
+		if err := l.processMeshWithTransform(doc, m, mesh, math3d.Identity(), nil, RigPart{}); err != nil {
+			return nil, fmt.Errorf("mesh %d: %w", i, err)
+		}
+		l.ensureNormals(mesh)
+		mesh.CalculateBounds()
+		scene.Meshes = append(scene.Meshes, mesh)
+	}
+	nodes := convertNodes(doc)
+	scene.Nodes = make([]SceneNode, len(nodes))
+	for i, n := range nodes {
+		if n.Name == "" {
+			n.Name = fmt.Sprintf("node %d", i)
+		}
+		scene.Nodes[i] = SceneNode{Node: n, Mesh: -1}
+		if doc.Nodes[i].Mesh != nil {
+			scene.Nodes[i].Mesh = *doc.Nodes[i].Mesh
+		}
+	}
+	scene.Roots = sceneRoots(doc)
+	sceneCamerasAndLights(doc, scene)
+	scene.CalculateBounds()
+	return scene, nil
+}
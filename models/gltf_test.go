@@ -2,6 +2,9 @@ package models
 
 import (
 	"testing"
+
+	"github.com/qmuntal/gltf"
+	"github.com/qmuntal/gltf/modeler"
 )
 
 func TestLoadGLBInvalidPath(t *testing.T) {
@@ -24,3 +27,77 @@ func TestGLTFLoaderCreation(t *testing.T) {
 		t.Error("SmoothNormals should default to true")
 	}
 }
+
+// primitiveModesDoc builds one primitive per mode, all counter-clockwise
+// (GLTF winding) when seen from +Z.
+func primitiveModesDoc() *gltf.Document {
+	doc := gltf.NewDocument()
+	quad := [][3]float32{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {1, 1, 0}}
+	prim := func(mode gltf.PrimitiveMode, positions [][3]float32, indices []uint16) *gltf.Primitive {
+		p := &gltf.Primitive{
+			Mode:       mode,
+			Attributes: gltf.PrimitiveAttributes{gltf.POSITION: modeler.WritePosition(doc, positions)},
+		}
+		if indices != nil {
+			p.Indices = gltf.Index(modeler.WriteIndices(doc, indices))
+		}
+		return p
+	}
+	doc.Meshes = []*gltf.Mesh{{Primitives: []*gltf.Primitive{
+		prim(gltf.PrimitiveTriangles, quad[:3], nil),
+		// Two triangles plus a degenerate stitch (3, 3)
+		prim(gltf.PrimitiveTriangleStrip, quad, []uint16{0, 1, 2, 3, 3}),
+		prim(gltf.PrimitiveTriangleFan, [][3]float32{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}}, nil),
+		prim(gltf.PrimitiveLines, quad, nil),
+		prim(gltf.PrimitiveLineStrip, quad[:3], nil),
+		prim(gltf.PrimitiveLineLoop, quad[:3], nil),
+		prim(gltf.PrimitivePoints, quad[:3], []uint16{2, 0, 1}),
+	}}}
+	doc.Nodes = []*gltf.Node{{Mesh: gltf.Index(0)}}
+	doc.Scenes = []*gltf.Scene{{Nodes: []int{0}}}
+	return doc
+}
+
+func TestGLTFPrimitiveModes(t *testing.T) {
+	mesh, err := LoadGLBFromFS(encodeGLB(t, primitiveModesDoc()), "model.glb")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	// 1 triangle + 2 strip + 2 fan
+	if len(mesh.Faces) != 5 {
+		t.Fatalf("faces = %d, want 5", len(mesh.Faces))
+	}
+	// All faces must wind like the plain triangle
+	winding := func(f Face) float64 {
+		a := mesh.Vertices[f.V[0]].Position
+		b := mesh.Vertices[f.V[1]].Position
+		c := mesh.Vertices[f.V[2]].Position
+		return b.Sub(a).Cross(c.Sub(a)).Z
+	}
+	want := winding(mesh.Faces[0])
+	for i, f := range mesh.Faces {
+		if w := winding(f); w*want <= 0 {
+			t.Errorf("face %d winding %g, want sign of %g", i, w, want)
+		}
+	}
+	// 2 lines + 2 strip + 3 loop
+	if len(mesh.Lines) != 7 {
+		t.Fatalf("lines = %d, want 7", len(mesh.Lines))
+	}
+	loop := mesh.Lines[6]
+	if loop.V[1] != mesh.Lines[4].V[0] {
+		t.Errorf("line loop not closed: %+v", mesh.Lines[4:])
+	}
+	if len(mesh.Points) != 3 {
+		t.Fatalf("points = %d, want 3", len(mesh.Points))
+	}
+	if p := mesh.Vertices[mesh.Points[0].V].Position; p.Y != 1 {
+		t.Errorf("first point at %v, want (0, 1, 0)", p)
+	}
+	// Compacting keeps vertices used only by lines and points
+	before := mesh.VertexCount()
+	mesh.RemoveUnreferencedVertices()
+	if mesh.VertexCount() != before {
+		t.Errorf("vertices %d -> %d after compaction", before, mesh.VertexCount())
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/ansipixels/trophy/math3d"
+	"github.com/qmuntal/gltf"
+	"github.com/qmuntal/gltf/modeler"
 )
 
 // TestMaterialDefaults verifies default material values.
@@ -134,3 +136,51 @@ func TestQuatToMat4Rotation(t *testing.T) {
 		t.Errorf("90° Y rotation should map X to -Z, got (%.3f, %.3f)", x, z)
 	}
 }
+
+func TestTextureTransformApply(t *testing.T) {
+	tr := TextureTransform{Offset: math3d.V2(0.5, 0), Rotation: math.Pi / 2, Scale: math3d.V2(2, 1)}
+	// Scaled to (2, 0), rotated to (0, -2), then offset
+	if got := tr.Apply(math3d.V2(1, 0)); got.Sub(math3d.V2(0.5, -2)).Len() > 1e-12 {
+		t.Errorf("Apply = %v, want (0.5, -2)", got)
+	}
+}
+
+// TestGLTFMaterialExtensions verifies unlit, emissive strength and texture
+// transform parsing, and that the transform is applied to vertex UVs.
+func TestGLTFMaterialExtensions(t *testing.T) {
+	doc := gltf.NewDocument()
+	doc.Materials = []*gltf.Material{{
+		Name: "atlas",
+		PBRMetallicRoughness: &gltf.PBRMetallicRoughness{
+			BaseColorTexture: &gltf.TextureInfo{Index: 0, Extensions: gltf.Extensions{
+				extTextureTransform: map[string]any{"offset": []float64{0.5, 0}, "scale": []float64{0.5, 0.5}},
+			}},
+		},
+		EmissiveFactor: [3]float64{1, 0.5, 0},
+		Extensions: gltf.Extensions{
+			extUnlit:            map[string]any{},
+			extEmissiveStrength: map[string]any{"emissiveStrength": 2},
+		},
+	}}
+	doc.Meshes = []*gltf.Mesh{{Primitives: []*gltf.Primitive{{
+		Material: gltf.Index(0),
+		Attributes: gltf.PrimitiveAttributes{
+			gltf.POSITION:   modeler.WritePosition(doc, [][3]float32{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}),
+			gltf.TEXCOORD_0: modeler.WriteTextureCoord(doc, [][2]float32{{0, 0}, {1, 0}, {1, 1}}),
+		},
+	}}}}
+	doc.Nodes = []*gltf.Node{{Mesh: gltf.Index(0)}}
+	doc.Scenes = []*gltf.Scene{{Nodes: []int{0}}}
+	mesh, err := LoadGLBFromFS(encodeGLB(t, doc), "model.glb")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	m := mesh.GetMaterial(0)
+	if m == nil || !m.Unlit || m.Emissive != [3]float64{2, 1, 0} || m.UVTransform == nil {
+		t.Fatalf("material = %+v", m)
+	}
+	// (1, 1) scaled to (0.5, 0.5), offset to (1, 0.5), V flipped
+	if uv := mesh.Vertices[2].UV; uv.Sub(math3d.V2(1, 0.5)).Len() > 1e-6 {
+		t.Errorf("uv = %v, want (1, 0.5)", uv)
+	}
+}
@@ -2,7 +2,10 @@
 package models
 
 import (
+	"bytes"
 	"image"
+	"image/color"
+	"math"
 
 	"github.com/ansipixels/trophy/math3d"
 )
@@ -13,22 +16,64 @@ type Mesh struct {
 	Vertices  []MeshVertex
 	Faces     []Face
 	Materials []Material
+	// Whether the vertices have colors (e.g. PLY scans)
+	VertexColors bool
+	// Optional named face groups (OBJ objects and groups, see GroupScene)
+	Groups []FaceGroup
+	// Optional line and point primitives sharing Vertices
+	Lines  []Line
+	Points []Point
 	// Bounding box (calculated on load)
 	BoundsMin math3d.Vec3
 	BoundsMax math3d.Vec3
+	// Optional spatial index over Faces (see BuildBVH)
+	BVH *BVH
+	// Optional skeleton and animation clips (see Pose)
+	Rig *Rig
+	// Length of a coordinate unit in millimeters, 0 for millimeters (see
+	// UnitSize)
+	Unit float64
 }
 
+// Lengths of the coordinate units of model files in millimeters, for
+// Mesh.Unit and Format.Unit: STL, OBJ, PLY, OFF and 3MF models are taken as
+// millimeters, glTF ones are in meters.
+const (
+	Millimeters = 1.0
+	Meters      = 1000.0
+)
+
 // MeshVertex holds all vertex attributes.
 type MeshVertex struct {
 	Position math3d.Vec3
 	Normal   math3d.Vec3
 	UV       math3d.Vec2
+	Color    color.RGBA // Vertex color, only meaningful when Mesh.VertexColors is set
 }
 
 // Face represents a triangle face with vertex indices and material reference.
 type Face struct {
 	V        [3]int // Indices into Mesh.Vertices
 	Material int    // Index into Mesh.Materials (-1 for no material)
+	Group    int    // Index into Mesh.Groups, ignored when out of range
+}
+
+// FaceGroup names a set of faces, e.g. an OBJ group within an object.
+type FaceGroup struct {
+	Name   string
+	Object string // Enclosing object, empty for none
+}
+
+// Line represents a line segment primitive.
+type Line struct {
+	V        [2]int // Indices into Mesh.Vertices
+	Material int    // Index into Mesh.Materials (-1 for no material)
+}
+
+// Point represents a point primitive.
+type Point struct {
+	V        int // Index into Mesh.Vertices
+	Material int // Index into Mesh.Materials (-1 for no material)
 }
 
 // Material represents a PBR material from GLTF.
@@ -39,6 +84,57 @@ type Material struct {
 	Roughness  float64     // 0 = smooth, 1 = rough
 	BaseMap    image.Image // Optional base color texture
 	HasTexture bool
+	// Extensions
+	Unlit       bool              // Shaded without lighting (KHR_materials_unlit)
+	Emissive    [3]float64        // Emitted RGB, including KHR_materials_emissive_strength
+	EmissiveMap image.Image       // Optional emissive texture, modulated by Emissive
+	UVTransform *TextureTransform // KHR_texture_transform, already applied to vertex UVs
+	// Wavefront MTL properties without a GLTF equivalent (not rendered)
+	Ambient   [3]float64  // Ka
+	Specular  [3]float64  // Ks
+	Shininess float64     // Ns, Phong exponent (0-1000), converted to Roughness
+	BumpMap   image.Image // map_Bump
+	AlphaMap  image.Image // map_d
+}
+
+// TextureTransform is a KHR_texture_transform UV transform: scale, then
+// rotation (radians), then offset, in GLTF UV space.
+type TextureTransform struct {
+	Offset   math3d.Vec2
+	Rotation float64
+	Scale    math3d.Vec2
+}
+
+// Apply transforms a GLTF texture coordinate.
+func (t TextureTransform) Apply(uv math3d.Vec2) math3d.Vec2 {
+	sin, cos := math.Sincos(t.Rotation)
+	x, y := uv.X*t.Scale.X, uv.Y*t.Scale.Y
+	return math3d.V2(cos*x+sin*y+t.Offset.X, -sin*x+cos*y+t.Offset.Y)
+}
+
+// EncodedImage is a texture image with the file data it was decoded from,
+// which writers embed as is rather than encoding the pixels again.
+type EncodedImage struct {
+	image.Image
+	Data     []byte
+	MIMEType string // Such as "image/jpeg"
+}
+
+// decodeImage decodes texture file data, keeping a copy of it.
+func decodeImage(data []byte) (image.Image, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return &EncodedImage{Image: img, Data: bytes.Clone(data), MIMEType: "image/" + format}, nil
+}
+
+// UnitSize returns the length of a coordinate unit in millimeters.
+func (m *Mesh) UnitSize() float64 {
+	if m.Unit > 0 {
+		return m.Unit
+	}
+	return Millimeters
 }
 
 // NewMesh creates an empty mesh.
@@ -137,6 +233,13 @@ func (m *Mesh) Transform(mat math3d.Mat4) {
 		m.Vertices[i].Normal = mat.MulVec3Dir(m.Vertices[i].Normal).Normalize()
 	}
 	m.CalculateBounds()
+	if m.BVH.Valid() {
+		m.BVH.Refit()
+	}
+	if m.Rig != nil {
+		// Keep future poses in the transformed space
+		m.Rig.Root = mat.Mul(m.Rig.Root)
+	}
 }
 
 // Clone creates a deep copy of the mesh.
@@ -148,10 +251,20 @@ func (m *Mesh) Clone() *Mesh {
 		Materials: make([]Material, len(m.Materials)),
 		BoundsMin: m.BoundsMin,
 		BoundsMax: m.BoundsMax,
+		// Vertex colors are copied with the vertices
+		VertexColors: m.VertexColors,
+		Unit:         m.Unit,
 	}
 	copy(clone.Vertices, m.Vertices)
 	copy(clone.Faces, m.Faces)
 	copy(clone.Materials, m.Materials)
+	clone.Groups = append([]FaceGroup(nil), m.Groups...)
+	clone.Lines = append([]Line(nil), m.Lines...)
+	clone.Points = append([]Point(nil), m.Points...)
+	if m.BVH.Valid() {
+		clone.BVH = m.BVH.clone(clone)
+	}
+	clone.Rig = m.Rig.clone()
 	return clone
 }
 
@@ -162,12 +275,48 @@ func (m *Mesh) GetVertex(i int) (pos, normal math3d.Vec3, uv math3d.Vec2) {
 	return v.Position, v.Normal, v.UV
 }
 
+// HasVertexColors returns whether the vertices have colors.
+// Implements render.ColorMeshRenderer interface.
+func (m *Mesh) HasVertexColors() bool {
+	return m.VertexColors
+}
+
+// GetVertexColor returns the color of vertex i.
+// Implements render.ColorMeshRenderer interface.
+func (m *Mesh) GetVertexColor(i int) color.RGBA {
+	return m.Vertices[i].Color
+}
+
 // GetFace returns the vertex indices for face i.
 // Implements render.MeshRenderer interface.
 func (m *Mesh) GetFace(i int) [3]int {
 	return m.Faces[i].V
 }
 
+// LineCount returns the number of line primitives.
+// Implements render.PrimitiveMeshRenderer interface.
+func (m *Mesh) LineCount() int {
+	return len(m.Lines)
+}
+
+// GetLine returns the vertex indices for line i.
+// Implements render.PrimitiveMeshRenderer interface.
+func (m *Mesh) GetLine(i int) [2]int {
+	return m.Lines[i].V
+}
+
+// PointCount returns the number of point primitives.
+// Implements render.PrimitiveMeshRenderer interface.
+func (m *Mesh) PointCount() int {
+	return len(m.Points)
+}
+
+// GetPoint returns the vertex index for point i.
+// Implements render.PrimitiveMeshRenderer interface.
+func (m *Mesh) GetPoint(i int) int {
+	return m.Points[i].V
+}
+
 // GetFaceMaterial returns the material index for face i.
 // Returns -1 if no material assigned.
 func (m *Mesh) GetFaceMaterial(i int) int {
@@ -194,6 +343,23 @@ func (m *Mesh) GetBounds() (minV, maxV math3d.Vec3) {
 	return m.BoundsMin, m.BoundsMax
 }
 
+// QueryFaces visits the faces that may pass test, using the BVH when one
+// has been built (see BVH.Query). Without a valid BVH the mesh bounds are
+// tested once and all faces are visited if they pass.
+// Implements render.SpatialMeshRenderer interface.
+func (m *Mesh) QueryFaces(test func(minV, maxV math3d.Vec3) (visible, contained bool), visit func(face int)) {
+	if m.BVH.Valid() {
+		m.BVH.Query(test, visit)
+		return
+	}
+	if visible, _ := test(m.BoundsMin, m.BoundsMax); !visible {
+		return
+	}
+	for i := range m.Faces {
+		visit(i)
+	}
+}
+
 // faceKey creates a canonical key for a face by sorting vertex indices.
 // Two faces with the same vertices (in any order) will have the same key.
 func faceKey(v0, v1, v2 int) [3]int {
@@ -321,28 +487,36 @@ func (m *Mesh) CleanMesh() int {
 	return removed
 }
 
+// degenerateArea is the area below which a face is degenerate.
+const degenerateArea = 1e-10
+
+// isDegenerate reports whether a face has repeated vertex indices or a zero
+// or near-zero area.
+func (m *Mesh) isDegenerate(f Face) bool {
+	// Check for duplicate vertex indices
+	if f.V[0] == f.V[1] || f.V[1] == f.V[2] || f.V[0] == f.V[2] {
+		return true
+	}
+	// Check for near-zero area using cross product magnitude
+	v0 := m.Vertices[f.V[0]].Position
+	v1 := m.Vertices[f.V[1]].Position
+	v2 := m.Vertices[f.V[2]].Position
+	edge1 := v1.Sub(v0)
+	edge2 := v2.Sub(v0)
+	cross := edge1.Cross(edge2)
+	area := cross.Len() * 0.5
+	return area <= degenerateArea
+}
+
 // RemoveDegenerateFaces removes faces with zero or near-zero area.
 // Returns the number of faces removed.
 func (m *Mesh) RemoveDegenerateFaces() int {
 	if len(m.Faces) == 0 {
 		return 0
 	}
-	const minArea = 1e-10
 	kept := make([]Face, 0, len(m.Faces))
 	for _, f := range m.Faces {
-		// Check for duplicate vertex indices
-		if f.V[0] == f.V[1] || f.V[1] == f.V[2] || f.V[0] == f.V[2] {
-			continue
-		}
-		// Check for near-zero area using cross product magnitude
-		v0 := m.Vertices[f.V[0]].Position
-		v1 := m.Vertices[f.V[1]].Position
-		v2 := m.Vertices[f.V[2]].Position
-		edge1 := v1.Sub(v0)
-		edge2 := v2.Sub(v0)
-		cross := edge1.Cross(edge2)
-		area := cross.Len() * 0.5
-		if area > minArea {
+		if !m.isDegenerate(f) {
 			kept = append(kept, f)
 		}
 	}
@@ -351,10 +525,10 @@ func (m *Mesh) RemoveDegenerateFaces() int {
 	return removed
 }
 
-// RemoveUnreferencedVertices removes vertices that are not referenced by any face.
-// This compacts the vertex array and updates face indices accordingly.
+// RemoveUnreferencedVertices removes vertices that are not referenced by any
+// face, line or point. This compacts the vertex array and updates indices accordingly.
 func (m *Mesh) RemoveUnreferencedVertices() {
-	if len(m.Faces) == 0 || len(m.Vertices) == 0 {
+	if len(m.Faces)+len(m.Lines)+len(m.Points) == 0 || len(m.Vertices) == 0 {
 		return
 	}
 	// Mark referenced vertices
@@ -364,6 +538,13 @@ func (m *Mesh) RemoveUnreferencedVertices() {
 		referenced[f.V[1]] = true
 		referenced[f.V[2]] = true
 	}
+	for _, l := range m.Lines {
+		referenced[l.V[0]] = true
+		referenced[l.V[1]] = true
+	}
+	for _, p := range m.Points {
+		referenced[p.V] = true
+	}
 	// Build compacted vertex list and index mapping
 	newIndex := make([]int, len(m.Vertices))
 	newVertices := make([]MeshVertex, 0, len(m.Vertices))
@@ -379,5 +560,15 @@ func (m *Mesh) RemoveUnreferencedVertices() {
 		m.Faces[i].V[1] = newIndex[m.Faces[i].V[1]]
 		m.Faces[i].V[2] = newIndex[m.Faces[i].V[2]]
 	}
+	for i := range m.Lines {
+		m.Lines[i].V[0] = newIndex[m.Lines[i].V[0]]
+		m.Lines[i].V[1] = newIndex[m.Lines[i].V[1]]
+	}
+	for i := range m.Points {
+		m.Points[i].V = newIndex[m.Points[i].V]
+	}
+	if m.Rig != nil && len(newVertices) != len(m.Vertices) {
+		m.Rig = nil // vertex ranges no longer match
+	}
 	m.Vertices = newVertices
 }
@@ -0,0 +1,553 @@
+package models
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/qmuntal/gltf"
+)
+
+// extMeshopt is the name of the meshoptimizer buffer view compression extension.
+const extMeshopt = "EXT_meshopt_compression"
+
+// meshoptCompression is the EXT_meshopt_compression buffer view extension.
+type meshoptCompression struct {
+	Buffer     int    `json:"buffer"`
+	ByteOffset int    `json:"byteOffset"`
+	ByteLength int    `json:"byteLength"`
+	ByteStride int    `json:"byteStride"`
+	Count      int    `json:"count"`
+	Mode       string `json:"mode"`
+	Filter     string `json:"filter"`
+}
+
+// Meshopt codec limits and headers.
+const (
+	meshoptVertexHeader   = 0xa0
+	meshoptIndexHeader    = 0xe0
+	meshoptSequenceHeader = 0xd0
+	meshoptByteGroupSize  = 16
+	meshoptBlockMaxSize   = 256
+	meshoptBlockSizeBytes = 8192
+	meshoptTailMinSize    = 32
+)
+
+// decompressMeshopt decodes every buffer view compressed with
+// EXT_meshopt_compression into a new buffer and points the view at it, so
+// accessors read it like uncompressed data.
+func decompressMeshopt(doc *gltf.Document) error {
+	for i, view := range doc.BufferViews {
+		var ext meshoptCompression
+		found, err := decodeExtension(view.Extensions, extMeshopt, &ext)
+		if err != nil {
+			return fmt.Errorf("buffer view %d: %w", i, err)
+		}
+		if !found {
+			continue
+		}
+		if ext.Buffer < 0 || ext.Buffer >= len(doc.Buffers) {
+			return fmt.Errorf("buffer view %d: buffer %d out of range", i, ext.Buffer)
+		}
+		src := doc.Buffers[ext.Buffer].Data
+		if ext.ByteOffset < 0 || ext.ByteLength < 0 || ext.ByteOffset+ext.ByteLength > len(src) {
+			return fmt.Errorf("buffer view %d: compressed data exceeds buffer", i)
+		}
+		data, err := decodeMeshopt(src[ext.ByteOffset:ext.ByteOffset+ext.ByteLength], ext)
+		if err != nil {
+			return fmt.Errorf("buffer view %d: %w", i, err)
+		}
+		doc.Buffers = append(doc.Buffers, &gltf.Buffer{ByteLength: len(data), Data: data})
+		view.Buffer = len(doc.Buffers) - 1
+		view.ByteOffset = 0
+		view.ByteLength = len(data)
+		delete(view.Extensions, extMeshopt)
+	}
+	return nil
+}
+
+// decodeMeshopt decodes count elements of byteStride bytes and applies the filter.
+func decodeMeshopt(src []byte, ext meshoptCompression) ([]byte, error) {
+	if ext.Count < 0 || ext.ByteStride <= 0 || ext.ByteStride > 256 {
+		return nil, fmt.Errorf("invalid count %d / stride %d", ext.Count, ext.ByteStride)
+	}
+	// Bound the output by what the compressed size can encode before allocating
+	if ext.Count > meshoptMaxCount(ext.Mode, len(src), ext.ByteStride) {
+		return nil, fmt.Errorf("count %d too large for %d compressed bytes", ext.Count, len(src))
+	}
+	out := make([]byte, ext.Count*ext.ByteStride)
+	var err error
+	switch ext.Mode {
+	case "ATTRIBUTES":
+		err = decodeMeshoptVertices(out, ext.Count, ext.ByteStride, src)
+	case "TRIANGLES":
+		err = decodeMeshoptTriangles(out, ext.Count, ext.ByteStride, src)
+	case "INDICES":
+		err = decodeMeshoptSequence(out, ext.Count, ext.ByteStride, src)
+	default:
+		return nil, fmt.Errorf("unsupported mode %q", ext.Mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch ext.Filter {
+	case "", "NONE":
+	case "OCTAHEDRAL":
+		err = meshoptFilterOct(out, ext.Count, ext.ByteStride)
+	case "QUATERNION":
+		err = meshoptFilterQuat(out, ext.Count, ext.ByteStride)
+	case "EXPONENTIAL":
+		err = meshoptFilterExp(out, ext.ByteStride)
+	default:
+		return nil, fmt.Errorf("unsupported filter %q", ext.Filter)
+	}
+	return out, err
+}
+
+// meshoptMaxCount returns the largest element count n compressed bytes can
+// hold in the given mode: vertex groups of 16 bytes need at least 2 header
+// bits, triangles a code byte and sequence indices a varint byte each.
+func meshoptMaxCount(mode string, n, stride int) int {
+	switch mode {
+	case "ATTRIBUTES":
+		return 64 * n / stride
+	case "TRIANGLES":
+		return 3 * n
+	default:
+		return n
+	}
+}
+
+var errMeshoptTruncated = errors.New("truncated meshopt data")
+
+// decodeMeshoptVertices decodes the vertex codec: blocks of byte-transposed,
+// zigzag delta encoded vertices followed by a tail holding the first vertex.
+func decodeMeshoptVertices(out []byte, count, stride int, src []byte) error {
+	if len(src) < 1 || src[0] != meshoptVertexHeader {
+		return errors.New("unsupported vertex codec version")
+	}
+	tailSize := max(stride, meshoptTailMinSize)
+	if len(src) < 1+tailSize {
+		return errMeshoptTruncated
+	}
+	end := len(src) - tailSize
+	last := make([]byte, stride)
+	copy(last, src[len(src)-stride:])
+	blockSize := min((meshoptBlockSizeBytes/stride)&^(meshoptByteGroupSize-1), meshoptBlockMaxSize)
+	var group [meshoptBlockMaxSize]byte
+	pos := 1
+	for first := 0; first < count; first += blockSize {
+		n := min(blockSize, count-first)
+		aligned := (n + meshoptByteGroupSize - 1) &^ (meshoptByteGroupSize - 1)
+		block := out[first*stride : (first+n)*stride]
+		for k := range stride {
+			var err error
+			if pos, err = meshoptDecodeBytes(src[:end], pos, group[:aligned]); err != nil {
+				return err
+			}
+			p := last[k]
+			for i := range n {
+				delta := group[i]
+				p += -(delta & 1) ^ (delta >> 1)
+				block[i*stride+k] = p
+			}
+		}
+		copy(last, block[(n-1)*stride:])
+	}
+	if pos != end {
+		return errors.New("unexpected data after vertex blocks")
+	}
+	return nil
+}
+
+// meshoptDecodeBytes decodes len(dst) bytes stored as groups of 16 with 0, 2,
+// 4 or 8 bits per value. Returns the position after the groups.
+func meshoptDecodeBytes(src []byte, pos int, dst []byte) (int, error) {
+	groups := len(dst) / meshoptByteGroupSize
+	headerSize := (groups + 3) / 4
+	if pos+headerSize > len(src) {
+		return 0, errMeshoptTruncated
+	}
+	header := src[pos : pos+headerSize]
+	pos += headerSize
+	for g := range groups {
+		out := dst[g*meshoptByteGroupSize : (g+1)*meshoptByteGroupSize]
+		switch bitsLog2 := (header[g/4] >> ((g % 4) * 2)) & 3; bitsLog2 {
+		case 0:
+			clear(out)
+		case 3:
+			if pos+meshoptByteGroupSize > len(src) {
+				return 0, errMeshoptTruncated
+			}
+			copy(out, src[pos:])
+			pos += meshoptByteGroupSize
+		default:
+			bits := 1 << bitsLog2 // 2 or 4
+			packed := meshoptByteGroupSize * bits / 8
+			if pos+packed > len(src) {
+				return 0, errMeshoptTruncated
+			}
+			extra := pos + packed
+			sentinel := byte(1<<bits - 1)
+			for i := range out {
+				bit := i * bits
+				v := (src[pos+bit/8] >> (8 - bits - bit%8)) & sentinel
+				if v == sentinel {
+					if extra >= len(src) {
+						return 0, errMeshoptTruncated
+					}
+					v = src[extra]
+					extra++
+				}
+				out[i] = v
+			}
+			pos = extra
+		}
+	}
+	return pos, nil
+}
+
+// meshoptReadVByte reads a little-endian base-128 varint.
+func meshoptReadVByte(src []byte, pos int) (uint32, int, error) {
+	var result uint32
+	for shift := 0; shift < 35; shift += 7 {
+		if pos >= len(src) {
+			return 0, 0, errMeshoptTruncated
+		}
+		b := src[pos]
+		pos++
+		result |= uint32(b&127) << shift
+		if b < 128 {
+			break
+		}
+	}
+	return result, pos, nil
+}
+
+// meshoptZigzag decodes a zigzag encoded signed delta.
+func meshoptZigzag(v uint32) uint32 {
+	return (v >> 1) ^ -(v & 1)
+}
+
+// putIndex writes index i of size bytes.
+func putIndex(out []byte, i, size int, v uint32) {
+	if size == 2 {
+		binary.LittleEndian.PutUint16(out[i*2:], uint16(v)) //nolint:gosec // index size chosen by the file
+	} else {
+		binary.LittleEndian.PutUint32(out[i*4:], v)
+	}
+}
+
+// decodeMeshoptTriangles decodes the triangle index codec, which predicts
+// vertices from FIFOs of recent edges and vertices.
+//
+//nolint:gocognit,gocyclo,funlen // follows the reference decoder step by step
+func decodeMeshoptTriangles(out []byte, count, size int, src []byte) error {
+	if size != 2 && size != 4 || count%3 != 0 {
+		return fmt.Errorf("invalid triangle index stride %d / count %d", size, count)
+	}
+	if len(src) < 1+count/3+16 {
+		return errMeshoptTruncated
+	}
+	version := src[0] & 0x0f
+	if src[0]&0xf0 != meshoptIndexHeader || version > 1 {
+		return errors.New("unsupported index codec version")
+	}
+	var edgeFifo [16][2]uint32
+	var vertexFifo [16]uint32
+	for i := range edgeFifo {
+		edgeFifo[i] = [2]uint32{math.MaxUint32, math.MaxUint32}
+		vertexFifo[i] = math.MaxUint32
+	}
+	edgeOffset, vertexOffset := 0, 0
+	pushVertex := func(v uint32, advance bool) {
+		vertexFifo[vertexOffset] = v
+		if advance {
+			vertexOffset = (vertexOffset + 1) & 15
+		}
+	}
+	pushEdge := func(a, b uint32) {
+		edgeFifo[edgeOffset] = [2]uint32{a, b}
+		edgeOffset = (edgeOffset + 1) & 15
+	}
+	var next, last uint32
+	fecMax := 15
+	if version >= 1 {
+		fecMax = 13
+	}
+	code := 1
+	pos := 1 + count/3
+	safeEnd := len(src) - 16
+	codeAux := src[safeEnd:]
+	readIndex := func() (uint32, error) {
+		v, p, err := meshoptReadVByte(src[:safeEnd], pos)
+		pos = p
+		last += meshoptZigzag(v)
+		return last, err
+	}
+	for i := 0; i < count; i += 3 {
+		if pos > safeEnd {
+			return errMeshoptTruncated
+		}
+		codeTri := src[code]
+		code++
+		var a, b, c uint32
+		switch {
+		case codeTri < 0xf0:
+			// Edge from the FIFO plus a vertex
+			edge := edgeFifo[(edgeOffset-1-int(codeTri>>4))&15]
+			a, b = edge[0], edge[1]
+			fec := int(codeTri & 15)
+			switch {
+			case fec == 0:
+				c = next
+				next++
+				pushVertex(c, true)
+			case fec < fecMax:
+				c = vertexFifo[(vertexOffset-1-fec)&15]
+				pushVertex(c, false)
+			default:
+				if fec == 15 {
+					var err error
+					if c, err = readIndex(); err != nil {
+						return err
+					}
+				} else {
+					// 13 and 14 encode -1 and +1 from the last free index
+					last += uint32(fec - (fec ^ 3)) //nolint:gosec // wraps like the reference decoder
+					c = last
+				}
+				pushVertex(c, true)
+			}
+			pushEdge(c, b)
+			pushEdge(a, c)
+		case codeTri < 0xfe:
+			// New vertex plus two vertices from the FIFO, via the code table
+			aux := codeAux[codeTri&15]
+			feb, fec := int(aux>>4), int(aux&15)
+			a = next
+			next++
+			if feb == 0 {
+				b = next
+				next++
+			} else {
+				b = vertexFifo[(vertexOffset-feb)&15]
+			}
+			if fec == 0 {
+				c = next
+				next++
+			} else {
+				c = vertexFifo[(vertexOffset-fec)&15]
+			}
+			pushVertex(a, true)
+			pushVertex(b, feb == 0)
+			pushVertex(c, fec == 0)
+			pushEdge(b, a)
+			pushEdge(c, b)
+			pushEdge(a, c)
+		default:
+			// Explicit code byte, free indices are delta encoded
+			if pos >= safeEnd {
+				return errMeshoptTruncated
+			}
+			aux := src[pos]
+			pos++
+			feb, fec := int(aux>>4), int(aux&15)
+			if aux == 0 {
+				next = 0
+			}
+			vertex := func(fe int) (uint32, error) {
+				switch fe {
+				case 0:
+					next++
+					return next - 1, nil
+				case 15:
+					return readIndex()
+				default:
+					return vertexFifo[(vertexOffset-fe)&15], nil
+				}
+			}
+			fea := 0
+			if codeTri == 0xff {
+				fea = 15
+			}
+			var err error
+			// Reads happen in this order to match the encoder
+			if fea == 0 {
+				a, _ = vertex(0)
+			}
+			if feb != 15 {
+				b, _ = vertex(feb)
+			}
+			if fec != 15 {
+				c, _ = vertex(fec)
+			}
+			if fea == 15 {
+				if a, err = readIndex(); err != nil {
+					return err
+				}
+			}
+			if feb == 15 {
+				if b, err = readIndex(); err != nil {
+					return err
+				}
+			}
+			if fec == 15 {
+				if c, err = readIndex(); err != nil {
+					return err
+				}
+			}
+			pushVertex(a, true)
+			pushVertex(b, feb == 0 || feb == 15)
+			pushVertex(c, fec == 0 || fec == 15)
+			pushEdge(b, a)
+			pushEdge(c, b)
+			pushEdge(a, c)
+		}
+		putIndex(out, i, size, a)
+		putIndex(out, i+1, size, b)
+		putIndex(out, i+2, size, c)
+	}
+	if pos != safeEnd {
+		return errors.New("unexpected data after triangles")
+	}
+	return nil
+}
+
+// decodeMeshoptSequence decodes the index sequence codec: deltas from one of
+// two baselines.
+func decodeMeshoptSequence(out []byte, count, size int, src []byte) error {
+	if size != 2 && size != 4 {
+		return fmt.Errorf("invalid index stride %d", size)
+	}
+	if len(src) < 1+count+4 {
+		return errMeshoptTruncated
+	}
+	if src[0]&0xf0 != meshoptSequenceHeader || src[0]&0x0f > 1 {
+		return errors.New("unsupported index sequence codec version")
+	}
+	safeEnd := len(src) - 4
+	var last [2]uint32
+	pos := 1
+	for i := range count {
+		if pos >= safeEnd {
+			return errMeshoptTruncated
+		}
+		v, p, err := meshoptReadVByte(src[:safeEnd], pos)
+		if err != nil {
+			return err
+		}
+		pos = p
+		baseline := v & 1
+		last[baseline] += meshoptZigzag(v >> 1)
+		putIndex(out, i, size, last[baseline])
+	}
+	if pos != safeEnd {
+		return errors.New("unexpected data after indices")
+	}
+	return nil
+}
+
+// meshoptFilterOct decodes octahedral encoded unit vectors (8 or 16 bit
+// components, the fourth component is preserved).
+func meshoptFilterOct(data []byte, count, stride int) error {
+	switch stride {
+	case 4:
+		for i := range count {
+			e := data[i*4 : i*4+4]
+			x, y, z := octDecode(float64(int8(e[0])), float64(int8(e[1])), float64(int8(e[2])), 127)
+			e[0], e[1], e[2] = byte(int8(x)), byte(int8(y)), byte(int8(z)) //nolint:gosec // in range
+		}
+	case 8:
+		for i := range count {
+			e := data[i*8 : i*8+8]
+			x, y, z := octDecode(float64(int16(binary.LittleEndian.Uint16(e))), //nolint:gosec // bit cast
+				float64(int16(binary.LittleEndian.Uint16(e[2:]))),        //nolint:gosec // bit cast
+				float64(int16(binary.LittleEndian.Uint16(e[4:]))), 32767) //nolint:gosec // bit cast
+			binary.LittleEndian.PutUint16(e, uint16(int16(x)))     //nolint:gosec // in range
+			binary.LittleEndian.PutUint16(e[2:], uint16(int16(y))) //nolint:gosec // in range
+			binary.LittleEndian.PutUint16(e[4:], uint16(int16(z))) //nolint:gosec // in range
+		}
+	default:
+		return fmt.Errorf("octahedral filter: invalid stride %d", stride)
+	}
+	return nil
+}
+
+// octDecode unwraps an octahedral vector, where z holds the encoding of 1,
+// and returns it normalized to scale.
+func octDecode(x, y, z, scale float64) (int, int, int) {
+	z = z - math.Abs(x) - math.Abs(y)
+	if z < 0 {
+		// Lower hemisphere is folded over the diagonals
+		x += foldSign(x, z)
+		y += foldSign(y, z)
+	}
+	l := math.Sqrt(x*x + y*y + z*z)
+	if l == 0 {
+		return 0, 0, 0
+	}
+	s := scale / l
+	return roundHalfAway(x * s), roundHalfAway(y * s), roundHalfAway(z * s)
+}
+
+// foldSign returns t for non-negative v and -t otherwise.
+func foldSign(v, t float64) float64 {
+	if v >= 0 {
+		return t
+	}
+	return -t
+}
+
+// roundHalfAway rounds to the nearest integer, halves away from zero.
+func roundHalfAway(v float64) int {
+	return int(math.Round(v))
+}
+
+// meshoptFilterQuat decodes quaternions stored as the three smallest
+// components plus the index of the largest one.
+func meshoptFilterQuat(data []byte, count, stride int) error {
+	if stride != 8 {
+		return fmt.Errorf("quaternion filter: invalid stride %d", stride)
+	}
+	for i := range count {
+		e := data[i*8 : i*8+8]
+		var q [4]int16
+		for j := range q {
+			q[j] = int16(binary.LittleEndian.Uint16(e[j*2:])) //nolint:gosec // bit cast
+		}
+		sf := int(q[3]) | 3
+		ss := math.Sqrt2 / 2 / float64(sf)
+		x := float64(q[0]) * ss
+		y := float64(q[1]) * ss
+		z := float64(q[2]) * ss
+		w := math.Sqrt(max(0, 1-x*x-y*y-z*z))
+		qc := int(q[3]) & 3
+		var res [4]int
+		res[(qc+1)&3] = roundHalfAway(x * 32767)
+		res[(qc+2)&3] = roundHalfAway(y * 32767)
+		res[(qc+3)&3] = roundHalfAway(z * 32767)
+		res[qc] = roundHalfAway(w * 32767)
+		for j, v := range res {
+			binary.LittleEndian.PutUint16(e[j*2:], uint16(int16(v))) //nolint:gosec // in range
+		}
+	}
+	return nil
+}
+
+// meshoptFilterExp decodes 32-bit values stored as a 24-bit mantissa and an
+// 8-bit exponent into floats.
+func meshoptFilterExp(data []byte, stride int) error {
+	if stride%4 != 0 {
+		return fmt.Errorf("exponential filter: invalid stride %d", stride)
+	}
+	for i := 0; i+4 <= len(data); i += 4 {
+		v := binary.LittleEndian.Uint32(data[i:])
+		m := int32(v<<8) >> 8 //nolint:gosec // sign extension
+		e := int32(v) >> 24   //nolint:gosec // sign extension
+		f := float32(math.Ldexp(float64(m), int(e)))
+		binary.LittleEndian.PutUint32(data[i:], math.Float32bits(f))
+	}
+	return nil
+}
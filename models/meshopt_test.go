@@ -0,0 +1,314 @@
+package models
+
+import (
+	"encoding/binary"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/ansipixels/trophy/math3d"
+	"github.com/qmuntal/gltf"
+)
+
+// encodeMeshoptVertices is a minimal vertex codec encoder for tests: each
+// group of 16 deltas uses the smallest of the 0, 2, 4 and 8 bit encodings.
+func encodeMeshoptVertices(data []byte, stride int) []byte {
+	count := len(data) / stride
+	out := []byte{meshoptVertexHeader}
+	last := make([]byte, stride)
+	copy(last, data)
+	blockSize := min((meshoptBlockSizeBytes/stride)&^(meshoptByteGroupSize-1), meshoptBlockMaxSize)
+	for first := 0; first < count; first += blockSize {
+		n := min(blockSize, count-first)
+		aligned := (n + meshoptByteGroupSize - 1) &^ (meshoptByteGroupSize - 1)
+		for k := range stride {
+			deltas := make([]byte, aligned)
+			p := last[k]
+			for i := range n {
+				v := data[(first+i)*stride+k]
+				d := v - p
+				deltas[i] = (d << 1) ^ byte(int8(d)>>7) //nolint:gosec // zigzag
+				p = v
+			}
+			header := make([]byte, (aligned/meshoptByteGroupSize+3)/4)
+			var body []byte
+			for g := range aligned / meshoptByteGroupSize {
+				group := deltas[g*meshoptByteGroupSize : (g+1)*meshoptByteGroupSize]
+				best, bestLog2 := []byte(nil), 0
+				for bitsLog2, bits := range []int{0, 2, 4, 8} {
+					enc, ok := packGroup(group, bits)
+					if ok && (best == nil || len(enc) < len(best)) {
+						best, bestLog2 = enc, bitsLog2
+					}
+				}
+				header[g/4] |= byte(bestLog2 << ((g % 4) * 2)) //nolint:gosec // 2 bits
+				body = append(body, best...)
+			}
+			out = append(out, header...)
+			out = append(out, body...)
+		}
+		copy(last, data[(first+n-1)*stride:])
+	}
+	for range max(stride, meshoptTailMinSize) - stride {
+		out = append(out, 0)
+	}
+	return append(out, data[:stride]...)
+}
+
+// packGroup packs 16 values with bits per value and sentinel escapes.
+func packGroup(group []byte, bits int) ([]byte, bool) {
+	switch bits {
+	case 0:
+		for _, v := range group {
+			if v != 0 {
+				return nil, false
+			}
+		}
+		return []byte{}, true
+	case 8:
+		return append([]byte(nil), group...), true
+	}
+	sentinel := byte(1<<bits - 1)
+	packed := make([]byte, len(group)*bits/8)
+	var extra []byte
+	for i, v := range group {
+		enc := v
+		if v >= sentinel {
+			enc = sentinel
+			extra = append(extra, v)
+		}
+		bit := i * bits
+		packed[bit/8] |= enc << (8 - bits - bit%8)
+	}
+	return append(packed, extra...), true
+}
+
+func TestMeshoptVertexCodec(t *testing.T) {
+	// 40 vertices of 12 bytes: two bit widths, a reset and raw bytes
+	const stride = 12
+	data := make([]byte, 40*stride)
+	for i := range 40 {
+		binary.LittleEndian.PutUint32(data[i*stride:], uint32(i))     //nolint:gosec // test data
+		binary.LittleEndian.PutUint32(data[i*stride+4:], uint32(i*7)) //nolint:gosec // test data
+		binary.LittleEndian.PutUint32(data[i*stride+8:], uint32(i*i*i*977))
+	}
+	// Force more than one block
+	big := make([]byte, 700*4)
+	for i := range 700 {
+		binary.LittleEndian.PutUint32(big[i*4:], uint32(i*3)) //nolint:gosec // test data
+	}
+	for _, tc := range []struct {
+		data   []byte
+		stride int
+	}{{data, stride}, {big, 4}} {
+		enc := encodeMeshoptVertices(tc.data, tc.stride)
+		out := make([]byte, len(tc.data))
+		if err := decodeMeshoptVertices(out, len(tc.data)/tc.stride, tc.stride, enc); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if string(out) != string(tc.data) {
+			t.Errorf("stride %d: round trip mismatch", tc.stride)
+		}
+		if err := decodeMeshoptVertices(out, len(tc.data)/tc.stride, tc.stride, enc[:len(enc)-1]); err == nil {
+			t.Errorf("stride %d: expected an error for truncated data", tc.stride)
+		}
+	}
+}
+
+// meshoptTriangleStream encodes (0,1,2) (2,1,3) (5,3,2) (5,2,6) with the
+// code table, an edge FIFO hit, an explicit free index and a version 1 delta.
+var meshoptTriangleStream = []byte{
+	0xe1,
+	0xf0, 0x10, 0xff, 0x0e, // codes
+	0x12, 0x0a, // codeaux for 0xff, free index +5
+	0x00, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // codeaux table
+}
+
+func readIndices16(b []byte) []int {
+	out := make([]int, len(b)/2)
+	for i := range out {
+		out[i] = int(binary.LittleEndian.Uint16(b[i*2:]))
+	}
+	return out
+}
+
+func TestMeshoptIndexCodecs(t *testing.T) {
+	out := make([]byte, 12*2)
+	if err := decodeMeshoptTriangles(out, 12, 2, meshoptTriangleStream); err != nil {
+		t.Fatalf("triangles: %v", err)
+	}
+	want := []int{0, 1, 2, 2, 1, 3, 5, 3, 2, 5, 2, 6}
+	if got := readIndices16(out); !slices.Equal(got, want) {
+		t.Errorf("triangles = %v, want %v", got, want)
+	}
+	// 0, 1, 2, 10 from the first baseline, 11 from the second
+	seq := []byte{0xd1, 0, 4, 4, 32, 45, 0, 0, 0, 0}
+	out = make([]byte, 5*2)
+	if err := decodeMeshoptSequence(out, 5, 2, seq); err != nil {
+		t.Fatalf("sequence: %v", err)
+	}
+	if got := readIndices16(out); !slices.Equal(got, []int{0, 1, 2, 10, 11}) {
+		t.Errorf("sequence = %v", got)
+	}
+	if err := decodeMeshoptSequence(out, 5, 2, seq[:8]); err == nil {
+		t.Error("expected an error for a truncated sequence")
+	}
+}
+
+// Reference streams from meshoptimizer's own decoder tests (encoder output).
+var (
+	meshoptRefIndexBuffer = []int{0, 1, 2, 2, 1, 3, 4, 6, 5, 7, 8, 9}
+	meshoptRefIndexV0     = []byte{
+		0xe0, 0xf0, 0x10, 0xfe, 0xff, 0xf0, 0x0c, 0xff, 0x02, 0x02, 0x02, 0x00, 0x76, 0x87, 0x56, 0x67,
+		0x78, 0xa9, 0x86, 0x65, 0x89, 0x68, 0x98, 0x01, 0x69, 0x00, 0x00,
+	}
+	meshoptRefSequence   = []int{0, 1, 51, 2, 49, 1000}
+	meshoptRefSequenceV1 = []byte{0xd1, 0x00, 0x04, 0xcd, 0x01, 0x04, 0x07, 0x98, 0x1f, 0x00, 0x00, 0x00, 0x00}
+	// Four 12 byte vertices (px, py, pz, nu, nv, tx, ty): (0, 0), (300, 0), (0, 300), (300, 300)
+	meshoptRefVertexV0 = append([]byte{
+		0xa0, 0x01, 0x3f, 0x00, 0x00, 0x00, 0x58, 0x57, 0x58, 0x01, 0x26, 0x00, 0x00, 0x00, 0x01,
+		0x0c, 0x00, 0x00, 0x00, 0x58, 0x01, 0x08,
+	}, make([]byte, 43)...)
+)
+
+func TestMeshoptReferenceVectors(t *testing.T) {
+	out := make([]byte, len(meshoptRefIndexBuffer)*4)
+	if err := decodeMeshoptTriangles(out, len(meshoptRefIndexBuffer), 4, meshoptRefIndexV0); err != nil {
+		t.Fatalf("triangles: %v", err)
+	}
+	for i, want := range meshoptRefIndexBuffer {
+		if got := int(binary.LittleEndian.Uint32(out[i*4:])); got != want {
+			t.Errorf("triangle index %d = %d, want %d", i, got, want)
+		}
+	}
+	out = make([]byte, len(meshoptRefSequence)*4)
+	if err := decodeMeshoptSequence(out, len(meshoptRefSequence), 4, meshoptRefSequenceV1); err != nil {
+		t.Fatalf("sequence: %v", err)
+	}
+	for i, want := range meshoptRefSequence {
+		if got := int(binary.LittleEndian.Uint32(out[i*4:])); got != want {
+			t.Errorf("sequence index %d = %d, want %d", i, got, want)
+		}
+	}
+	want := make([]byte, 4*12)
+	binary.LittleEndian.PutUint16(want[12:], 300)
+	binary.LittleEndian.PutUint16(want[26:], 300)
+	binary.LittleEndian.PutUint16(want[36:], 300)
+	binary.LittleEndian.PutUint16(want[38:], 300)
+	out = make([]byte, len(want))
+	if err := decodeMeshoptVertices(out, 4, 12, meshoptRefVertexV0); err != nil {
+		t.Fatalf("vertices: %v", err)
+	}
+	if !slices.Equal(out, want) {
+		t.Errorf("vertices = %v, want %v", out, want)
+	}
+}
+
+func TestMeshoptCountBound(t *testing.T) {
+	huge := meshoptCompression{Mode: "ATTRIBUTES", Count: 1 << 40, ByteStride: 16}
+	if _, err := decodeMeshopt(meshoptRefVertexV0, huge); err == nil {
+		t.Error("expected an error for a count the compressed data cannot hold")
+	}
+	huge = meshoptCompression{Mode: "INDICES", Count: 1 << 40, ByteStride: 4}
+	if _, err := decodeMeshopt(meshoptRefSequenceV1, huge); err == nil {
+		t.Error("expected an error for an index count the compressed data cannot hold")
+	}
+}
+
+func TestMeshoptFilters(t *testing.T) {
+	// Octahedral: +X and the folded -Z
+	oct := []byte{127, 0, 127, 9, 127, 127, 127, 0}
+	if err := meshoptFilterOct(oct, 2, 4); err != nil {
+		t.Fatal(err)
+	}
+	got := []int{int(int8(oct[0])), int(int8(oct[1])), int(int8(oct[2])), int(oct[3]),
+		int(int8(oct[4])), int(int8(oct[5])), int(int8(oct[6]))}
+	if !slices.Equal(got, []int{127, 0, 0, 9, 0, 0, -127}) {
+		t.Errorf("octahedral = %v", got)
+	}
+	// Quaternion: 90° around Z, stored without its largest (z) component
+	quat := make([]byte, 8)
+	for i, v := range []int16{32767, 0, 0, 32766} {
+		binary.LittleEndian.PutUint16(quat[i*2:], uint16(v)) //nolint:gosec // test data
+	}
+	if err := meshoptFilterQuat(quat, 1, 8); err != nil {
+		t.Fatal(err)
+	}
+	var q []int
+	for i := range 4 {
+		q = append(q, int(int16(binary.LittleEndian.Uint16(quat[i*2:])))) //nolint:gosec // test data
+	}
+	if !slices.Equal(q, []int{0, 0, 23170, 23170}) {
+		t.Errorf("quaternion = %v", q)
+	}
+	// Exponential: 3 * 2^-1 and -5 * 2^2
+	exp := binary.LittleEndian.AppendUint32(nil, 0xff000003)
+	exp = binary.LittleEndian.AppendUint32(exp, 0x02fffffb)
+	if err := meshoptFilterExp(exp, 8); err != nil {
+		t.Fatal(err)
+	}
+	a := math.Float32frombits(binary.LittleEndian.Uint32(exp))
+	b := math.Float32frombits(binary.LittleEndian.Uint32(exp[4:]))
+	if a != 1.5 || b != -20 {
+		t.Errorf("exponential = %v, %v", a, b)
+	}
+}
+
+// meshoptDoc builds the triangles of meshoptTriangleStream over 7 vertices,
+// with compressed positions and indices in buffer 0 and an empty fallback
+// buffer, as gltfpack -c does.
+func meshoptDoc() *gltf.Document {
+	var raw []byte
+	for i := range 7 {
+		for _, v := range []float32{float32(i), float32(i % 2), 0} {
+			raw = binary.LittleEndian.AppendUint32(raw, math.Float32bits(v))
+		}
+	}
+	b := newGLBBuilder()
+	posData := encodeMeshoptVertices(raw, 12)
+	b.view(posData, 0)
+	b.view(meshoptTriangleStream, 0)
+	doc := b.finish()
+	compressed := []map[string]any{
+		{"buffer": 0, "byteOffset": doc.BufferViews[0].ByteOffset, "byteLength": len(posData),
+			"byteStride": 12, "count": 7, "mode": "ATTRIBUTES"},
+		{"buffer": 0, "byteOffset": doc.BufferViews[1].ByteOffset, "byteLength": len(meshoptTriangleStream),
+			"byteStride": 2, "count": 12, "mode": "TRIANGLES"},
+	}
+	doc.Buffers = append(doc.Buffers, &gltf.Buffer{
+		ByteLength: len(raw) + 24,
+		Extensions: gltf.Extensions{extMeshopt: map[string]any{"fallback": true}},
+	})
+	doc.BufferViews = []*gltf.BufferView{
+		{Buffer: 1, ByteLength: len(raw), ByteStride: 12, Extensions: gltf.Extensions{extMeshopt: compressed[0]}},
+		{Buffer: 1, ByteOffset: len(raw), ByteLength: 24, Extensions: gltf.Extensions{extMeshopt: compressed[1]}},
+	}
+	doc.Accessors = []*gltf.Accessor{
+		{BufferView: gltf.Index(0), ComponentType: gltf.ComponentFloat, Type: gltf.AccessorVec3, Count: 7},
+		{BufferView: gltf.Index(1), ComponentType: gltf.ComponentUshort, Type: gltf.AccessorScalar, Count: 12},
+	}
+	doc.ExtensionsUsed = []string{extMeshopt}
+	doc.ExtensionsRequired = []string{extMeshopt}
+	doc.Meshes = []*gltf.Mesh{{Primitives: []*gltf.Primitive{{
+		Indices:    gltf.Index(1),
+		Attributes: gltf.PrimitiveAttributes{gltf.POSITION: 0},
+	}}}}
+	doc.Nodes = []*gltf.Node{{Mesh: gltf.Index(0)}}
+	doc.Scenes = []*gltf.Scene{{Nodes: []int{0}}}
+	return doc
+}
+
+func TestLoadMeshoptGLB(t *testing.T) {
+	mesh, err := LoadGLBFromFS(encodeGLB(t, meshoptDoc()), "model.glb")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if mesh.VertexCount() != 7 || mesh.TriangleCount() != 4 {
+		t.Fatalf("vertices=%d triangles=%d, want 7 and 4", mesh.VertexCount(), mesh.TriangleCount())
+	}
+	assertVec3(t, "vertex 5", mesh.Vertices[5].Position, math3d.V3(5, 1, 0))
+	// (5, 3, 2) with the engine's winding swap
+	if f := mesh.Faces[2].V; f != [3]int{5, 2, 3} {
+		t.Errorf("face 2 = %v, want [5 2 3]", f)
+	}
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// mtlTextureArgs is the number of arguments of the MTL texture map options.
+var mtlTextureArgs = map[string]int{
+	"-blendu": 1, "-blendv": 1, "-bm": 1, "-boost": 1, "-cc": 1, "-clamp": 1,
+	"-imfchan": 1, "-mm": 2, "-o": 3, "-s": 3, "-t": 3, "-texres": 1, "-type": 1,
+}
+
+// LoadMTLFromFS loads a Wavefront MTL material library from an fs.FS. Texture
+// maps are resolved relative to the library; missing or undecodable ones are
+// left nil.
+func LoadMTLFromFS(fsys fs.FS, mtlPath string) ([]Material, error) {
+	data, err := fs.ReadFile(fsys, mtlPath)
+	if err != nil {
+		return nil, fmt.Errorf("read MTL file: %w", err)
+	}
+	return ParseMTL(bytes.NewReader(data), fsys, path.Dir(mtlPath))
+}
+
+// ParseMTL parses a Wavefront MTL material library: Kd, Ka, Ks, Ke, Ns, d/Tr,
+// map_Kd, map_Bump (or bump) and map_d. Texture maps are loaded from fsys
+// relative to dir, and skipped when fsys is nil.
+//
+//nolint:gocognit,gocyclo // one case per statement.
+func ParseMTL(r io.Reader, fsys fs.FS, dir string) ([]Material, error) {
+	var materials []Material
+	var cur *Material
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0][0] == '#' {
+			continue
+		}
+		key := strings.ToLower(fields[0])
+		if key == "newmtl" {
+			materials = append(materials, newMTLMaterial(strings.Join(fields[1:], " ")))
+			cur = &materials[len(materials)-1]
+			continue
+		}
+		if cur == nil {
+			continue // statements before the first newmtl
+		}
+		var err error
+		switch key {
+		case "kd":
+			var kd [3]float64
+			kd, err = parseMTLColor(fields)
+			cur.BaseColor = [4]float64{kd[0], kd[1], kd[2], cur.BaseColor[3]}
+		case "ka":
+			cur.Ambient, err = parseMTLColor(fields)
+		case "ks":
+			cur.Specular, err = parseMTLColor(fields)
+		case "ke":
+			cur.Emissive, err = parseMTLColor(fields)
+		case "ns":
+			cur.Shininess, err = parseMTLFloat(fields)
+			// Usual Blinn-Phong exponent to roughness conversion
+			cur.Roughness = math.Min(1, math.Sqrt(2/(math.Max(0, cur.Shininess)+2)))
+		case "d":
+			cur.BaseColor[3], err = parseMTLFloat(fields)
+		case "tr":
+			var tr float64
+			tr, err = parseMTLFloat(fields)
+			cur.BaseColor[3] = 1 - tr
+		case "map_kd":
+			cur.BaseMap = loadMTLTexture(fsys, dir, fields)
+			cur.HasTexture = cur.BaseMap != nil
+		case "map_bump", "bump":
+			cur.BumpMap = loadMTLTexture(fsys, dir, fields)
+		case "map_d":
+			cur.AlphaMap = loadMTLTexture(fsys, dir, fields)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", lineNum, fields[0], err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading MTL: %w", err)
+	}
+	return materials, nil
+}
+
+// newMTLMaterial returns a material with the MTL defaults.
+func newMTLMaterial(name string) Material {
+	return Material{
+		Name:      name,
+		BaseColor: [4]float64{1, 1, 1, 1},
+		Roughness: 1,
+	}
+}
+
+// parseMTLColor parses the r g b arguments of a color statement; a single
+// value is used for all three.
+func parseMTLColor(fields []string) ([3]float64, error) {
+	var c [3]float64
+	if len(fields) < 2 {
+		return c, fmt.Errorf("missing color")
+	}
+	for i := range 3 {
+		arg := fields[1]
+		if len(fields) > 1+i {
+			arg = fields[1+i]
+		}
+		v, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return c, fmt.Errorf("invalid color component: %w", err)
+		}
+		c[i] = v
+	}
+	return c, nil
+}
+
+// parseMTLFloat parses the single argument of a statement.
+func parseMTLFloat(fields []string) (float64, error) {
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("missing value")
+	}
+	v, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value: %w", err)
+	}
+	return v, nil
+}
+
+// mtlTexturePath returns the file name of a texture map statement, skipping
+// its options. Names may contain spaces and Windows separators.
+func mtlTexturePath(fields []string) string {
+	args := fields[1:]
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		n, known := mtlTextureArgs[strings.ToLower(args[0])]
+		if !known {
+			break
+		}
+		args = args[1:]
+		// -o, -s and -t take 1 to 3 numbers
+		for ; n > 0 && len(args) > 1; n-- {
+			if _, err := strconv.ParseFloat(args[0], 64); err != nil && args[0] != "on" && args[0] != "off" {
+				break
+			}
+			args = args[1:]
+		}
+	}
+	return strings.ReplaceAll(strings.Join(args, " "), `\`, "/")
+}
+
+// loadMTLTexture loads the image of a texture map statement, nil if it
+// cannot be read or decoded.
+func loadMTLTexture(fsys fs.FS, dir string, fields []string) image.Image {
+	name := mtlTexturePath(fields)
+	if fsys == nil || name == "" {
+		return nil
+	}
+	data, err := fs.ReadFile(fsys, path.Join(dir, name))
+	if err != nil {
+		return nil
+	}
+	img, err := decodeImage(data)
+	if err != nil {
+		return nil
+	}
+	return img
+}
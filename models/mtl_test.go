@@ -0,0 +1,138 @@
+package models
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func pngBytes(t *testing.T, c color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for i := range 4 {
+		img.Set(i%2, i/2, c)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseMTL(t *testing.T) {
+	data := `
+# two materials
+Kd 1 0 0
+newmtl red
+Kd 1 0 0
+Ka 0.1
+Ks 0.5 0.5 0.5
+Ns 98
+d 0.5
+map_Kd -s 2 2 1 -o 0.5 0.5 -clamp on tex\red.png
+map_Bump -bm 0.3 bump.png
+map_d missing.png
+
+newmtl glass panel
+Tr 0.75
+Ke 0 0 1
+`
+	fsys := fstest.MapFS{
+		"lib/tex/red.png": {Data: pngBytes(t, color.RGBA{255, 0, 0, 255})},
+		"lib/bump.png":    {Data: pngBytes(t, color.Gray{128})},
+	}
+	materials, err := ParseMTL(strings.NewReader(data), fsys, "lib")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(materials) != 2 {
+		t.Fatalf("materials = %d, want 2", len(materials))
+	}
+	red := materials[0]
+	if red.Name != "red" || red.BaseColor != [4]float64{1, 0, 0, 0.5} || red.Ambient != [3]float64{0.1, 0.1, 0.1} ||
+		red.Specular != [3]float64{0.5, 0.5, 0.5} || red.Shininess != 98 || math.Abs(red.Roughness-0.1414) > 1e-3 {
+		t.Errorf("red = %+v", red)
+	}
+	if !red.HasTexture || red.BaseMap == nil || red.BumpMap == nil || red.AlphaMap != nil {
+		t.Errorf("red maps: base %v bump %v alpha %v", red.BaseMap != nil, red.BumpMap != nil, red.AlphaMap != nil)
+	}
+	glass := materials[1]
+	if glass.Name != "glass panel" || glass.BaseColor != [4]float64{1, 1, 1, 0.25} || glass.Emissive != [3]float64{0, 0, 1} ||
+		glass.Roughness != 1 {
+		t.Errorf("glass = %+v", glass)
+	}
+	if _, err := ParseMTL(strings.NewReader("newmtl x\nKd red\n"), nil, ""); err == nil {
+		t.Error("expected an error for an invalid color")
+	}
+}
+
+func TestMTLTexturePath(t *testing.T) {
+	tests := []struct {
+		line, want string
+	}{
+		{"map_Kd a.png", "a.png"},
+		{"map_Kd -o 1 -s 1 2 my texture.png", "my texture.png"},
+		{"map_Kd -blendu off -mm 0 1 sub\\b.jpg", "sub/b.jpg"},
+		{"map_Kd -s 2 2.png", "2.png"},
+	}
+	for _, tt := range tests {
+		if got := mtlTexturePath(strings.Fields(tt.line)); got != tt.want {
+			t.Errorf("%q: path = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestLoadOBJWithMTL(t *testing.T) {
+	obj := `
+mtllib missing.mtl materials.mtl
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+f 1 2 3
+usemtl blue
+f 1 3 4
+usemtl green
+f 1 2 4
+usemtl unknown
+f 2 3 4
+`
+	mtl := `
+newmtl green
+Kd 0 1 0
+newmtl blue
+Kd 0 0 1
+map_Kd blue.png
+`
+	fsys := fstest.MapFS{
+		"models/cube.obj":      {Data: []byte(obj)},
+		"models/materials.mtl": {Data: []byte(mtl)},
+		"models/blue.png":      {Data: pngBytes(t, color.RGBA{0, 0, 255, 255})},
+	}
+	mesh, err := LoadOBJFromFS(fsys, "models/cube.obj")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(mesh.Materials) != 2 {
+		t.Fatalf("materials = %d, want 2", len(mesh.Materials))
+	}
+	want := []int{-1, 1, 0, -1}
+	for i, w := range want {
+		if got := mesh.GetFaceMaterial(i); got != w {
+			t.Errorf("face %d material = %d, want %d", i, got, w)
+		}
+	}
+	if mesh.Materials[1].BaseMap == nil {
+		t.Error("blue texture not loaded")
+	}
+	// Without a filesystem the libraries are ignored
+	mesh, err = NewOBJLoader().Load(strings.NewReader(obj), "reader")
+	if err != nil || len(mesh.Materials) != 0 || mesh.GetFaceMaterial(1) != -1 {
+		t.Errorf("reader load: err %v, materials %d", err, len(mesh.Materials))
+	}
+}
@@ -2,10 +2,13 @@ package models
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
+	"path"
 	"strconv"
 	"strings"
 
@@ -27,30 +30,62 @@ func NewOBJLoader() *OBJLoader {
 	}
 }
 
-// LoadFile loads an OBJ file from disk.
+// LoadFile loads an OBJ file from disk, with its material libraries.
 func (l *OBJLoader) LoadFile(path string) (*Mesh, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open OBJ file: %w", err)
 	}
 	defer f.Close()
-	return l.Load(f, path)
+	fsys, _ := fsForPath(path)
+	return l.load(f, path, fsys, ".")
 }
 
-// Load parses an OBJ from a reader.
+// LoadFromFS loads an OBJ file from an fs.FS, with its material libraries
+// and their textures resolved relative to the OBJ file.
+func (l *OBJLoader) LoadFromFS(fsys fs.FS, objPath string) (*Mesh, error) {
+	data, err := fs.ReadFile(fsys, objPath)
+	if err != nil {
+		return nil, fmt.Errorf("read OBJ file: %w", err)
+	}
+	return l.load(bytes.NewReader(data), objPath, fsys, path.Dir(objPath))
+}
+
+// Load parses an OBJ from a reader. Material libraries (mtllib) cannot be
+// resolved from a reader and are ignored, see LoadFromFS.
+func (l *OBJLoader) Load(r io.Reader, name string) (*Mesh, error) {
+	return l.load(r, name, nil, "")
+}
+
+// load parses an OBJ, reading its material libraries from fsys relative to
+// dir when fsys is set.
 //
 //nolint:gocognit,gocyclo,funlen // inherited code.
-func (l *OBJLoader) Load(r io.Reader, name string) (*Mesh, error) {
+func (l *OBJLoader) load(r io.Reader, name string, fsys fs.FS, dir string) (*Mesh, error) {
 	mesh := NewMesh(name)
 	// Temporary storage for OBJ data (1-indexed in OBJ format)
 	var positions []math3d.Vec3
 	var normals []math3d.Vec3
 	var uvs []math3d.Vec2
 	// Map to deduplicate vertices (OBJ can have different indices for pos/uv/normal)
+	// Vertices are also split by smoothing group when normals are generated
 	type vertexKey struct {
-		pos, uv, normal int
+		pos, uv, normal, smooth int
 	}
 	vertexMap := make(map[vertexKey]int)
+	// Materials by name; faces reference usemtl names, resolved at the end
+	// since libraries may be loaded after their first use
+	materialIndex := make(map[string]int)
+	var usedNames []string
+	var faceNames []int
+	currentName := -1
+	// Objects and groups, recorded lazily so that empty ones are skipped
+	groupIndex := make(map[FaceGroup]int)
+	var group FaceGroup
+	currentGroup := -1
+	// Smoothing group (s), 0 for flat; polygons count faces for flat ones
+	smoothing, polygon := 0, 0
+	usesSmoothing := false
 	scanner := bufio.NewScanner(r)
 	lineNum := 0
 	for scanner.Scan() {
@@ -116,6 +151,16 @@ func (l *OBJLoader) Load(r io.Reader, name string) (*Mesh, error) {
 			if len(fields) < 4 {
 				return nil, fmt.Errorf("line %d: face needs at least 3 vertices", lineNum)
 			}
+			if currentGroup < 0 {
+				idx, ok := groupIndex[group]
+				if !ok {
+					idx = len(mesh.Groups)
+					groupIndex[group] = idx
+					mesh.Groups = append(mesh.Groups, group)
+				}
+				currentGroup = idx
+			}
+			polygon++
 			// Parse face vertices
 			var faceVerts []int
 			for i := 1; i < len(fields); i++ {
@@ -132,7 +177,14 @@ func (l *OBJLoader) Load(r io.Reader, name string) (*Mesh, error) {
 					return nil, fmt.Errorf("line %d: position index %d out of range", lineNum, posIdx+1)
 				}
 				// Create or reuse vertex
-				key := vertexKey{posIdx, uvIdx, normalIdx}
+				key := vertexKey{posIdx, uvIdx, normalIdx, 0}
+				switch {
+				case normalIdx >= 0 || !usesSmoothing:
+				case smoothing != 0:
+					key.smooth = smoothing
+				default:
+					key.smooth = -polygon // flat: own vertices
+				}
 				vertIdx, exists := vertexMap[key]
 				if !exists {
 					vert := MeshVertex{
@@ -150,19 +202,56 @@ func (l *OBJLoader) Load(r io.Reader, name string) (*Mesh, error) {
 				}
 				faceVerts = append(faceVerts, vertIdx)
 			}
-			// Triangulate (fan triangulation for convex polygons)
+			// Triangulate: fan for triangles and quads, ear clipping for
+			// larger (possibly concave) polygons
 			// Note: OBJ uses CCW winding for front-facing, but our engine uses CW
 			// (due to Y-flip in screen space), so we reverse the winding here
-			for i := 1; i < len(faceVerts)-1; i++ {
+			for _, tri := range triangulateFace(mesh, faceVerts) {
 				mesh.Faces = append(mesh.Faces, Face{
-					V: [3]int{faceVerts[0], faceVerts[i+1], faceVerts[i]}, // swapped i and i+1
+					V:     [3]int{tri[0], tri[2], tri[1]}, // swapped
+					Group: currentGroup,
 				})
+				faceNames = append(faceNames, currentName)
+			}
+		case "o": // Object, starts without a group
+			group = FaceGroup{Object: strings.Join(fields[1:], " ")}
+			currentGroup = -1
+		case "g": // Group(s) within the object
+			group.Name = strings.Join(fields[1:], " ")
+			if group.Name == "" {
+				group.Name = "default"
+			}
+			currentGroup = -1
+		case "mtllib": // Material libraries, missing ones are ignored
+			if fsys == nil {
+				continue
+			}
+			for _, lib := range fields[1:] {
+				materials, err := LoadMTLFromFS(fsys, path.Join(dir, strings.ReplaceAll(lib, `\`, "/")))
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				if err != nil {
+					return nil, fmt.Errorf("line %d: mtllib %s: %w", lineNum, lib, err)
+				}
+				for _, m := range materials {
+					materialIndex[m.Name] = len(mesh.Materials)
+					mesh.Materials = append(mesh.Materials, m)
+				}
 			}
-		case "o", "g": // Object/group name (use as mesh name)
-			if len(fields) > 1 {
-				mesh.Name = fields[1]
+		case "usemtl": // Material of the following faces
+			currentName = len(usedNames)
+			usedNames = append(usedNames, strings.Join(fields[1:], " "))
+		case "s": // Smoothing group, "off" or 0 for flat shading
+			usesSmoothing = true
+			smoothing = 0
+			if len(fields) > 1 && fields[1] != "off" {
+				n, err := strconv.Atoi(fields[1])
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid smoothing group: %w", lineNum, err)
+				}
+				smoothing = n
 			}
-		case "mtllib", "usemtl", "s": // Material library, material use, smoothing - ignore for now
 		default:
 			// Ignore unknown directives
 		}
@@ -170,11 +259,26 @@ func (l *OBJLoader) Load(r io.Reader, name string) (*Mesh, error) {
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("error reading OBJ: %w", err)
 	}
+	// Resolve face materials, -1 for none or unknown
+	for i, n := range faceNames {
+		mesh.Faces[i].Material = -1
+		if n < 0 {
+			continue
+		}
+		if m, ok := materialIndex[usedNames[n]]; ok {
+			mesh.Faces[i].Material = m
+		}
+	}
+	// A single unnamed group is no grouping
+	if len(mesh.Groups) == 1 && mesh.Groups[0] == (FaceGroup{}) {
+		mesh.Groups = nil
+	}
 	// Calculate bounds
 	mesh.CalculateBounds()
-	// Calculate normals if needed
+	// Calculate normals if needed. With smoothing groups, vertices are split
+	// per group (and per flat polygon), so averaging smooths within groups.
 	if l.CalculateNormals && len(normals) == 0 {
-		if l.SmoothNormals {
+		if l.SmoothNormals || usesSmoothing {
 			mesh.CalculateSmoothNormals()
 		} else {
 			mesh.CalculateNormals()
@@ -183,6 +287,23 @@ func (l *OBJLoader) Load(r io.Reader, name string) (*Mesh, error) {
 	return mesh, nil
 }
 
+// triangulateFace returns the triangles of an OBJ face, as mesh vertex
+// indices with the face's winding.
+func triangulateFace(mesh *Mesh, faceVerts []int) [][3]int {
+	if len(faceVerts) <= 4 {
+		return fanTriangles(faceVerts)
+	}
+	pts := make([]math3d.Vec3, len(faceVerts))
+	for i, v := range faceVerts {
+		pts[i] = mesh.Vertices[v].Position
+	}
+	tris := TriangulatePolygon(pts)
+	for i, t := range tris {
+		tris[i] = [3]int{faceVerts[t[0]], faceVerts[t[1]], faceVerts[t[2]]}
+	}
+	return tris
+}
+
 // parseFaceVertex parses a face vertex in format: v, v/vt, v/vt/vn, or v//vn
 // Returns 1-indexed values (0 means not specified).
 func parseFaceVertex(s string) (pos, uv, normal int, err error) {
@@ -235,9 +356,5 @@ func LoadOBJSmooth(path string) (*Mesh, error) {
 
 // LoadOBJFromFS loads an OBJ file from a filesystem interface.
 func LoadOBJFromFS(fsys fs.FS, path string) (*Mesh, error) {
-	data, err := fs.ReadFile(fsys, path)
-	if err != nil {
-		return nil, fmt.Errorf("read OBJ file: %w", err)
-	}
-	return NewOBJLoader().Load(strings.NewReader(string(data)), path)
+	return NewOBJLoader().LoadFromFS(fsys, path)
 }
@@ -136,3 +136,110 @@ func TestMeshClone(t *testing.T) {
 		t.Error("clone was affected by original modification")
 	}
 }
+
+func TestLoadOBJGroups(t *testing.T) {
+	obj := `
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+f 1 2 3
+o car
+g body
+f 1 3 4
+g wheel left
+f 1 2 4
+g body
+f 2 3 4
+o lamp
+f 1 2 3
+`
+	mesh, err := NewOBJLoader().Load(strings.NewReader(obj), "car.obj")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if mesh.Name != "car.obj" {
+		t.Errorf("mesh name = %q, want the file name", mesh.Name)
+	}
+	wantGroups := []FaceGroup{{}, {Name: "body", Object: "car"}, {Name: "wheel left", Object: "car"}, {Object: "lamp"}}
+	if len(mesh.Groups) != len(wantGroups) {
+		t.Fatalf("groups = %+v, want %+v", mesh.Groups, wantGroups)
+	}
+	for i, g := range wantGroups {
+		if mesh.Groups[i] != g {
+			t.Errorf("group %d = %+v, want %+v", i, mesh.Groups[i], g)
+		}
+	}
+	for i, want := range []int{0, 1, 2, 1, 3} {
+		if mesh.Faces[i].Group != want {
+			t.Errorf("face %d group = %d, want %d", i, mesh.Faces[i].Group, want)
+		}
+	}
+	scene := mesh.GroupScene()
+	if scene == nil || len(scene.Meshes) != 4 || len(scene.Roots) != 3 {
+		t.Fatalf("scene = %+v", scene)
+	}
+	var names []string
+	scene.Walk(func(node, depth int) {
+		names = append(names, strings.Repeat(" ", depth)+scene.Nodes[node].Name)
+	})
+	if got, want := strings.Join(names, "|"), "default|car| body| wheel left|lamp| lamp"; got != want {
+		t.Errorf("tree = %q, want %q", got, want)
+	}
+	body := scene.Meshes[scene.Nodes[2].Mesh]
+	if body.TriangleCount() != 2 || body.VertexCount() != 4 {
+		t.Errorf("body: %d triangles, %d vertices", body.TriangleCount(), body.VertexCount())
+	}
+	if scene.TriangleCount() != mesh.TriangleCount() {
+		t.Errorf("scene triangles = %d, want %d", scene.TriangleCount(), mesh.TriangleCount())
+	}
+	// Ungrouped files keep a single mesh
+	plain, err := NewOBJLoader().Load(strings.NewReader("v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 3\n"), "tri")
+	if err != nil || plain.Groups != nil || plain.GroupScene() != nil {
+		t.Errorf("ungrouped: err %v, groups %+v", err, plain.Groups)
+	}
+}
+
+func TestLoadOBJSmoothingGroups(t *testing.T) {
+	// Two quads folded at 90° along the x axis edge (vertices 1 and 2)
+	const geometry = `
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+v 1 0 -1
+v 0 0 -1
+`
+	load := func(s1, s2 string) *Mesh {
+		t.Helper()
+		obj := geometry + "s " + s1 + "\nf 1 2 3 4\ns " + s2 + "\nf 6 5 2 1\n"
+		mesh, err := NewOBJLoader().Load(strings.NewReader(obj), "fold")
+		if err != nil {
+			t.Fatalf("load: %v", err)
+		}
+		return mesh
+	}
+	edgeNormals := func(m *Mesh) []math3d.Vec3 {
+		var ns []math3d.Vec3
+		for _, v := range m.Vertices {
+			if v.Position == math3d.V3(1, 0, 0) {
+				ns = append(ns, v.Normal)
+			}
+		}
+		return ns
+	}
+	// Face normals follow the engine's reversed winding
+	first, second := math3d.V3(0, 0, -1), math3d.V3(0, 1, 0)
+	// Same group: one shared vertex averaging its three triangles
+	ns := edgeNormals(load("1", "1"))
+	if len(ns) != 1 || ns[0].Sub(first.Add(second.Scale(2)).Normalize()).Len() > 1e-9 {
+		t.Errorf("same group normals = %v", ns)
+	}
+	// Different groups or flat: split vertices with the face normals
+	for _, groups := range [][2]string{{"1", "2"}, {"off", "0"}} {
+		ns = edgeNormals(load(groups[0], groups[1]))
+		if len(ns) != 2 || ns[0].Sub(first).Len() > 1e-9 || ns[1].Sub(second).Len() > 1e-9 {
+			t.Errorf("groups %v normals = %v", groups, ns)
+		}
+	}
+}
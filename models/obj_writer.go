@@ -0,0 +1,177 @@
+package models
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+// WriteOBJ writes a mesh as a Wavefront OBJ: positions, then UVs and normals
+// when the mesh has them, and its faces, lines and points with their objects
+// and groups ("o", "g") and materials ("usemtl"). A non empty mtlLib is
+// referenced with mtllib; see WriteMTL for the library itself.
+func WriteOBJ(w io.Writer, mesh *Mesh, mtlLib string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %s\n", mesh.Name)
+	if mtlLib != "" && len(mesh.Materials) > 0 {
+		fmt.Fprintf(bw, "mtllib %s\n", mtlLib)
+	}
+	hasUV, hasNormal := mesh.hasVertexUVs(), mesh.hasVertexNormals()
+	for i := range mesh.Vertices {
+		p := mesh.Vertices[i].Position
+		fmt.Fprintf(bw, "v %g %g %g\n", p.X, p.Y, p.Z)
+	}
+	if hasUV {
+		for i := range mesh.Vertices {
+			uv := mesh.Vertices[i].UV
+			fmt.Fprintf(bw, "vt %g %g\n", uv.X, uv.Y)
+		}
+	}
+	if hasNormal {
+		for i := range mesh.Vertices {
+			n := mesh.Vertices[i].Normal
+			fmt.Fprintf(bw, "vn %g %g %g\n", n.X, n.Y, n.Z)
+		}
+	}
+	names := objMaterialNames(mesh.Materials)
+	group, material := -1, -1
+	object := ""
+	// state emits the group and material statements of a primitive
+	state := func(g, m int) {
+		if g != group && g >= 0 && g < len(mesh.Groups) {
+			fg := mesh.Groups[g]
+			if fg.Object != "" && fg.Object != object {
+				object = fg.Object
+				fmt.Fprintf(bw, "o %s\n", object)
+			}
+			fmt.Fprintf(bw, "g %s\n", fg.Name)
+			group = g
+		}
+		if m != material && m >= 0 && m < len(names) {
+			fmt.Fprintf(bw, "usemtl %s\n", names[m])
+			material = m
+		}
+	}
+	for _, f := range mesh.Faces {
+		state(f.Group, f.Material)
+		fmt.Fprint(bw, "f")
+		for _, v := range fileTriangle(f) {
+			v++ // 1-based, with the same index for each attribute
+			switch {
+			case hasUV && hasNormal:
+				fmt.Fprintf(bw, " %d/%d/%d", v, v, v)
+			case hasUV:
+				fmt.Fprintf(bw, " %d/%d", v, v)
+			case hasNormal:
+				fmt.Fprintf(bw, " %d//%d", v, v)
+			default:
+				fmt.Fprintf(bw, " %d", v)
+			}
+		}
+		fmt.Fprint(bw, "\n")
+	}
+	for _, l := range mesh.Lines {
+		state(group, l.Material)
+		fmt.Fprintf(bw, "l %d %d\n", l.V[0]+1, l.V[1]+1)
+	}
+	for _, p := range mesh.Points {
+		state(group, p.Material)
+		fmt.Fprintf(bw, "p %d\n", p.V+1)
+	}
+	return bw.Flush()
+}
+
+// WriteMTL writes materials as an MTL library, with the names WriteOBJ uses.
+// textures holds the file name of each material's base color texture, ""
+// (or a short slice) for none.
+func WriteMTL(w io.Writer, materials []Material, textures []string) error {
+	bw := bufio.NewWriter(w)
+	for i, name := range objMaterialNames(materials) {
+		m := &materials[i]
+		fmt.Fprintf(bw, "newmtl %s\n", name)
+		fmt.Fprintf(bw, "Kd %g %g %g\n", m.BaseColor[0], m.BaseColor[1], m.BaseColor[2])
+		if m.Ambient != [3]float64{} {
+			fmt.Fprintf(bw, "Ka %g %g %g\n", m.Ambient[0], m.Ambient[1], m.Ambient[2])
+		}
+		if m.Specular != [3]float64{} {
+			fmt.Fprintf(bw, "Ks %g %g %g\n", m.Specular[0], m.Specular[1], m.Specular[2])
+		}
+		if m.Emissive != [3]float64{} {
+			fmt.Fprintf(bw, "Ke %g %g %g\n", m.Emissive[0], m.Emissive[1], m.Emissive[2])
+		}
+		shininess := m.Shininess
+		if shininess == 0 && m.Roughness > 0 && m.Roughness < 1 {
+			// Inverse of the loader's roughness conversion
+			shininess = math.Min(1000, 2/(m.Roughness*m.Roughness)-2)
+		}
+		if shininess > 0 {
+			fmt.Fprintf(bw, "Ns %g\n", shininess)
+		}
+		if m.BaseColor[3] < 1 {
+			fmt.Fprintf(bw, "d %g\n", m.BaseColor[3])
+		}
+		if i < len(textures) && textures[i] != "" {
+			fmt.Fprintf(bw, "map_Kd %s\n", textures[i])
+		}
+		fmt.Fprint(bw, "\n")
+	}
+	return bw.Flush()
+}
+
+// SaveOBJ writes a mesh to an OBJ file with, when it has materials, an MTL
+// library of the same name and PNG files for the base color textures.
+func SaveOBJ(path string, mesh *Mesh) error {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	dir := filepath.Dir(path)
+	mtlLib := ""
+	if len(mesh.Materials) > 0 {
+		mtlLib = base + ".mtl"
+		textures := make([]string, len(mesh.Materials))
+		saved := make(map[image.Image]string)
+		for i := range mesh.Materials {
+			img := mesh.Materials[i].BaseMap
+			if img == nil {
+				continue
+			}
+			name, ok := saved[img]
+			if !ok {
+				name = fmt.Sprintf("%s_%d.png", base, len(saved))
+				if err := writeFile(filepath.Join(dir, name), func(w io.Writer) error { return png.Encode(w, img) }); err != nil {
+					return err
+				}
+				saved[img] = name
+			}
+			textures[i] = name
+		}
+		err := writeFile(filepath.Join(dir, mtlLib), func(w io.Writer) error {
+			return WriteMTL(w, mesh.Materials, textures)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return writeFile(path, func(w io.Writer) error { return WriteOBJ(w, mesh, mtlLib) })
+}
+
+// objMaterialNames returns unique, single spaced names for materials.
+func objMaterialNames(materials []Material) []string {
+	names := make([]string, len(materials))
+	used := make(map[string]bool)
+	for i := range materials {
+		name := strings.Join(strings.Fields(materials[i].Name), " ")
+		if name == "" {
+			name = fmt.Sprintf("material_%d", i)
+		}
+		for used[name] {
+			name = fmt.Sprintf("%s_%d", name, i)
+		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
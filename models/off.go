@@ -0,0 +1,285 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// OFFLoader loads Object File Format files (OFF, COFF, NOFF, STOFF and their
+// combinations), as used by the Princeton Shape Benchmark and ModelNet.
+type OFFLoader struct {
+	// Options
+	SmoothNormals bool // If true, average normals per-vertex when the file has none
+}
+
+// NewOFFLoader creates a new OFF loader with default settings (smooth
+// normals).
+func NewOFFLoader() *OFFLoader {
+	return &OFFLoader{SmoothNormals: true}
+}
+
+// offHeader is the vertex layout declared by an OFF keyword.
+type offHeader struct {
+	uv, colors, normals bool
+}
+
+// LoadFile loads an OFF file from disk.
+func (l *OFFLoader) LoadFile(path string) (*Mesh, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OFF file: %w", err)
+	}
+	return l.LoadBytes(data, path)
+}
+
+// Load parses an OFF from a reader.
+func (l *OFFLoader) Load(r io.Reader, name string) (*Mesh, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OFF data: %w", err)
+	}
+	return l.LoadBytes(data, name)
+}
+
+// LoadBytes parses an ASCII OFF from a byte slice. Vertices may have normals
+// (NOFF), colors (COFF) and UVs (STOFF); faces are polygons, triangulated
+// like OBJ ones, with an optional color that becomes a Material. Colors are
+// 0-255 integers or 0-1 floats. Without faces the vertices are loaded as a
+// point cloud.
+func (l *OFFLoader) LoadBytes(data []byte, name string) (*Mesh, error) {
+	lines := offLines(data)
+	if len(lines) == 0 {
+		return nil, errors.New("not an OFF file")
+	}
+	header, rest, err := parseOFFKeyword(lines[0])
+	if err != nil {
+		return nil, err
+	}
+	lines = lines[1:]
+	// ModelNet files put the counts on the keyword line
+	if len(rest) == 0 {
+		if len(lines) == 0 {
+			return nil, errors.New("missing OFF counts")
+		}
+		rest, lines = lines[0], lines[1:]
+	}
+	if len(rest) < 2 {
+		return nil, errors.New("invalid OFF counts")
+	}
+	numVerts, errV := strconv.Atoi(rest[0])
+	numFaces, errF := strconv.Atoi(rest[1])
+	if errV != nil || errF != nil || numVerts < 0 || numFaces < 0 {
+		return nil, fmt.Errorf("invalid OFF counts %v", rest)
+	}
+	if numVerts > len(lines) || numFaces > len(lines)-numVerts {
+		return nil, fmt.Errorf("counts %d vertices, %d faces exceed the file size", numVerts, numFaces)
+	}
+	mesh := NewMesh(name)
+	mesh.VertexColors = header.colors
+	mesh.Vertices = make([]MeshVertex, numVerts)
+	for i := range numVerts {
+		if err := parseOFFVertex(lines[i], header, &mesh.Vertices[i]); err != nil {
+			return nil, fmt.Errorf("vertex %d: %w", i, err)
+		}
+	}
+	if err := readOFFFaces(lines[numVerts:numVerts+numFaces], mesh); err != nil {
+		return nil, err
+	}
+	if len(mesh.Faces) == 0 {
+		// Point cloud
+		mesh.Points = make([]Point, len(mesh.Vertices))
+		for i := range mesh.Points {
+			mesh.Points[i] = Point{V: i, Material: -1}
+		}
+	}
+	mesh.CalculateBounds()
+	if len(mesh.Faces) > 0 && !header.normals {
+		if l.SmoothNormals {
+			mesh.CalculateSmoothNormals()
+		} else {
+			mesh.CalculateNormals()
+		}
+	}
+	return mesh, nil
+}
+
+// offLines splits an OFF into the fields of its lines, without comments and
+// blank lines.
+func offLines(data []byte) [][]string {
+	var lines [][]string
+	for line := range bytes.Lines(data) {
+		if i := bytes.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if fields := strings.Fields(string(line)); len(fields) > 0 {
+			lines = append(lines, fields)
+		}
+	}
+	return lines
+}
+
+// parseOFFKeyword parses the [ST][C][N]OFF keyword line, returning the
+// fields that follow the keyword.
+func parseOFFKeyword(fields []string) (offHeader, []string, error) {
+	var h offHeader
+	keyword := fields[0]
+	k := keyword
+	if rest, ok := strings.CutPrefix(k, "ST"); ok {
+		h.uv, k = true, rest
+	}
+	if rest, ok := strings.CutPrefix(k, "C"); ok {
+		h.colors, k = true, rest
+	}
+	if rest, ok := strings.CutPrefix(k, "N"); ok {
+		h.normals, k = true, rest
+	}
+	rest, ok := strings.CutPrefix(k, "OFF")
+	switch {
+	case !ok && strings.Contains(keyword, "OFF"):
+		return h, nil, fmt.Errorf("unsupported OFF variant %q", keyword)
+	case !ok:
+		return h, nil, errors.New("not an OFF file")
+	case len(fields) > 1 && fields[1] == "BINARY":
+		return h, nil, errors.New("binary OFF is not supported")
+	}
+	tail := fields[1:]
+	if rest != "" {
+		tail = append([]string{rest}, tail...)
+	}
+	return h, tail, nil
+}
+
+// parseOFFVertex parses "x y z [nx ny nz] [r g b [a]] [s t]".
+func parseOFFVertex(fields []string, h offHeader, v *MeshVertex) error {
+	values, err := parseOFFFloats(fields)
+	if err != nil {
+		return err
+	}
+	want := 3
+	if h.normals {
+		want += 3
+	}
+	if h.uv {
+		want += 2
+	}
+	if len(values) < want {
+		return fmt.Errorf("expected %d values, got %d", want, len(values))
+	}
+	v.Position = math3d.V3(values[0], values[1], values[2])
+	values, fields = values[3:], fields[3:]
+	if h.normals {
+		v.Normal = math3d.V3(values[0], values[1], values[2]).Normalize()
+		values, fields = values[3:], fields[3:]
+	}
+	if h.uv {
+		v.UV = math3d.V2(values[len(values)-2], values[len(values)-1])
+		values, fields = values[:len(values)-2], fields[:len(fields)-2]
+	}
+	if h.colors {
+		if len(values) != 3 && len(values) != 4 {
+			return fmt.Errorf("expected 3 or 4 color values, got %d", len(values))
+		}
+		v.Color = offColor(fields, values)
+	}
+	return nil
+}
+
+// readOFFFaces reads "n i1 ... in [r g b [a]]" faces. Each distinct face
+// color becomes a material.
+func readOFFFaces(lines [][]string, mesh *Mesh) error {
+	materials := make(map[color.RGBA]int)
+	var faceVerts []int
+	for i, fields := range lines {
+		n, err := strconv.Atoi(fields[0])
+		if err != nil || n < 0 || n > len(fields)-1 {
+			return fmt.Errorf("face %d: invalid vertex count %q", i, fields[0])
+		}
+		faceVerts = faceVerts[:0]
+		for _, f := range fields[1 : 1+n] {
+			idx, err := strconv.Atoi(f)
+			if err != nil || idx < 0 || idx >= len(mesh.Vertices) {
+				return fmt.Errorf("face %d: invalid vertex index %q", i, f)
+			}
+			faceVerts = append(faceVerts, idx)
+		}
+		if n < 3 {
+			continue
+		}
+		material := -1
+		// A single value is a color map index, which has no standard map
+		if colorFields := fields[1+n:]; len(colorFields) >= 3 {
+			values, err := parseOFFFloats(colorFields[:min(4, len(colorFields))])
+			if err != nil {
+				return fmt.Errorf("face %d: %w", i, err)
+			}
+			c := offColor(colorFields, values)
+			m, ok := materials[c]
+			if !ok {
+				m = len(mesh.Materials)
+				materials[c] = m
+				mesh.Materials = append(mesh.Materials, Material{
+					Name:      fmt.Sprintf("color #%02x%02x%02x%02x", c.R, c.G, c.B, c.A),
+					BaseColor: [4]float64{float64(c.R) / 255, float64(c.G) / 255, float64(c.B) / 255, float64(c.A) / 255},
+					Roughness: 1,
+				})
+			}
+			material = m
+		}
+		// OFF polygons are counter clockwise: reverse the winding
+		for _, tri := range triangulateFace(mesh, faceVerts) {
+			mesh.Faces = append(mesh.Faces, Face{V: [3]int{tri[0], tri[2], tri[1]}, Material: material})
+		}
+	}
+	return nil
+}
+
+// parseOFFFloats parses the numbers of fields.
+func parseOFFFloats(fields []string) ([]float64, error) {
+	values := make([]float64, len(fields))
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q", f)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// offColor converts 3 or 4 color values, 0-255 integers when none of fields
+// has a decimal point or exponent, 0-1 floats otherwise.
+func offColor(fields []string, values []float64) color.RGBA {
+	scale := 255.0
+	if !strings.ContainsAny(strings.Join(fields[:len(values)], ""), ".eE") {
+		scale = 1
+	}
+	c := [4]uint8{3: 255}
+	for i, v := range values {
+		c[i] = uint8(math.Round(math.Max(0, math.Min(255, v*scale))))
+	}
+	return color.RGBA{c[0], c[1], c[2], c[3]}
+}
+
+// LoadOFF is a convenience function to load an OFF file with default settings.
+func LoadOFF(path string) (*Mesh, error) {
+	return NewOFFLoader().LoadFile(path)
+}
+
+// LoadOFFFromFS loads an OFF file from a filesystem interface.
+func LoadOFFFromFS(fsys fs.FS, path string) (*Mesh, error) {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("read OFF file: %w", err)
+	}
+	return NewOFFLoader().LoadBytes(data, path)
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"image/color"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+func TestLoadOFF(t *testing.T) {
+	data := `OFF
+# a unit square and a triangle
+5 2 0
+0 0 0
+1 0 0
+1 1 0
+0 1 0
+0.5 0.5 1
+4 0 1 2 3 255 0 0
+3 0 1 4 1.0 0.0 0.0 1.0
+`
+	mesh, err := LoadOFFFromFS(fstest.MapFS{"shape.off": {Data: []byte(data)}}, "shape.off")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if mesh.VertexCount() != 5 || mesh.TriangleCount() != 3 || mesh.VertexColors {
+		t.Fatalf("vertices %d, triangles %d, colors %v", mesh.VertexCount(), mesh.TriangleCount(), mesh.VertexColors)
+	}
+	// Integer and float colors of the same red share a material
+	if len(mesh.Materials) != 1 || mesh.Materials[0].BaseColor != [4]float64{1, 0, 0, 1} {
+		t.Fatalf("materials %+v", mesh.Materials)
+	}
+	for _, f := range mesh.Faces {
+		if f.Material != 0 {
+			t.Errorf("face material %d", f.Material)
+		}
+	}
+	// Reversed winding for the engine, normals generated
+	f := mesh.Faces[0]
+	a, b, c := mesh.Vertices[f.V[0]].Position, mesh.Vertices[f.V[1]].Position, mesh.Vertices[f.V[2]].Position
+	if b.Sub(a).Cross(c.Sub(a)).Z >= 0 || mesh.Vertices[2].Normal.Len() == 0 {
+		t.Errorf("face %v not reversed or normals missing", f.V)
+	}
+}
+
+func TestLoadOFFVariants(t *testing.T) {
+	// ModelNet style counts on the keyword line
+	mesh, err := NewOFFLoader().LoadBytes([]byte("OFF3 1 0\n0 0 0\n1 0 0\n0 1 0\n3 0 1 2\n"), "modelnet.off")
+	if err != nil || mesh.TriangleCount() != 1 {
+		t.Fatalf("counts on the keyword line: %v", err)
+	}
+	// Normals, colors (with alpha) and texture coordinates
+	data := `STCNOFF
+3 1 0
+0 0 0  0 0 2  255 0 0 128  0 0
+1 0 0  0 0 1  0 255 0 255  1 0
+0 1 0  0 0 1  0 0 255 255  0 1
+3 0 1 2
+`
+	mesh, err = NewOFFLoader().LoadBytes([]byte(data), "full.off")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	v := mesh.Vertices[0]
+	if !mesh.VertexColors || v.Color != (color.RGBA{255, 0, 0, 128}) {
+		t.Errorf("color %v", v.Color)
+	}
+	assertVec3(t, "normal", v.Normal, math3d.V3(0, 0, 1))
+	if mesh.Vertices[2].UV != math3d.V2(0, 1) {
+		t.Errorf("uv %v", mesh.Vertices[2].UV)
+	}
+	// Point cloud
+	mesh, err = NewOFFLoader().LoadBytes([]byte("COFF\n2 0 0\n0 0 0 1.0 0.5 0\n1 1 1 0 0 1.0\n"), "cloud.off")
+	if err != nil || len(mesh.Points) != 2 || mesh.Vertices[0].Color != (color.RGBA{255, 128, 0, 255}) {
+		t.Fatalf("point cloud: %v %+v", err, mesh)
+	}
+}
+
+func TestLoadOFFErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"not off":      "PLY\n",
+		"4d":           "4OFF\n1 0 0\n0 0 0 0\n",
+		"binary":       "OFF BINARY\n",
+		"huge count":   "OFF\n1000000000 0 0\n",
+		"bad index":    "OFF\n3 1 0\n0 0 0\n1 0 0\n0 1 0\n3 0 1 3\n",
+		"short vertex": "NOFF\n1 0 0\n0 0 0\n",
+		"bad count":    "OFF\n3 1 0\n0 0 0\n1 0 0\n0 1 0\n4 0 1 2\n",
+	} {
+		if _, err := NewOFFLoader().LoadBytes([]byte(data), name); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,463 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// PLYLoader loads Stanford PLY files (ascii, binary_little_endian and
+// binary_big_endian).
+type PLYLoader struct {
+	// Options
+	SmoothNormals bool // If true, average normals per-vertex when the file has none
+}
+
+// NewPLYLoader creates a new PLY loader with default settings: smooth
+// normals, as PLY files are mostly scans with shared vertices.
+func NewPLYLoader() *PLYLoader {
+	return &PLYLoader{SmoothNormals: true}
+}
+
+// plyFormat is the encoding of a PLY body.
+type plyFormat int
+
+const (
+	plyASCII plyFormat = iota
+	plyLittleEndian
+	plyBigEndian
+)
+
+// plyType is a PLY scalar type, by size and kind.
+type plyType struct {
+	size     int
+	float    bool
+	unsigned bool
+}
+
+var plyTypes = map[string]plyType{
+	"char": {1, false, false}, "int8": {1, false, false},
+	"uchar": {1, false, true}, "uint8": {1, false, true},
+	"short": {2, false, false}, "int16": {2, false, false},
+	"ushort": {2, false, true}, "uint16": {2, false, true},
+	"int": {4, false, false}, "int32": {4, false, false},
+	"uint": {4, false, true}, "uint32": {4, false, true},
+	"float": {4, true, false}, "float32": {4, true, false},
+	"double": {8, true, false}, "float64": {8, true, false},
+}
+
+// plyProperty is a scalar or list property of an element.
+type plyProperty struct {
+	name  string
+	typ   plyType
+	list  bool
+	count plyType // Type of the list length
+}
+
+// plyElement is an element declaration of the header.
+type plyElement struct {
+	name  string
+	count int
+	props []plyProperty
+}
+
+// minSize returns the fewest body bytes an element instance can take.
+func (e *plyElement) minSize(format plyFormat) int {
+	if format == plyASCII {
+		return len(e.props) // one character per value
+	}
+	size := 0
+	for _, p := range e.props {
+		if p.list {
+			size += p.count.size
+		} else {
+			size += p.typ.size
+		}
+	}
+	return size
+}
+
+// plyReader reads the values of a PLY body.
+type plyReader struct {
+	format plyFormat
+	data   []byte
+	pos    int
+}
+
+// next reads one value of type typ.
+func (r *plyReader) next(typ plyType) (float64, error) {
+	if r.format == plyASCII {
+		for r.pos < len(r.data) && isPLYSpace(r.data[r.pos]) {
+			r.pos++
+		}
+		start := r.pos
+		for r.pos < len(r.data) && !isPLYSpace(r.data[r.pos]) {
+			r.pos++
+		}
+		if start == r.pos {
+			return 0, io.ErrUnexpectedEOF
+		}
+		v, err := strconv.ParseFloat(string(r.data[start:r.pos]), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid value: %w", err)
+		}
+		return v, nil
+	}
+	if r.pos+typ.size > len(r.data) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	b := r.data[r.pos : r.pos+typ.size]
+	r.pos += typ.size
+	var order binary.ByteOrder = binary.LittleEndian
+	if r.format == plyBigEndian {
+		order = binary.BigEndian
+	}
+	switch {
+	case typ.size == 1 && typ.unsigned:
+		return float64(b[0]), nil
+	case typ.size == 1:
+		return float64(int8(b[0])), nil //nolint:gosec // two's complement reinterpretation
+	case typ.size == 2 && typ.unsigned:
+		return float64(order.Uint16(b)), nil
+	case typ.size == 2:
+		return float64(int16(order.Uint16(b))), nil //nolint:gosec // two's complement reinterpretation
+	case typ.size == 4 && typ.float:
+		return float64(math.Float32frombits(order.Uint32(b))), nil
+	case typ.size == 4 && typ.unsigned:
+		return float64(order.Uint32(b)), nil
+	case typ.size == 4:
+		return float64(int32(order.Uint32(b))), nil //nolint:gosec // two's complement reinterpretation
+	default:
+		return math.Float64frombits(order.Uint64(b)), nil
+	}
+}
+
+func isPLYSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// LoadFile loads a PLY file from disk.
+func (l *PLYLoader) LoadFile(path string) (*Mesh, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PLY file: %w", err)
+	}
+	return l.LoadBytes(data, path)
+}
+
+// Load parses a PLY from a reader.
+func (l *PLYLoader) Load(r io.Reader, name string) (*Mesh, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PLY data: %w", err)
+	}
+	return l.LoadBytes(data, name)
+}
+
+// LoadBytes parses a PLY from a byte slice. Vertices may have normals (nx,
+// ny, nz), colors (red, green, blue, alpha) and UVs (s, t or u, v); faces are
+// polygons, triangulated like OBJ ones. Without faces the vertices are
+// loaded as a point cloud. Other elements and properties are skipped.
+func (l *PLYLoader) LoadBytes(data []byte, name string) (*Mesh, error) {
+	format, elements, body, err := parsePLYHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	r := &plyReader{format: format, data: body}
+	mesh := NewMesh(name)
+	hasNormals := false
+	hasFaces := false
+	for i := range elements {
+		e := &elements[i]
+		if minSize := e.minSize(format); minSize > 0 && e.count > (len(body)-r.pos)/minSize {
+			return nil, fmt.Errorf("element %s: count %d exceeds the file size", e.name, e.count)
+		}
+		switch e.name {
+		case "vertex":
+			hasNormals, err = readPLYVertices(r, e, mesh)
+		case "face":
+			hasFaces = e.count > 0
+			err = readPLYFaces(r, e, mesh)
+		default:
+			err = skipPLYElement(r, e)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("element %s: %w", e.name, err)
+		}
+	}
+	if !hasFaces {
+		// Point cloud
+		mesh.Points = make([]Point, len(mesh.Vertices))
+		for i := range mesh.Points {
+			mesh.Points[i] = Point{V: i, Material: -1}
+		}
+	}
+	mesh.CalculateBounds()
+	if hasFaces && !hasNormals {
+		if l.SmoothNormals {
+			mesh.CalculateSmoothNormals()
+		} else {
+			mesh.CalculateNormals()
+		}
+	}
+	return mesh, nil
+}
+
+// parsePLYHeader parses the header, returning the body that follows it.
+func parsePLYHeader(data []byte) (plyFormat, []plyElement, []byte, error) {
+	end := bytes.Index(data, []byte("end_header"))
+	if !bytes.HasPrefix(data, []byte("ply")) || end < 0 {
+		return 0, nil, nil, errors.New("not a PLY file")
+	}
+	body := data[end+len("end_header"):]
+	// The body starts after the header's line ending
+	if len(body) > 0 && body[0] == '\r' {
+		body = body[1:]
+	}
+	if len(body) > 0 && body[0] == '\n' {
+		body = body[1:]
+	}
+	var format plyFormat
+	var elements []plyElement
+	scanner := bufio.NewScanner(bytes.NewReader(data[:end]))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "format":
+			if len(fields) < 2 {
+				return 0, nil, nil, fmt.Errorf("header line %d: missing format", lineNum)
+			}
+			switch fields[1] {
+			case "ascii":
+				format = plyASCII
+			case "binary_little_endian":
+				format = plyLittleEndian
+			case "binary_big_endian":
+				format = plyBigEndian
+			default:
+				return 0, nil, nil, fmt.Errorf("header line %d: unsupported format %q", lineNum, fields[1])
+			}
+		case "element":
+			if len(fields) < 3 {
+				return 0, nil, nil, fmt.Errorf("header line %d: invalid element", lineNum)
+			}
+			count, err := strconv.Atoi(fields[2])
+			if err != nil || count < 0 {
+				return 0, nil, nil, fmt.Errorf("header line %d: invalid element count %q", lineNum, fields[2])
+			}
+			elements = append(elements, plyElement{name: fields[1], count: count})
+		case "property":
+			if len(elements) == 0 {
+				return 0, nil, nil, fmt.Errorf("header line %d: property outside of an element", lineNum)
+			}
+			prop, err := parsePLYProperty(fields)
+			if err != nil {
+				return 0, nil, nil, fmt.Errorf("header line %d: %w", lineNum, err)
+			}
+			e := &elements[len(elements)-1]
+			e.props = append(e.props, prop)
+		}
+	}
+	return format, elements, body, nil
+}
+
+// parsePLYProperty parses "property <type> <name>" or
+// "property list <count type> <item type> <name>".
+func parsePLYProperty(fields []string) (plyProperty, error) {
+	if len(fields) >= 5 && fields[1] == "list" {
+		count, okCount := plyTypes[fields[2]]
+		typ, okType := plyTypes[fields[3]]
+		if !okCount || !okType || count.float {
+			return plyProperty{}, fmt.Errorf("invalid list property types %s %s", fields[2], fields[3])
+		}
+		return plyProperty{name: fields[4], typ: typ, list: true, count: count}, nil
+	}
+	if len(fields) < 3 {
+		return plyProperty{}, errors.New("invalid property")
+	}
+	typ, ok := plyTypes[fields[1]]
+	if !ok {
+		return plyProperty{}, fmt.Errorf("unknown property type %s", fields[1])
+	}
+	return plyProperty{name: fields[2], typ: typ}, nil
+}
+
+// skipPLYElement reads past the instances of an element.
+func skipPLYElement(r *plyReader, e *plyElement) error {
+	var values []float64
+	for range e.count {
+		for _, p := range e.props {
+			var err error
+			if values, err = readPLYValues(r, p, values[:0]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readPLYValues appends the value of a scalar property, or the items of a
+// list property, to values.
+func readPLYValues(r *plyReader, p plyProperty, values []float64) ([]float64, error) {
+	if !p.list {
+		v, err := r.next(p.typ)
+		return append(values, v), err
+	}
+	n, err := r.next(p.count)
+	if err != nil {
+		return values, err
+	}
+	if n < 0 || int(n) > len(r.data)-r.pos {
+		return values, fmt.Errorf("invalid list length %v", n)
+	}
+	for range int(n) {
+		v, err := r.next(p.typ)
+		if err != nil {
+			return values, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// plyVertexSlots maps vertex property names to attribute slots.
+var plyVertexSlots = map[string]int{
+	"x": 0, "y": 1, "z": 2,
+	"nx": 3, "ny": 4, "nz": 5,
+	"red": 6, "green": 7, "blue": 8, "alpha": 9,
+	"r": 6, "g": 7, "b": 8, "a": 9,
+	"diffuse_red": 6, "diffuse_green": 7, "diffuse_blue": 8,
+	"s": 10, "t": 11, "u": 10, "v": 11,
+	"texture_u": 10, "texture_v": 11, "texture_s": 10, "texture_t": 11,
+}
+
+// readPLYVertices reads the vertex element, returning whether it has normals.
+func readPLYVertices(r *plyReader, e *plyElement, mesh *Mesh) (bool, error) {
+	slots := make([]int, len(e.props))
+	var present [12]bool
+	var colorScale [12]float64
+	for i, p := range e.props {
+		slot, ok := plyVertexSlots[p.name]
+		if !ok || p.list {
+			slot = -1
+		} else {
+			present[slot] = true
+			// Integer colors are 0 to the type's maximum, float ones 0-1
+			colorScale[slot] = 1
+			if !p.typ.float {
+				colorScale[slot] = 1 / float64(uint64(1)<<(8*p.typ.size)-1)
+			}
+		}
+		slots[i] = slot
+	}
+	if !present[0] || !present[1] || !present[2] {
+		return false, errors.New("missing x, y or z property")
+	}
+	hasNormals := present[3] && present[4] && present[5]
+	mesh.VertexColors = present[6] && present[7] && present[8]
+	mesh.Vertices = make([]MeshVertex, e.count)
+	var buf []float64
+	for i := range e.count {
+		values := [12]float64{9: 1} // opaque by default
+		for k, p := range e.props {
+			var err error
+			if buf, err = readPLYValues(r, p, buf[:0]); err != nil {
+				return false, fmt.Errorf("vertex %d: %w", i, err)
+			}
+			if slots[k] >= 0 {
+				values[slots[k]] = buf[0]
+			}
+		}
+		vert := &mesh.Vertices[i]
+		vert.Position = math3d.V3(values[0], values[1], values[2])
+		if hasNormals {
+			vert.Normal = math3d.V3(values[3], values[4], values[5]).Normalize()
+		}
+		vert.UV = math3d.V2(values[10], values[11])
+		if mesh.VertexColors {
+			c := func(slot int) uint8 {
+				scale := colorScale[slot]
+				if !present[slot] {
+					scale = 1
+				}
+				return uint8(math.Round(255 * math.Max(0, math.Min(1, values[slot]*scale))))
+			}
+			vert.Color = color.RGBA{c(6), c(7), c(8), c(9)}
+		}
+	}
+	return hasNormals, nil
+}
+
+// readPLYFaces reads the face element: its vertex_indices (or vertex_index,
+// or first) list property.
+func readPLYFaces(r *plyReader, e *plyElement, mesh *Mesh) error {
+	indices := -1
+	for i, p := range e.props {
+		if p.list && (p.name == "vertex_indices" || p.name == "vertex_index" || indices < 0) {
+			indices = i
+		}
+	}
+	if indices < 0 {
+		return errors.New("no vertex index list property")
+	}
+	var list, faceVerts []int
+	var values []float64
+	for i := range e.count {
+		for k, p := range e.props {
+			var err error
+			if values, err = readPLYValues(r, p, values[:0]); err != nil {
+				return fmt.Errorf("face %d: %w", i, err)
+			}
+			if k != indices {
+				continue
+			}
+			list = list[:0]
+			for _, v := range values {
+				idx := int(v)
+				if idx < 0 || idx >= len(mesh.Vertices) {
+					return fmt.Errorf("face %d: vertex index %d out of range", i, idx)
+				}
+				list = append(list, idx)
+			}
+		}
+		if len(list) < 3 {
+			continue
+		}
+		faceVerts = append(faceVerts[:0], list...)
+		// PLY polygons are counter clockwise like OBJ ones: reverse the winding
+		for _, tri := range triangulateFace(mesh, faceVerts) {
+			mesh.Faces = append(mesh.Faces, Face{V: [3]int{tri[0], tri[2], tri[1]}, Material: -1})
+		}
+	}
+	return nil
+}
+
+// LoadPLY is a convenience function to load a PLY file with default settings.
+func LoadPLY(path string) (*Mesh, error) {
+	return NewPLYLoader().LoadFile(path)
+}
+
+// LoadPLYFromFS loads a PLY file from a filesystem interface.
+func LoadPLYFromFS(fsys fs.FS, path string) (*Mesh, error) {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("read PLY file: %w", err)
+	}
+	return NewPLYLoader().LoadBytes(data, path)
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/color"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+const asciiPLY = `ply
+format ascii 1.0
+comment made by hand
+element vertex 4
+property float x
+property float y
+property float z
+property uchar red
+property uchar green
+property uchar blue
+property float s
+property float t
+element face 1
+property list uchar int vertex_indices
+property uchar flags
+element edge 0
+property int vertex1
+property int vertex2
+end_header
+0 0 0 255 0 0 0 0
+1 0 0 0 255 0 1 0
+1 1 0 0 0 255 1 1
+0 1 0 255 255 255 0 1
+4 0 1 2 3 7
+`
+
+func TestLoadPLYASCII(t *testing.T) {
+	mesh, err := LoadPLYFromFS(fstest.MapFS{"quad.ply": {Data: []byte(asciiPLY)}}, "quad.ply")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if mesh.VertexCount() != 4 || mesh.TriangleCount() != 2 || len(mesh.Points) != 0 {
+		t.Fatalf("vertices %d, triangles %d, points %d", mesh.VertexCount(), mesh.TriangleCount(), len(mesh.Points))
+	}
+	if !mesh.VertexColors || mesh.Vertices[1].Color != (color.RGBA{0, 255, 0, 255}) {
+		t.Errorf("vertex colors %v: %v", mesh.VertexColors, mesh.Vertices[1].Color)
+	}
+	if mesh.Vertices[2].UV != math3d.V2(1, 1) {
+		t.Errorf("uv = %v", mesh.Vertices[2].UV)
+	}
+	// Reversed winding for the engine, normals generated
+	f := mesh.Faces[0]
+	a, b, c := mesh.Vertices[f.V[0]].Position, mesh.Vertices[f.V[1]].Position, mesh.Vertices[f.V[2]].Position
+	if b.Sub(a).Cross(c.Sub(a)).Z >= 0 || mesh.Vertices[0].Normal.Len() == 0 {
+		t.Errorf("face %v not reversed or normals missing", f.V)
+	}
+}
+
+// binaryPLY encodes a point cloud with double positions, float normals,
+// ushort colors and an extra list property.
+func binaryPLY(order binary.ByteOrder, format string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("ply\nformat " + format + " 1.0\nelement vertex 2\n" +
+		"property double x\nproperty double y\nproperty double z\n" +
+		"property float nx\nproperty float ny\nproperty float nz\n" +
+		"property ushort red\nproperty ushort green\nproperty ushort blue\nproperty ushort alpha\n" +
+		"property list uchar short extra\nend_header\n")
+	for i := range 2 {
+		_ = binary.Write(&buf, order, [3]float64{float64(i), -2, 3.5})
+		_ = binary.Write(&buf, order, [3]float32{0, 0, 2})
+		_ = binary.Write(&buf, order, [4]uint16{65535, 0, 0, 65535})
+		buf.WriteByte(2)
+		_ = binary.Write(&buf, order, [2]int16{-1, 1})
+	}
+	return buf.Bytes()
+}
+
+func TestLoadPLYBinaryPointCloud(t *testing.T) {
+	for _, tt := range []struct {
+		format string
+		order  binary.ByteOrder
+	}{
+		{"binary_little_endian", binary.LittleEndian},
+		{"binary_big_endian", binary.BigEndian},
+	} {
+		mesh, err := NewPLYLoader().LoadBytes(binaryPLY(tt.order, tt.format), "cloud")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if mesh.TriangleCount() != 0 || len(mesh.Points) != 2 || mesh.Points[1].V != 1 {
+			t.Fatalf("%s: triangles %d, points %+v", tt.format, mesh.TriangleCount(), mesh.Points)
+		}
+		assertVec3(t, tt.format+" position", mesh.Vertices[1].Position, math3d.V3(1, -2, 3.5))
+		assertVec3(t, tt.format+" normal", mesh.Vertices[1].Normal, math3d.V3(0, 0, 1))
+		if mesh.Vertices[0].Color != (color.RGBA{255, 0, 0, 255}) {
+			t.Errorf("%s: color = %v", tt.format, mesh.Vertices[0].Color)
+		}
+	}
+}
+
+func TestLoadPLYErrors(t *testing.T) {
+	header := "ply\nformat ascii 1.0\nelement vertex 3\nproperty float x\nproperty float y\nproperty float z\n" +
+		"element face 1\nproperty list uchar int vertex_indices\nend_header\n"
+	tests := []struct {
+		name, data string
+	}{
+		{"not ply", "solid x\n"},
+		{"format", "ply\nformat xml 1.0\nend_header\n"},
+		{"huge count", "ply\nformat binary_little_endian 1.0\nelement vertex 1000000000\nproperty float x\nend_header\n"},
+		{"truncated", header + "0 0 0\n1 0 0\n"},
+		{"bad index", header + "0 0 0\n1 0 0\n0 1 0\n3 0 1 5\n"},
+		{"bad value", header + "0 0 0\n1 zero 0\n0 1 0\n3 0 1 2\n"},
+		{"no position", "ply\nformat ascii 1.0\nelement vertex 1\nproperty float x\nend_header\n1\n"},
+	}
+	for _, tt := range tests {
+		if _, err := NewPLYLoader().Load(strings.NewReader(tt.data), tt.name); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+	// Float colors are 0-1
+	data := "ply\nformat ascii 1.0\nelement vertex 1\nproperty float x\nproperty float y\nproperty float z\n" +
+		"property float red\nproperty float green\nproperty float blue\nend_header\n0 0 0 1 0.5 0\n"
+	mesh, err := NewPLYLoader().Load(strings.NewReader(data), "float colors")
+	if err != nil || mesh.Vertices[0].Color != (color.RGBA{255, 128, 0, 255}) {
+		t.Errorf("float colors: %v %v", err, mesh.Vertices[0].Color)
+	}
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// WritePLY writes a mesh as a binary little endian PLY: vertex positions,
+// with normals, colors (uchar RGBA) and UVs (s, t) when the mesh has them,
+// and triangle faces. A mesh without faces is written as a point cloud.
+func WritePLY(w io.Writer, mesh *Mesh) error {
+	if len(mesh.Vertices) > math.MaxInt32 {
+		return errors.New("too many vertices for PLY")
+	}
+	hasNormal, hasColor, hasUV := mesh.hasVertexNormals(), mesh.VertexColors, mesh.hasVertexUVs()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "ply\nformat binary_little_endian 1.0\ncomment %s\n", strings.Join(strings.Fields(mesh.Name), " "))
+	fmt.Fprintf(bw, "element vertex %d\nproperty float x\nproperty float y\nproperty float z\n", len(mesh.Vertices))
+	if hasNormal {
+		fmt.Fprint(bw, "property float nx\nproperty float ny\nproperty float nz\n")
+	}
+	if hasColor {
+		fmt.Fprint(bw, "property uchar red\nproperty uchar green\nproperty uchar blue\nproperty uchar alpha\n")
+	}
+	if hasUV {
+		fmt.Fprint(bw, "property float s\nproperty float t\n")
+	}
+	if len(mesh.Faces) > 0 {
+		fmt.Fprintf(bw, "element face %d\nproperty list uchar int vertex_indices\n", len(mesh.Faces))
+	}
+	fmt.Fprint(bw, "end_header\n")
+	buf := make([]byte, 0, 40)
+	putFloat := func(v float64) {
+		buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(float32(v)))
+	}
+	for i := range mesh.Vertices {
+		v := &mesh.Vertices[i]
+		buf = buf[:0]
+		putFloat(v.Position.X)
+		putFloat(v.Position.Y)
+		putFloat(v.Position.Z)
+		if hasNormal {
+			putFloat(v.Normal.X)
+			putFloat(v.Normal.Y)
+			putFloat(v.Normal.Z)
+		}
+		if hasColor {
+			buf = append(buf, v.Color.R, v.Color.G, v.Color.B, v.Color.A)
+		}
+		if hasUV {
+			putFloat(v.UV.X)
+			putFloat(v.UV.Y)
+		}
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	for _, f := range mesh.Faces {
+		buf = append(buf[:0], 3)
+		for _, v := range fileTriangle(f) {
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(v)) //nolint:gosec // vertex count checked above
+		}
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"math"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// volumeIntegrals integrates over the solid enclosed by the faces with the
+// divergence theorem, summing the signed tetrahedra between each face and the
+// origin. Returns the volume, its first moment (∫x dV) and its second moment
+// (∫x xᵀ dV).
+func (m *Mesh) volumeIntegrals() (volume float64, moment math3d.Vec3, second [3][3]float64) {
+	for _, f := range m.Faces {
+		// File winding, counter clockwise seen from outside (see faceVolume)
+		a := m.Vertices[f.V[0]].Position
+		b := m.Vertices[f.V[2]].Position
+		c := m.Vertices[f.V[1]].Position
+		det := a.Dot(b.Cross(c))
+		volume += det / 6
+		sum := a.Add(b).Add(c)
+		moment = moment.Add(sum.Scale(det / 24))
+		// Canonical tetrahedron covariance: det/120 (Σ p pᵀ + s sᵀ)
+		pa, pb, pc, s := vec3Array(a), vec3Array(b), vec3Array(c), vec3Array(sum)
+		for i := range 3 {
+			for j := range 3 {
+				second[i][j] += det / 120 * (pa[i]*pa[j] + pb[i]*pb[j] + pc[i]*pc[j] + s[i]*s[j])
+			}
+		}
+	}
+	return volume, moment, second
+}
+
+func vec3Array(v math3d.Vec3) [3]float64 {
+	return [3]float64{v.X, v.Y, v.Z}
+}
+
+// SignedVolume returns the volume enclosed by the mesh: positive when its
+// faces point outward, negative when it is inside out. It is only meaningful
+// for closed meshes (see Analyze).
+func (m *Mesh) SignedVolume() float64 {
+	volume := 0.0
+	for _, f := range m.Faces {
+		volume += m.faceVolume(f)
+	}
+	return volume
+}
+
+// SurfaceArea returns the total area of the faces.
+func (m *Mesh) SurfaceArea() float64 {
+	area := 0.0
+	for _, f := range m.Faces {
+		p0 := m.Vertices[f.V[0]].Position
+		area += m.Vertices[f.V[1]].Position.Sub(p0).Cross(m.Vertices[f.V[2]].Position.Sub(p0)).Len() / 2
+	}
+	return area
+}
+
+// Centroid returns the center of mass of the solid enclosed by the mesh, of
+// uniform density. Meshes without volume return the area weighted center of
+// their faces instead.
+func (m *Mesh) Centroid() math3d.Vec3 {
+	volume, moment, _ := m.volumeIntegrals()
+	if volume != 0 {
+		return moment.Scale(1 / volume)
+	}
+	var center math3d.Vec3
+	area := 0.0
+	for _, f := range m.Faces {
+		p0, p1, p2 := m.Vertices[f.V[0]].Position, m.Vertices[f.V[1]].Position, m.Vertices[f.V[2]].Position
+		a := p1.Sub(p0).Cross(p2.Sub(p0)).Len() / 2
+		center = center.Add(p0.Add(p1).Add(p2).Scale(a / 3))
+		area += a
+	}
+	if area == 0 {
+		return center
+	}
+	return center.Scale(1 / area)
+}
+
+// InertiaTensor returns the inertia tensor of the solid enclosed by the mesh
+// about its centroid, for a density of 1: multiply it by the density for the
+// actual tensor. Row i, column j is the product of inertia of axes i and j
+// (0 for X, 1 for Y, 2 for Z), the diagonal holding the moments of inertia.
+func (m *Mesh) InertiaTensor() [3][3]float64 {
+	volume, moment, second := m.volumeIntegrals()
+	var inertia [3][3]float64
+	if volume == 0 {
+		return inertia
+	}
+	// Second moment about the centroid
+	c := vec3Array(moment.Scale(1 / volume))
+	for i := range 3 {
+		for j := range 3 {
+			second[i][j] -= volume * c[i] * c[j]
+		}
+	}
+	trace := second[0][0] + second[1][1] + second[2][2]
+	for i := range 3 {
+		for j := range 3 {
+			inertia[i][j] = -second[i][j]
+		}
+		inertia[i][i] += trace
+	}
+	// Clean up the rounding noise of symmetric meshes
+	scale := math.Abs(trace)
+	for i := range 3 {
+		for j := range 3 {
+			if math.Abs(inertia[i][j]) < 1e-12*scale {
+				inertia[i][j] = 0
+			}
+		}
+	}
+	return inertia
+}
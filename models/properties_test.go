@@ -0,0 +1,76 @@
+package models
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// boxMesh returns a closed, outward box from the origin to size.
+func boxMesh(size math3d.Vec3) *Mesh {
+	mesh := NewMesh("box")
+	for i := range 8 {
+		p := math3d.V3(float64(i&1)*size.X, float64(i>>1&1)*size.Y, float64(i>>2&1)*size.Z)
+		mesh.Vertices = append(mesh.Vertices, MeshVertex{Position: p})
+	}
+	// Quads counter clockwise seen from outside, reversed into engine winding
+	for _, q := range [][4]int{{0, 2, 3, 1}, {4, 5, 7, 6}, {0, 1, 5, 4}, {2, 6, 7, 3}, {0, 4, 6, 2}, {1, 3, 7, 5}} {
+		mesh.Faces = append(mesh.Faces, Face{V: [3]int{q[0], q[2], q[1]}}, Face{V: [3]int{q[0], q[3], q[2]}})
+	}
+	return mesh
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestTetrahedronProperties(t *testing.T) {
+	mesh := tetrahedronMesh()
+	if v := mesh.SignedVolume(); !near(v, 1.0/6) {
+		t.Errorf("SignedVolume() = %g, want 1/6", v)
+	}
+	if a, want := mesh.SurfaceArea(), 1.5+math.Sqrt(3)/2; !near(a, want) {
+		t.Errorf("SurfaceArea() = %g, want %g", a, want)
+	}
+	if c := mesh.Centroid(); !near(c.X, 0.25) || !near(c.Y, 0.25) || !near(c.Z, 0.25) {
+		t.Errorf("Centroid() = %v, want (0.25, 0.25, 0.25)", c)
+	}
+	for i := range mesh.Faces {
+		mesh.flipFace(i)
+	}
+	if v := mesh.SignedVolume(); !near(v, -1.0/6) {
+		t.Errorf("inside out SignedVolume() = %g, want -1/6", v)
+	}
+}
+
+func TestBoxProperties(t *testing.T) {
+	w, h, d := 2.0, 3.0, 4.0
+	mesh := boxMesh(math3d.V3(w, h, d))
+	mesh.Transform(math3d.Translate(math3d.V3(5, -7, 1)))
+	volume := w * h * d
+	if v := mesh.SignedVolume(); !near(v, volume) {
+		t.Errorf("SignedVolume() = %g, want %g", v, volume)
+	}
+	if a, want := mesh.SurfaceArea(), 2*(w*h+h*d+w*d); !near(a, want) {
+		t.Errorf("SurfaceArea() = %g, want %g", a, want)
+	}
+	if c := mesh.Centroid(); !near(c.X, 6) || !near(c.Y, -5.5) || !near(c.Z, 3) {
+		t.Errorf("Centroid() = %v, want (6, -5.5, 3)", c)
+	}
+	// Solid cuboid: I = m (b² + c²) / 12 on the diagonal, no products of inertia
+	want := [3][3]float64{
+		{volume * (h*h + d*d) / 12, 0, 0},
+		{0, volume * (w*w + d*d) / 12, 0},
+		{0, 0, volume * (w*w + h*h) / 12},
+	}
+	got := mesh.InertiaTensor()
+	for i := range 3 {
+		for j := range 3 {
+			if !near(got[i][j], want[i][j]) {
+				t.Errorf("InertiaTensor() = %v, want %v", got, want)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// sniffLen is the number of leading bytes given to Format.Sniff.
+const sniffLen = 512
+
+// Format is a model file format of the loader registry.
+type Format struct {
+	Name       string   // Short name, such as "STL"
+	Extensions []string // File extensions with their dot, matched case insensitively
+	// Sniff reports whether a file is in this format from its first bytes
+	// (up to 512) and its size (-1 if unknown). It may be nil.
+	Sniff func(head []byte, size int64) bool
+	// Load loads a model from fsys: a mesh or, for scene formats, a scene,
+	// with optionally the model's main texture.
+	Load func(fsys fs.FS, path string) (*Mesh, *Scene, image.Image, error)
+	// Unit is the length of the file coordinate unit in millimeters, 0 for
+	// millimeters. LoadModel gives it to the models that have none and
+	// SaveMesh converts to it.
+	Unit float64
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []Format
+)
+
+// Register adds a format to the loader registry. Formats registered later
+// take precedence, so a format can replace a built-in one.
+func Register(f Format) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, f)
+}
+
+// Formats returns the registered formats, by decreasing precedence.
+func Formats() []Format {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	formats := slices.Clone(registry)
+	slices.Reverse(formats)
+	return formats
+}
+
+// DetectFormat returns the format of a model file: the first one whose Sniff
+// recognizes its content, else the first one with its extension.
+func DetectFormat(fsys fs.FS, name string) (Format, error) {
+	head, size, err := readHead(fsys, name)
+	if err != nil {
+		return Format{}, err
+	}
+	formats := Formats()
+	for _, f := range formats {
+		if f.Sniff != nil && f.Sniff(head, size) {
+			return f, nil
+		}
+	}
+	ext := strings.ToLower(path.Ext(name))
+	for _, f := range formats {
+		if slices.Contains(f.Extensions, ext) {
+			return f, nil
+		}
+	}
+	var exts []string
+	for _, f := range formats {
+		exts = append(exts, f.Extensions...)
+	}
+	return Format{}, fmt.Errorf("unknown model format: %s (supported: %s)", name, strings.Join(exts, ", "))
+}
+
+// LoadModel loads a model file in any registered format, detected by
+// DetectFormat.
+func LoadModel(fsys fs.FS, name string) (*Mesh, *Scene, image.Image, error) {
+	f, err := DetectFormat(fsys, name)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	mesh, scene, img, err := f.Load(fsys, name)
+	if mesh != nil && mesh.Unit == 0 {
+		mesh.Unit = f.Unit
+	}
+	if scene != nil && scene.Unit == 0 {
+		scene.Unit = f.Unit
+		for _, m := range scene.Meshes {
+			if m.Unit == 0 {
+				m.Unit = f.Unit
+			}
+		}
+	}
+	return mesh, scene, img, err
+}
+
+// formatUnit returns the coordinate unit of the format with an extension,
+// in millimeters.
+func formatUnit(ext string) float64 {
+	for _, f := range Formats() {
+		if slices.Contains(f.Extensions, ext) && f.Unit > 0 {
+			return f.Unit
+		}
+	}
+	return Millimeters
+}
+
+// readHead returns the first sniffLen bytes of a file and its size.
+func readHead(fsys fs.FS, name string) ([]byte, int64, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+	size := int64(-1)
+	if info, err := file.Stat(); err == nil && info.Mode().IsRegular() {
+		size = info.Size()
+	}
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, 0, fmt.Errorf("read %s: %w", name, err)
+	}
+	return head[:n], size, nil
+}
+
+// meshLoader adapts a mesh loader to Format.Load.
+func meshLoader(load func(fs.FS, string) (*Mesh, error)) func(fs.FS, string) (*Mesh, *Scene, image.Image, error) {
+	return func(fsys fs.FS, name string) (*Mesh, *Scene, image.Image, error) {
+		mesh, err := load(fsys, name)
+		return mesh, nil, nil, err
+	}
+}
+
+func init() {
+	// Lowest precedence first: the text formats with the loosest sniffing
+	Register(Format{Name: "OBJ", Extensions: []string{".obj"}, Sniff: sniffOBJ, Load: meshLoader(LoadOBJFromFS)})
+	Register(Format{Name: "STL", Extensions: []string{".stl"}, Sniff: sniffSTL, Load: meshLoader(LoadSTLFromFS)})
+	Register(Format{Name: "OFF", Extensions: []string{".off"}, Sniff: sniffOFF, Load: meshLoader(LoadOFFFromFS)})
+	Register(Format{Name: "PLY", Extensions: []string{".ply"}, Sniff: sniffPLY, Load: meshLoader(LoadPLYFromFS)})
+	Register(Format{Name: "Collada", Extensions: []string{".dae"}, Sniff: sniffCollada, Load: meshLoader(LoadColladaFromFS)})
+	Register(Format{Name: "3MF", Extensions: []string{".3mf"}, Sniff: sniff3MF, Load: meshLoader(Load3MFFromFS)})
+	Register(Format{
+		Name: "glTF", Extensions: []string{".gltf", ".glb"}, Sniff: sniffGLTF, Load: LoadGLTFModelFromFS, Unit: Meters,
+	})
+}
+
+// sniffGLTF recognizes the GLB magic, or a JSON object with glTF properties.
+func sniffGLTF(head []byte, _ int64) bool {
+	if bytes.HasPrefix(head, []byte("glTF")) {
+		return true
+	}
+	text := bytes.TrimLeft(head, " \t\r\n\xef\xbb\xbf")
+	if !bytes.HasPrefix(text, []byte("{")) {
+		return false
+	}
+	for _, key := range []string{`"asset"`, `"accessors"`, `"bufferViews"`, `"meshes"`, `"nodes"`} {
+		if bytes.Contains(text, []byte(key)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffSTL recognizes ASCII STL by its "solid" keyword followed by a facet,
+// and binary STL by its size matching its triangle count or, for streams
+// of unknown size, by its binary data.
+func sniffSTL(head []byte, size int64) bool {
+	if size < 0 && len(head) < sniffLen {
+		size = int64(len(head)) // The whole stream
+	}
+	if isBinarySTLHead(head, size) {
+		// Anything not starting with "solid" is binary to the loader
+		return size < 0 || size == binarySTLSize(head)
+	}
+	text := bytes.TrimLeft(head, " \t\r\n")
+	return bytes.HasPrefix(text, []byte("solid")) &&
+		(bytes.Contains(text, []byte("facet")) || bytes.Contains(text, []byte("endsolid")))
+}
+
+// sniffPLY recognizes the "ply" magic line.
+func sniffPLY(head []byte, _ int64) bool {
+	return bytes.HasPrefix(head, []byte("ply\n")) || bytes.HasPrefix(head, []byte("ply\r\n"))
+}
+
+// sniffOFF recognizes an OFF keyword line.
+func sniffOFF(head []byte, _ int64) bool {
+	lines := offLines(head)
+	if len(lines) == 0 {
+		return false
+	}
+	_, _, err := parseOFFKeyword(lines[0])
+	return err == nil
+}
+
+// sniffCollada recognizes the COLLADA root element.
+func sniffCollada(head []byte, _ int64) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(head, " \t\r\n\xef\xbb\xbf"), []byte("<")) && bytes.Contains(head, []byte("<COLLADA"))
+}
+
+// sniff3MF recognizes a zip package with Open Packaging Conventions parts.
+func sniff3MF(head []byte, _ int64) bool {
+	if !bytes.HasPrefix(head, []byte("PK\x03\x04")) {
+		return false
+	}
+	for _, part := range []string{"[Content_Types].xml", "_rels/.rels", "3D/"} {
+		if bytes.Contains(head, []byte(part)) {
+			return true
+		}
+	}
+	return false
+}
+
+// objKeywords are the statements of OBJ files.
+var objKeywords = map[string]bool{
+	"v": true, "vt": true, "vn": true, "vp": true, "f": true, "l": true, "p": true,
+	"o": true, "g": true, "s": true, "mtllib": true, "usemtl": true,
+}
+
+// sniffOBJ recognizes text whose complete lines are all comments or OBJ
+// statements, with at least one vertex.
+func sniffOBJ(head []byte, size int64) bool {
+	if bytes.IndexByte(head, 0) >= 0 {
+		return false
+	}
+	if int64(len(head)) == sniffLen && size != sniffLen {
+		// Drop the last, possibly truncated, line
+		if i := bytes.LastIndexByte(head, '\n'); i >= 0 {
+			head = head[:i]
+		}
+	}
+	vertex := false
+	for line := range bytes.Lines(head) {
+		fields := strings.Fields(string(line))
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if !objKeywords[fields[0]] {
+			return false
+		}
+		vertex = vertex || fields[0] == "v"
+	}
+	return vertex
+}
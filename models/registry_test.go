@@ -0,0 +1,143 @@
+package models
+
+import (
+	"bytes"
+	"image"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDetectFormat(t *testing.T) {
+	mesh := writerTestMesh()
+	var stlBinary, stlASCII, obj, ply, glb bytes.Buffer
+	for _, w := range []struct {
+		buf   *bytes.Buffer
+		write func() error
+	}{
+		{&stlBinary, func() error { return WriteSTL(&stlBinary, mesh) }},
+		{&stlASCII, func() error { return WriteSTLASCII(&stlASCII, mesh) }},
+		{&obj, func() error { return WriteOBJ(&obj, mesh, "") }},
+		{&ply, func() error { return WritePLY(&ply, mesh) }},
+		{&glb, func() error { return WriteGLB(&glb, mesh) }},
+	} {
+		if err := w.write(); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	fsys := fstest.MapFS{
+		"binary.bin":   {Data: stlBinary.Bytes()},
+		"ascii.dat":    {Data: stlASCII.Bytes()},
+		"model.txt":    {Data: obj.Bytes()},
+		"scan":         {Data: ply.Bytes()},
+		"blob":         {Data: glb.Bytes()},
+		"scene.json":   {Data: []byte(`{"asset": {"version": "2.0"}, "scenes": []}`)},
+		"chair.xyz":    {Data: []byte("# ModelNet\nOFF 3 1 0\n0 0 0\n1 0 0\n0 1 0\n3 0 1 2\n")},
+		"house.xml":    {Data: []byte(colladaDoc)},
+		"plate.zip":    {Data: encode3MF(t, map[string]string{"_rels/.rels": threeMFRelsXML, "3D/3dmodel.model": threeMFRootXML})},
+		"wrong.obj":    {Data: stlBinary.Bytes()},
+		"empty.STL":    {Data: nil},
+		"notes.txt":    {Data: []byte("solid advice\nv is for vertex\n")},
+		"download.bin": {Data: bytes.Repeat([]byte{1, 2, 3}, 100)},
+	}
+	tests := []struct {
+		name, want string
+	}{
+		{"binary.bin", "STL"},
+		{"ascii.dat", "STL"},
+		{"model.txt", "OBJ"},
+		{"scan", "PLY"},
+		{"blob", "glTF"},
+		{"scene.json", "glTF"},
+		{"chair.xyz", "OFF"},
+		{"house.xml", "Collada"},
+		{"plate.zip", "3MF"},
+		{"wrong.obj", "STL"},
+		{"empty.STL", "STL"}, // by extension
+		{"notes.txt", ""},
+		{"download.bin", ""},
+	}
+	for _, tt := range tests {
+		f, err := DetectFormat(fsys, tt.name)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s: detected %s, want an error", tt.name, f.Name)
+			}
+			continue
+		}
+		if err != nil || f.Name != tt.want {
+			t.Errorf("%s: got %q, %v, want %s", tt.name, f.Name, err, tt.want)
+		}
+	}
+	got, _, _, err := LoadModel(fsys, "binary.bin")
+	if err != nil || got.TriangleCount() != mesh.TriangleCount() {
+		t.Fatalf("LoadModel(binary.bin) = %v, %v", got, err)
+	}
+	if _, err := DetectFormat(fsys, "missing.stl"); err == nil {
+		t.Error("DetectFormat of a missing file did not fail")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register(Format{
+		Name:       "Test",
+		Extensions: []string{".tst"},
+		Sniff:      func(head []byte, _ int64) bool { return bytes.HasPrefix(head, []byte("TEST3D")) },
+		Load: func(fsys fs.FS, path string) (*Mesh, *Scene, image.Image, error) {
+			data, err := fs.ReadFile(fsys, path)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			return NewMesh(strings.TrimPrefix(string(data), "TEST3D ")), nil, nil, nil
+		},
+	})
+	if f := Formats()[0]; f.Name != "Test" {
+		t.Errorf("Formats()[0] = %s, want the last registered format", f.Name)
+	}
+	mesh, _, _, err := LoadModel(fstest.MapFS{"custom.stl": {Data: []byte("TEST3D sample")}}, "custom.stl")
+	if err != nil || mesh.Name != "sample" {
+		t.Fatalf("LoadModel = %v, %v, want the Test format's mesh", mesh, err)
+	}
+}
+
+func TestSniffSTLStream(t *testing.T) {
+	// Binary STL with a header starting with "solid", as some exporters write
+	binarySTL := func(triangles int) []byte {
+		var buf bytes.Buffer
+		if err := WriteSTL(&buf, sphereMesh(triangles/4+1, 2)); err != nil {
+			t.Fatalf("WriteSTL: %v", err)
+		}
+		data := buf.Bytes()
+		copy(data, "solid exported by a CAD program")
+		return data
+	}
+	var ascii bytes.Buffer
+	if err := WriteSTLASCII(&ascii, writerTestMesh()); err != nil {
+		t.Fatalf("WriteSTLASCII: %v", err)
+	}
+	small, large := binarySTL(4), binarySTL(40)
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"small binary", small, true},
+		{"large binary", large, true},
+		{"truncated binary", small[:len(small)-10], false},
+		{"ascii", ascii.Bytes(), true},
+		{"text", []byte(strings.Repeat("solid but not STL\n", 40)), false},
+	}
+	for _, tt := range tests {
+		head := tt.data[:min(len(tt.data), sniffLen)]
+		if got := sniffSTL(head, -1); got != tt.want {
+			t.Errorf("%s: sniffSTL(%d of %d bytes, -1) = %v, want %v", tt.name, len(head), len(tt.data), got, tt.want)
+		}
+		if !tt.want {
+			continue
+		}
+		if got := isBinarySTLHead(head, -1); got != strings.HasSuffix(tt.name, "binary") {
+			t.Errorf("%s: isBinarySTLHead(head, -1) = %v", tt.name, got)
+		}
+	}
+}
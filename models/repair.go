@@ -0,0 +1,346 @@
+package models
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// RepairOptions selects the operations of Mesh.Repair.
+type RepairOptions struct {
+	WeldTJunctions bool    // Split faces at the vertices lying on their open edges
+	UnifyWinding   bool    // Wind each connected surface consistently
+	FillHoles      bool    // Close the boundary loops with triangulated caps
+	OrientOutward  bool    // Turn the closed surfaces wound inside out
+	Tolerance      float64 // T-junction distance, 0 for 1e-6 of the bounding box diagonal
+}
+
+// RepairStats counts the changes made by Mesh.Repair.
+type RepairStats struct {
+	TJunctions   int // Vertices welded into face edges
+	FlippedFaces int // Faces whose winding was reversed
+	FilledHoles  int // Boundary loops closed
+	AddedFaces   int // Faces added by T-junction splits and hole caps
+}
+
+// Repair applies the selected repairs, in the order T-junction welding,
+// winding unification, hole filling and outward orientation, so that each
+// one works on the surfaces fixed by the previous ones. Degenerate and
+// duplicate faces are ignored; remove them first with CleanMesh.
+func (m *Mesh) Repair(opts RepairOptions) RepairStats {
+	var stats RepairStats
+	faces := len(m.Faces)
+	if opts.WeldTJunctions {
+		stats.TJunctions = m.WeldTJunctions(opts.Tolerance)
+	}
+	if opts.UnifyWinding {
+		stats.FlippedFaces += m.UnifyWinding()
+	}
+	if opts.FillHoles {
+		stats.FilledHoles = m.FillHoles()
+	}
+	if opts.OrientOutward {
+		stats.FlippedFaces += m.OrientOutward()
+	}
+	stats.AddedFaces = len(m.Faces) - faces
+	return stats
+}
+
+// topology returns the welded vertex numbers (see Analyze) and the edges of
+// the non degenerate, non duplicate faces.
+func (m *Mesh) topology() ([]int, []meshEdge) {
+	ids, _ := m.weldedIDs()
+	valid, _, _ := m.topologyFaces(ids)
+	return ids, m.meshEdges(ids, valid)
+}
+
+// flipFace reverses the winding of a face.
+func (m *Mesh) flipFace(i int) {
+	f := &m.Faces[i]
+	f.V[1], f.V[2] = f.V[2], f.V[1]
+}
+
+// UnifyWinding flips the faces wound against the majority of their surface,
+// walking the faces across the edges they share. Returns the number of
+// flipped faces.
+func (m *Mesh) UnifyWinding() int {
+	_, edges := m.topology()
+	flips := inconsistentFaces(len(m.Faces), edges)
+	for _, f := range flips {
+		m.flipFace(f)
+	}
+	return len(flips)
+}
+
+// faceVolume returns the signed volume of the tetrahedron between a face and
+// the origin, positive for faces wound counter clockwise seen from the
+// positive side: the file winding, which the loaders reverse.
+func (m *Mesh) faceVolume(f Face) float64 {
+	p0 := m.Vertices[f.V[0]].Position
+	p1 := m.Vertices[f.V[1]].Position
+	p2 := m.Vertices[f.V[2]].Position
+	return p0.Dot(p2.Cross(p1)) / 6
+}
+
+// shell is a closed, connected surface.
+type shell struct {
+	faces    []int
+	volume   float64
+	min, max math3d.Vec3
+}
+
+// OrientOutward flips the closed surfaces wound inside out, detected by
+// their signed volume, so that their faces point outward; surfaces nested in
+// an odd number of others (the walls of hollow parts) point inward instead.
+// Surfaces must be consistently wound (see UnifyWinding) and open surfaces
+// are left as is. Returns the number of flipped faces.
+func (m *Mesh) OrientOutward() int {
+	_, edges := m.topology()
+	manifold := make([]int, len(m.Faces))
+	for _, e := range edges {
+		if len(e.uses) == 2 {
+			manifold[e.uses[0].face]++
+			manifold[e.uses[1].face]++
+		}
+	}
+	var shells []shell
+	orientationComponents(len(m.Faces), edges, func(component []int, _ []bool) {
+		s := shell{
+			faces: slices.Clone(component),
+			min:   math3d.V3(math.Inf(1), math.Inf(1), math.Inf(1)),
+			max:   math3d.V3(math.Inf(-1), math.Inf(-1), math.Inf(-1)),
+		}
+		for _, f := range component {
+			if manifold[f] != 3 {
+				return
+			}
+			s.volume += m.faceVolume(m.Faces[f])
+			fMin, fMax := m.faceBounds(m.Faces[f])
+			s.min, s.max = s.min.Min(fMin), s.max.Max(fMax)
+		}
+		shells = append(shells, s)
+	})
+	flipped := 0
+	for i, s := range shells {
+		inward := false
+		for j := range shells {
+			if i != j && m.shellContains(&shells[j], &s) {
+				inward = !inward
+			}
+		}
+		if (s.volume < 0) != inward {
+			for _, f := range s.faces {
+				m.flipFace(f)
+			}
+			flipped += len(s.faces)
+		}
+	}
+	return flipped
+}
+
+// shellContains reports whether the closed surface outer encloses inner, by
+// the parity of the crossings of a ray from a point of inner.
+func (m *Mesh) shellContains(outer, inner *shell) bool {
+	if inner.min.Min(outer.min) != outer.min || inner.max.Max(outer.max) != outer.max {
+		return false
+	}
+	f := m.Faces[inner.faces[0]]
+	origin := m.Vertices[f.V[0]].Position.Add(m.Vertices[f.V[1]].Position).
+		Add(m.Vertices[f.V[2]].Position).Scale(1.0 / 3)
+	// An arbitrary direction, unlikely to graze edges of axis aligned models
+	dir := math3d.V3(0.6027, 0.4563, 0.6547).Normalize()
+	crossings := 0
+	for _, fi := range outer.faces {
+		g := m.Faces[fi]
+		t, _, _, ok := rayTriangle(origin, dir,
+			m.Vertices[g.V[0]].Position, m.Vertices[g.V[1]].Position, m.Vertices[g.V[2]].Position)
+		if ok && t > 0 {
+			crossings++
+		}
+	}
+	return crossings%2 == 1
+}
+
+// capEdge is a directed edge of a hole cap, against the winding of the
+// boundary face it closes.
+type capEdge struct {
+	from, to int // Vertex indices
+	face     int // Boundary face
+}
+
+// FillHoles closes each boundary loop with a cap triangulated by ear
+// clipping, wound like the surface around it and using the material and
+// group of one of its faces. Returns the number of holes filled.
+func (m *Mesh) FillHoles() int {
+	ids, edges := m.topology()
+	// Outgoing cap edges by welded vertex
+	outgoing := make(map[int][]capEdge)
+	var starts []capEdge
+	for _, e := range edges {
+		if len(e.uses) != 1 {
+			continue
+		}
+		c := capEdge{from: e.v[1], to: e.v[0], face: e.uses[0].face}
+		outgoing[ids[c.from]] = append(outgoing[ids[c.from]], c)
+		starts = append(starts, c)
+	}
+	used := make(map[capEdge]bool, len(starts))
+	filled := 0
+	for _, start := range starts {
+		if used[start] {
+			continue
+		}
+		used[start] = true
+		loop := []int{start.from}
+		for at := start; ids[at.to] != ids[start.from]; {
+			next, ok := nextCapEdge(outgoing[ids[at.to]], used)
+			if !ok {
+				loop = nil // Open chain through a non-manifold vertex
+				break
+			}
+			used[next] = true
+			loop = append(loop, next.from)
+			at = next
+		}
+		if m.addCap(loop, m.Faces[start.face]) {
+			filled++
+		}
+	}
+	return filled
+}
+
+// nextCapEdge returns the first unused edge of candidates.
+func nextCapEdge(candidates []capEdge, used map[capEdge]bool) (capEdge, bool) {
+	for _, c := range candidates {
+		if !used[c] {
+			return c, true
+		}
+	}
+	return capEdge{}, false
+}
+
+// addCap triangulates a loop of vertices into faces like template, reporting
+// whether any was added.
+func (m *Mesh) addCap(loop []int, template Face) bool {
+	if len(loop) < 3 {
+		return false
+	}
+	pts := make([]math3d.Vec3, len(loop))
+	for i, v := range loop {
+		pts[i] = m.Vertices[v].Position
+	}
+	tris := TriangulatePolygon(pts)
+	for _, t := range tris {
+		face := template
+		face.V = [3]int{loop[t[0]], loop[t[1]], loop[t[2]]}
+		m.Faces = append(m.Faces, face)
+	}
+	return len(tris) > 0
+}
+
+// edgeSplit is a vertex welded into a face edge, at parameter t along it.
+type edgeSplit struct {
+	t float64
+	v int
+}
+
+// WeldTJunctions splits the faces whose open edges pass through a vertex of
+// another open edge, within tolerance (0 for 1e-6 of the bounding box
+// diagonal), so that both sides share the same edges. Returns the number of
+// vertices welded.
+func (m *Mesh) WeldTJunctions(tolerance float64) int {
+	ids, edges := m.topology()
+	// Vertices of the open edges, sorted by X for range queries
+	var candidates []int
+	seen := make(map[int]bool)
+	for _, e := range edges {
+		if len(e.uses) != 1 {
+			continue
+		}
+		for _, v := range e.v {
+			if !seen[ids[v]] {
+				seen[ids[v]] = true
+				candidates = append(candidates, v)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return 0
+	}
+	pos := func(v int) math3d.Vec3 { return m.Vertices[v].Position }
+	slices.SortFunc(candidates, func(a, b int) int { return cmp.Compare(pos(a).X, pos(b).X) })
+	if tolerance <= 0 {
+		lo, hi := m.vertexBounds()
+		tolerance = 1e-6 * hi.Sub(lo).Len()
+	}
+	splits := make(map[int]*[3][]edgeSplit)
+	welded := 0
+	for _, e := range edges {
+		if len(e.uses) != 1 {
+			continue
+		}
+		fi := e.uses[0].face
+		f := m.Faces[fi]
+		k := slices.Index(f.V[:], e.v[0])
+		a, b := pos(e.v[0]), pos(e.v[1])
+		ab := b.Sub(a)
+		lenSq := ab.LenSq()
+		first, _ := slices.BinarySearchFunc(candidates, math.Min(a.X, b.X)-tolerance,
+			func(v int, x float64) int { return cmp.Compare(pos(v).X, x) })
+		for _, c := range candidates[first:] {
+			p := pos(c)
+			if p.X > math.Max(a.X, b.X)+tolerance {
+				break
+			}
+			if ids[c] == ids[e.v[0]] || ids[c] == ids[e.v[1]] {
+				continue
+			}
+			t := p.Sub(a).Dot(ab) / lenSq
+			if t <= 0 || t >= 1 || a.Add(ab.Scale(t)).Sub(p).Len() > tolerance {
+				continue
+			}
+			if splits[fi] == nil {
+				splits[fi] = new([3][]edgeSplit)
+			}
+			splits[fi][k] = append(splits[fi][k], edgeSplit{t, c})
+			welded++
+		}
+	}
+	// Re-triangulate the split faces as polygons, in face order
+	split := make([]int, 0, len(splits))
+	for fi := range splits {
+		split = append(split, fi)
+	}
+	slices.Sort(split)
+	for _, fi := range split {
+		f := m.Faces[fi]
+		var loop []int
+		for k, vertices := range splits[fi] {
+			loop = append(loop, f.V[k])
+			slices.SortFunc(vertices, func(a, b edgeSplit) int { return cmp.Compare(a.t, b.t) })
+			for _, s := range vertices {
+				loop = append(loop, s.v)
+			}
+		}
+		faces := len(m.Faces)
+		if !m.addCap(loop, f) {
+			continue
+		}
+		// The first new triangle replaces the split face
+		m.Faces[fi] = m.Faces[faces]
+		m.Faces = append(m.Faces[:faces], m.Faces[faces+1:]...)
+	}
+	return welded
+}
+
+// vertexBounds returns the bounding box of the vertices.
+func (m *Mesh) vertexBounds() (lo, hi math3d.Vec3) {
+	lo = math3d.V3(math.Inf(1), math.Inf(1), math.Inf(1))
+	hi = math3d.V3(math.Inf(-1), math.Inf(-1), math.Inf(-1))
+	for _, v := range m.Vertices {
+		lo, hi = lo.Min(v.Position), hi.Max(v.Position)
+	}
+	return lo, hi
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+func TestUnifyWinding(t *testing.T) {
+	mesh := tetrahedronMesh()
+	mesh.flipFace(2)
+	if n := mesh.UnifyWinding(); n != 1 {
+		t.Errorf("UnifyWinding() = %d, want 1", n)
+	}
+	if r := Analyze(mesh); !r.Printable() {
+		t.Errorf("after UnifyWinding: %+v", r)
+	}
+	if n := mesh.UnifyWinding(); n != 0 {
+		t.Errorf("second UnifyWinding() = %d, want 0", n)
+	}
+}
+
+func TestOrientOutward(t *testing.T) {
+	mesh := tetrahedronMesh()
+	want := mesh.SignedVolume()
+	if want <= 0 {
+		t.Fatalf("test tetrahedron volume %g, want positive", want)
+	}
+	for i := range mesh.Faces {
+		mesh.flipFace(i)
+	}
+	if n := mesh.OrientOutward(); n != 4 || mesh.SignedVolume() != want {
+		t.Errorf("OrientOutward() = %d, volume %g, want 4 and %g", n, mesh.SignedVolume(), want)
+	}
+	// A smaller outward tetrahedron inside is the wall of a cavity
+	inner := tetrahedronMesh()
+	inner.Transform(math3d.Translate(math3d.V3(0.1, 0.1, 0.1)).Mul(math3d.ScaleUniform(0.2)))
+	first := len(mesh.Vertices)
+	mesh.Vertices = append(mesh.Vertices, inner.Vertices...)
+	for _, f := range inner.Faces {
+		mesh.Faces = append(mesh.Faces, Face{V: [3]int{f.V[0] + first, f.V[1] + first, f.V[2] + first}})
+	}
+	if n := mesh.OrientOutward(); n != 4 {
+		t.Errorf("OrientOutward() with a cavity = %d, want 4", n)
+	}
+	if v := mesh.SignedVolume(); v >= want || v <= 0 {
+		t.Errorf("volume with a cavity %g, want less than %g", v, want)
+	}
+}
+
+func TestFillHoles(t *testing.T) {
+	mesh := tetrahedronMesh()
+	removed := mesh.Faces[3]
+	mesh.Faces = mesh.Faces[:3]
+	if n := mesh.FillHoles(); n != 1 || len(mesh.Faces) != 4 {
+		t.Fatalf("FillHoles() = %d with %d faces, want 1 hole and 4 faces", n, len(mesh.Faces))
+	}
+	if r := Analyze(mesh); !r.Printable() {
+		t.Errorf("after FillHoles: %+v", r)
+	}
+	ids, _ := mesh.weldedIDs()
+	got := mesh.Faces[3]
+	if faceKey(ids[got.V[0]], ids[got.V[1]], ids[got.V[2]]) != faceKey(ids[removed.V[0]], ids[removed.V[1]], ids[removed.V[2]]) {
+		t.Errorf("cap %v, want the removed face %v", got.V, removed.V)
+	}
+}
+
+func TestWeldTJunctions(t *testing.T) {
+	mesh := NewMesh("t-junction")
+	for _, p := range []math3d.Vec3{
+		math3d.V3(0, 0, 0), math3d.V3(2, 0, 0), math3d.V3(1, 1, 0), // One triangle above the X axis
+		math3d.V3(1, -1, 0), math3d.V3(1, 0, 0), // Two below, with a vertex on its edge
+	} {
+		mesh.Vertices = append(mesh.Vertices, MeshVertex{Position: p})
+	}
+	mesh.Faces = []Face{{V: [3]int{0, 2, 1}}, {V: [3]int{0, 4, 3}}, {V: [3]int{4, 1, 3}}}
+	if r := Analyze(mesh); len(r.BoundaryEdges) != 7 {
+		t.Fatalf("before: %d boundary edges, want 7", len(r.BoundaryEdges))
+	}
+	if n := mesh.WeldTJunctions(0); n != 1 || len(mesh.Faces) != 4 {
+		t.Errorf("WeldTJunctions() = %d with %d faces, want 1 and 4 faces", n, len(mesh.Faces))
+	}
+	r := Analyze(mesh)
+	if len(r.BoundaryEdges) != 4 || len(r.NonManifoldEdges) != 0 || len(r.InconsistentFaces) != 0 {
+		t.Errorf("after: %+v, want 4 consistent boundary edges", r)
+	}
+}
+
+func TestSTLLoaderRepair(t *testing.T) {
+	mesh := tetrahedronMesh()
+	mesh.Faces = mesh.Faces[:3]
+	for i := range mesh.Faces {
+		mesh.flipFace(i)
+	}
+	mesh.flipFace(1)
+	var buf bytes.Buffer
+	if err := WriteSTL(&buf, mesh); err != nil {
+		t.Fatal(err)
+	}
+	loader := NewSTLLoader()
+	loader.Repair = RepairOptions{UnifyWinding: true, FillHoles: true, OrientOutward: true}
+	got, err := loader.LoadBytes(buf.Bytes(), "broken.stl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := Analyze(got); !r.Printable() || got.SignedVolume() <= 0 {
+		t.Errorf("repaired STL: %+v, volume %g", r, got.SignedVolume())
+	}
+}
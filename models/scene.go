@@ -0,0 +1,353 @@
+package models
+
+import (
+	"image/color"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// Scene is a node hierarchy referencing shared meshes, as authored in GLTF.
+// Unlike the flattened Mesh returned by the loaders, a mesh used by several
+// nodes is stored once and drawn once per instance.
+type Scene struct {
+	Name   string
+	Nodes  []SceneNode
+	Roots  []int   // Top level nodes
+	Meshes []*Mesh // Shared geometry in mesh (node) space
+	// Materials shared by all meshes (Face.Material indexes this)
+	Materials []Material
+	// Authored cameras and KHR_lights_punctual lights
+	Cameras []SceneCamera
+	Lights  []SceneLight
+	// Root is applied above the root nodes (accumulates Transform).
+	Root math3d.Mat4
+	// Isolated restricts drawing to the subtree of this node (-1 for none).
+	Isolated int
+	// Bounding box of the visible instances (see CalculateBounds)
+	BoundsMin math3d.Vec3
+	BoundsMax math3d.Vec3
+	// Length of a coordinate unit in millimeters, 0 for millimeters (see
+	// Mesh.Unit)
+	Unit float64
+}
+
+// SceneNode is a scene node with an optional mesh.
+type SceneNode struct {
+	Node
+	Mesh   int  // Index into Scene.Meshes, -1 for none
+	Hidden bool // Hides the node and its subtree
+}
+
+// Instance is one placement of a shared mesh.
+type Instance struct {
+	Node      int
+	Mesh      *Mesh
+	Transform math3d.Mat4 // Mesh space to scene space, including Scene.Root
+}
+
+// NewScene creates an empty scene.
+func NewScene(name string) *Scene {
+	return &Scene{
+		Name:     name,
+		Root:     math3d.Identity(),
+		Isolated: -1,
+	}
+}
+
+// WorldTransforms returns the scene space transform of every node.
+func (s *Scene) WorldTransforms() []math3d.Mat4 {
+	world := make([]math3d.Mat4, len(s.Nodes))
+	done := make([]bool, len(s.Nodes))
+	var worldOf func(i int) math3d.Mat4
+	worldOf = func(i int) math3d.Mat4 {
+		if done[i] {
+			return world[i]
+		}
+		done[i] = true // also guards against cycles in malformed files
+		parent := s.Root
+		if p := s.Nodes[i].Parent; p >= 0 && p < len(s.Nodes) {
+			parent = worldOf(p)
+		}
+		world[i] = parent.Mul(s.Nodes[i].Local())
+		return world[i]
+	}
+	for i := range s.Nodes {
+		worldOf(i)
+	}
+	return world
+}
+
+// Walk visits the nodes depth first from the roots, with their depth.
+func (s *Scene) Walk(visit func(node, depth int)) {
+	seen := make([]bool, len(s.Nodes))
+	var walk func(i, depth int)
+	walk = func(i, depth int) {
+		if i < 0 || i >= len(s.Nodes) || seen[i] {
+			return
+		}
+		seen[i] = true
+		visit(i, depth)
+		for _, c := range s.Nodes[i].Children {
+			walk(c, depth+1)
+		}
+	}
+	for _, r := range s.Roots {
+		walk(r, 0)
+	}
+}
+
+// InSubtree returns true if node is ancestor or one of its descendants.
+func (s *Scene) InSubtree(node, ancestor int) bool {
+	for steps := 0; node >= 0 && node < len(s.Nodes) && steps <= len(s.Nodes); steps++ {
+		if node == ancestor {
+			return true
+		}
+		node = s.Nodes[node].Parent
+	}
+	return false
+}
+
+// Visible returns true if neither the node nor any ancestor is hidden and the
+// node is within the isolated subtree, if any.
+func (s *Scene) Visible(node int) bool {
+	if s.Isolated >= 0 && !s.InSubtree(node, s.Isolated) {
+		return false
+	}
+	for steps := 0; node >= 0 && node < len(s.Nodes) && steps <= len(s.Nodes); steps++ {
+		if s.Nodes[node].Hidden {
+			return false
+		}
+		node = s.Nodes[node].Parent
+	}
+	return true
+}
+
+// SetHidden hides or shows a node and its subtree.
+func (s *Scene) SetHidden(node int, hidden bool) {
+	if node >= 0 && node < len(s.Nodes) {
+		s.Nodes[node].Hidden = hidden
+	}
+}
+
+// Isolate restricts drawing to the subtree of node; -1 shows everything again.
+func (s *Scene) Isolate(node int) {
+	if node >= len(s.Nodes) {
+		node = -1
+	}
+	s.Isolated = node
+}
+
+// ShowAll clears all hidden flags and the isolation.
+func (s *Scene) ShowAll() {
+	for i := range s.Nodes {
+		s.Nodes[i].Hidden = false
+	}
+	s.Isolated = -1
+}
+
+// Instances returns the visible mesh instances with their transforms.
+func (s *Scene) Instances() []Instance {
+	world := s.WorldTransforms()
+	var result []Instance
+	s.Walk(func(i, _ int) {
+		n := &s.Nodes[i]
+		if n.Mesh < 0 || n.Mesh >= len(s.Meshes) || !s.Visible(i) {
+			return
+		}
+		result = append(result, Instance{Node: i, Mesh: s.Meshes[n.Mesh], Transform: world[i]})
+	})
+	return result
+}
+
+// UnitSize returns the length of a coordinate unit in millimeters.
+func (s *Scene) UnitSize() float64 {
+	if s.Unit > 0 {
+		return s.Unit
+	}
+	return Millimeters
+}
+
+// VertexCount returns the number of vertices drawn for the visible instances.
+func (s *Scene) VertexCount() int {
+	count := 0
+	for _, inst := range s.Instances() {
+		count += inst.Mesh.VertexCount()
+	}
+	return count
+}
+
+// TriangleCount returns the number of triangles drawn for the visible instances.
+func (s *Scene) TriangleCount() int {
+	count := 0
+	for _, inst := range s.Instances() {
+		count += inst.Mesh.TriangleCount()
+	}
+	return count
+}
+
+// CalculateBounds computes the bounding box of the visible instances.
+func (s *Scene) CalculateBounds() {
+	first := true
+	for _, inst := range s.Instances() {
+		if inst.Mesh.VertexCount() == 0 {
+			continue
+		}
+		lo, hi := inst.Mesh.BoundsMin, inst.Mesh.BoundsMax
+		for c := range 8 {
+			corner := math3d.V3(lo.X, lo.Y, lo.Z)
+			if c&1 != 0 {
+				corner.X = hi.X
+			}
+			if c&2 != 0 {
+				corner.Y = hi.Y
+			}
+			if c&4 != 0 {
+				corner.Z = hi.Z
+			}
+			p := inst.Transform.MulVec3(corner)
+			if first {
+				s.BoundsMin, s.BoundsMax = p, p
+				first = false
+				continue
+			}
+			s.BoundsMin = s.BoundsMin.Min(p)
+			s.BoundsMax = s.BoundsMax.Max(p)
+		}
+	}
+}
+
+// Center returns the center of the bounding box.
+func (s *Scene) Center() math3d.Vec3 {
+	return s.BoundsMin.Add(s.BoundsMax).Scale(0.5)
+}
+
+// Size returns the dimensions of the bounding box.
+func (s *Scene) Size() math3d.Vec3 {
+	return s.BoundsMax.Sub(s.BoundsMin)
+}
+
+// Transform applies a transformation above the root nodes. The shared
+// meshes are not modified.
+func (s *Scene) Transform(mat math3d.Mat4) {
+	s.Root = mat.Mul(s.Root)
+	s.CalculateBounds()
+}
+
+// BuildBVH builds the spatial index of every shared mesh.
+func (s *Scene) BuildBVH() {
+	for _, m := range s.Meshes {
+		m.BuildBVH()
+	}
+}
+
+// Flatten bakes the visible instances into a single mesh.
+func (s *Scene) Flatten() *Mesh {
+	mesh := NewMesh(s.Name)
+	mesh.Unit = s.Unit
+	mesh.Materials = append(mesh.Materials, s.Materials...)
+	instances := s.Instances()
+	for _, inst := range instances {
+		mesh.VertexColors = mesh.VertexColors || inst.Mesh.VertexColors
+	}
+	for _, inst := range instances {
+		first := len(mesh.Vertices)
+		for _, v := range inst.Mesh.Vertices {
+			if mesh.VertexColors && !inst.Mesh.VertexColors {
+				v.Color = color.RGBA{255, 255, 255, 255}
+			}
+			v.Position = inst.Transform.MulVec3(v.Position)
+			v.Normal = inst.Transform.MulVec3Dir(v.Normal).Normalize()
+			mesh.Vertices = append(mesh.Vertices, v)
+		}
+		for _, f := range inst.Mesh.Faces {
+			f.V = [3]int{f.V[0] + first, f.V[1] + first, f.V[2] + first}
+			mesh.Faces = append(mesh.Faces, f)
+		}
+		for _, l := range inst.Mesh.Lines {
+			l.V = [2]int{l.V[0] + first, l.V[1] + first}
+			mesh.Lines = append(mesh.Lines, l)
+		}
+		for _, p := range inst.Mesh.Points {
+			p.V += first
+			mesh.Points = append(mesh.Points, p)
+		}
+	}
+	mesh.CalculateBounds()
+	return mesh
+}
+
+// GroupScene splits a mesh by face group into a scene: a node per object,
+// holding a child node per group with its own mesh. Groups without an object
+// are root nodes. Returns nil when the mesh has no groups.
+func (m *Mesh) GroupScene() *Scene {
+	if len(m.Groups) == 0 {
+		return nil
+	}
+	scene := NewScene(m.Name)
+	scene.Materials = m.Materials
+	scene.Unit = m.Unit
+	newNode := func(name string, parent int) int {
+		idx := len(scene.Nodes)
+		scene.Nodes = append(scene.Nodes, SceneNode{
+			Node: Node{Name: name, Parent: parent, Rotation: math3d.QuatIdentity(), Scale: math3d.V3(1, 1, 1)},
+			Mesh: -1,
+		})
+		if parent < 0 {
+			scene.Roots = append(scene.Roots, idx)
+		} else {
+			scene.Nodes[parent].Children = append(scene.Nodes[parent].Children, idx)
+		}
+		return idx
+	}
+	objects := make(map[string]int)
+	groupMesh := make([]*Mesh, len(m.Groups))
+	remaps := make([]map[int]int, len(m.Groups))
+	for i, g := range m.Groups {
+		parent := -1
+		if g.Object != "" {
+			var ok bool
+			if parent, ok = objects[g.Object]; !ok {
+				parent = newNode(g.Object, -1)
+				objects[g.Object] = parent
+			}
+		}
+		name := g.Name
+		switch {
+		case name == "" && parent >= 0:
+			name = g.Object
+		case name == "":
+			name = "default"
+		}
+		node := newNode(name, parent)
+		scene.Nodes[node].Mesh = len(scene.Meshes)
+		groupMesh[i] = NewMesh(name)
+		groupMesh[i].Materials = m.Materials
+		groupMesh[i].VertexColors = m.VertexColors
+		groupMesh[i].Unit = m.Unit
+		remaps[i] = make(map[int]int)
+		scene.Meshes = append(scene.Meshes, groupMesh[i])
+	}
+	for _, f := range m.Faces {
+		g := f.Group
+		if g < 0 || g >= len(m.Groups) {
+			continue
+		}
+		sub := groupMesh[g]
+		for k, v := range f.V {
+			idx, ok := remaps[g][v]
+			if !ok {
+				idx = len(sub.Vertices)
+				remaps[g][v] = idx
+				sub.Vertices = append(sub.Vertices, m.Vertices[v])
+			}
+			f.V[k] = idx
+		}
+		f.Group = 0
+		sub.Faces = append(sub.Faces, f)
+	}
+	for _, sub := range scene.Meshes {
+		sub.CalculateBounds()
+	}
+	scene.CalculateBounds()
+	return scene
+}
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ansipixels/trophy/math3d"
+	"github.com/qmuntal/gltf"
+	"github.com/qmuntal/gltf/modeler"
+)
+
+// instancedDoc builds a "group" node with two children placing the same
+// triangle mesh at x=-2 and x=+2, plus an empty "marker" root.
+func instancedDoc() *gltf.Document {
+	doc := gltf.NewDocument()
+	pos := modeler.WritePosition(doc, [][3]float32{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}})
+	doc.Meshes = []*gltf.Mesh{{
+		Name:       "tri",
+		Primitives: []*gltf.Primitive{{Attributes: gltf.PrimitiveAttributes{gltf.POSITION: pos}}},
+	}}
+	doc.Nodes = []*gltf.Node{
+		{Name: "group", Children: []int{1, 2}, Translation: [3]float64{0, 0, -1}},
+		{Name: "left", Mesh: gltf.Index(0), Translation: [3]float64{-2, 0, 0}},
+		{Name: "right", Mesh: gltf.Index(0), Translation: [3]float64{2, 0, 0}},
+		{Name: "marker"},
+	}
+	doc.Scenes = []*gltf.Scene{{Nodes: []int{0, 3}}}
+	return doc
+}
+
+func TestLoadGLTFScene(t *testing.T) {
+	fsys := encodeGLB(t, instancedDoc())
+	scene, err := LoadGLTFSceneFromFS(fsys, "model.glb")
+	if err != nil {
+		t.Fatalf("load scene: %v", err)
+	}
+	if len(scene.Meshes) != 1 || len(scene.Nodes) != 4 || len(scene.Roots) != 2 {
+		t.Fatalf("meshes=%d nodes=%d roots=%d, want 1, 4, 2", len(scene.Meshes), len(scene.Nodes), len(scene.Roots))
+	}
+	if scene.Nodes[1].Name != "left" || scene.Nodes[1].Parent != 0 || scene.Nodes[3].Mesh != -1 {
+		t.Errorf("unexpected nodes: %+v", scene.Nodes)
+	}
+	inst := scene.Instances()
+	if len(inst) != 2 || inst[0].Mesh != inst[1].Mesh {
+		t.Fatalf("expected two instances of the same mesh, got %+v", inst)
+	}
+	assertVec3(t, "left origin", inst[0].Transform.MulVec3(math3d.Zero3()), math3d.V3(-2, 0, -1))
+	assertVec3(t, "right origin", inst[1].Transform.MulVec3(math3d.Zero3()), math3d.V3(2, 0, -1))
+	assertVec3(t, "bounds min", scene.BoundsMin, math3d.V3(-2, 0, -1))
+	assertVec3(t, "bounds max", scene.BoundsMax, math3d.V3(3, 1, -1))
+	if scene.TriangleCount() != 2 {
+		t.Errorf("triangles = %d, want 2", scene.TriangleCount())
+	}
+	var order []int
+	var depths []int
+	scene.Walk(func(node, depth int) {
+		order = append(order, node)
+		depths = append(depths, depth)
+	})
+	if len(order) != 4 || order[1] != 1 || depths[1] != 1 || depths[3] != 0 {
+		t.Errorf("walk order %v depths %v", order, depths)
+	}
+	// Flattening matches the mesh loader
+	flat := scene.Flatten()
+	mesh, err := LoadGLBFromFS(fsys, "model.glb")
+	if err != nil {
+		t.Fatalf("load mesh: %v", err)
+	}
+	if flat.VertexCount() != mesh.VertexCount() || flat.TriangleCount() != mesh.TriangleCount() {
+		t.Fatalf("flattened %d/%d, loader %d/%d", flat.VertexCount(), flat.TriangleCount(),
+			mesh.VertexCount(), mesh.TriangleCount())
+	}
+	for i := range flat.Vertices {
+		assertVec3(t, "flattened vertex", flat.Vertices[i].Position, mesh.Vertices[i].Position)
+	}
+}
+
+func TestSceneVisibility(t *testing.T) {
+	scene, err := LoadGLTFSceneFromFS(encodeGLB(t, instancedDoc()), "model.glb")
+	if err != nil {
+		t.Fatalf("load scene: %v", err)
+	}
+	scene.SetHidden(1, true)
+	if inst := scene.Instances(); len(inst) != 1 || inst[0].Node != 2 {
+		t.Errorf("hiding left: %+v", inst)
+	}
+	scene.SetHidden(0, true)
+	if scene.Visible(2) || len(scene.Instances()) != 0 {
+		t.Error("hiding the group should hide its subtree")
+	}
+	scene.ShowAll()
+	scene.Isolate(2)
+	if inst := scene.Instances(); len(inst) != 1 || inst[0].Node != 2 {
+		t.Errorf("isolating right: %+v", inst)
+	}
+	scene.Isolate(0)
+	if len(scene.Instances()) != 2 {
+		t.Error("isolating the group should keep both children")
+	}
+	scene.Isolate(-1)
+	scene.Transform(math3d.ScaleUniform(2))
+	assertVec3(t, "scaled bounds min", scene.BoundsMin, math3d.V3(-4, 0, -2))
+	// Shared geometry is untouched
+	assertVec3(t, "mesh vertex", scene.Meshes[0].Vertices[1].Position, math3d.V3(1, 0, 0))
+}
+
+func TestLoadGLTFModel(t *testing.T) {
+	mesh, scene, _, err := LoadGLTFModelFromFS(encodeGLB(t, instancedDoc()), "model.glb")
+	if err != nil {
+		t.Fatalf("load static: %v", err)
+	}
+	if mesh != nil || scene == nil || scene.VertexCount() != 6 {
+		t.Fatalf("static file should load as a scene only, got mesh=%v scene=%v", mesh != nil, scene != nil)
+	}
+	mesh, scene, _, err = LoadGLTFModelFromFS(encodeGLB(t, morphQuadDoc()), "model.glb")
+	if err != nil {
+		t.Fatalf("load morphed: %v", err)
+	}
+	if scene != nil || mesh == nil || mesh.Rig == nil {
+		t.Fatal("morphed file should load as a posable mesh only")
+	}
+}
+
+func TestSceneCamerasAndLights(t *testing.T) {
+	doc := instancedDoc()
+	s := math.Sqrt2 / 2
+	doc.Cameras = []*gltf.Camera{
+		{Name: "persp", Perspective: &gltf.Perspective{Yfov: 0.8, Znear: 0.1, Zfar: gltf.Float(100)}},
+		{Name: "ortho", Orthographic: &gltf.Orthographic{Xmag: 2, Ymag: 1, Znear: 0.5, Zfar: 10}},
+	}
+	doc.Extensions = gltf.Extensions{extLightsPunctual: map[string]any{"lights": []any{
+		map[string]any{"type": "directional", "intensity": 3},
+		map[string]any{"name": "lamp", "type": "spot", "color": []float64{1, 0.5, 0}, "range": 4, "spot": map[string]any{}},
+	}}}
+	// The camera looks down -X (90° around Y), scaled by 2 under "group"
+	doc.Nodes = append(doc.Nodes,
+		&gltf.Node{Name: "cam", Camera: gltf.Index(0), Rotation: [4]float64{0, s, 0, s}, Scale: [3]float64{2, 2, 2}},
+		&gltf.Node{Name: "top", Camera: gltf.Index(1), Translation: [3]float64{0, 5, 0}},
+		&gltf.Node{Name: "sun", Extensions: gltf.Extensions{extLightsPunctual: map[string]any{"light": 0}}},
+		&gltf.Node{Name: "lamp", Translation: [3]float64{1, 2, 3}, Extensions: gltf.Extensions{
+			extLightsPunctual: map[string]any{"light": 1},
+		}},
+		&gltf.Node{Name: "bad", Extensions: gltf.Extensions{extLightsPunctual: map[string]any{"light": 7}}},
+	)
+	doc.Nodes[0].Children = append(doc.Nodes[0].Children, 4)
+	doc.Scenes[0].Nodes = append(doc.Scenes[0].Nodes, 5, 6, 7, 8)
+	scene, err := LoadGLTFSceneFromFS(encodeGLB(t, doc), "model.glb")
+	if err != nil {
+		t.Fatalf("load scene: %v", err)
+	}
+	cams := scene.CameraViews()
+	if len(cams) != 2 {
+		t.Fatalf("cameras = %d, want 2", len(cams))
+	}
+	c := cams[0]
+	if c.Name != "persp" || c.Orthographic || math.Abs(c.YFov-0.8) > 1e-9 || math.Abs(c.ZFar-200) > 1e-9 {
+		t.Errorf("perspective camera = %+v", c.Camera)
+	}
+	assertVec3(t, "camera position", c.Position, math3d.V3(0, 0, -1))
+	assertVec3(t, "camera forward", c.Forward, math3d.V3(-1, 0, 0))
+	assertVec3(t, "camera up", c.Up, math3d.V3(0, 1, 0))
+	if o := cams[1]; !o.Orthographic || o.XMag != 2 || o.YMag != 1 || o.ZFar != 10 {
+		t.Errorf("orthographic camera = %+v", o.Camera)
+	}
+	lights := scene.LightViews()
+	if len(lights) != 2 {
+		t.Fatalf("lights = %d, want 2", len(lights))
+	}
+	sun, lamp := lights[0], lights[1]
+	if sun.Type != LightDirectional || sun.Intensity != 3 || sun.Color != [3]float64{1, 1, 1} {
+		t.Errorf("sun = %+v", sun.Light)
+	}
+	assertVec3(t, "sun direction", sun.Direction, math3d.V3(0, 0, -1))
+	if lamp.Type != LightSpot || lamp.Name != "lamp" || lamp.Intensity != 1 || lamp.Range != 4 ||
+		lamp.Color != [3]float64{1, 0.5, 0} || math.Abs(lamp.OuterConeAngle-math.Pi/4) > 1e-9 {
+		t.Errorf("lamp = %+v", lamp.Light)
+	}
+	assertVec3(t, "lamp position", lamp.Position, math3d.V3(1, 2, 3))
+	// Hidden lights are skipped, Root applies to cameras and lights
+	scene.SetHidden(6, true)
+	scene.Transform(math3d.ScaleUniform(0.5))
+	if lights = scene.LightViews(); len(lights) != 1 || lights[0].Range != 2 {
+		t.Errorf("lights after hide and scale = %+v", lights)
+	}
+	if cams = scene.CameraViews(); math.Abs(cams[1].YMag-0.5) > 1e-9 {
+		t.Errorf("scaled orthographic camera = %+v", cams[1].Camera)
+	}
+}
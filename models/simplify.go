@@ -0,0 +1,823 @@
+package models
+
+import (
+	"container/heap"
+	"math"
+	"slices"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// Weights of the vertex attributes in the simplification error, whose
+// distances are measured in bounding box diagonals.
+const (
+	simplifyUVWeight     = 1.0
+	simplifyNormalWeight = 0.5
+	simplifyColorWeight  = 0.5
+	// Weight of the planes holding the borders and attribute seams in place
+	simplifyBorderWeight = 10.0
+	// Weight of the distance to the original vertex positions, which breaks
+	// the ties of flat areas in favor of short edges and well shaped faces
+	simplifyPointWeight = 0.01
+)
+
+// quadric is a symmetric 4x4 matrix measuring the sum of the weighted squared
+// distances to a set of planes (Garland and Heckbert).
+type quadric struct {
+	xx, xy, xz, yy, yz, zz float64
+	x, y, z, c             float64
+}
+
+// planeQuadric returns the quadric of the plane n·p + d = 0 scaled by weight,
+// n being a unit normal for actual distances.
+func planeQuadric(n math3d.Vec3, d, weight float64) quadric {
+	return quadric{
+		xx: weight * n.X * n.X, xy: weight * n.X * n.Y, xz: weight * n.X * n.Z,
+		yy: weight * n.Y * n.Y, yz: weight * n.Y * n.Z, zz: weight * n.Z * n.Z,
+		x: weight * n.X * d, y: weight * n.Y * d, z: weight * n.Z * d,
+		c: weight * d * d,
+	}
+}
+
+// pointQuadric returns the quadric of the squared distance to p, scaled by
+// weight.
+func pointQuadric(p math3d.Vec3, weight float64) quadric {
+	return quadric{
+		xx: weight, yy: weight, zz: weight,
+		x: -weight * p.X, y: -weight * p.Y, z: -weight * p.Z,
+		c: weight * p.LenSq(),
+	}
+}
+
+func (q *quadric) add(o *quadric) {
+	q.xx += o.xx
+	q.xy += o.xy
+	q.xz += o.xz
+	q.yy += o.yy
+	q.yz += o.yz
+	q.zz += o.zz
+	q.x += o.x
+	q.y += o.y
+	q.z += o.z
+	q.c += o.c
+}
+
+// eval returns the error at p.
+func (q *quadric) eval(p math3d.Vec3) float64 {
+	return q.xx*p.X*p.X + q.yy*p.Y*p.Y + q.zz*p.Z*p.Z +
+		2*(q.xy*p.X*p.Y+q.xz*p.X*p.Z+q.yz*p.Y*p.Z) +
+		2*(q.x*p.X+q.y*p.Y+q.z*p.Z) + q.c
+}
+
+// collapse is a candidate collapse of node u into node v, valid while both
+// nodes keep their versions.
+type collapse struct {
+	cost     float64
+	u, v     int32
+	uVersion uint32
+	vVersion uint32
+	reversed bool // Already retried in the other direction
+}
+
+// collapseHeap is a min-heap of collapses by cost, for container/heap.
+type collapseHeap []collapse
+
+func (h collapseHeap) Len() int           { return len(h) }
+func (h collapseHeap) Less(i, j int) bool { return h[i].cost < h[j].cost }
+func (h collapseHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *collapseHeap) Push(x any) { *h = append(*h, x.(collapse)) }
+
+func (h *collapseHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// simplifier holds the state of Mesh.Simplify. Nodes are the welded vertex
+// positions, which collapse into each other; the vertices at a node (split
+// along UV, normal and color seams) carry the attributes.
+type simplifier struct {
+	m        *Mesh
+	node     []int         // Node of each vertex
+	pos      []math3d.Vec3 // Position of each node, in bounding box diagonals
+	faces    [][]int       // Faces around each node, including removed ones
+	dead     []bool        // Removed faces
+	removed  []bool        // Collapsed nodes
+	locked   []bool        // Nodes that never move: corners and non-manifold vertices
+	features [][]int       // Nodes across the border and seam edges of each node
+	geometry []quadric     // Plane quadric of each node
+	version  []uint32
+	// Attribute error of each vertex: Σ w (g·p + d - s)² over the linear
+	// interpolations (gradient g, offset d) of its faces' attributes s
+	attrs    int       // Attribute components
+	values   []float64 // Weighted attributes of each vertex
+	gradient []quadric // Σ w (g·p + d)²
+	weight   []float64 // Σ w
+	linear   []float32 // Σ w g and Σ w d of each attribute component
+	// Scratch space
+	from, to []int // Vertex mapping of the last evaluated collapse
+	other    []int // Vertices at u in faces without v
+	moved    []movedFace
+	mark     []int
+	stamp    int
+	heap     collapseHeap
+	grid     faceGrid
+}
+
+// Simplify reduces the mesh to about targetTriangles triangles by collapsing
+// its edges in the order of least quadric error (Garland and Heckbert). The
+// error includes the UV, normal and vertex color changes measured against
+// the linear interpolation of the attributes over the faces. Each edge
+// collapses onto the end point of least error, so that the kept vertices keep
+// their attributes. Borders, material boundaries and attribute seams only
+// slide along themselves and their corners stay, so holes and UV charts keep
+// their outline; the last edges of a border, around a small hole or a flap,
+// stay. Collapses that would flip faces, make faces cross or make the
+// surface non-manifold are skipped, which can stop the simplification above
+// the target. Degenerate and duplicate faces are dropped. Rigged meshes, whose
+// skins are indexed by vertex, are left as is. Returns the number of faces
+// removed; rebuild the BVH afterwards.
+func (m *Mesh) Simplify(targetTriangles int) int {
+	if m.Rig != nil || targetTriangles >= len(m.Faces) {
+		return 0
+	}
+	before := len(m.Faces)
+	s := newSimplifier(m)
+	if s == nil {
+		return 0
+	}
+	s.run(targetTriangles)
+	kept := m.Faces[:0]
+	for i, f := range m.Faces {
+		if !s.dead[i] {
+			kept = append(kept, f)
+		}
+	}
+	m.Faces = kept
+	m.RemoveUnreferencedVertices()
+	return before - len(m.Faces)
+}
+
+// newSimplifier merges the vertices with identical attributes, classifies
+// the nodes and accumulates their quadrics. Returns nil for meshes without
+// extent.
+//
+//nolint:funlen // one pass per kind of data.
+func newSimplifier(m *Mesh) *simplifier {
+	lo, hi := m.vertexBounds()
+	diagonal := hi.Sub(lo).Len()
+	if len(m.Faces) == 0 || diagonal == 0 {
+		return nil
+	}
+	// Identical vertices are one
+	same := make(map[MeshVertex]int, len(m.Vertices))
+	canonical := make([]int, len(m.Vertices))
+	for i, v := range m.Vertices {
+		c, ok := same[v]
+		if !ok {
+			c = i
+			same[v] = i
+		}
+		canonical[i] = c
+	}
+	for i := range m.Faces {
+		for k, v := range m.Faces[i].V {
+			m.Faces[i].V[k] = canonical[v]
+		}
+	}
+	ids, nodes := m.weldedIDs()
+	valid, _, _ := m.topologyFaces(ids)
+	s := &simplifier{
+		m:        m,
+		node:     ids,
+		pos:      make([]math3d.Vec3, nodes),
+		faces:    make([][]int, nodes),
+		dead:     make([]bool, len(m.Faces)),
+		removed:  make([]bool, nodes),
+		locked:   make([]bool, nodes),
+		features: make([][]int, nodes),
+		geometry: make([]quadric, nodes),
+		version:  make([]uint32, nodes),
+		mark:     make([]int, nodes),
+	}
+	for i, v := range m.Vertices {
+		s.pos[ids[i]] = v.Position.Sub(lo).Scale(1 / diagonal)
+	}
+	for i, f := range m.Faces {
+		s.dead[i] = !valid[i]
+		if valid[i] {
+			for _, v := range f.V {
+				s.faces[ids[v]] = append(s.faces[ids[v]], i)
+			}
+		}
+	}
+	s.initAttributes()
+	// Face planes, weighted by area, and attribute gradients
+	for i, f := range m.Faces {
+		if s.dead[i] {
+			continue
+		}
+		q0, q1, q2 := s.pos[ids[f.V[0]]], s.pos[ids[f.V[1]]], s.pos[ids[f.V[2]]]
+		n := q1.Sub(q0).Cross(q2.Sub(q0))
+		area := n.Len() / 2
+		if area == 0 {
+			continue
+		}
+		n = n.Scale(0.5 / area)
+		plane := planeQuadric(n, -n.Dot(q0), area)
+		for _, v := range f.V {
+			point := pointQuadric(s.pos[ids[v]], simplifyPointWeight*area)
+			s.geometry[ids[v]].add(&plane)
+			s.geometry[ids[v]].add(&point)
+		}
+		s.addGradients(f, q0, q1, q2, area)
+	}
+	// Borders, seams and non-manifold edges
+	for _, e := range m.meshEdges(ids, valid) {
+		a, b := ids[e.v[0]], ids[e.v[1]]
+		switch {
+		case len(e.uses) > 2:
+			s.locked[a], s.locked[b] = true, true
+			continue
+		case len(e.uses) == 2 && !s.seam(e.uses[0].face, e.uses[1].face, a, b):
+			continue
+		}
+		s.features[a] = append(s.features[a], b)
+		s.features[b] = append(s.features[b], a)
+		// Planes through the edge, perpendicular to its faces
+		edge := s.pos[b].Sub(s.pos[a])
+		for _, use := range e.uses {
+			f := m.Faces[use.face]
+			q0 := s.pos[ids[f.V[0]]]
+			normal := s.pos[ids[f.V[1]]].Sub(q0).Cross(s.pos[ids[f.V[2]]].Sub(q0))
+			n := edge.Cross(normal)
+			if n.LenSq() == 0 {
+				continue
+			}
+			n = n.Normalize()
+			plane := planeQuadric(n, -n.Dot(s.pos[a]), simplifyBorderWeight*edge.LenSq())
+			s.geometry[a].add(&plane)
+			s.geometry[b].add(&plane)
+		}
+	}
+	// Line and point vertices stay
+	for _, l := range m.Lines {
+		s.locked[ids[l.V[0]]], s.locked[ids[l.V[1]]] = true, true
+	}
+	for _, p := range m.Points {
+		s.locked[ids[p.V]] = true
+	}
+	// Feature vertices slide along their one border or seam, others stay
+	for n, f := range s.features {
+		if len(f) != 0 && len(f) != 2 {
+			s.locked[n] = true
+		}
+	}
+	return s
+}
+
+// seam reports whether the edge between nodes a and b, shared by faces f0
+// and f1, separates materials, groups or vertex attributes.
+func (s *simplifier) seam(f0, f1, a, b int) bool {
+	face0, face1 := s.m.Faces[f0], s.m.Faces[f1]
+	return face0.Material != face1.Material || face0.Group != face1.Group ||
+		s.vertexAt(f0, a) != s.vertexAt(f1, a) || s.vertexAt(f0, b) != s.vertexAt(f1, b)
+}
+
+// vertexAt returns the vertex of a face at a node, -1 if it has none.
+func (s *simplifier) vertexAt(face, node int) int {
+	for _, v := range s.m.Faces[face].V {
+		if s.node[v] == node {
+			return v
+		}
+	}
+	return -1
+}
+
+// initAttributes collects the weighted attributes present in the mesh.
+func (s *simplifier) initAttributes() {
+	m := s.m
+	hasUV, hasNormal := false, false
+	for _, v := range m.Vertices {
+		hasUV = hasUV || v.UV != (math3d.Vec2{})
+		hasNormal = hasNormal || v.Normal != (math3d.Vec3{})
+	}
+	if hasUV {
+		s.attrs += 2
+	}
+	if hasNormal {
+		s.attrs += 3
+	}
+	if m.VertexColors {
+		s.attrs += 3
+	}
+	s.values = make([]float64, 0, len(m.Vertices)*s.attrs)
+	for _, v := range m.Vertices {
+		if hasUV {
+			s.values = append(s.values, simplifyUVWeight*v.UV.X, simplifyUVWeight*v.UV.Y)
+		}
+		if hasNormal {
+			n := v.Normal.Scale(simplifyNormalWeight)
+			s.values = append(s.values, n.X, n.Y, n.Z)
+		}
+		if m.VertexColors {
+			const scale = simplifyColorWeight / 255
+			s.values = append(s.values, scale*float64(v.Color.R), scale*float64(v.Color.G), scale*float64(v.Color.B))
+		}
+	}
+	s.gradient = make([]quadric, len(m.Vertices))
+	s.weight = make([]float64, len(m.Vertices))
+	s.linear = make([]float32, len(m.Vertices)*s.attrs*4)
+}
+
+// addGradients adds the linear interpolation of a face's attributes, at
+// positions q0, q1 and q2, to the attribute error of its vertices.
+func (s *simplifier) addGradients(f Face, q0, q1, q2 math3d.Vec3, area float64) {
+	if s.attrs == 0 {
+		return
+	}
+	e1, e2 := q1.Sub(q0), q2.Sub(q0)
+	a, b, c := e1.Dot(e1), e1.Dot(e2), e2.Dot(e2)
+	det := a*c - b*b
+	if det <= 0 {
+		return
+	}
+	var sum quadric
+	for i := range s.attrs {
+		s0 := s.values[f.V[0]*s.attrs+i]
+		d1 := s.values[f.V[1]*s.attrs+i] - s0
+		d2 := s.values[f.V[2]*s.attrs+i] - s0
+		// Gradient in the plane of the face: g·e1 = d1 and g·e2 = d2
+		g := e1.Scale((c*d1 - b*d2) / det).Add(e2.Scale((a*d2 - b*d1) / det))
+		d := s0 - g.Dot(q0)
+		plane := planeQuadric(g, d, area)
+		sum.add(&plane)
+		for _, v := range f.V {
+			l := s.linear[(v*s.attrs+i)*4:]
+			l[0] += float32(area * g.X)
+			l[1] += float32(area * g.Y)
+			l[2] += float32(area * g.Z)
+			l[3] += float32(area * d)
+		}
+	}
+	for _, v := range f.V {
+		s.gradient[v].add(&sum)
+		s.weight[v] += area
+	}
+}
+
+// attributeError returns the attribute error of vertex v moved to p with
+// the attributes of vertex target.
+func (s *simplifier) attributeError(v int, p math3d.Vec3, target int) float64 {
+	if s.attrs == 0 {
+		return 0
+	}
+	e := s.gradient[v].eval(p)
+	sq := 0.0
+	for i := range s.attrs {
+		l := s.linear[(v*s.attrs+i)*4:]
+		value := s.values[target*s.attrs+i]
+		e -= 2 * value * (float64(l[0])*p.X + float64(l[1])*p.Y + float64(l[2])*p.Z + float64(l[3]))
+		sq += value * value
+	}
+	return e + s.weight[v]*sq
+}
+
+// addAttributeError adds the attribute error of vertex from to vertex to.
+func (s *simplifier) addAttributeError(to, from int) {
+	if s.attrs == 0 {
+		return
+	}
+	s.gradient[to].add(&s.gradient[from])
+	s.weight[to] += s.weight[from]
+	dst := s.linear[to*s.attrs*4 : (to+1)*s.attrs*4]
+	for i, l := range s.linear[from*s.attrs*4 : (from+1)*s.attrs*4] {
+		dst[i] += l
+	}
+}
+
+// mapVertices maps each vertex at node u to the vertex at node v sharing a
+// face with it, into from and to. Fails when a vertex has none, or several
+// (u's faces around the edge being on different sides of a seam at v).
+func (s *simplifier) mapVertices(u, v int) bool {
+	s.from, s.to, s.other = s.from[:0], s.to[:0], s.other[:0]
+	for _, f := range s.faces[u] {
+		if s.dead[f] {
+			continue
+		}
+		source, target := -1, -1
+		for _, w := range s.m.Faces[f].V {
+			switch s.node[w] {
+			case u:
+				source = w
+			case v:
+				target = w
+			}
+		}
+		switch i := slices.Index(s.from, source); {
+		case target < 0:
+			s.other = append(s.other, source)
+		case i < 0:
+			s.from = append(s.from, source)
+			s.to = append(s.to, target)
+		case s.to[i] != target:
+			return false
+		}
+	}
+	if len(s.from) == 0 {
+		return false
+	}
+	for _, source := range s.other {
+		if !slices.Contains(s.from, source) {
+			return false
+		}
+	}
+	return true
+}
+
+// cost returns the error of collapsing node u into node v, and whether u may
+// move there. It leaves the vertex mapping in from and to.
+func (s *simplifier) cost(u, v int) (float64, bool) {
+	if s.locked[u] || (len(s.features[u]) > 0 && !slices.Contains(s.features[u], v)) || !s.mapVertices(u, v) {
+		return 0, false
+	}
+	p := s.pos[v]
+	q := s.geometry[u]
+	q.add(&s.geometry[v])
+	cost := q.eval(p)
+	for i, source := range s.from {
+		cost += s.attributeError(source, p, s.to[i])
+		if slices.Index(s.to, s.to[i]) == i {
+			cost += s.attributeError(s.to[i], p, s.to[i])
+		}
+	}
+	return max(cost, 0), true // Rounding can make nearly exact attribute errors negative
+}
+
+// push queues the collapse of the edge between nodes a and b in its cheapest
+// allowed direction.
+func (s *simplifier) push(a, b int) {
+	c := collapse{cost: math.Inf(1)}
+	if cost, ok := s.cost(a, b); ok {
+		c = collapse{cost: cost, u: int32(a), v: int32(b)} //nolint:gosec // node counts fit faces
+	}
+	if cost, ok := s.cost(b, a); ok && cost < c.cost {
+		c = collapse{cost: cost, u: int32(b), v: int32(a)} //nolint:gosec // node counts fit faces
+	}
+	if math.IsInf(c.cost, 1) {
+		return
+	}
+	c.uVersion, c.vVersion = s.version[c.u], s.version[c.v]
+	heap.Push(&s.heap, c)
+}
+
+// neighbors calls visit with each node sharing a live face with node n.
+func (s *simplifier) neighbors(n int, visit func(int)) {
+	s.stamp++
+	stamp := s.stamp
+	s.mark[n] = stamp
+	for _, f := range s.faces[n] {
+		if s.dead[f] {
+			continue
+		}
+		for _, v := range s.m.Faces[f].V {
+			if x := s.node[v]; s.mark[x] != stamp {
+				s.mark[x] = stamp
+				visit(x)
+			}
+		}
+	}
+}
+
+// valid reports whether collapsing node u into node v keeps the surface
+// manifold, without flipped or duplicate faces.
+func (s *simplifier) valid(u, v int) bool {
+	// Link condition: the only nodes next to both are across the edge's faces
+	s.stamp++
+	around := s.stamp
+	for _, f := range s.faces[u] {
+		if !s.dead[f] {
+			for _, w := range s.m.Faces[f].V {
+				s.mark[s.node[w]] = around
+			}
+		}
+	}
+	s.stamp++
+	common, across := 0, 0
+	for _, f := range s.faces[v] {
+		if s.dead[f] {
+			continue
+		}
+		if s.vertexAt(f, u) >= 0 {
+			across++
+			continue
+		}
+		for _, w := range s.m.Faces[f].V {
+			if x := s.node[w]; x != v && s.mark[x] == around {
+				s.mark[x] = s.stamp
+				common++
+			}
+		}
+	}
+	if common > across {
+		return false
+	}
+	// Border edges merging across a removed face must leave a border: the
+	// last edges of a hole or of a flap on a non-manifold edge stay
+	for _, f := range s.faces[u] {
+		if s.dead[f] || s.vertexAt(f, v) < 0 {
+			continue
+		}
+		for _, w := range s.m.Faces[f].V {
+			if x := s.node[w]; x != u && x != v {
+				if cu, cv := s.edgeFaces(u, x), s.edgeFaces(v, x); (cu == 1 || cv == 1) && cu+cv != 3 {
+					return false
+				}
+			}
+		}
+	}
+	p := s.pos[v]
+	s.moved = s.moved[:0]
+	for _, f := range s.faces[u] {
+		if s.dead[f] || s.vertexAt(f, v) >= 0 {
+			continue
+		}
+		var before, after [3]math3d.Vec3
+		for k, w := range s.m.Faces[f].V {
+			before[k] = s.pos[s.node[w]]
+			after[k] = before[k]
+			if s.node[w] == u {
+				after[k] = p
+			}
+		}
+		n0 := before[1].Sub(before[0]).Cross(before[2].Sub(before[0]))
+		n1 := after[1].Sub(after[0]).Cross(after[2].Sub(after[0]))
+		if n0.Dot(n1) <= 0 || s.duplicates(f, u, v) {
+			return false
+		}
+		lo, hi := triangleBounds(after)
+		s.moved = append(s.moved, movedFace{face: f, after: after, lo: lo, hi: hi})
+	}
+	return !s.crosses(u, v)
+}
+
+// edgeFaces returns the number of live faces on the edge between nodes a
+// and b.
+func (s *simplifier) edgeFaces(a, b int) int {
+	n := 0
+	for _, f := range s.faces[a] {
+		if !s.dead[f] && s.vertexAt(f, b) >= 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// movedFace is a face of a collapsing node at its new position.
+type movedFace struct {
+	face   int
+	after  [3]math3d.Vec3
+	lo, hi math3d.Vec3
+}
+
+// crosses reports whether the moved faces of node u, collapsing into node
+// v, would cross faces without common node.
+func (s *simplifier) crosses(u, v int) bool {
+	if len(s.moved) == 0 {
+		return false
+	}
+	lo, hi := s.moved[0].lo, s.moved[0].hi
+	for _, m := range s.moved[1:] {
+		lo, hi = lo.Min(m.lo), hi.Max(m.hi)
+	}
+	found := false
+	s.grid.query(s, lo, hi, func(g int) bool {
+		other := s.triangle(g)
+		gLo, gHi := triangleBounds(other)
+		if !boxesOverlap(lo, hi, gLo, gHi) {
+			return true
+		}
+		nodes := s.m.Faces[g].V
+		for k, w := range nodes {
+			nodes[k] = s.node[w]
+			if nodes[k] == u || nodes[k] == v {
+				return true // Moving or dropped with the collapse, or touching
+			}
+		}
+		for _, m := range s.moved {
+			if !boxesOverlap(m.lo, m.hi, gLo, gHi) || s.vertexAt(m.face, nodes[0]) >= 0 ||
+				s.vertexAt(m.face, nodes[1]) >= 0 || s.vertexAt(m.face, nodes[2]) >= 0 {
+				continue
+			}
+			if trianglesIntersect(m.after, other, 1e-9) {
+				found = true
+				return false
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// triangle returns the node positions of a face.
+func (s *simplifier) triangle(f int) [3]math3d.Vec3 {
+	v := s.m.Faces[f].V
+	return [3]math3d.Vec3{s.pos[s.node[v[0]]], s.pos[s.node[v[1]]], s.pos[s.node[v[2]]]}
+}
+
+// duplicates reports whether face f of node u, moved to node v, would
+// coincide with a face of v.
+func (s *simplifier) duplicates(f, u, v int) bool {
+	var moved [3]int
+	for k, w := range s.m.Faces[f].V {
+		moved[k] = s.node[w]
+		if moved[k] == u {
+			moved[k] = v
+		}
+	}
+	key := faceKey(moved[0], moved[1], moved[2])
+	for _, g := range s.faces[v] {
+		if s.dead[g] {
+			continue
+		}
+		w := s.m.Faces[g].V
+		if faceKey(s.node[w[0]], s.node[w[1]], s.node[w[2]]) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// collapse moves node u into node v, with the vertex mapping from cost,
+// returning the number of faces removed.
+func (s *simplifier) collapse(u, v int) int {
+	removed := 0
+	for _, f := range s.faces[u] {
+		if s.dead[f] {
+			continue
+		}
+		if s.vertexAt(f, v) >= 0 {
+			s.dead[f] = true
+			removed++
+			continue
+		}
+		face := &s.m.Faces[f]
+		for k, w := range face.V {
+			if s.node[w] == u {
+				face.V[k] = s.to[slices.Index(s.from, w)]
+			}
+		}
+		s.faces[v] = append(s.faces[v], f)
+		s.grid.add(s, f)
+	}
+	s.faces[u] = nil
+	s.faces[v] = slices.DeleteFunc(s.faces[v], func(f int) bool { return s.dead[f] })
+	s.geometry[v].add(&s.geometry[u])
+	for i, source := range s.from {
+		s.addAttributeError(s.to[i], source)
+	}
+	for _, x := range s.features[u] {
+		s.features[x] = slices.DeleteFunc(s.features[x], func(n int) bool { return n == u })
+		if x != v && !slices.Contains(s.features[x], v) {
+			s.features[x] = append(s.features[x], v)
+			s.features[v] = append(s.features[v], x)
+		}
+	}
+	s.features[u] = nil
+	s.removed[u] = true
+	s.version[u]++
+	s.version[v]++
+	return removed
+}
+
+// run collapses edges until at most target faces are left or no collapse
+// is possible.
+func (s *simplifier) run(target int) {
+	alive := 0
+	for _, dead := range s.dead {
+		if !dead {
+			alive++
+		}
+	}
+	for n := range s.faces {
+		s.neighbors(n, func(x int) {
+			if n < x {
+				s.push(n, x)
+			}
+		})
+	}
+	s.grid.build(s, alive)
+	for alive > target && len(s.heap) > 0 {
+		if alive <= s.grid.alive/2 {
+			s.grid.build(s, alive)
+		}
+		c := heap.Pop(&s.heap).(collapse)
+		u, v := int(c.u), int(c.v)
+		if s.removed[u] || s.removed[v] || c.uVersion != s.version[u] || c.vVersion != s.version[v] {
+			continue
+		}
+		cost, ok := s.cost(u, v)
+		if !ok || !s.valid(u, v) {
+			// The faces around may have changed: try the other direction once
+			if !c.reversed {
+				if cost, ok := s.cost(v, u); ok {
+					heap.Push(&s.heap, collapse{cost: cost, u: c.v, v: c.u, uVersion: c.vVersion, vVersion: c.uVersion, reversed: true})
+				}
+			}
+			continue
+		}
+		if cost > c.cost+1e-9*c.cost+1e-18 {
+			// Neighboring collapses changed the vertex mapping: queue again
+			c.cost = cost
+			heap.Push(&s.heap, c)
+			continue
+		}
+		alive -= s.collapse(u, v)
+		s.neighbors(v, func(x int) { s.push(x, v) })
+	}
+}
+
+// faceGrid is a uniform grid over the live faces of a simplifier, for the
+// intersection tests of moved faces. Faces are added again where they move
+// and stay listed where they were; queries check their current position. It
+// is rebuilt with larger cells as the faces grow.
+type faceGrid struct {
+	size  float64
+	cells map[[3]int32][]int32
+	alive int   // Faces at the last build
+	seen  []int // Stamp of the last query listing each face
+	stamp int
+}
+
+// build lists the live faces in cells a few times their mean size.
+func (g *faceGrid) build(s *simplifier, alive int) {
+	area := 0.0
+	for f := range s.m.Faces {
+		if !s.dead[f] {
+			t := s.triangle(f)
+			area += t[1].Sub(t[0]).Cross(t[2].Sub(t[0])).Len() / 2
+		}
+	}
+	g.size = 4 * math.Sqrt(area/float64(max(alive, 1)))
+	if g.size == 0 {
+		g.size = 1
+	}
+	g.cells = make(map[[3]int32][]int32, alive)
+	g.alive = alive
+	if g.seen == nil {
+		g.seen = make([]int, len(s.m.Faces))
+	}
+	for f := range s.m.Faces {
+		if !s.dead[f] {
+			g.add(s, f)
+		}
+	}
+}
+
+// cell returns the cell of a position.
+func (g *faceGrid) cell(p math3d.Vec3) [3]int32 {
+	return [3]int32{int32(math.Floor(p.X / g.size)), int32(math.Floor(p.Y / g.size)), int32(math.Floor(p.Z / g.size))}
+}
+
+// add lists face f in the cells of its current bounding box.
+func (g *faceGrid) add(s *simplifier, f int) {
+	lo, hi := triangleBounds(s.triangle(f))
+	c0, c1 := g.cell(lo), g.cell(hi)
+	for x := c0[0]; x <= c1[0]; x++ {
+		for y := c0[1]; y <= c1[1]; y++ {
+			for z := c0[2]; z <= c1[2]; z++ {
+				key := [3]int32{x, y, z}
+				g.cells[key] = append(g.cells[key], int32(f)) //nolint:gosec // face counts fit
+			}
+		}
+	}
+}
+
+// query calls visit once with each live face listed in the cells of a box,
+// until it returns false.
+func (g *faceGrid) query(s *simplifier, lo, hi math3d.Vec3, visit func(int) bool) {
+	g.stamp++
+	c0, c1 := g.cell(lo), g.cell(hi)
+	for x := c0[0]; x <= c1[0]; x++ {
+		for y := c0[1]; y <= c1[1]; y++ {
+			for z := c0[2]; z <= c1[2]; z++ {
+				for _, f := range g.cells[[3]int32{x, y, z}] {
+					if s.dead[f] || g.seen[f] == g.stamp {
+						continue
+					}
+					g.seen[f] = g.stamp
+					if !visit(int(f)) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// triangleBounds returns the bounding box of a triangle.
+func triangleBounds(t [3]math3d.Vec3) (lo, hi math3d.Vec3) {
+	return t[0].Min(t[1]).Min(t[2]), t[0].Max(t[1]).Max(t[2])
+}
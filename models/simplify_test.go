@@ -0,0 +1,167 @@
+package models
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// seamGridMesh returns an n x n grid of squares over the unit square, gently
+// curved, with a UV seam along x = 0.5 (split vertices with distinct UVs).
+func seamGridMesh(n int) *Mesh {
+	mesh := NewMesh("grid")
+	vertex := func(i, j int, chart float64) int {
+		x, y := float64(i)/float64(n), float64(j)/float64(n)
+		mesh.Vertices = append(mesh.Vertices, MeshVertex{
+			Position: math3d.V3(x, y, 0.05*math.Sin(3*x)*math.Cos(2*y)),
+			Normal:   math3d.V3(0, 0, 1),
+			UV:       math3d.V2(x+chart, y),
+		})
+		return len(mesh.Vertices) - 1
+	}
+	for i := range n {
+		for j := range n {
+			chart := 0.0
+			if 2*i >= n {
+				chart = 1 // Right half in another UV chart
+			}
+			a, b, c, d := vertex(i, j, chart), vertex(i+1, j, chart), vertex(i+1, j+1, chart), vertex(i, j+1, chart)
+			mesh.Faces = append(mesh.Faces, Face{V: [3]int{a, c, b}}, Face{V: [3]int{a, d, c}})
+		}
+	}
+	return mesh
+}
+
+// sphereMesh returns a closed UV sphere of radius 1.
+func sphereMesh(rings, segments int) *Mesh {
+	mesh := NewMesh("sphere")
+	at := func(ring, segment int) int {
+		if ring == 0 {
+			return 0
+		}
+		if ring == rings {
+			return 1
+		}
+		return 2 + (ring-1)*segments + segment%segments
+	}
+	mesh.Vertices = append(mesh.Vertices, MeshVertex{Position: math3d.V3(0, 1, 0)}, MeshVertex{Position: math3d.V3(0, -1, 0)})
+	for ring := 1; ring < rings; ring++ {
+		theta := math.Pi * float64(ring) / float64(rings)
+		for segment := range segments {
+			phi := 2 * math.Pi * float64(segment) / float64(segments)
+			p := math3d.V3(math.Sin(theta)*math.Cos(phi), math.Cos(theta), math.Sin(theta)*math.Sin(phi))
+			mesh.Vertices = append(mesh.Vertices, MeshVertex{Position: p, Normal: p})
+		}
+	}
+	for ring := range rings {
+		for segment := range segments {
+			a, b := at(ring, segment), at(ring, segment+1)
+			c, d := at(ring+1, segment), at(ring+1, segment+1)
+			if ring > 0 {
+				mesh.Faces = append(mesh.Faces, Face{V: [3]int{a, c, b}})
+			}
+			if ring < rings-1 {
+				mesh.Faces = append(mesh.Faces, Face{V: [3]int{b, c, d}})
+			}
+		}
+	}
+	return mesh
+}
+
+func TestSimplifyClosedMesh(t *testing.T) {
+	mesh := sphereMesh(32, 64)
+	volume := mesh.SignedVolume()
+	if volume <= 0 || !Analyze(mesh).Printable() {
+		t.Fatalf("test sphere volume %g, want a printable outward mesh", volume)
+	}
+	before := len(mesh.Faces)
+	target := before / 10
+	if removed := mesh.Simplify(target); removed != before-len(mesh.Faces) || len(mesh.Faces) > target {
+		t.Fatalf("Simplify(%d) = %d, %d faces left", target, removed, len(mesh.Faces))
+	}
+	if r := Analyze(mesh); !r.Watertight() || len(r.InconsistentFaces) != 0 || len(r.DegenerateFaces) != 0 {
+		t.Errorf("simplified sphere: %+v", r)
+	}
+	if v := mesh.SignedVolume(); math.Abs(v-volume) > 0.05*volume {
+		t.Errorf("simplified volume %g, want within 5%% of %g", v, volume)
+	}
+}
+
+func TestSimplifyBordersAndSeams(t *testing.T) {
+	mesh := seamGridMesh(20)
+	area := mesh.SurfaceArea()
+	target := len(mesh.Faces) / 8
+	mesh.Simplify(target)
+	if len(mesh.Faces) > target {
+		t.Errorf("%d faces left, want at most %d", len(mesh.Faces), target)
+	}
+	if a := mesh.SurfaceArea(); math.Abs(a-area) > 0.01*area {
+		t.Errorf("simplified area %g, want within 1%% of %g", a, area)
+	}
+	// The square outline stays and the seam does not open
+	r := Analyze(mesh)
+	onBorder := func(p math3d.Vec3) bool { return p.X == 0 || p.X == 1 || p.Y == 0 || p.Y == 1 }
+	for _, e := range r.BoundaryEdges {
+		if !onBorder(e.From) || !onBorder(e.To) || (e.From.X != e.To.X && e.From.Y != e.To.Y) {
+			t.Fatalf("boundary edge %v - %v inside the square", e.From, e.To)
+		}
+	}
+	if len(r.InconsistentFaces) != 0 || len(r.NonManifoldEdges) != 0 {
+		t.Errorf("simplified grid: %+v", r)
+	}
+	// Faces keep a single UV chart
+	for _, f := range mesh.Faces {
+		left := mesh.Vertices[f.V[0]].UV.X < 1
+		for _, v := range f.V {
+			if mesh.Vertices[v].UV.X < 1 != left {
+				t.Fatalf("face %v mixes UV charts", f.V)
+			}
+		}
+	}
+}
+
+func TestSimplifyRiggedMesh(t *testing.T) {
+	mesh := seamGridMesh(4)
+	mesh.Rig = NewRig()
+	if removed := mesh.Simplify(4); removed != 0 || len(mesh.Faces) != 32 {
+		t.Errorf("Simplify() of a rigged mesh = %d, %d faces left, want it unchanged", removed, len(mesh.Faces))
+	}
+}
+
+func TestSimplifyKeepsOpenBorders(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(*Mesh)
+	}{
+		{"hole", func(m *Mesh) { m.Faces = slices.Delete(m.Faces, 100, 101) }},
+		{"flap", func(m *Mesh) {
+			// A triangle sticking out of a sphere edge, which becomes non-manifold
+			f := m.Faces[100]
+			a, b := m.Vertices[f.V[0]].Position, m.Vertices[f.V[1]].Position
+			tip := a.Add(b).Scale(0.6)
+			m.Vertices = append(m.Vertices, MeshVertex{Position: tip, Normal: tip})
+			m.Faces = append(m.Faces, Face{V: [3]int{f.V[0], f.V[1], len(m.Vertices) - 1}})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mesh := sphereMesh(16, 32)
+			tt.edit(mesh)
+			before := Analyze(mesh)
+			if len(before.BoundaryEdges) == 0 {
+				t.Fatal("test mesh has no border")
+			}
+			mesh.Simplify(len(mesh.Faces) / 10)
+			after := Analyze(mesh)
+			if len(after.BoundaryEdges) != len(before.BoundaryEdges) || len(after.NonManifoldEdges) != len(before.NonManifoldEdges) {
+				t.Errorf("%d boundary and %d non-manifold edges left, want %d and %d", len(after.BoundaryEdges),
+					len(after.NonManifoldEdges), len(before.BoundaryEdges), len(before.NonManifoldEdges))
+			}
+			if len(after.SelfIntersections) != 0 {
+				t.Errorf("simplified mesh crosses itself: %v", after.SelfIntersections)
+			}
+		})
+	}
+}
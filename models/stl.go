@@ -22,6 +22,8 @@ type STLLoader struct {
 	NoDedupe       bool    // If true, don't deduplicate vertices (each triangle gets its own)
 	CleanMesh      bool    // If true, clean mesh after loading (remove degenerate/duplicate/internal faces)
 	MergeTolerance float64 // Tolerance for vertex merging (default 1e-6, 0 = exact match)
+	// Repairs applied after cleaning: winding, holes and T-junctions (see Mesh.Repair)
+	Repair RepairOptions
 }
 
 // quantizedKey creates a hashable key from a position by quantizing to a grid.
@@ -83,25 +85,44 @@ func (l *STLLoader) Load(r io.Reader, name string) (*Mesh, error) {
 // Binary STL starts with 80-byte header, then 4-byte triangle count.
 // ASCII STL starts with "solid".
 func isBinarySTL(data []byte) bool {
-	if len(data) < 84 {
+	return isBinarySTLHead(data, int64(len(data)))
+}
+
+// isBinarySTLHead is isBinarySTL for a file of the given size (-1 if
+// unknown) starting with head.
+func isBinarySTLHead(head []byte, size int64) bool {
+	if len(head) < 84 {
 		return false
 	}
+	if size < 0 {
+		// The triangle count and the float data are not text
+		return !isText(head[80:])
+	}
 	// Check if it starts with "solid" (ASCII format)
-	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	trimmed := bytes.TrimLeft(head, " \t\r\n")
 	if bytes.HasPrefix(trimmed, []byte("solid")) {
 		// Could still be binary if "solid" appears in header
 		// Check if triangle count matches file size
-		triCount := binary.LittleEndian.Uint32(data[80:84])
-		expectedSize := 84 + triCount*50
-		//nolint:gosec // G115: triCount is validated to be reasonable size
-		if uint32(len(data)) == expectedSize {
-			return true
+		return size == binarySTLSize(head)
+	}
+	return true
+}
+
+// isText reports whether data has no control characters but white space.
+func isText(data []byte) bool {
+	for _, b := range data {
+		if (b < ' ' && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != '\v') || b == 0x7f {
+			return false
 		}
-		return false
 	}
 	return true
 }
 
+// binarySTLSize returns the size of a binary STL file from its header.
+func binarySTLSize(head []byte) int64 {
+	return 84 + 50*int64(binary.LittleEndian.Uint32(head[80:84]))
+}
+
 // loadBinary parses binary STL format.
 func (l *STLLoader) loadBinary(data []byte, name string) (*Mesh, error) {
 	if len(data) < 84 {
@@ -176,14 +197,23 @@ func (l *STLLoader) loadBinary(data []byte, name string) (*Mesh, error) {
 			mesh.Vertices[i].Normal = mesh.Vertices[i].Normal.Normalize()
 		}
 	}
+	l.postProcess(mesh)
+	return mesh, nil
+}
+
+// postProcess applies the cleaning, repair and normal options to a loaded
+// mesh.
+func (l *STLLoader) postProcess(mesh *Mesh) {
+	if l.CleanMesh {
+		mesh.CleanMesh()
+	}
+	if l.Repair != (RepairOptions{}) {
+		mesh.Repair(l.Repair)
+	}
 	mesh.CalculateBounds()
 	if l.SmoothNormals {
 		mesh.CalculateSmoothNormals()
 	}
-	if l.CleanMesh {
-		mesh.CleanMesh()
-	}
-	return mesh, nil
 }
 
 // readFloat32LE reads a little-endian float32 from a byte slice.
@@ -314,13 +344,7 @@ func (l *STLLoader) loadASCII(data []byte, name string) (*Mesh, error) {
 			mesh.Vertices[i].Normal = mesh.Vertices[i].Normal.Normalize()
 		}
 	}
-	mesh.CalculateBounds()
-	if l.SmoothNormals {
-		mesh.CalculateSmoothNormals()
-	}
-	if l.CleanMesh {
-		mesh.CleanMesh()
-	}
+	l.postProcess(mesh)
 	return mesh, nil
 }
 
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// WriteSTL writes the triangles of a mesh as a binary STL, with their face
+// normals. Lines and points are not written.
+func WriteSTL(w io.Writer, mesh *Mesh) error {
+	if len(mesh.Faces) > math.MaxUint32 {
+		return errors.New("too many triangles for STL")
+	}
+	// The header must not start with "solid", which marks ASCII files
+	var header [84]byte
+	copy(header[:80], "binary STL "+mesh.Name)
+	binary.LittleEndian.PutUint32(header[80:], uint32(len(mesh.Faces))) //nolint:gosec // checked above
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(header[:]); err != nil {
+		return err
+	}
+	var record [50]byte
+	for _, f := range mesh.Faces {
+		n := mesh.fileFaceNormal(f)
+		values := [12]float64{n.X, n.Y, n.Z}
+		for k, v := range fileTriangle(f) {
+			p := mesh.Vertices[v].Position
+			values[3+k*3], values[4+k*3], values[5+k*3] = p.X, p.Y, p.Z
+		}
+		for i, v := range values {
+			binary.LittleEndian.PutUint32(record[i*4:], math.Float32bits(float32(v)))
+		}
+		if _, err := bw.Write(record[:]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteSTLASCII writes the triangles of a mesh as an ASCII STL, with their
+// face normals.
+func WriteSTLASCII(w io.Writer, mesh *Mesh) error {
+	name := strings.Join(strings.Fields(mesh.Name), "_")
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "solid %s\n", name)
+	for _, f := range mesh.Faces {
+		n := mesh.fileFaceNormal(f)
+		fmt.Fprintf(bw, "  facet normal %g %g %g\n    outer loop\n", float32(n.X), float32(n.Y), float32(n.Z))
+		for _, v := range fileTriangle(f) {
+			p := mesh.Vertices[v].Position
+			fmt.Fprintf(bw, "      vertex %g %g %g\n", float32(p.X), float32(p.Y), float32(p.Z))
+		}
+		fmt.Fprintf(bw, "    endloop\n  endfacet\n")
+	}
+	fmt.Fprintf(bw, "endsolid %s\n", name)
+	return bw.Flush()
+}
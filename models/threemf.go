@@ -0,0 +1,418 @@
+package models
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// 3MF package parts.
+const (
+	threeMFRels      = "_rels/.rels"
+	threeMFModelType = "http://schemas.microsoft.com/3dmanufacturing/2013/01/3dmodel"
+	threeMFDefault   = "3D/3dmodel.model"
+	threeMFMaxDepth  = 32      // Component nesting limit (guards against cycles)
+	threeMFMaxPlaced = 1 << 20 // Object instances limit (guards against shared component blowup)
+)
+
+// threeMFUnits are the sizes of the 3MF model units in millimeters.
+var threeMFUnits = map[string]float64{
+	"micron":     0.001,
+	"millimeter": 1,
+	"centimeter": 10,
+	"inch":       25.4,
+	"foot":       304.8,
+	"meter":      1000,
+}
+
+// threeMFModel is a 3D model part.
+type threeMFModel struct {
+	Unit      string `xml:"unit,attr"`
+	Resources struct {
+		BaseMaterials []threeMFBaseMaterials `xml:"basematerials"`
+		ColorGroups   []threeMFColorGroup    `xml:"colorgroup"`
+		Objects       []threeMFObject        `xml:"object"`
+	} `xml:"resources"`
+	Items []threeMFItem `xml:"build>item"`
+}
+
+type threeMFBaseMaterials struct {
+	ID    int `xml:"id,attr"`
+	Bases []struct {
+		Name  string `xml:"name,attr"`
+		Color string `xml:"displaycolor,attr"`
+	} `xml:"base"`
+}
+
+type threeMFColorGroup struct {
+	ID     int `xml:"id,attr"`
+	Colors []struct {
+		Color string `xml:"color,attr"`
+	} `xml:"color"`
+}
+
+type threeMFObject struct {
+	ID         int           `xml:"id,attr"`
+	Name       string        `xml:"name,attr"`
+	PID        string        `xml:"pid,attr"`
+	PIndex     string        `xml:"pindex,attr"`
+	Mesh       *threeMFMesh  `xml:"mesh"`
+	Components []threeMFItem `xml:"components>component"`
+}
+
+type threeMFMesh struct {
+	Vertices []struct {
+		X float64 `xml:"x,attr"`
+		Y float64 `xml:"y,attr"`
+		Z float64 `xml:"z,attr"`
+	} `xml:"vertices>vertex"`
+	Triangles []struct {
+		V1  int    `xml:"v1,attr"`
+		V2  int    `xml:"v2,attr"`
+		V3  int    `xml:"v3,attr"`
+		PID string `xml:"pid,attr"`
+		P1  string `xml:"p1,attr"`
+	} `xml:"triangles>triangle"`
+}
+
+// threeMFItem is a build item or a component: an object reference with a
+// transform, in another model part of the package when Path is set
+// (production extension).
+type threeMFItem struct {
+	ObjectID  int    `xml:"objectid,attr"`
+	Transform string `xml:"transform,attr"`
+	Path      string `xml:"path,attr"`
+}
+
+// threeMFRelationships is the package relationships part.
+type threeMFRelationships struct {
+	Relationships []struct {
+		Target string `xml:"Target,attr"`
+		Type   string `xml:"Type,attr"`
+	} `xml:"Relationship"`
+}
+
+// threeMFMaterialKey identifies a material of a model part.
+type threeMFMaterialKey struct {
+	part       string
+	id, pindex int
+}
+
+// threeMFLoader flattens the build of a 3MF package into a mesh.
+type threeMFLoader struct {
+	pkg       *zip.Reader
+	parts     map[string]*threeMFModel
+	mesh      *Mesh
+	materials map[threeMFMaterialKey]int
+	placed    int // Object instances added so far
+}
+
+// Load3MF loads a 3MF file from disk.
+func Load3MF(path string) (*Mesh, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read 3MF file: %w", err)
+	}
+	return Load3MFBytes(data, path)
+}
+
+// Load3MFFromFS loads a 3MF file from a filesystem interface.
+func Load3MFFromFS(fsys fs.FS, path string) (*Mesh, error) {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("read 3MF file: %w", err)
+	}
+	return Load3MFBytes(data, path)
+}
+
+// Load3MFBytes parses a 3MF package: the mesh objects and components of its
+// build items, with their transforms, flattened into one mesh. Base materials
+// and color groups (per triangle, or per object by default) become
+// Materials, and each build item a FaceGroup when there are several.
+// Coordinates are converted to millimeters from the unit of the root model
+// part, and mirroring transforms keep the faces outward.
+func Load3MFBytes(data []byte, name string) (*Mesh, error) {
+	pkg, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("open 3MF package: %w", err)
+	}
+	l := &threeMFLoader{
+		pkg:       pkg,
+		parts:     make(map[string]*threeMFModel),
+		mesh:      NewMesh(name),
+		materials: make(map[threeMFMaterialKey]int),
+	}
+	root := l.rootPart()
+	model, err := l.part(root)
+	if err != nil {
+		return nil, err
+	}
+	unit := 1.0
+	if model.Unit != "" {
+		var ok bool
+		if unit, ok = threeMFUnits[model.Unit]; !ok {
+			return nil, fmt.Errorf("unsupported unit %q", model.Unit)
+		}
+	}
+	toMM := math3d.Scale(math3d.V3(unit, unit, unit))
+	for i, item := range model.Items {
+		part := root
+		if item.Path != "" {
+			part = threeMFPartPath(root, item.Path)
+		}
+		transform, err := parse3MFTransform(item.Transform)
+		if err != nil {
+			return nil, fmt.Errorf("build item %d: %w", i, err)
+		}
+		groupName, err := l.objectName(part, item.ObjectID)
+		if err != nil {
+			return nil, fmt.Errorf("build item %d: %w", i, err)
+		}
+		l.mesh.Groups = append(l.mesh.Groups, FaceGroup{Name: groupName})
+		if err := l.addObject(part, item.ObjectID, toMM.Mul(transform), len(l.mesh.Groups)-1, 0); err != nil {
+			return nil, fmt.Errorf("build item %d: %w", i, err)
+		}
+	}
+	if len(l.mesh.Groups) == 1 {
+		l.mesh.Groups = nil
+	}
+	l.mesh.CalculateBounds()
+	l.mesh.CalculateSmoothNormals()
+	return l.mesh, nil
+}
+
+// rootPart returns the path of the package's 3D model part.
+func (l *threeMFLoader) rootPart() string {
+	data, err := l.readFile(threeMFRels)
+	if err != nil {
+		return threeMFDefault
+	}
+	var rels threeMFRelationships
+	if xml.Unmarshal(data, &rels) != nil {
+		return threeMFDefault
+	}
+	for _, r := range rels.Relationships {
+		if r.Type == threeMFModelType {
+			// Relative to the package root, the source of _rels/.rels
+			return threeMFPartPath("", r.Target)
+		}
+	}
+	return threeMFDefault
+}
+
+// readFile reads a package part, matching its name case insensitively as
+// some producers do not preserve the case of their relationships.
+func (l *threeMFLoader) readFile(name string) ([]byte, error) {
+	data, err := fs.ReadFile(l.pkg, name)
+	if !errors.Is(err, fs.ErrNotExist) {
+		return data, err
+	}
+	for _, f := range l.pkg.File {
+		if strings.EqualFold(f.Name, name) {
+			return fs.ReadFile(l.pkg, f.Name)
+		}
+	}
+	return nil, err
+}
+
+// part returns the decoded model part at name.
+func (l *threeMFLoader) part(name string) (*threeMFModel, error) {
+	if model, ok := l.parts[name]; ok {
+		return model, nil
+	}
+	data, err := l.readFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("read model part: %w", err)
+	}
+	model := &threeMFModel{}
+	if err := xml.Unmarshal(data, model); err != nil {
+		return nil, fmt.Errorf("parse model part %s: %w", name, err)
+	}
+	l.parts[name] = model
+	return model, nil
+}
+
+// object returns the object with the given id in a model part.
+func (l *threeMFLoader) object(part string, id int) (*threeMFModel, *threeMFObject, error) {
+	model, err := l.part(part)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range model.Resources.Objects {
+		if model.Resources.Objects[i].ID == id {
+			return model, &model.Resources.Objects[i], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("object %d not found in %s", id, part)
+}
+
+// objectName returns the name of an object, or a default one.
+func (l *threeMFLoader) objectName(part string, id int) (string, error) {
+	_, obj, err := l.object(part, id)
+	if err != nil {
+		return "", err
+	}
+	if obj.Name != "" {
+		return obj.Name, nil
+	}
+	return fmt.Sprintf("object %d", id), nil
+}
+
+// addObject appends an object, and recursively its components, transformed.
+func (l *threeMFLoader) addObject(part string, id int, transform math3d.Mat4, group, depth int) error {
+	if depth > threeMFMaxDepth {
+		return errors.New("components nested too deeply")
+	}
+	if l.placed++; l.placed > threeMFMaxPlaced {
+		return errors.New("too many object instances")
+	}
+	model, obj, err := l.object(part, id)
+	if err != nil {
+		return err
+	}
+	for _, c := range obj.Components {
+		childPart := part
+		if c.Path != "" {
+			childPart = threeMFPartPath(part, c.Path)
+		}
+		local, err := parse3MFTransform(c.Transform)
+		if err != nil {
+			return fmt.Errorf("object %d component: %w", id, err)
+		}
+		if err := l.addObject(childPart, c.ObjectID, transform.Mul(local), group, depth+1); err != nil {
+			return err
+		}
+	}
+	if obj.Mesh == nil {
+		return nil
+	}
+	first := len(l.mesh.Vertices)
+	for _, v := range obj.Mesh.Vertices {
+		l.mesh.Vertices = append(l.mesh.Vertices, MeshVertex{Position: transform.MulVec3(math3d.V3(v.X, v.Y, v.Z))})
+	}
+	count := len(obj.Mesh.Vertices)
+	defaultMaterial := l.material(model, part, obj.PID, obj.PIndex)
+	// Mirroring transforms flip the winding
+	flip := transform.Determinant() < 0
+	for _, t := range obj.Mesh.Triangles {
+		if t.V1 < 0 || t.V2 < 0 || t.V3 < 0 || t.V1 >= count || t.V2 >= count || t.V3 >= count {
+			return fmt.Errorf("object %d: triangle vertex out of range", id)
+		}
+		material := defaultMaterial
+		if t.PID != "" || t.P1 != "" {
+			pid := t.PID
+			if pid == "" {
+				pid = obj.PID
+			}
+			material = l.material(model, part, pid, t.P1)
+		}
+		// 3MF triangles are counter clockwise: reverse the winding
+		face := Face{V: [3]int{first + t.V1, first + t.V3, first + t.V2}, Material: material, Group: group}
+		if flip {
+			face.V[1], face.V[2] = face.V[2], face.V[1]
+		}
+		l.mesh.Faces = append(l.mesh.Faces, face)
+	}
+	return nil
+}
+
+// material returns the mesh material of a property group and index (base
+// materials or color group), adding it on first use; -1 when there is none.
+func (l *threeMFLoader) material(model *threeMFModel, part, pid, pindex string) int {
+	id, err := strconv.Atoi(pid)
+	if err != nil {
+		return -1
+	}
+	index := 0
+	if pindex != "" {
+		if index, err = strconv.Atoi(pindex); err != nil {
+			return -1
+		}
+	}
+	key := threeMFMaterialKey{part, id, index}
+	if m, ok := l.materials[key]; ok {
+		return m
+	}
+	var mat *Material
+	for _, g := range model.Resources.BaseMaterials {
+		if g.ID == id && index >= 0 && index < len(g.Bases) {
+			mat = &Material{Name: g.Bases[index].Name, BaseColor: parse3MFColor(g.Bases[index].Color), Roughness: 1}
+		}
+	}
+	for _, g := range model.Resources.ColorGroups {
+		if g.ID == id && index >= 0 && index < len(g.Colors) {
+			mat = &Material{
+				Name:      fmt.Sprintf("color %d/%d", id, index),
+				BaseColor: parse3MFColor(g.Colors[index].Color),
+				Roughness: 1,
+			}
+		}
+	}
+	m := -1
+	if mat != nil {
+		m = len(l.mesh.Materials)
+		l.mesh.Materials = append(l.mesh.Materials, *mat)
+	}
+	l.materials[key] = m
+	return m
+}
+
+// parse3MFTransform parses a 3MF "m00 m01 m02 m10 ... m32" affine transform.
+// 3MF transforms row vectors, so the values are the matrix columns.
+func parse3MFTransform(s string) (math3d.Mat4, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return math3d.Identity(), nil
+	}
+	if len(fields) != 12 {
+		return math3d.Mat4{}, fmt.Errorf("invalid transform %q", s)
+	}
+	m := math3d.Identity()
+	for c := range 4 {
+		for r := range 3 {
+			v, err := strconv.ParseFloat(fields[c*3+r], 64)
+			if err != nil {
+				return math3d.Mat4{}, fmt.Errorf("invalid transform %q: %w", s, err)
+			}
+			m[r+c*4] = v
+		}
+	}
+	return m, nil
+}
+
+// parse3MFColor parses an sRGB "#RRGGBB" or "#RRGGBBAA" color, white if invalid.
+func parse3MFColor(s string) [4]float64 {
+	c := [4]float64{1, 1, 1, 1}
+	s = strings.TrimPrefix(s, "#")
+	if len(s) != 6 && len(s) != 8 {
+		return c
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return c
+	}
+	if len(s) == 6 {
+		v = v<<8 | 0xff
+	}
+	for i := range 4 {
+		c[i] = float64(v>>(24-8*i)&0xff) / 255
+	}
+	return c
+}
+
+// threeMFPartPath joins a relative part reference to the directory of part.
+func threeMFPartPath(part, ref string) string {
+	if strings.HasPrefix(ref, "/") {
+		return ref[1:]
+	}
+	return path.Join(path.Dir(part), ref)
+}
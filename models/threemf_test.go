@@ -0,0 +1,217 @@
+package models
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+const threeMFRelsXML = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+ <Relationship Target="/3D/Plate.model" Id="rel0" Type="http://schemas.microsoft.com/3dmanufacturing/2013/01/3dmodel"/>
+</Relationships>`
+
+const threeMFRootXML = `<?xml version="1.0" encoding="UTF-8"?>
+<model unit="millimeter" xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02"
+ xmlns:m="http://schemas.microsoft.com/3dmanufacturing/material/2015/02"
+ xmlns:p="http://schemas.microsoft.com/3dmanufacturing/production/2015/06">
+ <resources>
+  <basematerials id="1">
+   <base name="PLA Red" displaycolor="#FF0000"/>
+   <base name="PLA Blue" displaycolor="#0000FF80"/>
+  </basematerials>
+  <m:colorgroup id="2">
+   <m:color color="#00FF00"/>
+  </m:colorgroup>
+  <object id="3" name="tri" type="model" pid="1" pindex="1">
+   <mesh>
+    <vertices>
+     <vertex x="0" y="0" z="0"/>
+     <vertex x="1" y="0" z="0"/>
+     <vertex x="0" y="1" z="0"/>
+     <vertex x="1" y="1" z="0"/>
+    </vertices>
+    <triangles>
+     <triangle v1="0" v2="1" v3="2"/>
+     <triangle v1="1" v2="3" v3="2" pid="2" p1="0"/>
+    </triangles>
+   </mesh>
+  </object>
+  <object id="4" name="assembly">
+   <components>
+    <component objectid="3" transform="1 0 0 0 1 0 0 0 1 10 0 0"/>
+    <component objectid="5" p:path="/3D/Objects/part.model"/>
+   </components>
+  </object>
+ </resources>
+ <build>
+  <item objectid="3"/>
+  <item objectid="4" transform="0 1 0 -1 0 0 0 0 1 0 0 5"/>
+ </build>
+</model>`
+
+const threeMFPartXML = `<?xml version="1.0" encoding="UTF-8"?>
+<model unit="millimeter" xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02">
+ <resources>
+  <basematerials id="1">
+   <base name="PETG White" displaycolor="#FFFFFF"/>
+  </basematerials>
+  <object id="5" pid="1">
+   <mesh>
+    <vertices>
+     <vertex x="0" y="0" z="0"/>
+     <vertex x="0" y="0" z="1"/>
+     <vertex x="0" y="1" z="0"/>
+    </vertices>
+    <triangles>
+     <triangle v1="0" v2="1" v3="2"/>
+    </triangles>
+   </mesh>
+  </object>
+ </resources>
+</model>`
+
+// encode3MF zips the given parts into a 3MF package.
+func encode3MF(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip create: %v", err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("zip write: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestLoad3MF(t *testing.T) {
+	data := encode3MF(t, map[string]string{
+		"_rels/.rels":           threeMFRelsXML,
+		"3D/Plate.model":        threeMFRootXML,
+		"3D/Objects/part.model": threeMFPartXML,
+	})
+	mesh, err := Load3MFFromFS(fstest.MapFS{"plate.3mf": {Data: data}}, "plate.3mf")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	// tri (4 vertices), then the assembly: tri again and the external part
+	if mesh.VertexCount() != 11 || mesh.TriangleCount() != 5 {
+		t.Fatalf("vertices %d, triangles %d", mesh.VertexCount(), mesh.TriangleCount())
+	}
+	if len(mesh.Groups) != 2 || mesh.Groups[0].Name != "tri" || mesh.Groups[1].Name != "assembly" {
+		t.Fatalf("groups %+v", mesh.Groups)
+	}
+	if mesh.Faces[0].Group != 0 || mesh.Faces[4].Group != 1 {
+		t.Errorf("face groups %d %d", mesh.Faces[0].Group, mesh.Faces[4].Group)
+	}
+	// Object default material, per triangle color, and the other part's own
+	if len(mesh.Materials) != 3 {
+		t.Fatalf("materials %+v", mesh.Materials)
+	}
+	blue := mesh.Materials[mesh.Faces[0].Material]
+	if blue.Name != "PLA Blue" || blue.BaseColor != [4]float64{0, 0, 1, 128.0 / 255} {
+		t.Errorf("object material %+v", blue)
+	}
+	if green := mesh.Materials[mesh.Faces[1].Material]; green.BaseColor != [4]float64{0, 1, 0, 1} {
+		t.Errorf("triangle color %+v", green)
+	}
+	if mesh.Faces[2].Material != mesh.Faces[0].Material {
+		t.Errorf("shared object material not reused: %d %d", mesh.Faces[2].Material, mesh.Faces[0].Material)
+	}
+	if white := mesh.Materials[mesh.Faces[4].Material]; white.Name != "PETG White" {
+		t.Errorf("part material %+v", white)
+	}
+	// Component translation then the item's rotation about Z and lift
+	assertVec3(t, "component vertex", mesh.Vertices[5].Position, math3d.V3(0, 11, 5))
+	assertVec3(t, "part vertex", mesh.Vertices[9].Position, math3d.V3(0, 0, 6))
+	// Reversed winding for the engine
+	f := mesh.Faces[0]
+	a, b, c := mesh.Vertices[f.V[0]].Position, mesh.Vertices[f.V[1]].Position, mesh.Vertices[f.V[2]].Position
+	if b.Sub(a).Cross(c.Sub(a)).Z >= 0 {
+		t.Errorf("face %v not reversed", f.V)
+	}
+	if scene := mesh.GroupScene(); scene == nil || len(scene.Meshes) != 2 {
+		t.Errorf("group scene %+v", scene)
+	}
+}
+
+func TestLoad3MFErrors(t *testing.T) {
+	cases := map[string]map[string]string{
+		"missing object": {"3D/3dmodel.model": `<model><build><item objectid="9"/></build></model>`},
+		"bad index": {"3D/3dmodel.model": `<model><resources><object id="1"><mesh>
+			<vertices><vertex x="0" y="0" z="0"/></vertices>
+			<triangles><triangle v1="0" v2="0" v3="1"/></triangles>
+			</mesh></object></resources><build><item objectid="1"/></build></model>`},
+		"cycle": {"3D/3dmodel.model": `<model><resources><object id="1"><components>
+			<component objectid="1"/></components></object></resources>
+			<build><item objectid="1"/></build></model>`},
+		"bad transform": {"3D/3dmodel.model": `<model><resources><object id="1"/></resources>
+			<build><item objectid="1" transform="1 0 0"/></build></model>`},
+		"no model": {"readme.txt": "hello"},
+	}
+	for name, parts := range cases {
+		if _, err := Load3MFBytes(encode3MF(t, parts), name); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := Load3MFBytes([]byte("not a zip"), "x"); err == nil {
+		t.Error("expected an error for a non zip file")
+	}
+}
+
+// threeMFTetrahedron is an outward tetrahedron object of volume 1/6.
+const threeMFTetrahedron = `<object id="1" name="tetrahedron"><mesh>
+   <vertices>
+    <vertex x="0" y="0" z="0"/><vertex x="1" y="0" z="0"/><vertex x="0" y="1" z="0"/><vertex x="0" y="0" z="1"/>
+   </vertices>
+   <triangles>
+    <triangle v1="0" v2="2" v3="1"/><triangle v1="0" v2="1" v3="3"/>
+    <triangle v1="0" v2="3" v3="2"/><triangle v1="1" v2="2" v3="3"/>
+   </triangles>
+  </mesh></object>`
+
+func TestLoad3MFUnitsAndMirroring(t *testing.T) {
+	for unit, mm := range map[string]float64{"": 1, "micron": 0.001, "millimeter": 1, "centimeter": 10, "inch": 25.4, "foot": 304.8, "meter": 1000} {
+		attr := ""
+		if unit != "" {
+			attr = ` unit="` + unit + `"`
+		}
+		// The tetrahedron as is, mirrored by its build item, and in an
+		// assembly as is and mirrored by a component
+		doc := `<model` + attr + `><resources>` + threeMFTetrahedron + `
+		 <object id="2" name="assembly"><components>
+		  <component objectid="1"/>
+		  <component objectid="1" transform="-1 0 0 0 1 0 0 0 1 -2 0 0"/>
+		 </components></object>
+		</resources><build>
+		 <item objectid="1"/>
+		 <item objectid="1" transform="1 0 0 0 1 0 0 0 -1 0 0 -2"/>
+		 <item objectid="2" transform="1 0 0 0 1 0 0 0 1 0 4 0"/>
+		</build></model>`
+		mesh, err := Load3MFBytes(encode3MF(t, map[string]string{"3D/3dmodel.model": doc}), unit)
+		if err != nil {
+			t.Fatalf("%s: %v", unit, err)
+		}
+		// Every instance outward, scaled to millimeters
+		if v, want := mesh.SignedVolume(), 4*mm*mm*mm/6; !near(v, want) {
+			t.Errorf("%s: volume %g, want %g", unit, v, want)
+		}
+		if r := Analyze(mesh); len(r.InconsistentFaces) != 0 {
+			t.Errorf("%s: inconsistent faces %v", unit, r.InconsistentFaces)
+		}
+		assertVec3(t, unit+" mirrored component vertex", mesh.Vertices[13].Position, math3d.V3(-3*mm, 4*mm, 0))
+	}
+	if _, err := Load3MFBytes(encode3MF(t, map[string]string{"3D/3dmodel.model": `<model unit="furlong"/>`}), "x"); err == nil {
+		t.Error("expected an error for an unknown unit")
+	}
+}
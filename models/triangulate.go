@@ -0,0 +1,138 @@
+package models
+
+import (
+	"math"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// TriangulatePolygon splits a simple, possibly concave, planar polygon into
+// triangles by ear clipping on its best-fit (Newell) plane. The triangles are
+// index triples into pts with the polygon's winding. Duplicate and collinear
+// vertices produce no degenerate triangles; a degenerate or self-intersecting
+// polygon falls back to a fan for what cannot be clipped.
+func TriangulatePolygon(pts []math3d.Vec3) [][3]int {
+	// Drop consecutive duplicates, including the closing one
+	idx := make([]int, 0, len(pts))
+	for i, p := range pts {
+		if len(idx) > 0 && p == pts[idx[len(idx)-1]] {
+			continue
+		}
+		idx = append(idx, i)
+	}
+	for len(idx) > 1 && pts[idx[0]] == pts[idx[len(idx)-1]] {
+		idx = idx[:len(idx)-1]
+	}
+	if len(idx) < 3 {
+		return nil
+	}
+	normal := newellNormal(pts, idx)
+	if normal.LenSq() == 0 {
+		// Zero area, or self-intersecting with cancelling lobes
+		var tris [][3]int
+		for _, t := range fanTriangles(idx) {
+			if pts[t[1]].Sub(pts[t[0]]).Cross(pts[t[2]].Sub(pts[t[0]])).LenSq() > 0 {
+				tris = append(tris, t)
+			}
+		}
+		return tris
+	}
+	// Plane basis with u × v = normal, so the polygon winds counter clockwise
+	u := normal.Cross(math3d.V3(1, 0, 0))
+	if u.LenSq() < 1e-6*normal.LenSq() {
+		u = normal.Cross(math3d.V3(0, 1, 0))
+	}
+	u = u.Normalize()
+	v := normal.Normalize().Cross(u)
+	flat := make([]math3d.Vec2, len(pts))
+	lo, hi := math3d.V2(math.Inf(1), math.Inf(1)), math3d.V2(math.Inf(-1), math.Inf(-1))
+	for _, i := range idx {
+		p := math3d.V2(pts[i].Dot(u), pts[i].Dot(v))
+		flat[i] = p
+		lo = math3d.V2(math.Min(lo.X, p.X), math.Min(lo.Y, p.Y))
+		hi = math3d.V2(math.Max(hi.X, p.X), math.Max(hi.Y, p.Y))
+	}
+	d := hi.Sub(lo)
+	eps := 1e-12 * (d.X*d.X + d.Y*d.Y)
+	return earClip(flat, idx, eps)
+}
+
+// newellNormal returns the (area weighted) normal of the polygon idx.
+func newellNormal(pts []math3d.Vec3, idx []int) math3d.Vec3 {
+	var n math3d.Vec3
+	for k, i := range idx {
+		a, b := pts[i], pts[idx[(k+1)%len(idx)]]
+		n.X += (a.Y - b.Y) * (a.Z + b.Z)
+		n.Y += (a.Z - b.Z) * (a.X + b.X)
+		n.Z += (a.X - b.X) * (a.Y + b.Y)
+	}
+	return n
+}
+
+// cross2 returns the z component of (b - a) × (c - b), positive for a left turn.
+func cross2(a, b, c math3d.Vec2) float64 {
+	return (b.X-a.X)*(c.Y-b.Y) - (b.Y-a.Y)*(c.X-b.X)
+}
+
+// earClip triangulates the counter clockwise polygon idx of the 2D points flat.
+func earClip(flat []math3d.Vec2, idx []int, eps float64) [][3]int {
+	tris := make([][3]int, 0, len(idx)-2)
+	poly := append([]int(nil), idx...)
+	for len(poly) > 3 {
+		n := len(poly)
+		clipped := false
+		for k := range n {
+			ia, ib, ic := poly[(k+n-1)%n], poly[k], poly[(k+1)%n]
+			turn := cross2(flat[ia], flat[ib], flat[ic])
+			if math.Abs(turn) <= eps {
+				// Collinear vertex or zero width spike: drop it, it adds no area
+				poly = append(poly[:k], poly[k+1:]...)
+				clipped = true
+				break
+			}
+			if turn <= eps || !isEar(flat, poly, ia, ib, ic, eps) {
+				continue
+			}
+			tris = append(tris, [3]int{ia, ib, ic})
+			poly = append(poly[:k], poly[k+1:]...)
+			clipped = true
+			break
+		}
+		if !clipped {
+			// Self-intersecting or numerically degenerate remainder
+			return append(tris, fanTriangles(poly)...)
+		}
+	}
+	if len(poly) == 3 && math.Abs(cross2(flat[poly[0]], flat[poly[1]], flat[poly[2]])) > eps {
+		tris = append(tris, [3]int{poly[0], poly[1], poly[2]})
+	}
+	return tris
+}
+
+// isEar reports whether no other vertex of poly lies in the convex triangle abc.
+// Vertices coinciding with a corner (bridged holes) do not block it.
+func isEar(flat []math3d.Vec2, poly []int, ia, ib, ic int, eps float64) bool {
+	a, b, c := flat[ia], flat[ib], flat[ic]
+	for _, j := range poly {
+		if j == ia || j == ib || j == ic {
+			continue
+		}
+		p := flat[j]
+		if p == a || p == b || p == c {
+			continue
+		}
+		if cross2(a, b, p) >= -eps && cross2(b, c, p) >= -eps && cross2(c, a, p) >= -eps {
+			return false
+		}
+	}
+	return true
+}
+
+// fanTriangles triangulates a polygon as a fan around its first vertex.
+func fanTriangles(idx []int) [][3]int {
+	tris := make([][3]int, 0, max(0, len(idx)-2))
+	for i := 1; i < len(idx)-1; i++ {
+		tris = append(tris, [3]int{idx[0], idx[i], idx[i+1]})
+	}
+	return tris
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// lShape is a concave hexagon of area 3 in the XY plane.
+func lShape() []math3d.Vec3 {
+	return []math3d.Vec3{
+		math3d.V3(0, 0, 0), math3d.V3(2, 0, 0), math3d.V3(2, 1, 0),
+		math3d.V3(1, 1, 0), math3d.V3(1, 2, 0), math3d.V3(0, 2, 0),
+	}
+}
+
+// star is a five pointed star in the XY plane.
+func star() []math3d.Vec3 {
+	pts := make([]math3d.Vec3, 10)
+	for i := range pts {
+		r := 1.0
+		if i%2 == 1 {
+			r = 0.4
+		}
+		a := float64(i) * math.Pi / 5
+		pts[i] = math3d.V3(r*math.Cos(a), r*math.Sin(a), 0)
+	}
+	return pts
+}
+
+// insidePolygon reports whether p (XY) is inside the polygon (even-odd rule).
+func insidePolygon(pts []math3d.Vec3, p math3d.Vec3) bool {
+	in := false
+	for i := range pts {
+		a, b := pts[i], pts[(i+1)%len(pts)]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < a.X+(p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y) {
+			in = !in
+		}
+	}
+	return in
+}
+
+// checkTriangulation verifies the triangles tile the polygon, with its winding.
+func checkTriangulation(t *testing.T, name string, pts []math3d.Vec3, tris [][3]int, wantTris int) {
+	t.Helper()
+	if len(tris) != wantTris {
+		t.Fatalf("%s: triangles = %d, want %d", name, len(tris), wantTris)
+	}
+	normal := newellNormal(pts, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}[:len(pts)])
+	area := 0.0
+	for _, tri := range tris {
+		a, b, c := pts[tri[0]], pts[tri[1]], pts[tri[2]]
+		n := b.Sub(a).Cross(c.Sub(a))
+		if n.Dot(normal) <= 0 {
+			t.Errorf("%s: triangle %v is degenerate or flipped", name, tri)
+		}
+		area += n.Len() / 2
+		if centroid := a.Add(b).Add(c).Scale(1.0 / 3); !insidePolygon(pts, centroid) {
+			t.Errorf("%s: triangle %v is outside the polygon", name, tri)
+		}
+	}
+	if want := normal.Len() / 2; math.Abs(area-want) > 1e-9 {
+		t.Errorf("%s: area = %v, want %v", name, area, want)
+	}
+}
+
+func TestTriangulatePolygon(t *testing.T) {
+	checkTriangulation(t, "L", lShape(), TriangulatePolygon(lShape()), 4)
+	checkTriangulation(t, "star", star(), TriangulatePolygon(star()), 8)
+	// Clockwise input keeps its winding
+	cw := lShape()
+	for i, j := 0, len(cw)-1; i < j; i, j = i+1, j-1 {
+		cw[i], cw[j] = cw[j], cw[i]
+	}
+	checkTriangulation(t, "clockwise L", cw, TriangulatePolygon(cw), 4)
+	// Duplicate vertices add no triangles, the collinear one is kept as a corner
+	messy := []math3d.Vec3{
+		math3d.V3(0, 0, 0), math3d.V3(1, 0, 0), math3d.V3(2, 0, 0), math3d.V3(2, 0, 0),
+		math3d.V3(2, 1, 0), math3d.V3(1, 1, 0), math3d.V3(1, 2, 0), math3d.V3(0, 2, 0), math3d.V3(0, 0, 0),
+	}
+	checkTriangulation(t, "messy L", messy, TriangulatePolygon(messy), 5)
+	if tris := TriangulatePolygon([]math3d.Vec3{math3d.V3(0, 0, 0), math3d.V3(1, 1, 1), math3d.V3(2, 2, 2)}); len(tris) != 0 {
+		t.Errorf("collinear polygon: %v", tris)
+	}
+}
+
+func TestTriangulateTiltedPolygon(t *testing.T) {
+	// The star rotated out of the XY plane still triangulates on its plane
+	rot := math3d.RotateX(1).Mul(math3d.RotateY(0.5))
+	pts := star()
+	tilted := make([]math3d.Vec3, len(pts))
+	for i, p := range pts {
+		tilted[i] = rot.MulVec3(p)
+	}
+	tris := TriangulatePolygon(tilted)
+	// Checked back in the XY plane
+	checkTriangulation(t, "tilted star", pts, tris, 8)
+}
+
+func TestLoadOBJConcaveFaces(t *testing.T) {
+	obj := `
+v 0 0 0
+v 2 0 0
+v 2 1 0
+v 1 1 0
+v 1 2 0
+v 0 2 0
+f 1 2 3 4 5 6
+`
+	mesh, err := NewOBJLoader().Load(strings.NewReader(obj), "L")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if mesh.TriangleCount() != 4 {
+		t.Fatalf("triangles = %d, want 4", mesh.TriangleCount())
+	}
+	for _, f := range mesh.Faces {
+		a, b, c := mesh.Vertices[f.V[0]].Position, mesh.Vertices[f.V[1]].Position, mesh.Vertices[f.V[2]].Position
+		// Reversed (clockwise) winding for the engine, and no triangle over the notch
+		if b.Sub(a).Cross(c.Sub(a)).Z >= 0 {
+			t.Errorf("face %v not reversed", f.V)
+		}
+		if centroid := a.Add(b).Add(c).Scale(1.0 / 3); centroid.X > 1 && centroid.Y > 1 {
+			t.Errorf("face %v covers the notch", f.V)
+		}
+	}
+}
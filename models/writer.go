@@ -0,0 +1,106 @@
+package models
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// SaveMesh writes a mesh to a file in the format of its extension: binary
+// STL (.stl), OBJ with its MTL library and textures (.obj), binary PLY (.ply)
+// or GLB (.glb). A copy of the mesh is scaled to the unit of the format
+// (Format.Unit) when its own differs, such as millimeters to glTF meters.
+func SaveMesh(path string, mesh *Mesh) error {
+	var write func(io.Writer, *Mesh) error
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".stl":
+		write = WriteSTL
+	case ".obj":
+		// SaveOBJ, with the MTL library and textures
+	case ".ply":
+		write = WritePLY
+	case ".glb":
+		write = WriteGLB
+	default:
+		return fmt.Errorf("unsupported output format: %s (use .stl, .obj, .ply or .glb)", ext)
+	}
+	if unit := formatUnit(ext); unit != mesh.UnitSize() {
+		mesh = mesh.Clone()
+		mesh.Transform(math3d.ScaleUniform(mesh.UnitSize() / unit))
+		mesh.Unit = unit
+	}
+	if write == nil {
+		return SaveOBJ(path, mesh)
+	}
+	return writeFile(path, func(w io.Writer) error { return write(w, mesh) })
+}
+
+// writeFile creates path and writes it through a buffer.
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	w := bufio.NewWriter(f)
+	err = write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
+
+// fileTriangle returns the vertices of a face in the counter clockwise order
+// of model files, reversing the engine winding like the loaders do.
+func fileTriangle(f Face) [3]int {
+	return [3]int{f.V[0], f.V[2], f.V[1]}
+}
+
+// fileFaceNormal returns the outward unit normal of a face, zero if it is
+// degenerate.
+func (m *Mesh) fileFaceNormal(f Face) math3d.Vec3 {
+	t := fileTriangle(f)
+	a, b, c := m.Vertices[t[0]].Position, m.Vertices[t[1]].Position, m.Vertices[t[2]].Position
+	n := b.Sub(a).Cross(c.Sub(a))
+	if n.LenSq() == 0 {
+		return n
+	}
+	return n.Normalize()
+}
+
+// hasVertexNormals reports whether any vertex has a normal.
+func (m *Mesh) hasVertexNormals() bool {
+	for i := range m.Vertices {
+		if m.Vertices[i].Normal.LenSq() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasVertexUVs reports whether the mesh has texture coordinates: any
+// textured material or non zero UV.
+func (m *Mesh) hasVertexUVs() bool {
+	for i := range m.Materials {
+		if m.Materials[i].HasTexture {
+			return true
+		}
+	}
+	for i := range m.Vertices {
+		if m.Vertices[i].UV != (math3d.Vec2{}) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,279 @@
+package models
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// writerTestMesh returns a unit quad in the XY plane facing +Z, with UVs,
+// normals and colors, a red and a textured material and two groups.
+func writerTestMesh() *Mesh {
+	mesh := NewMesh("quad")
+	corners := []math3d.Vec3{math3d.V3(0, 0, 0), math3d.V3(1, 0, 0), math3d.V3(1, 1, 0), math3d.V3(0, 1, 0)}
+	colors := []color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 128}, {255, 255, 255, 255}}
+	for i, p := range corners {
+		mesh.Vertices = append(mesh.Vertices, MeshVertex{
+			Position: p,
+			Normal:   math3d.V3(0, 0, 1),
+			UV:       math3d.V2(p.X, p.Y),
+			Color:    colors[i],
+		})
+	}
+	mesh.VertexColors = true
+	tex := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	tex.Set(1, 1, color.RGBA{10, 20, 30, 255})
+	mesh.Materials = []Material{
+		{Name: "red paint", BaseColor: [4]float64{1, 0, 0, 1}, Roughness: 0.5},
+		{Name: "wood", BaseColor: [4]float64{1, 1, 1, 1}, Roughness: 1, BaseMap: tex, HasTexture: true},
+	}
+	mesh.Groups = []FaceGroup{{Name: "left", Object: "panel"}, {Name: "right", Object: "panel"}}
+	// Counter clockwise (+Z) faces, stored with the engine winding
+	mesh.Faces = []Face{
+		{V: [3]int{0, 2, 1}, Material: 0, Group: 0},
+		{V: [3]int{0, 3, 2}, Material: 1, Group: 1},
+	}
+	mesh.CalculateBounds()
+	return mesh
+}
+
+// assertSameTriangles checks that got has the triangles of want, with the
+// same winding, in order.
+func assertSameTriangles(t *testing.T, got, want *Mesh) {
+	t.Helper()
+	if len(got.Faces) != len(want.Faces) {
+		t.Fatalf("%d faces, want %d", len(got.Faces), len(want.Faces))
+	}
+	for i := range want.Faces {
+		for k := range 3 {
+			assertVec3(t, "face vertex", got.Vertices[got.Faces[i].V[k]].Position, want.Vertices[want.Faces[i].V[k]].Position)
+		}
+	}
+}
+
+func TestWriteSTLRoundTrip(t *testing.T) {
+	mesh := writerTestMesh()
+	for name, write := range map[string]func(*bytes.Buffer) error{
+		"binary": func(b *bytes.Buffer) error { return WriteSTL(b, mesh) },
+		"ascii":  func(b *bytes.Buffer) error { return WriteSTLASCII(b, mesh) },
+	} {
+		var buf bytes.Buffer
+		if err := write(&buf); err != nil {
+			t.Fatalf("%s: write: %v", name, err)
+		}
+		if binary := isBinarySTL(buf.Bytes()); binary != (name == "binary") {
+			t.Errorf("%s: detected as binary %v", name, binary)
+		}
+		got, err := NewSTLLoader().LoadBytes(buf.Bytes(), "quad.stl")
+		if err != nil {
+			t.Fatalf("%s: load: %v", name, err)
+		}
+		assertSameTriangles(t, got, mesh)
+		// Face normals point out of the counter clockwise side
+		assertVec3(t, name+" normal", got.Vertices[0].Normal, math3d.V3(0, 0, 1))
+	}
+}
+
+func TestWriteOBJRoundTrip(t *testing.T) {
+	mesh := writerTestMesh()
+	path := filepath.Join(t.TempDir(), "quad.obj")
+	if err := SaveMesh(path, mesh); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	for _, name := range []string{"quad.mtl", "quad_0.png"} {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(path), name)); err != nil {
+			t.Errorf("missing %s: %v", name, err)
+		}
+	}
+	got, err := NewOBJLoader().LoadFile(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	assertSameTriangles(t, got, mesh)
+	if len(got.Materials) != 2 || got.Materials[0].Name != "red paint" ||
+		got.Materials[0].BaseColor != mesh.Materials[0].BaseColor {
+		t.Fatalf("materials %+v", got.Materials)
+	}
+	if r := got.Materials[0].Roughness; r < 0.49 || r > 0.51 {
+		t.Errorf("roughness %v", r)
+	}
+	wood := got.Materials[got.Faces[1].Material]
+	if !wood.HasTexture || color.RGBAModel.Convert(wood.BaseMap.At(1, 1)) != (color.RGBA{10, 20, 30, 255}) {
+		t.Errorf("texture not written: %+v", wood)
+	}
+	if len(got.Groups) != 2 || got.Groups[1] != mesh.Groups[1] {
+		t.Errorf("groups %+v", got.Groups)
+	}
+	v := got.Vertices[got.Faces[0].V[1]]
+	if v.UV != math3d.V2(1, 1) {
+		t.Errorf("uv %v", v.UV)
+	}
+	assertVec3(t, "normal", v.Normal, math3d.V3(0, 0, 1))
+}
+
+func TestWritePLYRoundTrip(t *testing.T) {
+	mesh := writerTestMesh()
+	var buf bytes.Buffer
+	if err := WritePLY(&buf, mesh); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	got, err := NewPLYLoader().LoadBytes(buf.Bytes(), "quad.ply")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	assertSameTriangles(t, got, mesh)
+	for i := range mesh.Vertices {
+		if got.Vertices[i].Color != mesh.Vertices[i].Color || got.Vertices[i].UV != mesh.Vertices[i].UV {
+			t.Errorf("vertex %d: %+v, want %+v", i, got.Vertices[i], mesh.Vertices[i])
+		}
+	}
+	// Point clouds stay point clouds
+	mesh.Faces = nil
+	buf.Reset()
+	if err := WritePLY(&buf, mesh); err != nil {
+		t.Fatalf("write points: %v", err)
+	}
+	if got, err = NewPLYLoader().LoadBytes(buf.Bytes(), "points.ply"); err != nil || len(got.Points) != 4 {
+		t.Errorf("point cloud: %v, %d points", err, len(got.Points))
+	}
+}
+
+func TestWriteGLBRoundTrip(t *testing.T) {
+	mesh := writerTestMesh()
+	mesh.Materials[0].Emissive = [3]float64{4, 2, 0}
+	mesh.Materials[1].Unlit = true
+	mesh.Lines = []Line{{V: [2]int{0, 2}, Material: -1}}
+	var buf bytes.Buffer
+	if err := WriteGLB(&buf, mesh); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	got, err := LoadGLBFromFS(fstest.MapFS{"quad.glb": {Data: buf.Bytes()}}, "quad.glb")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	assertSameTriangles(t, got, mesh)
+	if len(got.Lines) != 1 {
+		t.Errorf("lines %+v", got.Lines)
+	}
+	if !got.VertexColors {
+		t.Error("vertex colors lost")
+	}
+	for _, f := range got.Faces {
+		for _, vi := range f.V {
+			v := got.Vertices[vi]
+			want := mesh.Vertices[0]
+			for _, w := range mesh.Vertices {
+				if w.Position == v.Position {
+					want = w
+				}
+			}
+			if v.Color != want.Color || v.UV.Distance(want.UV) > 1e-6 {
+				t.Errorf("vertex %v: color %v uv %v, want %v %v", v.Position, v.Color, v.UV, want.Color, want.UV)
+			}
+		}
+	}
+	red, wood := got.Materials[got.Faces[0].Material], got.Materials[got.Faces[1].Material]
+	if red.Name != "red paint" || red.Roughness != 0.5 || red.Emissive != [3]float64{4, 2, 0} {
+		t.Errorf("red material %+v", red)
+	}
+	if !wood.Unlit || !wood.HasTexture || color.RGBAModel.Convert(wood.BaseMap.At(1, 1)) != (color.RGBA{10, 20, 30, 255}) {
+		t.Errorf("wood material %+v", wood)
+	}
+}
+
+func TestWriteGLBKeepsEncodedTextures(t *testing.T) {
+	pixels := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := range pixels.Pix {
+		pixels.Pix[i] = uint8(i * 7)
+	}
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, pixels, nil); err != nil {
+		t.Fatalf("jpeg: %v", err)
+	}
+	loaded, err := decodeImage(jpg.Bytes())
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	mesh := writerTestMesh()
+	mesh.Materials[0].EmissiveMap = pixels // In memory: encoded as PNG
+	mesh.Materials[1].BaseMap = loaded
+	var buf bytes.Buffer
+	if err := WriteGLB(&buf, mesh); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	got, err := LoadGLBFromFS(fstest.MapFS{"quad.glb": {Data: buf.Bytes()}}, "quad.glb")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	red, wood := got.Materials[got.Faces[0].Material], got.Materials[got.Faces[1].Material]
+	if e, ok := wood.BaseMap.(*EncodedImage); !ok || e.MIMEType != "image/jpeg" || !bytes.Equal(e.Data, jpg.Bytes()) {
+		t.Errorf("base color texture %T not the original JPEG", wood.BaseMap)
+	}
+	if e, ok := red.EmissiveMap.(*EncodedImage); !ok || e.MIMEType != "image/png" ||
+		color.RGBAModel.Convert(e.At(3, 2)) != pixels.At(3, 2) {
+		t.Errorf("emissive texture %T not the PNG encoded pixels", red.EmissiveMap)
+	}
+}
+
+func TestSaveMeshUnsupported(t *testing.T) {
+	if err := SaveMesh(filepath.Join(t.TempDir(), "quad.fbx"), writerTestMesh()); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
+
+// loadFlat loads a model with LoadModel, flattening scenes.
+func loadFlat(fsys fs.FS, name string) (*Mesh, error) {
+	mesh, scene, _, err := LoadModel(fsys, name)
+	if scene != nil {
+		mesh = scene.Flatten()
+	}
+	return mesh, err
+}
+
+func TestSaveMeshConvertsUnits(t *testing.T) {
+	dir := t.TempDir()
+	mesh := writerTestMesh()
+	mesh.Transform(math3d.ScaleUniform(100)) // 100 mm quad
+	stl := filepath.Join(dir, "quad.stl")
+	if err := SaveMesh(stl, mesh); err != nil {
+		t.Fatalf("save stl: %v", err)
+	}
+	fsys := os.DirFS(dir)
+	part, err := loadFlat(fsys, "quad.stl")
+	if err != nil {
+		t.Fatalf("load stl: %v", err)
+	}
+	if part.UnitSize() != Millimeters {
+		t.Errorf("stl unit %v, want millimeters", part.UnitSize())
+	}
+	if err := SaveMesh(filepath.Join(dir, "quad.glb"), part); err != nil {
+		t.Fatalf("save glb: %v", err)
+	}
+	glb, err := loadFlat(fsys, "quad.glb")
+	if err != nil {
+		t.Fatalf("load glb: %v", err)
+	}
+	if glb.Unit != Meters {
+		t.Errorf("glb unit %v, want meters", glb.Unit)
+	}
+	assertVec3(t, "glb size", glb.Size(), math3d.V3(0.1, 0.1, 0))
+	if err := SaveMesh(filepath.Join(dir, "back.stl"), glb); err != nil {
+		t.Fatalf("save stl: %v", err)
+	}
+	back, err := loadFlat(fsys, "back.stl")
+	if err != nil {
+		t.Fatalf("load stl: %v", err)
+	}
+	assertVec3(t, "stl size", back.Size(), math3d.V3(100, 100, 0))
+	if part.Size() != mesh.Size() || glb.Size().X > 1 {
+		t.Error("saving scaled the saved mesh")
+	}
+}
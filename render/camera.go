@@ -19,6 +19,9 @@ type Camera struct {
 	AspectRatio float64 // Width / Height
 	Near        float64 // Near clipping plane
 	Far         float64 // Far clipping plane
+	// Orthographic projection with OrthoHeight as the half height of the view
+	Orthographic bool
+	OrthoHeight  float64
 	// Cached matrices (computed on demand)
 	viewMatrix     math3d.Mat4
 	projMatrix     math3d.Mat4
@@ -63,6 +66,14 @@ func (c *Camera) SetFOV(fov float64) {
 	c.projDirty = true
 }
 
+// SetOrthographic switches between perspective and orthographic projection,
+// halfHeight being the half height of the orthographic view.
+func (c *Camera) SetOrthographic(ortho bool, halfHeight float64) {
+	c.Orthographic = ortho
+	c.OrthoHeight = halfHeight
+	c.projDirty = true
+}
+
 // SetAspectRatio sets the aspect ratio.
 func (c *Camera) SetAspectRatio(aspect float64) {
 	c.AspectRatio = aspect
@@ -141,6 +152,12 @@ func (c *Camera) computeViewMatrix() {
 }
 
 func (c *Camera) computeProjectionMatrix() {
+	if c.Orthographic {
+		h := c.OrthoHeight
+		w := h * c.AspectRatio
+		c.projMatrix = math3d.Orthographic(-w, w, -h, h, c.Near, c.Far)
+		return
+	}
 	c.projMatrix = math3d.Perspective(c.FOV, c.AspectRatio, c.Near, c.Far)
 }
 
@@ -187,6 +204,13 @@ func (c *Camera) LookAt(target math3d.Vec3) {
 	c.viewDirty = true
 }
 
+// SetOrientation points the camera along forward, rolled so that up (which
+// needs not be exactly perpendicular to forward) is the top of the view.
+func (c *Camera) SetOrientation(forward, up math3d.Vec3) {
+	c.LookAt(c.Position.Add(forward))
+	c.Roll = math.Atan2(-up.Dot(c.Right()), up.Dot(c.Up()))
+}
+
 // WorldToScreen transforms a world point to screen coordinates.
 // Returns (screenX, screenY, depth, visible).
 func (c *Camera) WorldToScreen(worldPos math3d.Vec3, screenWidth, screenHeight int) (x, y, depth float64, visible bool) {
@@ -0,0 +1,55 @@
+package render
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+func assertNear(t *testing.T, name string, got, want math3d.Vec3) {
+	t.Helper()
+	if got.Sub(want).Len() > 1e-9 {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
+
+func TestCameraSetOrientation(t *testing.T) {
+	tests := []struct {
+		name        string
+		forward, up math3d.Vec3
+	}{
+		{"default", math3d.V3(0, 0, -1), math3d.V3(0, 1, 0)},
+		{"down -X", math3d.V3(-1, 0, 0), math3d.V3(0, 1, 0)},
+		{"rolled", math3d.V3(0, 0, -1), math3d.V3(1, 0, 0)},
+		{"oblique", math3d.V3(1, -1, -1).Normalize(), math3d.V3(0, 0, 1)},
+	}
+	for _, tt := range tests {
+		c := NewCamera()
+		c.SetPosition(math3d.V3(1, 2, 3))
+		c.SetOrientation(tt.forward, tt.up)
+		view := c.ViewMatrix()
+		assertNear(t, tt.name+" forward", view.MulVec3(c.Position.Add(tt.forward)), math3d.V3(0, 0, -1))
+		// The up hint projected on the view plane is the top of the view
+		up := tt.up.Sub(tt.forward.Scale(tt.up.Dot(tt.forward))).Normalize()
+		assertNear(t, tt.name+" up", view.MulVec3(c.Position.Add(up)), math3d.V3(0, 1, 0))
+	}
+}
+
+func TestCameraOrthographic(t *testing.T) {
+	c := NewCamera()
+	c.SetPosition(math3d.V3(0, 0, 10))
+	c.SetAspectRatio(2)
+	c.SetOrthographic(true, 2)
+	// The same point at any depth projects to the same place
+	for _, z := range []float64{0, 5} {
+		x, y, _, ok := c.WorldToScreen(math3d.V3(2, 1, z), 100, 100)
+		if !ok || math.Abs(x-75) > 1e-9 || math.Abs(y-25) > 1e-9 {
+			t.Errorf("z=%v: screen = (%v, %v, %v), want (75, 25)", z, x, y, ok)
+		}
+	}
+	c.SetOrthographic(false, 0)
+	if x, _, _, _ := c.WorldToScreen(math3d.V3(2, 1, 5), 100, 100); math.Abs(x-75) < 1 {
+		t.Errorf("perspective projection unchanged: x = %v", x)
+	}
+}
@@ -0,0 +1,121 @@
+package render
+
+import "github.com/ansipixels/trophy/math3d"
+
+// MaxClipPlanes is the maximum number of user clip planes honored by the rasterizer.
+const MaxClipPlanes = 6
+
+// NewPlaneFromPointNormal creates a plane through point with the given normal.
+// The normal is normalized; points on its side have a positive distance.
+func NewPlaneFromPointNormal(point, normal math3d.Vec3) Plane {
+	n := normal.Normalize()
+	return Plane{Normal: n, D: -n.Dot(point)}
+}
+
+// Transform returns the plane moved by the transformation matrix m,
+// so that points transformed by m keep their side of the plane.
+func (p Plane) Transform(m math3d.Mat4) Plane {
+	point := m.MulVec3(p.Normal.Scale(-p.D))
+	// Normals transform with the inverse transpose
+	normal := m.Inverse().Transpose().MulVec3Dir(p.Normal)
+	return NewPlaneFromPointNormal(point, normal)
+}
+
+// SetClipPlanes sets the user clip planes (world space). Fragments on the
+// negative side of any plane are discarded by all triangle paths, wireframe
+// lines and points; section caps (ClipCap) are only drawn by the optimized
+// (*Opt) paths. Only the first MaxClipPlanes planes are used.
+// Pass no planes to disable clipping.
+func (r *Rasterizer) SetClipPlanes(planes ...Plane) {
+	if len(planes) > MaxClipPlanes {
+		planes = planes[:MaxClipPlanes]
+	}
+	r.ClipPlanes = append(r.ClipPlanes[:0], planes...)
+}
+
+// capping returns true if back faces should be drawn as section caps.
+func (r *Rasterizer) capping() bool {
+	return r.ClipCap && len(r.ClipPlanes) > 0
+}
+
+// triangleClip holds the clip planes that cut through a triangle and the
+// signed distance of each vertex to them, for per-fragment tests.
+type triangleClip struct {
+	planes int
+	dist   [MaxClipPlanes][3]float64
+	invW   [3]float64
+}
+
+// clipTriangle classifies a world-space triangle against the clip planes.
+// Returns false if the triangle is entirely clipped away.
+func (r *Rasterizer) clipTriangle(tri *Triangle, tc *triangleClip) bool {
+	tc.planes = 0
+	for i := range r.ClipPlanes {
+		plane := &r.ClipPlanes[i]
+		var d [3]float64
+		inside := 0
+		for v := range 3 {
+			d[v] = plane.DistanceToPoint(tri.V[v].Position)
+			if d[v] >= 0 {
+				inside++
+			}
+		}
+		switch inside {
+		case 0:
+			return false
+		case 3:
+			continue // not cut by this plane
+		}
+		tc.dist[tc.planes] = d
+		tc.planes++
+	}
+	return true
+}
+
+// setW records 1/w for perspective-correct interpolation of the distances.
+func (tc *triangleClip) setW(sv *[3]screenVertex) {
+	for i := range 3 {
+		tc.invW[i] = 0
+		if sv[i].W != 0 {
+			tc.invW[i] = 1.0 / sv[i].W
+		}
+	}
+}
+
+// discard returns true if the fragment with screen-space barycentric
+// coordinates (bc0, bc1, bc2) lies behind any clip plane.
+func (tc *triangleClip) discard(bc0, bc1, bc2 float64) bool {
+	pw0 := bc0 * tc.invW[0]
+	pw1 := bc1 * tc.invW[1]
+	pw2 := bc2 * tc.invW[2]
+	oneOverW := pw0 + pw1 + pw2
+	if oneOverW == 0 {
+		return false
+	}
+	for i := range tc.planes {
+		d := &tc.dist[i]
+		if (pw0*d[0]+pw1*d[1]+pw2*d[2])/oneOverW < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// clipSegment clips a world-space line segment against the clip planes.
+// Returns false if nothing of the segment remains.
+func (r *Rasterizer) clipSegment(a, b math3d.Vec3) (math3d.Vec3, math3d.Vec3, bool) {
+	for i := range r.ClipPlanes {
+		plane := &r.ClipPlanes[i]
+		da := plane.DistanceToPoint(a)
+		db := plane.DistanceToPoint(b)
+		switch {
+		case da < 0 && db < 0:
+			return a, b, false
+		case da < 0:
+			a = a.Lerp(b, da/(da-db))
+		case db < 0:
+			b = b.Lerp(a, db/(db-da))
+		}
+	}
+	return a, b, true
+}
@@ -0,0 +1,184 @@
+package render
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// clipTestRasterizer returns a rasterizer looking at the origin from z=10.
+func clipTestRasterizer() (*Rasterizer, *Framebuffer) {
+	r, fb := createTestRasterizer(64, 64)
+	r.camera.SetFOV(math.Pi / 3)
+	fb.BG = RGB(0, 0, 0)
+	fb.Clear()
+	r.ClearDepth()
+	return r, fb
+}
+
+// quadTriangle returns a front-facing triangle covering the center of the view.
+func quadTriangle(color Color) Triangle {
+	n := math3d.V3(0, 0, 1)
+	return Triangle{V: [3]Vertex{
+		{Position: math3d.V3(-3, -3, 0), Normal: n, Color: color},
+		{Position: math3d.V3(0, 3, 0), Normal: n, Color: color},
+		{Position: math3d.V3(3, -3, 0), Normal: n, Color: color},
+	}}
+}
+
+func countLit(fb *Framebuffer, inRegion func(x, y int) bool) int {
+	n := 0
+	for y := range fb.Height {
+		for x := range fb.Width {
+			c := fb.GetPixel(x, y)
+			if (c.R > 0 || c.G > 0 || c.B > 0) && inRegion(x, y) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestNewPlaneFromPointNormal(t *testing.T) {
+	p := NewPlaneFromPointNormal(math3d.V3(0, 2, 0), math3d.V3(0, 5, 0))
+	if d := p.DistanceToPoint(math3d.V3(3, 5, -1)); math.Abs(d-3) > 1e-12 {
+		t.Errorf("DistanceToPoint = %v, want 3", d)
+	}
+}
+
+func TestPlaneTransform(t *testing.T) {
+	p := NewPlaneFromPointNormal(math3d.V3(1, 0, 0), math3d.V3(1, 0, 0))
+	m := math3d.Translate(math3d.V3(0, 0, 5)).Mul(math3d.RotateY(math.Pi / 2))
+	moved := p.Transform(m)
+	// Points keep their signed distance when both are transformed
+	for _, pt := range []math3d.Vec3{math3d.V3(3, 1, 2), math3d.V3(-2, 0, 0), math3d.V3(1, 7, -4)} {
+		want := p.DistanceToPoint(pt)
+		got := moved.DistanceToPoint(m.MulVec3(pt))
+		if math.Abs(got-want) > 1e-9 {
+			t.Errorf("distance of %v after transform = %v, want %v", pt, got, want)
+		}
+	}
+}
+
+func TestClipSegment(t *testing.T) {
+	r, _ := clipTestRasterizer()
+	r.SetClipPlanes(NewPlaneFromPointNormal(math3d.Zero3(), math3d.V3(1, 0, 0)))
+	a, b, ok := r.clipSegment(math3d.V3(-1, 0, 0), math3d.V3(3, 0, 0))
+	if !ok || a.Distance(math3d.Zero3()) > 1e-12 || b != math3d.V3(3, 0, 0) {
+		t.Errorf("clipSegment = %v, %v, %v; want (0,0,0), (3,0,0), true", a, b, ok)
+	}
+	if _, _, ok := r.clipSegment(math3d.V3(-1, 0, 0), math3d.V3(-3, 0, 0)); ok {
+		t.Error("segment fully behind the plane should be rejected")
+	}
+}
+
+func TestSetClipPlanesLimit(t *testing.T) {
+	r, _ := clipTestRasterizer()
+	planes := make([]Plane, MaxClipPlanes+2)
+	r.SetClipPlanes(planes...)
+	if len(r.ClipPlanes) != MaxClipPlanes {
+		t.Errorf("len(ClipPlanes) = %d, want %d", len(r.ClipPlanes), MaxClipPlanes)
+	}
+	r.SetClipPlanes()
+	if len(r.ClipPlanes) != 0 {
+		t.Error("SetClipPlanes() should clear the planes")
+	}
+}
+
+func TestClipPlaneDiscardsFragments(t *testing.T) {
+	left := func(x, _ int) bool { return x < 30 }
+	right := func(x, _ int) bool { return x > 34 }
+	light := math3d.V3(0, 0, 1)
+	r, fb := clipTestRasterizer()
+	r.DrawTriangleGouraudOpt(quadTriangle(RGB(200, 200, 200)), light)
+	if countLit(fb, left) == 0 || countLit(fb, right) == 0 {
+		t.Fatal("unclipped triangle should cover both halves")
+	}
+	// Keep only x >= 0
+	r, fb = clipTestRasterizer()
+	r.SetClipPlanes(NewPlaneFromPointNormal(math3d.Zero3(), math3d.V3(1, 0, 0)))
+	r.DrawTriangleGouraudOpt(quadTriangle(RGB(200, 200, 200)), light)
+	if n := countLit(fb, left); n != 0 {
+		t.Errorf("%d pixels drawn on the clipped side", n)
+	}
+	if countLit(fb, right) == 0 {
+		t.Error("kept side should still be drawn")
+	}
+	// Textured path honors the same planes
+	r, fb = clipTestRasterizer()
+	r.SetClipPlanes(NewPlaneFromPointNormal(math3d.Zero3(), math3d.V3(1, 0, 0)))
+	tex := NewCheckerTexture(4, 4, 2, RGB(255, 255, 255), RGB(128, 128, 128))
+	r.DrawTriangleTexturedOpt(quadTriangle(RGB(255, 255, 255)), tex, light)
+	if countLit(fb, left) != 0 || countLit(fb, right) == 0 {
+		t.Error("textured triangle not clipped to the kept side")
+	}
+	// Fully clipped triangle draws nothing
+	r, fb = clipTestRasterizer()
+	r.SetClipPlanes(NewPlaneFromPointNormal(math3d.V3(10, 0, 0), math3d.V3(1, 0, 0)))
+	r.DrawTriangleGouraudOpt(quadTriangle(RGB(200, 200, 200)), light)
+	if n := countLit(fb, func(int, int) bool { return true }); n != 0 {
+		t.Errorf("fully clipped triangle drew %d pixels", n)
+	}
+}
+
+func TestClipCapDrawsBackFaces(t *testing.T) {
+	all := func(int, int) bool { return true }
+	back := quadTriangle(RGB(200, 200, 200))
+	back.V[1], back.V[2] = back.V[2], back.V[1]
+	keepAll := NewPlaneFromPointNormal(math3d.V3(0, 0, 5), math3d.V3(0, 0, -1))
+	// Culling on and no cap: back face is invisible
+	r, fb := clipTestRasterizer()
+	r.SetClipPlanes(keepAll)
+	r.DrawTriangleGouraudOpt(back, math3d.V3(0, 0, 1))
+	if n := countLit(fb, all); n != 0 {
+		t.Errorf("culled back face drew %d pixels", n)
+	}
+	// With the cap enabled it is drawn in the cap color
+	r, fb = clipTestRasterizer()
+	r.SetClipPlanes(keepAll)
+	r.ClipCap = true
+	r.DrawTriangleGouraudOpt(back, math3d.V3(0, 0, 1))
+	if countLit(fb, all) == 0 {
+		t.Fatal("back face should be drawn as a cap")
+	}
+	if c := fb.GetPixel(32, 32); c != r.ClipCapColor {
+		t.Errorf("cap pixel = %v, want %v", c, r.ClipCapColor)
+	}
+}
+
+func TestBackFacesSkippedWithoutCap(t *testing.T) {
+	back := quadTriangle(RGB(200, 200, 200))
+	back.V[1], back.V[2] = back.V[2], back.V[1]
+	r, fb := clipTestRasterizer()
+	r.DrawTriangleGouraudOpt(back, math3d.V3(0, 0, 1))
+	tex := NewCheckerTexture(4, 4, 2, RGB(255, 255, 255), RGB(128, 128, 128))
+	r.DrawTriangleTexturedOpt(back, tex, math3d.V3(0, 0, 1))
+	if n := countLit(fb, func(int, int) bool { return true }); n != 0 {
+		t.Errorf("back face drew %d pixels without section caps", n)
+	}
+}
+
+func TestClipPlanesOnAllTrianglePaths(t *testing.T) {
+	left := func(x, _ int) bool { return x < 30 }
+	right := func(x, _ int) bool { return x > 34 }
+	light := math3d.V3(0, 0, 1)
+	tex := NewCheckerTexture(4, 4, 2, RGB(255, 255, 255), RGB(128, 128, 128))
+	paths := map[string]func(r *Rasterizer, tri Triangle){
+		"flat":            func(r *Rasterizer, tri Triangle) { r.DrawTriangle(tri) },
+		"gouraud":         func(r *Rasterizer, tri Triangle) { r.DrawTriangleGouraud(tri, light) },
+		"textured":        func(r *Rasterizer, tri Triangle) { r.DrawTriangleTextured(tri, tex, light) },
+		"textured+shaded": func(r *Rasterizer, tri Triangle) { r.DrawTriangleTexturedGouraud(tri, tex, light) },
+	}
+	for name, draw := range paths {
+		r, fb := clipTestRasterizer()
+		r.SetClipPlanes(NewPlaneFromPointNormal(math3d.Zero3(), math3d.V3(1, 0, 0)))
+		draw(r, quadTriangle(RGB(200, 200, 200)))
+		if n := countLit(fb, left); n != 0 {
+			t.Errorf("%s: %d pixels drawn on the clipped side", name, n)
+		}
+		if countLit(fb, right) == 0 {
+			t.Errorf("%s: kept side should still be drawn", name)
+		}
+	}
+}
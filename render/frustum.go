@@ -200,6 +200,16 @@ func (f Frustum) ContainsAABB(box AABB) bool {
 	return f.testAABB(box, false)
 }
 
+// ClassifyAABB reports whether the AABB is at least partly visible and
+// whether it is completely inside the frustum, in a single call.
+// Hierarchical culling uses the second result to stop testing children.
+func (f Frustum) ClassifyAABB(box AABB) (visible, contained bool) {
+	if !f.IntersectAABB(box) {
+		return false, false
+	}
+	return true, f.ContainsAABB(box)
+}
+
 // ContainsPoint tests if a point is inside the frustum.
 func (f Frustum) ContainsPoint(p math3d.Vec3) bool {
 	for i := range f.Planes {
@@ -219,6 +219,74 @@ func TestFrustumIntersectAABB(t *testing.T) {
 	}
 }
 
+func TestFrustumClassifyAABB(t *testing.T) {
+	frustum := NewFrustumFromMatrix(math3d.Perspective(math.Pi/3, 16.0/9.0, 1.0, 100.0))
+	tests := []struct {
+		name               string
+		box                AABB
+		visible, contained bool
+	}{
+		{"fully inside", NewAABB(math3d.V3(-1, -1, -10), math3d.V3(1, 1, -5)), true, true},
+		{"crossing near plane", NewAABB(math3d.V3(-1, -1, -2), math3d.V3(1, 1, 2)), true, false},
+		{"behind camera", NewAABB(math3d.V3(-1, -1, 5), math3d.V3(1, 1, 10)), false, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			visible, contained := frustum.ClassifyAABB(tc.box)
+			if visible != tc.visible || contained != tc.contained {
+				t.Errorf("ClassifyAABB(%v) = (%v, %v), want (%v, %v)",
+					tc.box, visible, contained, tc.visible, tc.contained)
+			}
+		})
+	}
+}
+
+// splitMesh is a SpatialMeshRenderer with two single-triangle "nodes"
+// placed far apart, one of which is outside the view.
+type splitMesh struct {
+	simpleMesh
+}
+
+func (m *splitMesh) GetBounds() (minV, maxV math3d.Vec3) {
+	return m.bounds.Min, m.bounds.Max
+}
+
+func (m *splitMesh) QueryFaces(test func(minV, maxV math3d.Vec3) (bool, bool), visit func(face int)) {
+	for i, f := range m.faces {
+		box := NewAABB(m.vertices[f[0]].pos, m.vertices[f[0]].pos)
+		for _, vi := range f[1:] {
+			box.Min = box.Min.Min(m.vertices[vi].pos)
+			box.Max = box.Max.Max(m.vertices[vi].pos)
+		}
+		if visible, _ := test(box.Min, box.Max); visible {
+			visit(i)
+		}
+	}
+}
+
+func TestRasterizerCullsSpatialNodes(t *testing.T) {
+	rast, _ := createTestRasterizer(64, 64)
+	mesh := &splitMesh{simpleMesh: simpleMesh{
+		vertices: []meshVertex{
+			{pos: math3d.V3(-1, -1, 0)}, {pos: math3d.V3(1, -1, 0)}, {pos: math3d.V3(0, 1, 0)},
+			{pos: math3d.V3(99, -1, 0)}, {pos: math3d.V3(101, -1, 0)}, {pos: math3d.V3(100, 1, 0)},
+		},
+		faces:  [][3]int{{0, 1, 2}, {3, 4, 5}},
+		bounds: NewAABB(math3d.V3(-1, -1, 0), math3d.V3(101, 1, 0)),
+	}}
+	var drawn []int
+	rast.forEachVisibleFace(mesh, math3d.Identity(), func(face int) {
+		drawn = append(drawn, face)
+	})
+	if len(drawn) != 1 || drawn[0] != 0 {
+		t.Errorf("drawn faces = %v, want [0]", drawn)
+	}
+	stats := rast.CullingStats
+	if stats.MeshesDrawn != 1 || stats.NodesTested != 2 || stats.NodesCulled != 1 {
+		t.Errorf("stats = %+v, want 1 mesh drawn, 2 nodes tested, 1 culled", stats)
+	}
+}
+
 func TestFrustumIntersectsSphere(t *testing.T) {
 	// Create frustum
 	proj := math3d.Perspective(math.Pi/3, 16.0/9.0, 1.0, 100.0)
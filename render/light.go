@@ -0,0 +1,91 @@
+package render
+
+import (
+	"math"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// LightKind is the kind of a Light.
+type LightKind int
+
+// Light kinds.
+const (
+	DirectionalLight LightKind = iota
+	PointLight
+	SpotLight
+)
+
+// ambient is the share of the vertex color lit regardless of the lights.
+const ambient = 0.3
+
+// Light is a colored light, in world space, used instead of the light
+// direction by the *Opt paths when set in Rasterizer.Lights.
+type Light struct {
+	Kind      LightKind
+	Position  math3d.Vec3 // Point and spot lights
+	Direction math3d.Vec3 // Direction the light travels (directional and spot lights)
+	Color     [3]float64  // RGB times intensity, 1 lights a facing surface fully
+	Range     float64     // Distance where point and spot lights fade out, 0 for none
+	// Spot cone angles in radians
+	InnerCone float64
+	OuterCone float64
+}
+
+// Irradiance returns the RGB light received at pos by a surface with the
+// (unit) normal n: Lambert term, inverse square falloff windowed by Range and
+// smooth spot cone edges, as in KHR_lights_punctual.
+func (l *Light) Irradiance(pos, n math3d.Vec3) [3]float64 {
+	var toLight math3d.Vec3
+	attenuation := 1.0
+	if l.Kind == DirectionalLight {
+		toLight = l.Direction.Negate().Normalize()
+	} else {
+		d := l.Position.Sub(pos)
+		dist2 := d.LenSq()
+		if dist2 == 0 {
+			return [3]float64{}
+		}
+		dist := math.Sqrt(dist2)
+		toLight = d.Scale(1 / dist)
+		attenuation = 1 / dist2
+		if l.Range > 0 {
+			r := dist / l.Range
+			attenuation *= math.Max(0, math.Min(1, 1-r*r*r*r))
+		}
+		if l.Kind == SpotLight {
+			cosOuter, cosInner := math.Cos(l.OuterCone), math.Cos(l.InnerCone)
+			cd := -toLight.Dot(l.Direction.Normalize())
+			t := 1.0
+			if cosInner > cosOuter {
+				t = math.Max(0, math.Min(1, (cd-cosOuter)/(cosInner-cosOuter)))
+			} else if cd < cosOuter {
+				t = 0
+			}
+			attenuation *= t * t
+		}
+	}
+	k := math.Max(0, n.Dot(toLight)) * attenuation
+	return [3]float64{l.Color[0] * k, l.Color[1] * k, l.Color[2] * k}
+}
+
+// vertexLighting returns the per channel lighting factors of a vertex: the
+// ambient term plus either the Lambert term of normLight or the irradiance of
+// r.Lights. Unlit vertices are fully lit.
+func (r *Rasterizer) vertexLighting(v *Vertex, normLight math3d.Vec3, unlit bool) [3]float64 {
+	if unlit {
+		return [3]float64{1, 1, 1}
+	}
+	if len(r.Lights) == 0 {
+		i := ambient + (1-ambient)*math.Max(0, v.Normal.Dot(normLight))
+		return [3]float64{i, i, i}
+	}
+	f := [3]float64{ambient, ambient, ambient}
+	for i := range r.Lights {
+		e := r.Lights[i].Irradiance(v.Position, v.Normal)
+		for c := range 3 {
+			f[c] += (1 - ambient) * e[c]
+		}
+	}
+	return f
+}
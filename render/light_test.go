@@ -0,0 +1,71 @@
+package render
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+func TestLightIrradiance(t *testing.T) {
+	up := math3d.V3(0, 1, 0)
+	down := math3d.V3(0, -1, 0)
+	tests := []struct {
+		name  string
+		light Light
+		pos   math3d.Vec3
+		want  float64
+	}{
+		{"directional", Light{Kind: DirectionalLight, Direction: down, Color: [3]float64{2, 2, 2}}, math3d.Zero3(), 2},
+		{"directional behind", Light{Kind: DirectionalLight, Direction: up, Color: [3]float64{1, 1, 1}}, math3d.Zero3(), 0},
+		{"point", Light{Kind: PointLight, Position: math3d.V3(0, 2, 0), Color: [3]float64{1, 1, 1}}, math3d.Zero3(), 0.25},
+		{"point grazing", Light{Kind: PointLight, Position: math3d.V3(2, 0, 0), Color: [3]float64{1, 1, 1}}, math3d.Zero3(), 0},
+		{"point in range", Light{Kind: PointLight, Position: math3d.V3(0, 1, 0), Color: [3]float64{1, 1, 1}, Range: 2},
+			math3d.Zero3(), 1 - 1.0/16},
+		{"point out of range", Light{Kind: PointLight, Position: math3d.V3(0, 3, 0), Color: [3]float64{1, 1, 1}, Range: 2},
+			math3d.Zero3(), 0},
+		{"spot inside", Light{Kind: SpotLight, Position: math3d.V3(0, 1, 0), Direction: down, Color: [3]float64{1, 1, 1},
+			InnerCone: 0.2, OuterCone: 0.4}, math3d.Zero3(), 1},
+		{"spot outside", Light{Kind: SpotLight, Position: math3d.V3(0, 1, 0), Direction: down, Color: [3]float64{1, 1, 1},
+			InnerCone: 0.2, OuterCone: 0.4}, math3d.V3(1, 0, 0), 0},
+	}
+	for _, tt := range tests {
+		got := tt.light.Irradiance(tt.pos, up)
+		if math.Abs(got[0]-tt.want) > 1e-9 {
+			t.Errorf("%s: irradiance = %v, want %v", tt.name, got[0], tt.want)
+		}
+	}
+	// The spot edge is smooth between the cones
+	spot := Light{Kind: SpotLight, Position: math3d.V3(0, 1, 0), Direction: down, Color: [3]float64{1, 1, 1},
+		InnerCone: 0, OuterCone: math.Pi / 2}
+	if e := spot.Irradiance(math3d.V3(0.5, 0, 0), up)[0]; e <= 0 || e >= 0.8 {
+		t.Errorf("spot edge irradiance = %v, want between 0 and 0.8", e)
+	}
+}
+
+func TestColoredLights(t *testing.T) {
+	surfaces := []Surface{{Color: RGB(200, 200, 200)}}
+	front := math3d.V3(0, 0, -1)
+	r, fb := clipTestRasterizer()
+	// A red light facing the quad and a green one behind it
+	r.Lights = []Light{
+		{Kind: DirectionalLight, Direction: front, Color: [3]float64{1, 0, 0}},
+		{Kind: DirectionalLight, Direction: front.Negate(), Color: [3]float64{0, 1, 0}},
+	}
+	mesh := &mockMaterialMesh{mockMesh: surfaceQuad(), material: 0}
+	// The light direction is ignored when lights are set
+	r.DrawMeshSurfacesOpt(mesh, math3d.Identity(), surfaces, Surface{}, front.Negate())
+	if got, want := fb.GetPixel(32, 28), RGB(200, 60, 60); !colorNear(got, want) {
+		t.Errorf("gouraud pixel = %v, want %v", got, want)
+	}
+	white := NewTexture(1, 1)
+	white.SetPixel(0, 0, RGB(255, 255, 255))
+	// Point lights are evaluated at the vertices, here the quad corners
+	r, fb = clipTestRasterizer()
+	r.Lights = []Light{{Kind: PointLight, Position: math3d.V3(0, 0, 5), Color: [3]float64{0, 0, 100}}}
+	r.DrawMeshSurfacesOpt(mesh, math3d.Identity(), []Surface{{Color: RGB(255, 255, 255), Texture: white}},
+		Surface{}, front)
+	if got := fb.GetPixel(32, 28); got.B < 200 || got.R < 70 || got.R > 80 {
+		t.Errorf("textured pixel = %v, want blue tinted over 0.3 ambient", got)
+	}
+}
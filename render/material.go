@@ -0,0 +1,66 @@
+package render
+
+import "github.com/ansipixels/trophy/math3d"
+
+// Surface describes how the faces of one material are shaded.
+type Surface struct {
+	Color       Color    // Base color, tints Texture when both are set
+	Texture     *Texture // Optional base color texture
+	Unlit       bool     // Skip the Lambert term (baked or scanned materials)
+	Emissive    Color    // Added after lighting, regardless of the light direction
+	EmissiveMap *Texture // Optional emissive texture, modulated by Emissive
+}
+
+// MaterialMeshRenderer extends MeshRenderer with per face material indices,
+// -1 meaning no material.
+type MaterialMeshRenderer interface {
+	MeshRenderer
+	GetFaceMaterial(i int) int
+}
+
+// ColorMeshRenderer extends MeshRenderer with per vertex colors.
+type ColorMeshRenderer interface {
+	MeshRenderer
+	HasVertexColors() bool
+	GetVertexColor(i int) Color
+}
+
+// vertexColors returns the mesh as a ColorMeshRenderer if it has vertex colors.
+func vertexColors(mesh MeshRenderer) ColorMeshRenderer {
+	if colors, ok := mesh.(ColorMeshRenderer); ok && colors.HasVertexColors() {
+		return colors
+	}
+	return nil
+}
+
+// DrawMeshSurfacesOpt renders a mesh shading each face with the surface of
+// its material. Faces without a valid material, or all faces when the mesh
+// does not implement MaterialMeshRenderer, use fallback. Vertex colors
+// (see ColorMeshRenderer) tint untextured surfaces.
+func (r *Rasterizer) DrawMeshSurfacesOpt(
+	mesh MeshRenderer, transform math3d.Mat4, surfaces []Surface, fallback Surface, lightDir math3d.Vec3,
+) {
+	materials, _ := mesh.(MaterialMeshRenderer)
+	colors := vertexColors(mesh)
+	r.forEachVisibleFace(mesh, transform, func(i int) {
+		surface := &fallback
+		if materials != nil {
+			if m := materials.GetFaceMaterial(i); m >= 0 && m < len(surfaces) {
+				surface = &surfaces[m]
+			}
+		}
+		face := mesh.GetFace(i)
+		if surface.Texture == nil && surface.EmissiveMap == nil {
+			tri := buildGouraudTriangle(mesh, face, transform, surface.Color)
+			if colors != nil {
+				for k := range 3 {
+					tri.V[k].Color = ModulateColor(surface.Color, colors.GetVertexColor(face[k]))
+				}
+			}
+			r.drawTriangleGouraudOpt(tri, lightDir, surface)
+			return
+		}
+		tri := buildTexturedTriangle(mesh, face, transform)
+		r.drawTriangleTexturedOpt(tri, surface.Texture, lightDir, surface)
+	})
+}
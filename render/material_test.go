@@ -0,0 +1,111 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// mockMaterialMesh implements MaterialMeshRenderer with one material for all faces.
+type mockMaterialMesh struct {
+	*mockMesh
+	material int
+}
+
+func (m *mockMaterialMesh) GetFaceMaterial(int) int { return m.material }
+
+// surfaceQuad is a quad facing the camera, lit from behind.
+func surfaceQuad() *mockMesh {
+	n := math3d.V3(0, 0, 1)
+	return &mockMesh{
+		vertices: []struct {
+			pos    math3d.Vec3
+			normal math3d.Vec3
+			uv     math3d.Vec2
+		}{
+			{math3d.V3(-5, -5, 0), n, math3d.V2(0, 0)},
+			{math3d.V3(5, -5, 0), n, math3d.V2(1, 0)},
+			{math3d.V3(5, 5, 0), n, math3d.V2(1, 1)},
+			{math3d.V3(-5, 5, 0), n, math3d.V2(0, 1)},
+		},
+		faces: [][3]int{{0, 3, 2}, {0, 2, 1}},
+	}
+}
+
+func TestDrawMeshSurfacesOpt(t *testing.T) {
+	white := NewTexture(1, 1)
+	white.SetPixel(0, 0, RGB(255, 255, 255))
+	surfaces := []Surface{
+		{Color: RGB(200, 10, 0), Unlit: true},
+		{Color: RGB(100, 100, 100), Emissive: RGB(0, 0, 90)},
+		{Color: RGB(100, 200, 50), Texture: white, Unlit: true},
+		{Color: RGB(0, 0, 0), EmissiveMap: white, Emissive: RGB(0, 80, 0)},
+	}
+	fallback := Surface{Color: RGB(100, 100, 100), Unlit: true}
+	behind := math3d.V3(0, 0, -1)
+	tests := []struct {
+		name     string
+		material int
+		want     Color
+	}{
+		{"unlit", 0, RGB(200, 10, 0)},
+		{"emissive", 1, RGB(30, 30, 120)}, // 0.3 ambient + emissive
+		{"tinted texture", 2, RGB(100, 200, 50)},
+		{"emissive map", 3, RGB(0, 80, 0)},
+		{"no material", -1, RGB(100, 100, 100)},
+		{"out of range", 9, RGB(100, 100, 100)},
+	}
+	for _, tt := range tests {
+		r, fb := clipTestRasterizer()
+		mesh := &mockMaterialMesh{mockMesh: surfaceQuad(), material: tt.material}
+		r.DrawMeshSurfacesOpt(mesh, math3d.Identity(), surfaces, fallback, behind)
+		if got := fb.GetPixel(32, 28); !colorNear(got, tt.want) {
+			t.Errorf("%s: pixel = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	// Meshes without materials use the fallback
+	r, fb := clipTestRasterizer()
+	r.DrawMeshSurfacesOpt(surfaceQuad(), math3d.Identity(), surfaces, fallback, behind)
+	if got := fb.GetPixel(32, 28); !colorNear(got, fallback.Color) {
+		t.Errorf("plain mesh: pixel = %v, want %v", got, fallback.Color)
+	}
+}
+
+// colorNear compares colors allowing for interpolation rounding.
+func colorNear(a, b Color) bool {
+	return absInt(int(a.R)-int(b.R)) <= 1 && absInt(int(a.G)-int(b.G)) <= 1 && absInt(int(a.B)-int(b.B)) <= 1
+}
+
+func TestAddColor(t *testing.T) {
+	if got := AddColor(RGB(200, 10, 0), RGB(100, 20, 0)); got != RGB(255, 30, 0) {
+		t.Errorf("AddColor = %v", got)
+	}
+}
+
+// coloredMesh implements ColorMeshRenderer with one color per vertex.
+type coloredMesh struct {
+	*mockMesh
+	colors []Color
+}
+
+func (m *coloredMesh) HasVertexColors() bool      { return m.colors != nil }
+func (m *coloredMesh) GetVertexColor(i int) Color { return m.colors[i] }
+
+func TestVertexColors(t *testing.T) {
+	blue := RGB(0, 0, 255)
+	mesh := &coloredMesh{mockMesh: surfaceQuad(), colors: []Color{blue, blue, blue, blue}}
+	r, fb := clipTestRasterizer()
+	r.DrawMeshSurfacesOpt(mesh, math3d.Identity(), nil, Surface{Color: RGB(200, 200, 200), Unlit: true},
+		math3d.V3(0, 0, 1))
+	if got, want := fb.GetPixel(32, 28), RGB(0, 0, 200); !colorNear(got, want) {
+		t.Errorf("pixel = %v, want %v", got, want)
+	}
+	// Without colors the surface color is used
+	mesh.colors = nil
+	r, fb = clipTestRasterizer()
+	r.DrawMeshSurfacesOpt(mesh, math3d.Identity(), nil, Surface{Color: RGB(200, 200, 200), Unlit: true},
+		math3d.V3(0, 0, 1))
+	if got, want := fb.GetPixel(32, 28), RGB(200, 200, 200); !colorNear(got, want) {
+		t.Errorf("uncolored pixel = %v, want %v", got, want)
+	}
+}
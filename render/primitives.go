@@ -0,0 +1,80 @@
+package render
+
+import (
+	"math"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// PrimitiveMeshRenderer extends MeshRenderer with line and point primitives
+// stored alongside the triangles.
+type PrimitiveMeshRenderer interface {
+	MeshRenderer
+	LineCount() int
+	GetLine(i int) [2]int
+	PointCount() int
+	GetPoint(i int) int
+}
+
+// DrawMeshPrimitives renders the line and point primitives of a mesh, if it
+// has any. Lines are not depth tested; points are drawn as depth-tested
+// squares of PointSize pixels. Vertex colors (see ColorMeshRenderer) replace
+// color; lines use the color of their first vertex.
+// Automatically performs frustum culling if the mesh provides bounds.
+func (r *Rasterizer) DrawMeshPrimitives(mesh MeshRenderer, transform math3d.Mat4, color Color) {
+	prims, ok := mesh.(PrimitiveMeshRenderer)
+	if !ok || prims.LineCount()+prims.PointCount() == 0 {
+		return
+	}
+	if r.tryFrustumCull(mesh, transform) {
+		return
+	}
+	colors := vertexColors(mesh)
+	colorOf := func(v int) Color {
+		if colors == nil {
+			return color
+		}
+		return colors.GetVertexColor(v)
+	}
+	for i := range prims.LineCount() {
+		line := prims.GetLine(i)
+		p0, _, _ := mesh.GetVertex(line[0])
+		p1, _, _ := mesh.GetVertex(line[1])
+		r.drawLine3D(transform.MulVec3(p0), transform.MulVec3(p1), colorOf(line[0]))
+	}
+	for i := range prims.PointCount() {
+		v := prims.GetPoint(i)
+		p, _, _ := mesh.GetVertex(v)
+		r.DrawPoint3D(transform.MulVec3(p), colorOf(v))
+	}
+}
+
+// DrawPoint3D draws a world-space point as a depth-tested square splat.
+// Points outside the clip planes or the depth range are skipped.
+func (r *Rasterizer) DrawPoint3D(p math3d.Vec3, color Color) {
+	if _, _, ok := r.clipSegment(p, p); !ok {
+		return
+	}
+	clipPos := r.camera.ViewProjectionMatrix().MulVec4(math3d.V4FromV3(p, 1))
+	if clipPos.W <= 0 {
+		return
+	}
+	z := clipPos.Z / clipPos.W
+	if z < -1 || z > 1 {
+		return
+	}
+	size := max(1, r.PointSize)
+	sx := (clipPos.X/clipPos.W + 1) * 0.5 * float64(r.Width())
+	sy := (1 - clipPos.Y/clipPos.W) * 0.5 * float64(r.Height())
+	x0 := int(math.Floor(sx - float64(size)/2 + 0.5))
+	y0 := int(math.Floor(sy - float64(size)/2 + 0.5))
+	for y := max(0, y0); y < min(r.Height(), y0+size); y++ {
+		for x := max(0, x0); x < min(r.Width(), x0+size); x++ {
+			if z >= r.getDepth(x, y) {
+				continue
+			}
+			r.setDepth(x, y, z)
+			r.fb.SetPixel(x, y, color)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/ansipixels/trophy/math3d"
+)
+
+// primitiveMesh adds lines and points to mockMesh.
+type primitiveMesh struct {
+	mockMesh
+	lines  [][2]int
+	points []int
+}
+
+func (m *primitiveMesh) LineCount() int       { return len(m.lines) }
+func (m *primitiveMesh) GetLine(i int) [2]int { return m.lines[i] }
+func (m *primitiveMesh) PointCount() int      { return len(m.points) }
+func (m *primitiveMesh) GetPoint(i int) int   { return m.points[i] }
+
+func TestDrawPoint3DDepthTest(t *testing.T) {
+	all := func(int, int) bool { return true }
+	r, fb := clipTestRasterizer()
+	r.DrawPoint3D(math3d.Zero3(), RGB(255, 0, 0))
+	if n := countLit(fb, all); n != r.PointSize*r.PointSize {
+		t.Errorf("point splat covered %d pixels, want %d", n, r.PointSize*r.PointSize)
+	}
+	// Hidden behind a triangle at z=0, visible in front of it
+	light := math3d.V3(0, 0, 1)
+	r, fb = clipTestRasterizer()
+	r.DrawTriangleGouraudOpt(quadTriangle(RGB(0, 0, 200)), light)
+	r.DrawPoint3D(math3d.V3(0, 0, -1), RGB(255, 0, 0))
+	red := func(x, y int) bool { return fb.GetPixel(x, y).R > 0 }
+	if n := countLit(fb, red); n != 0 {
+		t.Errorf("occluded point drew %d pixels", n)
+	}
+	r.DrawPoint3D(math3d.V3(0, 0, 1), RGB(255, 0, 0))
+	if countLit(fb, red) == 0 {
+		t.Error("point in front of the triangle should be drawn")
+	}
+	// Clip planes discard points
+	r, fb = clipTestRasterizer()
+	r.SetClipPlanes(NewPlaneFromPointNormal(math3d.V3(1, 0, 0), math3d.V3(1, 0, 0)))
+	r.DrawPoint3D(math3d.Zero3(), RGB(255, 0, 0))
+	if n := countLit(fb, all); n != 0 {
+		t.Errorf("clipped point drew %d pixels", n)
+	}
+}
+
+func TestDrawMeshPrimitives(t *testing.T) {
+	mesh := &primitiveMesh{lines: [][2]int{{0, 1}}, points: []int{2}}
+	for _, p := range []math3d.Vec3{math3d.V3(-2, -2, 0), math3d.V3(-2, 2, 0), math3d.V3(2, 0, 0)} {
+		mesh.vertices = append(mesh.vertices, struct {
+			pos    math3d.Vec3
+			normal math3d.Vec3
+			uv     math3d.Vec2
+		}{pos: p})
+	}
+	r, fb := clipTestRasterizer()
+	r.DrawMeshPrimitives(mesh, math3d.Identity(), RGB(255, 255, 255))
+	if countLit(fb, func(x, _ int) bool { return x < 32 }) == 0 {
+		t.Error("line not drawn")
+	}
+	if countLit(fb, func(x, _ int) bool { return x > 32 }) == 0 {
+		t.Error("point not drawn")
+	}
+	// Plain meshes have nothing to draw
+	r, fb = clipTestRasterizer()
+	r.DrawMeshPrimitives(&mesh.mockMesh, math3d.Identity(), RGB(255, 255, 255))
+	if n := countLit(fb, func(int, int) bool { return true }); n != 0 {
+		t.Errorf("mesh without primitives drew %d pixels", n)
+	}
+}
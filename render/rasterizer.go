@@ -29,6 +29,13 @@ type Rasterizer struct {
 	frustumDirty           bool         // Whether frustum needs recalculation
 	CullingStats           CullingStats // Statistics for debugging/benchmarking
 	DisableBackfaceCulling bool         // If true, render both sides of triangles
+	ClipPlanes             []Plane      // User clip planes (world space), see SetClipPlanes
+	ClipCap                bool         // If true, back faces are drawn as solid section caps while clipping
+	ClipCapColor           Color        // Color of section caps
+	PointSize              int          // Size in pixels of point primitives (see DrawPoint3D)
+	// Lights replace the light direction of the *Opt paths when set
+	// (the other paths always use the light direction)
+	Lights []Light
 }
 
 // CullingStats tracks frustum culling performance.
@@ -36,6 +43,8 @@ type CullingStats struct {
 	MeshesTested int // Total meshes tested for culling
 	MeshesCulled int // Meshes culled (not rendered)
 	MeshesDrawn  int // Meshes that passed culling
+	NodesTested  int // BVH nodes tested for culling (SpatialMeshRenderer only)
+	NodesCulled  int // BVH nodes culled along with their faces
 }
 
 // NewRasterizer creates a new rasterizer.
@@ -44,6 +53,8 @@ func NewRasterizer(camera *Camera, fb *Framebuffer) *Rasterizer {
 		camera:       camera,
 		fb:           fb,
 		frustumDirty: true,
+		ClipCapColor: RGB(220, 80, 60),
+		PointSize:    2,
 	}
 	r.Resize()
 	return r
@@ -180,7 +191,8 @@ func (r *Rasterizer) projectTriangle(tri Triangle, sv *[3]screenVertex) bool {
 	return allBehind
 }
 
-func (r *Rasterizer) rasterizeInterpolatedColor(sv [3]screenVertex) {
+func (r *Rasterizer) rasterizeInterpolatedColor(sv [3]screenVertex, clip *triangleClip) {
+	clip.setW(&sv)
 	minX := int(math.Max(0, math.Floor(min3(sv[0].X, sv[1].X, sv[2].X))))
 	maxX := int(math.Min(float64(r.Width()-1), math.Ceil(max3(sv[0].X, sv[1].X, sv[2].X))))
 	minY := int(math.Max(0, math.Floor(min3(sv[0].Y, sv[1].Y, sv[2].Y))))
@@ -201,6 +213,9 @@ func (r *Rasterizer) rasterizeInterpolatedColor(sv [3]screenVertex) {
 			if z >= r.getDepth(x, y) {
 				continue
 			}
+			if clip.planes > 0 && clip.discard(bc.X, bc.Y, bc.Z) {
+				continue
+			}
 			color := interpolateColor3(sv[0].Color, sv[1].Color, sv[2].Color, bc)
 			r.setDepth(x, y, z)
 			r.fb.SetPixel(x, y, color)
@@ -210,6 +225,11 @@ func (r *Rasterizer) rasterizeInterpolatedColor(sv [3]screenVertex) {
 
 // DrawTriangle rasterizes a single triangle.
 func (r *Rasterizer) DrawTriangle(tri Triangle) {
+	// User clip planes: skip fully clipped triangles, remember the cutting ones
+	var clip triangleClip
+	if !r.clipTriangle(&tri, &clip) {
+		return
+	}
 	// Transform vertices to screen space
 	var sv [3]screenVertex
 	allBehind := r.projectTriangle(tri, &sv)
@@ -224,11 +244,15 @@ func (r *Rasterizer) DrawTriangle(tri Triangle) {
 	if cross < 0 {
 		return // Back-facing
 	}
-	r.rasterizeInterpolatedColor(sv)
+	r.rasterizeInterpolatedColor(sv, &clip)
 }
 
 // DrawTriangleTextured rasterizes a textured triangle with perspective-correct UV interpolation.
 func (r *Rasterizer) DrawTriangleTextured(tri Triangle, tex *Texture, lightDir math3d.Vec3) {
+	var clip triangleClip
+	if !r.clipTriangle(&tri, &clip) {
+		return
+	}
 	// Transform vertices to screen space
 	var sv [3]screenVertex
 	allBehind := r.projectTriangle(tri, &sv)
@@ -254,6 +278,7 @@ func (r *Rasterizer) DrawTriangleTextured(tri Triangle, tex *Texture, lightDir m
 	maxX := int(math.Min(float64(r.Width()-1), math.Ceil(max3(sv[0].X, sv[1].X, sv[2].X))))
 	minY := int(math.Max(0, math.Floor(min3(sv[0].Y, sv[1].Y, sv[2].Y))))
 	maxY := int(math.Min(float64(r.Height()-1), math.Ceil(max3(sv[0].Y, sv[1].Y, sv[2].Y))))
+	clip.setW(&sv)
 	// Precompute perspective-correct interpolation factors (1/w for each vertex)
 	var invW [3]float64
 	for i := range 3 {
@@ -284,6 +309,9 @@ func (r *Rasterizer) DrawTriangleTextured(tri Triangle, tex *Texture, lightDir m
 			if z >= r.getDepth(x, y) {
 				continue
 			}
+			if clip.planes > 0 && clip.discard(bc.X, bc.Y, bc.Z) {
+				continue
+			}
 			// Perspective-correct UV interpolation
 			// Interpolate UV/W and 1/W, then divide to get correct UV
 			w0, w1, w2 := bc.X*invW[0], bc.Y*invW[1], bc.Z*invW[2]
@@ -533,6 +561,10 @@ func max3(a, b, c float64) float64 {
 // DrawTriangleGouraud rasterizes a triangle with Gouraud shading (per-vertex lighting).
 // Lighting is calculated at each vertex and interpolated across the triangle.
 func (r *Rasterizer) DrawTriangleGouraud(tri Triangle, lightDir math3d.Vec3) {
+	var clip triangleClip
+	if !r.clipTriangle(&tri, &clip) {
+		return
+	}
 	// Transform vertices to screen space
 	var sv [3]screenVertex
 	allBehind := r.projectTriangle(tri, &sv)
@@ -557,12 +589,16 @@ func (r *Rasterizer) DrawTriangleGouraud(tri Triangle, lightDir math3d.Vec3) {
 	if cross < 0 {
 		return // Back-facing
 	}
-	r.rasterizeInterpolatedColor(sv)
+	r.rasterizeInterpolatedColor(sv, &clip)
 }
 
 // DrawTriangleTexturedGouraud rasterizes a textured triangle with Gouraud shading.
 // Per-vertex lighting is calculated and interpolated, then modulated with texture.
 func (r *Rasterizer) DrawTriangleTexturedGouraud(tri Triangle, tex *Texture, lightDir math3d.Vec3) {
+	var clip triangleClip
+	if !r.clipTriangle(&tri, &clip) {
+		return
+	}
 	// Transform vertices to screen space
 	var sv [3]screenVertex
 	var vertexIntensity [3]float64 // Store lighting intensity per vertex
@@ -588,6 +624,7 @@ func (r *Rasterizer) DrawTriangleTexturedGouraud(tri Triangle, tex *Texture, lig
 	maxX := int(math.Min(float64(r.Width()-1), math.Ceil(max3(sv[0].X, sv[1].X, sv[2].X))))
 	minY := int(math.Max(0, math.Floor(min3(sv[0].Y, sv[1].Y, sv[2].Y))))
 	maxY := int(math.Min(float64(r.Height()-1), math.Ceil(max3(sv[0].Y, sv[1].Y, sv[2].Y))))
+	clip.setW(&sv)
 	// Precompute perspective-correct interpolation factors (1/w for each vertex)
 	var invW [3]float64
 	for i := range 3 {
@@ -618,6 +655,9 @@ func (r *Rasterizer) DrawTriangleTexturedGouraud(tri Triangle, tex *Texture, lig
 			if z >= r.getDepth(x, y) {
 				continue
 			}
+			if clip.planes > 0 && clip.discard(bc.X, bc.Y, bc.Z) {
+				continue
+			}
 			// Perspective-correct interpolation
 			w0, w1, w2 := bc.X*invW[0], bc.Y*invW[1], bc.Z*invW[2]
 			oneOverW := w0 + w1 + w2
@@ -655,6 +695,15 @@ type BoundedMeshRenderer interface {
 	GetBounds() (minV, maxV math3d.Vec3)
 }
 
+// SpatialMeshRenderer extends BoundedMeshRenderer with a spatial hierarchy
+// (such as a BVH) so culling can happen per node instead of per mesh.
+// QueryFaces calls test with local-space node bounds and visit for every
+// face below nodes that pass.
+type SpatialMeshRenderer interface {
+	BoundedMeshRenderer
+	QueryFaces(test func(minV, maxV math3d.Vec3) (visible, contained bool), visit func(face int))
+}
+
 // tryFrustumCull attempts to cull a mesh using its bounds if available.
 // Returns true if the mesh should be culled (not visible).
 func (r *Rasterizer) tryFrustumCull(mesh MeshRenderer, transform math3d.Mat4) bool {
@@ -677,17 +726,38 @@ func (r *Rasterizer) tryFrustumCull(mesh MeshRenderer, transform math3d.Mat4) bo
 	return false
 }
 
-// DrawMesh renders a mesh with the given transform and color.
-// Automatically performs frustum culling if the mesh provides bounds.
-func (r *Rasterizer) DrawMesh(mesh MeshRenderer, transform math3d.Mat4, color Color, lightDir math3d.Vec3) {
-	// Frustum culling check
+// forEachVisibleFace calls visit for every face of the mesh that survives
+// frustum culling. Meshes implementing SpatialMeshRenderer are culled at
+// node granularity, others as a whole.
+func (r *Rasterizer) forEachVisibleFace(mesh MeshRenderer, transform math3d.Mat4, visit func(face int)) {
 	if r.tryFrustumCull(mesh, transform) {
 		return
 	}
+	spatial, ok := mesh.(SpatialMeshRenderer)
+	if !ok {
+		for i := range mesh.TriangleCount() {
+			visit(i)
+		}
+		return
+	}
+	frustum := r.GetFrustum()
+	spatial.QueryFaces(func(minV, maxV math3d.Vec3) (bool, bool) {
+		r.CullingStats.NodesTested++
+		visible, contained := frustum.ClassifyAABB(TransformAABB(AABB{Min: minV, Max: maxV}, transform))
+		if !visible {
+			r.CullingStats.NodesCulled++
+		}
+		return visible, contained
+	}, visit)
+}
+
+// DrawMesh renders a mesh with the given transform and color.
+// Automatically performs frustum culling if the mesh provides bounds.
+func (r *Rasterizer) DrawMesh(mesh MeshRenderer, transform math3d.Mat4, color Color, lightDir math3d.Vec3) {
 	// Transform light to local space
 	invTransform := transform.Inverse()
 	localLight := invTransform.MulVec3Dir(lightDir).Normalize()
-	for i := range mesh.TriangleCount() {
+	r.forEachVisibleFace(mesh, transform, func(i int) {
 		face := mesh.GetFace(i)
 		// Get vertices
 		p0, _, _ := mesh.GetVertex(face[0])
@@ -698,51 +768,39 @@ func (r *Rasterizer) DrawMesh(mesh MeshRenderer, transform math3d.Mat4, color Co
 		v1 := transform.MulVec3(p1)
 		v2 := transform.MulVec3(p2)
 		r.DrawTriangleLit(v0, v1, v2, color, localLight)
-	}
+	})
 }
 
 // DrawMeshTextured renders a mesh with texture mapping.
 // Automatically performs frustum culling if the mesh provides bounds.
 func (r *Rasterizer) DrawMeshTextured(mesh MeshRenderer, transform math3d.Mat4, tex *Texture, lightDir math3d.Vec3) {
-	// Frustum culling check
-	if r.tryFrustumCull(mesh, transform) {
-		return
-	}
-	for i := range mesh.TriangleCount() {
+	r.forEachVisibleFace(mesh, transform, func(i int) {
 		face := mesh.GetFace(i)
 		tri := buildTexturedTriangle(mesh, face, transform)
 		r.DrawTriangleTextured(tri, tex, lightDir)
-	}
+	})
 }
 
 // DrawMeshGouraud renders a mesh with Gouraud shading (per-vertex lighting).
 // This produces smoother shading than flat shading by interpolating lighting across triangles.
 // Automatically performs frustum culling if the mesh provides bounds.
 func (r *Rasterizer) DrawMeshGouraud(mesh MeshRenderer, transform math3d.Mat4, color Color, lightDir math3d.Vec3) {
-	// Frustum culling check
-	if r.tryFrustumCull(mesh, transform) {
-		return
-	}
-	for i := range mesh.TriangleCount() {
+	r.forEachVisibleFace(mesh, transform, func(i int) {
 		face := mesh.GetFace(i)
 		tri := buildGouraudTriangle(mesh, face, transform, color)
 		r.DrawTriangleGouraud(tri, lightDir)
-	}
+	})
 }
 
 // DrawMeshTexturedGouraud renders a mesh with texture mapping and Gouraud shading.
 // Combines perspective-correct texture mapping with smooth per-vertex lighting.
 // Automatically performs frustum culling if the mesh provides bounds.
 func (r *Rasterizer) DrawMeshTexturedGouraud(mesh MeshRenderer, transform math3d.Mat4, tex *Texture, lightDir math3d.Vec3) {
-	// Frustum culling check
-	if r.tryFrustumCull(mesh, transform) {
-		return
-	}
-	for i := range mesh.TriangleCount() {
+	r.forEachVisibleFace(mesh, transform, func(i int) {
 		face := mesh.GetFace(i)
 		tri := buildTexturedTriangle(mesh, face, transform)
 		r.DrawTriangleTexturedGouraud(tri, tex, lightDir)
-	}
+	})
 }
 
 // DrawMeshGouraudCulled renders a mesh with Gouraud shading, with frustum culling.
@@ -828,11 +886,7 @@ func (r *Rasterizer) DrawMeshTexturedCulled(
 // DrawMeshWireframe renders a mesh as wireframe.
 // Automatically performs frustum culling if the mesh provides bounds.
 func (r *Rasterizer) DrawMeshWireframe(mesh MeshRenderer, transform math3d.Mat4, color Color) {
-	// Frustum culling check
-	if r.tryFrustumCull(mesh, transform) {
-		return
-	}
-	for i := range mesh.TriangleCount() {
+	r.forEachVisibleFace(mesh, transform, func(i int) {
 		face := mesh.GetFace(i)
 		p0, _, _ := mesh.GetVertex(face[0])
 		p1, _, _ := mesh.GetVertex(face[1])
@@ -844,11 +898,16 @@ func (r *Rasterizer) DrawMeshWireframe(mesh MeshRenderer, transform math3d.Mat4,
 		r.drawLine3D(v0, v1, color)
 		r.drawLine3D(v1, v2, color)
 		r.drawLine3D(v2, v0, color)
-	}
+	})
 }
 
 // drawLine3D draws a 3D line (projected to screen).
+// The line is clipped against the user clip planes first.
 func (r *Rasterizer) drawLine3D(a, b math3d.Vec3, color Color) {
+	a, b, ok := r.clipSegment(a, b)
+	if !ok {
+		return
+	}
 	viewProj := r.camera.ViewProjectionMatrix()
 	// Transform to clip space
 	clipA := viewProj.MulVec4(math3d.V4FromV3(a, 1))
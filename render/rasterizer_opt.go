@@ -31,6 +31,17 @@ func edgeFunc(A, B, C, x, y float64) float64 {
 
 // DrawTriangleGouraudOpt is an optimized version using edge functions with incremental updates.
 func (r *Rasterizer) DrawTriangleGouraudOpt(tri Triangle, lightDir math3d.Vec3) {
+	r.drawTriangleGouraudOpt(tri, lightDir, nil)
+}
+
+// drawTriangleGouraudOpt draws a Gouraud shaded triangle, skipping the
+// lighting and adding the emissive color of surface when set.
+func (r *Rasterizer) drawTriangleGouraudOpt(tri Triangle, lightDir math3d.Vec3, surface *Surface) {
+	// User clip planes: skip fully clipped triangles, remember the cutting ones
+	var clip triangleClip
+	if !r.clipTriangle(&tri, &clip) {
+		return
+	}
 	// Transform vertices to screen space
 	var sv [3]screenVertex
 	allBehind := true
@@ -52,13 +63,12 @@ func (r *Rasterizer) DrawTriangleGouraudOpt(tri Triangle, lightDir math3d.Vec3)
 		sv[i].X = (sv[i].X + 1) * 0.5 * float64(r.Width())
 		sv[i].Y = (1 - sv[i].Y) * 0.5 * float64(r.Height())
 		// Per-vertex lighting
-		intensity := math.Max(0, tri.V[i].Normal.Dot(normLight))
-		intensity = 0.3 + 0.7*intensity
-		sv[i].Color = RGB(
-			uint8(float64(tri.V[i].Color.R)*intensity),
-			uint8(float64(tri.V[i].Color.G)*intensity),
-			uint8(float64(tri.V[i].Color.B)*intensity),
-		)
+		light := r.vertexLighting(&tri.V[i], normLight, surface != nil && surface.Unlit)
+		sv[i].Color = ScaleColor(tri.V[i].Color, light)
+		sv[i].Color.A = 255
+		if surface != nil {
+			sv[i].Color = AddColor(sv[i].Color, surface.Emissive)
+		}
 	}
 	if allBehind {
 		return
@@ -69,9 +79,17 @@ func (r *Rasterizer) DrawTriangleGouraudOpt(tri Triangle, lightDir math3d.Vec3)
 	edge2X := sv[2].X - sv[0].X
 	edge2Y := sv[2].Y - sv[0].Y
 	cross := edge1X*edge2Y - edge1Y*edge2X
-	if cross < 0 && !r.DisableBackfaceCulling {
+	backFacing := cross < 0
+	capping := r.capping()
+	if backFacing && !capping && !r.DisableBackfaceCulling {
 		return
 	}
+	if backFacing && capping {
+		// Back faces seen through the cut are the inside of the solid
+		for i := range 3 {
+			sv[i].Color = r.ClipCapColor
+		}
+	}
 	// Bounding box (clamped to screen)
 	minX := int(math.Max(0, math.Floor(min3(sv[0].X, sv[1].X, sv[2].X))))
 	maxX := int(math.Min(float64(r.Width()-1), math.Ceil(max3(sv[0].X, sv[1].X, sv[2].X))))
@@ -90,7 +108,13 @@ func (r *Rasterizer) DrawTriangleGouraudOpt(tri Triangle, lightDir math3d.Vec3)
 	if area2 == 0 {
 		return
 	}
+	if backFacing && capping {
+		// Flip edge functions so the inside of a section cap is positive too
+		A0, B0, C0, A1, B1, C1, A2, B2, C2 = -A0, -B0, -C0, -A1, -B1, -C1, -A2, -B2, -C2
+		area2 = -area2
+	}
 	invArea := 1.0 / area2
+	clip.setW(&sv)
 	// Pre-compute depth deltas
 	dZ0 := sv[0].Z
 	dZ1 := sv[1].Z
@@ -125,7 +149,7 @@ func (r *Rasterizer) DrawTriangleGouraudOpt(tri Triangle, lightDir math3d.Vec3)
 				z := bc0*dZ0 + bc1*dZ1 + bc2*dZ2
 				// Z-buffer test (no bounds check - we're within clamped bounds)
 				idx := rowOffset + x
-				if z < zbuffer[idx] {
+				if z < zbuffer[idx] && (clip.planes == 0 || !clip.discard(bc0, bc1, bc2)) {
 					// Interpolate color
 					cr := uint8(r0*bc0 + r1*bc1 + r2*bc2)
 					cg := uint8(g0*bc0 + g1*bc1 + g2*bc2)
@@ -148,20 +172,29 @@ func (r *Rasterizer) DrawTriangleGouraudOpt(tri Triangle, lightDir math3d.Vec3)
 
 // DrawMeshGouraudOpt renders a mesh with optimized Gouraud shading.
 func (r *Rasterizer) DrawMeshGouraudOpt(mesh MeshRenderer, transform math3d.Mat4, color Color, lightDir math3d.Vec3) {
-	if r.tryFrustumCull(mesh, transform) {
-		return
-	}
-	for i := range mesh.TriangleCount() {
+	r.forEachVisibleFace(mesh, transform, func(i int) {
 		face := mesh.GetFace(i)
 		tri := buildGouraudTriangle(mesh, face, transform, color)
 		r.DrawTriangleGouraudOpt(tri, lightDir)
-	}
+	})
 }
 
 // DrawTriangleTexturedOpt is an optimized textured triangle rasterizer with Gouraud shading.
 func (r *Rasterizer) DrawTriangleTexturedOpt(tri Triangle, tex *Texture, lightDir math3d.Vec3) {
+	r.drawTriangleTexturedOpt(tri, tex, lightDir, nil)
+}
+
+// drawTriangleTexturedOpt draws a textured triangle. With a surface the
+// texture is tinted by its color (tex may then be nil to use the color
+// alone), lighting is skipped for unlit surfaces and the emissive color,
+// modulated by the emissive map, is added after lighting.
+func (r *Rasterizer) drawTriangleTexturedOpt(tri Triangle, tex *Texture, lightDir math3d.Vec3, surface *Surface) {
+	var clip triangleClip
+	if !r.clipTriangle(&tri, &clip) {
+		return
+	}
 	var sv [3]screenVertex
-	var vertexIntensity [3]float64
+	var vertexLight [3][3]float64
 	allBehind := true
 	viewProj := r.camera.ViewProjectionMatrix()
 	normLight := lightDir.Normalize()
@@ -181,8 +214,7 @@ func (r *Rasterizer) DrawTriangleTexturedOpt(tri Triangle, tex *Texture, lightDi
 		sv[i].Y = (1 - sv[i].Y) * 0.5 * float64(r.Height())
 		sv[i].UV = tri.V[i].UV
 		// Per-vertex lighting (Gouraud)
-		intensity := math.Max(0, tri.V[i].Normal.Dot(normLight))
-		vertexIntensity[i] = 0.3 + 0.7*intensity
+		vertexLight[i] = r.vertexLighting(&tri.V[i], normLight, surface != nil && surface.Unlit)
 	}
 	if allBehind {
 		return
@@ -193,7 +225,18 @@ func (r *Rasterizer) DrawTriangleTexturedOpt(tri Triangle, tex *Texture, lightDi
 	edge2X := sv[2].X - sv[0].X
 	edge2Y := sv[2].Y - sv[0].Y
 	cross := edge1X*edge2Y - edge1Y*edge2X
-	if cross < 0 && !r.DisableBackfaceCulling {
+	backFacing := cross < 0
+	capping := r.capping()
+	if backFacing && !capping && !r.DisableBackfaceCulling {
+		return
+	}
+	if backFacing && capping {
+		// Draw the inside of the solid as a flat section cap instead
+		capTri := tri
+		for i := range 3 {
+			capTri.V[i].Color = r.ClipCapColor
+		}
+		r.DrawTriangleGouraudOpt(capTri, lightDir)
 		return
 	}
 	minX := int(math.Max(0, math.Floor(min3(sv[0].X, sv[1].X, sv[2].X))))
@@ -212,6 +255,13 @@ func (r *Rasterizer) DrawTriangleTexturedOpt(tri Triangle, tex *Texture, lightDi
 		return
 	}
 	invArea := 1.0 / area2
+	clip.setW(&sv)
+	white := RGB(255, 255, 255)
+	tint, emissive := white, Color{}
+	if surface != nil {
+		tint, emissive = surface.Color, surface.Emissive
+	}
+	emits := emissive.R|emissive.G|emissive.B != 0
 	// Perspective-correct interpolation: precompute 1/W
 	var invW [3]float64
 	for i := range 3 {
@@ -239,7 +289,7 @@ func (r *Rasterizer) DrawTriangleTexturedOpt(tri Triangle, tex *Texture, lightDi
 				bc2 := w2 * invArea
 				z := bc0*sv[0].Z + bc1*sv[1].Z + bc2*sv[2].Z
 				idx := rowOffset + x
-				if idx < len(zbuffer) && z < zbuffer[idx] {
+				if idx < len(zbuffer) && z < zbuffer[idx] && (clip.planes == 0 || !clip.discard(bc0, bc1, bc2)) {
 					// Perspective-correct interpolation
 					pw0 := bc0 * invW[0]
 					pw1 := bc1 * invW[1]
@@ -249,10 +299,26 @@ func (r *Rasterizer) DrawTriangleTexturedOpt(tri Triangle, tex *Texture, lightDi
 						invOneOverW := 1.0 / oneOverW
 						u := (pw0*sv[0].UV.X + pw1*sv[1].UV.X + pw2*sv[2].UV.X) * invOneOverW
 						v := (pw0*sv[0].UV.Y + pw1*sv[1].UV.Y + pw2*sv[2].UV.Y) * invOneOverW
-						// Perspective-correct lighting intensity
-						intensity := (pw0*vertexIntensity[0] + pw1*vertexIntensity[1] + pw2*vertexIntensity[2]) * invOneOverW
-						texColor := tex.Sample(u, v)
-						litColor := MultiplyColor(texColor, intensity)
+						// Perspective-correct lighting
+						var light [3]float64
+						for c := range 3 {
+							light[c] = (pw0*vertexLight[0][c] + pw1*vertexLight[1][c] + pw2*vertexLight[2][c]) * invOneOverW
+						}
+						texColor := tint
+						if tex != nil {
+							texColor = tex.Sample(u, v)
+							if tint != white {
+								texColor = ModulateColor(texColor, tint)
+							}
+						}
+						litColor := ScaleColor(texColor, light)
+						if emits {
+							e := emissive
+							if surface.EmissiveMap != nil {
+								e = ModulateColor(surface.EmissiveMap.Sample(u, v), e)
+							}
+							litColor = AddColor(litColor, e)
+						}
 						zbuffer[idx] = z
 						fb.SetPixel(x, y, litColor)
 					}
@@ -270,12 +336,9 @@ func (r *Rasterizer) DrawTriangleTexturedOpt(tri Triangle, tex *Texture, lightDi
 
 // DrawMeshTexturedOpt renders a textured mesh with optimized rasterization.
 func (r *Rasterizer) DrawMeshTexturedOpt(mesh MeshRenderer, transform math3d.Mat4, tex *Texture, lightDir math3d.Vec3) {
-	if r.tryFrustumCull(mesh, transform) {
-		return
-	}
-	for i := range mesh.TriangleCount() {
+	r.forEachVisibleFace(mesh, transform, func(i int) {
 		face := mesh.GetFace(i)
 		tri := buildTexturedTriangle(mesh, face, transform)
 		r.DrawTriangleTexturedOpt(tri, tex, lightDir)
-	}
+	})
 }
@@ -253,6 +253,16 @@ func MultiplyColor(c Color, intensity float64) Color {
 	}
 }
 
+// ScaleColor multiplies each channel of a color by its own factor (colored lighting).
+func ScaleColor(c Color, f [3]float64) Color {
+	return Color{
+		R: uint8(math.Min(255, float64(c.R)*f[0])),
+		G: uint8(math.Min(255, float64(c.G)*f[1])),
+		B: uint8(math.Min(255, float64(c.B)*f[2])),
+		A: c.A,
+	}
+}
+
 // ModulateColor modulates one color by another (texture * vertex color).
 func ModulateColor(a, b Color) Color {
 	//nolint:gosec // G115: multiplication of uint8 values safe, result scaled to 0-255 range
@@ -263,3 +273,13 @@ func ModulateColor(a, b Color) Color {
 		A: uint8((int(a.A) * int(b.A)) / 255),
 	}
 }
+
+// AddColor adds two colors, saturating each channel (for emissive light).
+func AddColor(a, b Color) Color {
+	return Color{
+		R: uint8(min(255, int(a.R)+int(b.R))), //nolint:gosec // clamped to 255
+		G: uint8(min(255, int(a.G)+int(b.G))), //nolint:gosec // clamped to 255
+		B: uint8(min(255, int(a.B)+int(b.B))), //nolint:gosec // clamped to 255
+		A: a.A,
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"fortio.org/log"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// stdinPath is the model argument reading the model from stdin.
+	stdinPath = "-"
+	// stdinName is the name of the stdin model in its filesystem, without
+	// extension so that its format is sniffed.
+	stdinName = "stdin"
+	// archiveSeparator separates a zip archive from the model path in it.
+	archiveSeparator = ".zip:"
+	// maxModelSize bounds the size of models read in memory (stdin and
+	// decompressed streams).
+	maxModelSize = 1 << 30
+)
+
+// Magic bytes of the compressed streams.
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// readStdin reads a model from stdin, then gives stdin back to the terminal
+// so the viewer still gets keyboard and mouse events.
+func readStdin() (fs.FS, string, error) {
+	data, err := readAllLimited(os.Stdin)
+	if err != nil {
+		return nil, "", fmt.Errorf("read stdin: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, "", errors.New("no model on stdin")
+	}
+	ttyName := "/dev/tty"
+	if runtime.GOOS == "windows" {
+		ttyName = "CONIN$"
+	}
+	if tty, err := os.OpenFile(ttyName, os.O_RDWR, 0); err == nil {
+		os.Stdin = tty
+	}
+	return &memFS{name: stdinName, data: data, modTime: time.Now()}, stdinName, nil
+}
+
+// splitArchivePath splits "archive.zip:path/in/archive" model arguments.
+func splitArchivePath(modelPath string) (archive, member string, ok bool) {
+	i := strings.Index(strings.ToLower(modelPath), archiveSeparator)
+	if i < 0 || i+len(archiveSeparator) == len(modelPath) {
+		return "", "", false
+	}
+	sep := i + len(archiveSeparator)
+	return modelPath[:sep-1], modelPath[sep:], true
+}
+
+// openArchive opens a zip archive as a filesystem, for a model and the
+// textures and buffers next to it. The archive stays open until exit.
+func openArchive(archive, member string) (fs.FS, string, error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, "", fmt.Errorf("open archive %s: %w", archive, err)
+	}
+	member = path.Clean(strings.TrimPrefix(filepath.ToSlash(member), "/"))
+	if info, err := fs.Stat(zr, member); err != nil || info.IsDir() {
+		zr.Close()
+		return nil, "", fmt.Errorf("file not found in archive %s: %s", archive, member)
+	}
+	return zr, member, nil
+}
+
+// localFilesystem returns the filesystem and path of a local file, rooted at
+// the current directory for relative paths and at the volume root for
+// absolute ones.
+func localFilesystem(modelPath string) (fs.FS, string) {
+	cleanPath := filepath.Clean(modelPath)
+	if !filepath.IsAbs(cleanPath) {
+		return os.DirFS("."), filepath.ToSlash(cleanPath)
+	}
+	root := filepath.VolumeName(cleanPath) + string(filepath.Separator)
+	rel, err := filepath.Rel(root, cleanPath)
+	if err != nil {
+		return os.DirFS("."), filepath.ToSlash(cleanPath)
+	}
+	return os.DirFS(root), filepath.ToSlash(rel)
+}
+
+// decompress returns the filesystem and path of a model with its gzip or
+// zstd stream decompressed, detected by magic bytes: the decompressed model
+// replaces the compressed file, without its .gz or .zst extension, and its
+// siblings stay reachable. Uncompressed models are returned as is.
+func decompress(fsys fs.FS, name string) (fs.FS, string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	head := make([]byte, len(zstdMagic))
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, "", fmt.Errorf("read %s: %w", name, err)
+	}
+	head = head[:n]
+	var r io.Reader
+	stream := io.MultiReader(bytes.NewReader(head), f)
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		gz, err := gzip.NewReader(stream)
+		if err != nil {
+			return nil, "", fmt.Errorf("gzip %s: %w", name, err)
+		}
+		defer gz.Close()
+		r = gz
+	case bytes.HasPrefix(head, zstdMagic):
+		zr, err := zstd.NewReader(stream)
+		if err != nil {
+			return nil, "", fmt.Errorf("zstd %s: %w", name, err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return fsys, name, nil
+	}
+	data, err := readAllLimited(r)
+	if err != nil {
+		return nil, "", fmt.Errorf("decompress %s: %w", name, err)
+	}
+	inner := name
+	for _, ext := range []string{".gz", ".zst"} {
+		if len(name) > len(ext) && strings.EqualFold(name[len(name)-len(ext):], ext) {
+			inner = name[:len(name)-len(ext)]
+		}
+	}
+	log.Infof("Decompressed %s: %d bytes", name, len(data))
+	return &memFS{FS: fsys, name: inner, data: data, modTime: time.Now()}, inner, nil
+}
+
+// readAllLimited reads r to the end, up to maxModelSize bytes.
+func readAllLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxModelSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxModelSize {
+		return nil, fmt.Errorf("model larger than %d bytes", maxModelSize)
+	}
+	return data, nil
+}
+
+// memFS is a filesystem serving one in memory file, and the files of the
+// optional underlying filesystem for every other name.
+type memFS struct {
+	fs.FS
+	name    string
+	data    []byte
+	modTime time.Time
+}
+
+// Open implements fs.FS.
+func (m *memFS) Open(name string) (fs.File, error) {
+	if name == m.name {
+		return &memFile{Reader: bytes.NewReader(m.data), fs: m}, nil
+	}
+	if m.FS == nil || !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return m.FS.Open(name)
+}
+
+// memFile is an open memFS file. The memFS is its fs.FileInfo.
+type memFile struct {
+	*bytes.Reader
+	fs *memFS
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.fs, nil }
+func (f *memFile) Close() error               { return nil }
+
+func (m *memFS) Name() string       { return path.Base(m.name) }
+func (m *memFS) Size() int64        { return int64(len(m.data)) }
+func (m *memFS) Mode() fs.FileMode  { return 0o444 }
+func (m *memFS) ModTime() time.Time { return m.modTime }
+func (m *memFS) IsDir() bool        { return false }
+func (m *memFS) Sys() any           { return nil }
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestSplitArchivePath(t *testing.T) {
+	tests := []struct {
+		path, archive, member string
+		ok                    bool
+	}{
+		{"models.zip:cube.stl", "models.zip", "cube.stl", true},
+		{"dir/Models.ZIP:parts/cube.stl", "dir/Models.ZIP", "parts/cube.stl", true},
+		{`C:\models\kit.zip:parts\cube.stl`, `C:\models\kit.zip`, `parts\cube.stl`, true},
+		{"a.zip:b.zip:cube.stl", "a.zip", "b.zip:cube.stl", true},
+		{"models.zip", "", "", false},
+		{"models.zip:", "", "", false},
+		{"backup.zip/cube.stl", "", "", false},
+		{"cube.zipped.stl", "", "", false},
+		{"old.zip.d/cube.stl", "", "", false},
+		{"res:cube.zip.stl", "", "", false},
+		{"cube.stl", "", "", false},
+	}
+	for _, tt := range tests {
+		archive, member, ok := splitArchivePath(tt.path)
+		if archive != tt.archive || member != tt.member || ok != tt.ok {
+			t.Errorf("splitArchivePath(%q) = %q, %q, %v, want %q, %q, %v",
+				tt.path, archive, member, ok, tt.archive, tt.member, tt.ok)
+		}
+	}
+}
+
+// teapotSTL returns the embedded teapot model.
+func teapotSTL(t *testing.T) []byte {
+	t.Helper()
+	data, err := fs.ReadFile(docsFS, "teapot.stl")
+	if err != nil {
+		t.Fatalf("read teapot: %v", err)
+	}
+	return data
+}
+
+func TestDecompress(t *testing.T) {
+	model := teapotSTL(t)
+	var gz, zst bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	if _, err := gw.Write(model); err != nil || gw.Close() != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	zw, err := zstd.NewWriter(&zst)
+	if err != nil {
+		t.Fatalf("zstd: %v", err)
+	}
+	if _, err := zw.Write(model); err != nil || zw.Close() != nil {
+		t.Fatalf("zstd: %v", err)
+	}
+	fsys := fstest.MapFS{
+		"parts/teapot.stl.gz":  {Data: gz.Bytes()},
+		"parts/teapot.STL.ZST": {Data: zst.Bytes()},
+		"parts/packed":         {Data: gz.Bytes()},
+		"parts/teapot.stl":     {Data: model},
+		"parts/texture.png":    {Data: []byte("sibling")},
+	}
+	tests := []struct {
+		name, want string
+	}{
+		{"parts/teapot.stl.gz", "parts/teapot.stl"},
+		{"parts/teapot.STL.ZST", "parts/teapot.STL"},
+		{"parts/packed", "parts/packed"},
+		{"parts/teapot.stl", "parts/teapot.stl"},
+	}
+	for _, tt := range tests {
+		got, name, err := decompress(fsys, tt.name)
+		if err != nil || name != tt.want {
+			t.Errorf("decompress(%s) = %q, %v, want %q", tt.name, name, err, tt.want)
+			continue
+		}
+		data, err := fs.ReadFile(got, name)
+		if err != nil || !bytes.Equal(data, model) {
+			t.Errorf("decompress(%s): read %d bytes, %v, want the %d bytes of the model", tt.name, len(data), err, len(model))
+		}
+		if info, err := fs.Stat(got, name); err != nil || info.Size() != int64(len(model)) {
+			t.Errorf("decompress(%s): stat %v, %v", tt.name, info, err)
+		}
+		if sibling, err := fs.ReadFile(got, "parts/texture.png"); err != nil || string(sibling) != "sibling" {
+			t.Errorf("decompress(%s): sibling %q, %v", tt.name, sibling, err)
+		}
+	}
+	bad := fstest.MapFS{"broken.gz": {Data: gz.Bytes()[:len(gz.Bytes())/2]}}
+	if _, _, err := decompress(bad, "broken.gz"); err == nil {
+		t.Error("decompress of a truncated gzip stream did not fail")
+	}
+}
+
+func TestOpenArchive(t *testing.T) {
+	model := teapotSTL(t)
+	archive := filepath.Join(t.TempDir(), "kit.zip")
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range map[string][]byte{"parts/teapot.stl": model, "parts/texture.png": []byte("sibling")} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip: %v", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("zip: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+	if err := os.WriteFile(archive, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, member := range []string{"parts/teapot.stl", "/parts/teapot.stl", "parts/../parts/teapot.stl"} {
+		fsys, name, err := openArchive(archive, member)
+		if err != nil || name != "parts/teapot.stl" {
+			t.Errorf("openArchive(%s) = %q, %v", member, name, err)
+			continue
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil || !bytes.Equal(data, model) {
+			t.Errorf("openArchive(%s): read %d bytes, %v", member, len(data), err)
+		}
+		if _, err := fs.Stat(fsys, "parts/texture.png"); err != nil {
+			t.Errorf("openArchive(%s): sibling: %v", member, err)
+		}
+		if c, ok := fsys.(io.Closer); ok {
+			c.Close()
+		}
+	}
+	for _, member := range []string{"parts/missing.stl", "parts"} {
+		if _, _, err := openArchive(archive, member); err == nil {
+			t.Errorf("openArchive(%s) did not fail", member)
+		}
+	}
+	// Through the model argument, as the viewer and subcommands resolve it
+	fsys, name, err := selectFilesystem(archive + ":parts/teapot.stl")
+	if err != nil {
+		t.Fatalf("selectFilesystem: %v", err)
+	}
+	mesh, err := loadMesh(fsys, name, 0)
+	if err != nil || mesh.TriangleCount() != 9438 {
+		t.Errorf("loadMesh from the archive = %v, %v", mesh, err)
+	}
+}
+
+func TestReadStdin(t *testing.T) {
+	model := teapotSTL(t)
+	saved := os.Stdin
+	t.Cleanup(func() { os.Stdin = saved })
+	for _, data := range [][]byte{model, nil} {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			w.Write(data)
+			w.Close()
+		}()
+		os.Stdin = r
+		fsys, name, err := readStdin()
+		r.Close()
+		if data == nil {
+			if err == nil {
+				t.Error("readStdin of an empty stream did not fail")
+			}
+			continue
+		}
+		if err != nil || name != stdinName {
+			t.Fatalf("readStdin() = %q, %v", name, err)
+		}
+		mesh, err := loadMesh(fsys, name, 0)
+		if err != nil || mesh.TriangleCount() != 9438 {
+			t.Errorf("loadMesh from stdin = %v, %v", mesh, err)
+		}
+	}
+}